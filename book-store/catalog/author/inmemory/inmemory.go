@@ -3,6 +3,7 @@ package inmemory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -61,8 +62,9 @@ func (r *Repository) Create(ctx context.Context, dto author.CreateDTO) (book.Aut
 	defer r.lock.Unlock()
 
 	a := book.Author{
-		ID:   uuid.New(),
-		Name: dto.Name,
+		ID:      uuid.New(),
+		Name:    dto.Name,
+		Version: 1,
 	}
 	stored := author.StoredAuthor{
 		Author: a,
@@ -86,9 +88,15 @@ func (r *Repository) Update(ctx context.Context, dto book.Author) (book.Author,
 			if item.IsDeleted() {
 				return book.Author{}, &commonerrors.NotFound{What: fmt.Sprintf("Author with ID %s", dto.ID)}
 			}
+			if dto.Version != 0 && dto.Version != item.Version {
+				return book.Author{}, &commonerrors.VersionMismatch{
+					What: fmt.Sprintf("Author with ID %s", dto.ID), Expected: dto.Version, Actual: item.Version,
+				}
+			}
 			a := book.Author{
-				ID:   dto.ID,
-				Name: dto.Name,
+				ID:      dto.ID,
+				Name:    dto.Name,
+				Version: item.Version + 1,
 			}
 			r.data[i] = author.StoredAuthor{
 				Author: a,
@@ -115,8 +123,9 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Author, err
 				return book.Author{}, &commonerrors.NotFound{What: fmt.Sprintf("Author with ID %s", id)}
 			}
 			a := book.Author{
-				ID:   id,
-				Name: item.Name,
+				ID:      id,
+				Name:    item.Name,
+				Version: item.Version + 1,
 			}
 			r.data[i] = author.StoredAuthor{
 				Author: a,
@@ -131,3 +140,60 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Author, err
 	}
 	return book.Author{}, &commonerrors.NotFound{What: fmt.Sprintf("Author with ID %s", id)}
 }
+
+// GetDeleted gets deleted items from in-memory repository, the latest deleted first
+func (r *Repository) GetDeleted(ctx context.Context, from, count uint) ([]author.StoredAuthor, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	deleted := make([]author.StoredAuthor, 0)
+	for _, item := range r.data {
+		if item.IsDeleted() {
+			deleted = append(deleted, item)
+		}
+	}
+	sort.SliceStable(deleted, func(i, j int) bool {
+		return deleted[i].DeletedAt.After(deleted[j].DeletedAt)
+	})
+	if from >= uint(len(deleted)) {
+		return []author.StoredAuthor{}, nil
+	}
+	deleted = deleted[from:]
+	if count != 0 && count < uint(len(deleted)) {
+		deleted = deleted[:count]
+	}
+	return deleted, nil
+}
+
+// Restore undeletes item in in-memory repository
+func (r *Repository) Restore(ctx context.Context, id uuid.UUID) (book.Author, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, item := range r.data {
+		if item.ID == id && item.IsDeleted() {
+			item.Version++
+			item.UpdatedAt = time.Now()
+			item.DeletedAt = time.Time{}
+			r.data[i] = item
+			return item.ToAuthor(), nil
+		}
+	}
+	return book.Author{}, &commonerrors.NotFound{What: fmt.Sprintf("Deleted author with ID %s", id)}
+}
+
+// Purge removes items deleted before given time from in-memory repository
+func (r *Repository) Purge(ctx context.Context, before time.Time) (uint64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	kept := r.data[:0]
+	for _, item := range r.data {
+		if !item.IsDeleted() || !item.DeletedAt.Before(before) {
+			kept = append(kept, item)
+		}
+	}
+	purged := uint64(len(r.data) - len(kept))
+	r.data = kept
+	return purged, nil
+}
@@ -44,6 +44,10 @@ func TestUpdateWithSomeDeleted(t *testing.T) {
 	tests.RepoUpdateWithSomeDeleted(t, constructor)
 }
 
+func TestUpdateVersion(t *testing.T) {
+	tests.RepoUpdateVersion(t, constructor)
+}
+
 func TestDelete(t *testing.T) {
 	tests.RepoDelete(t, constructor)
 }
@@ -55,3 +59,15 @@ func TestDeleteTwice(t *testing.T) {
 func TestDeleteNonExisting(t *testing.T) {
 	tests.RepoDeleteNonExisting(t, constructor)
 }
+
+func TestGetDeleted(t *testing.T) {
+	tests.RepoGetDeleted(t, constructor)
+}
+
+func TestRestore(t *testing.T) {
+	tests.RepoRestore(t, constructor)
+}
+
+func TestPurge(t *testing.T) {
+	tests.RepoPurge(t, constructor)
+}
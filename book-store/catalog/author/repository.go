@@ -2,6 +2,7 @@ package author
 
 import (
 	"context"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/stored"
@@ -24,14 +25,22 @@ type Repository interface {
 	GetAll(ctx context.Context) ([]book.Author, error)
 	Get(ctx context.Context, id uuid.UUID) (book.Author, error)
 	Create(ctx context.Context, dto CreateDTO) (book.Author, error)
+	// Update updates author, returns *commonerrors.VersionMismatch if version of dto is set and does not match stored one
 	Update(ctx context.Context, dto book.Author) (book.Author, error)
 	Delete(ctx context.Context, id uuid.UUID) (book.Author, error)
+	// GetDeleted gets count deleted authors from some number, the latest deleted first, all of them if count is 0
+	GetDeleted(ctx context.Context, from, count uint) ([]StoredAuthor, error)
+	// Restore undeletes author, returns *commonerrors.NotFound if there is no deleted author with id
+	Restore(ctx context.Context, id uuid.UUID) (book.Author, error)
+	// Purge permanently removes authors deleted before given time, authors of stored books are kept
+	Purge(ctx context.Context, before time.Time) (uint64, error)
 }
 
 // ToAuthor converts stored version to actual entity
 func (s StoredAuthor) ToAuthor() book.Author {
 	return book.Author{
-		ID:   s.ID,
-		Name: s.Name,
+		ID:      s.ID,
+		Name:    s.Name,
+		Version: s.Version,
 	}
 }
@@ -2,8 +2,11 @@ package authorservice
 
 import (
 	"context"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/catalog/author"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
@@ -11,12 +14,20 @@ import (
 
 // Service handles authors manipulation
 type Service struct {
-	repo author.Repository
+	repo      author.Repository
+	publisher event.Publisher
+	audit     *audit.Recorder
 }
 
 // New creates new instance of Service
-func New(r author.Repository) *Service {
-	return &Service{r}
+func New(r author.Repository, p event.Publisher) *Service {
+	return &Service{repo: r, publisher: p}
+}
+
+// UseAudit makes service record every change of authors with given recorder
+// Entries are saved in transactions of changes, so change fails if its entry can not be saved.
+func (s *Service) UseAudit(r *audit.Recorder) {
+	s.audit = r
 }
 
 // GetAuthor reads stored author by id
@@ -30,5 +41,88 @@ func (s *Service) CreateAuthor(ctx context.Context, name string) (book.Author, e
 	if name == "" {
 		return empty, &commonerrors.InvalidInput{Reason: "name is required"}
 	}
-	return s.repo.Create(ctx, author.CreateDTO{Name: name})
+	var a book.Author
+	err := s.publisher.Publish(ctx, event.Author, event.Created, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if a, err = s.repo.Create(ctx, author.CreateDTO{Name: name}); err != nil {
+			return
+		}
+		return a.ID, s.audit.Record(ctx, event.Author.String(), a.ID.String(), audit.Create, nil, a)
+	})
+	if err != nil {
+		return empty, err
+	}
+	return a, nil
+}
+
+// UpdateAuthor validates data, updates author if data is valid and author is found, returns error otherwise.
+// Non-zero version must match current version of author.
+func (s *Service) UpdateAuthor(ctx context.Context, id uuid.UUID, version uint64, name string) (book.Author, error) {
+	var empty book.Author
+	if name == "" {
+		return empty, &commonerrors.InvalidInput{Reason: "name is required"}
+	}
+	before, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return empty, err
+	}
+	var a book.Author
+	err = s.publisher.Publish(ctx, event.Author, event.Updated, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if a, err = s.repo.Update(ctx, book.Author{ID: id, Name: name, Version: version}); err != nil {
+			return
+		}
+		return a.ID, s.audit.Record(ctx, event.Author.String(), a.ID.String(), audit.Update, before, a)
+	})
+	if err != nil {
+		return empty, err
+	}
+	return a, nil
+}
+
+// DeleteAuthor deletes stored author by id
+func (s *Service) DeleteAuthor(ctx context.Context, id uuid.UUID) (book.Author, error) {
+	var a book.Author
+	err := s.publisher.Publish(ctx, event.Author, event.Deleted, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if a, err = s.repo.Delete(ctx, id); err != nil {
+			return
+		}
+		return a.ID, s.audit.Record(ctx, event.Author.String(), a.ID.String(), audit.Delete, a, nil)
+	})
+	if err != nil {
+		return book.Author{}, err
+	}
+	return a, nil
+}
+
+// GetDeletedAuthors fetches count deleted authors from some number, the latest deleted first
+func (s *Service) GetDeletedAuthors(ctx context.Context, from, count uint) ([]author.StoredAuthor, error) {
+	if count == 0 {
+		count = 10
+	}
+	return s.repo.GetDeleted(ctx, from, count)
+}
+
+// RestoreAuthor undeletes stored author by id
+func (s *Service) RestoreAuthor(ctx context.Context, id uuid.UUID) (book.Author, error) {
+	var a book.Author
+	err := s.publisher.Publish(ctx, event.Author, event.Restored, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if a, err = s.repo.Restore(ctx, id); err != nil {
+			return
+		}
+		return a.ID, s.audit.Record(ctx, event.Author.String(), a.ID.String(), audit.Restore, nil, a)
+	})
+	if err != nil {
+		return book.Author{}, err
+	}
+	return a, nil
+}
+
+// PurgeAuthors permanently removes authors deleted before given time, returns number of removed ones
+func (s *Service) PurgeAuthors(ctx context.Context, before time.Time) (n uint64, err error) {
+	err = s.audit.InTransaction(ctx, func(ctx context.Context) (err error) {
+		if n, err = s.repo.Purge(ctx, before); err != nil || n == 0 {
+			return
+		}
+		return s.audit.Record(ctx, event.Author.String(), "", audit.Purge, nil, audit.Purged{DeletedBefore: before, Count: n})
+	})
+	return
 }
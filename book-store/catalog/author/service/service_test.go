@@ -6,6 +6,8 @@ import (
 
 	"github.com/Vesninovich/go-tasks/book-store/catalog/author/inmemory"
 	authorservice "github.com/Vesninovich/go-tasks/book-store/catalog/author/service"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	eventInMemory "github.com/Vesninovich/go-tasks/book-store/catalog/event/inmemory"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
 )
 
@@ -28,6 +30,30 @@ func TestCreateWithEmptyName(t *testing.T) {
 	}
 }
 
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	s := createService()
+	created, err := s.CreateAuthor(ctx, "test")
+	if err != nil {
+		t.Fatalf("Got error while creating valid author: %s", err)
+	}
+	if _, err = s.DeleteAuthor(ctx, created.ID); err != nil {
+		t.Fatalf("Got error while deleting author: %s", err)
+	}
+	if deleted, _ := s.GetDeletedAuthors(ctx, 0, 0); len(deleted) != 1 || deleted[0].ID != created.ID {
+		t.Errorf("Expected to get deleted author, got %v", deleted)
+	}
+	if _, err = s.RestoreAuthor(ctx, created.ID); err != nil {
+		t.Fatalf("Got error while restoring author: %s", err)
+	}
+	if _, err = s.GetAuthor(ctx, created.ID); err != nil {
+		t.Errorf("Got error while getting restored author: %s", err)
+	}
+	if _, err = s.RestoreAuthor(ctx, created.ID); err == nil {
+		t.Error("Expected to get error while restoring not deleted author")
+	}
+}
+
 func createService() *authorservice.Service {
-	return authorservice.New(inmemory.New())
+	return authorservice.New(inmemory.New(), event.NewBus(eventInMemory.New()))
 }
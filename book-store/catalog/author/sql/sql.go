@@ -9,6 +9,8 @@ import (
 	"github.com/Vesninovich/go-tasks/book-store/catalog/author"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/sqltx"
+	"github.com/Vesninovich/go-tasks/book-store/common/stored"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 	"github.com/jmoiron/sqlx"
 )
@@ -20,8 +22,16 @@ type Repository struct {
 }
 
 type fromDB struct {
-	ID   string
-	Name string
+	ID      string
+	Name    string
+	Version uint64
+}
+
+type deletedFromDB struct {
+	fromDB
+	CreatedAt sql.NullTime `db:"created_at"`
+	UpdatedAt sql.NullTime `db:"updated_at"`
+	DeletedAt time.Time    `db:"deleted_at"`
 }
 
 // New creates a new instance of SQLRepository
@@ -31,19 +41,22 @@ func New(db *sqlx.DB, schema string) *Repository {
 
 // CreateTableStmt of authors
 func (r *Repository) CreateTableStmt() string {
-	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.authors(
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s.authors(
   id uuid PRIMARY KEY,
   name text NOT NULL,
   created_at timestamp,
   updated_at timestamp,
-  deleted_at timestamp
-);`, r.schema)
+  deleted_at timestamp,
+  version bigint NOT NULL DEFAULT 1
+);
+
+ALTER TABLE %[1]s.authors ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;`, r.schema)
 }
 
 // GetAll gets all non-deleted authors
 func (r *Repository) GetAll(ctx context.Context) (authors []book.Author, err error) {
 	data := []fromDB{}
-	err = r.db.SelectContext(ctx, &data, fmt.Sprintf("SELECT id, name FROM %s.authors WHERE deleted_at=$1;", r.schema), time.Time{})
+	err = sqltx.DB(ctx, r.db).SelectContext(ctx, &data, fmt.Sprintf("SELECT id, name, version FROM %s.authors WHERE deleted_at=$1;", r.schema), time.Time{})
 	if err != nil {
 		return
 	}
@@ -55,8 +68,9 @@ func (r *Repository) GetAll(ctx context.Context) (authors []book.Author, err err
 			return
 		}
 		authors[i] = book.Author{
-			ID:   id,
-			Name: item.Name,
+			ID:      id,
+			Name:    item.Name,
+			Version: item.Version,
 		}
 	}
 	return
@@ -65,8 +79,8 @@ func (r *Repository) GetAll(ctx context.Context) (authors []book.Author, err err
 // Get gets non-deleted author by ID
 func (r *Repository) Get(ctx context.Context, id uuid.UUID) (book.Author, error) {
 	a := fromDB{}
-	err := r.db.GetContext(
-		ctx, &a, fmt.Sprintf("SELECT id, name FROM %s.authors WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
+	err := sqltx.DB(ctx, r.db).GetContext(
+		ctx, &a, fmt.Sprintf("SELECT id, name, version FROM %s.authors WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
 	)
 	if err == sql.ErrNoRows {
 		return book.Author{}, &commonerrors.NotFound{What: fmt.Sprintf("Author with ID %s", id)}
@@ -75,61 +89,75 @@ func (r *Repository) Get(ctx context.Context, id uuid.UUID) (book.Author, error)
 		return book.Author{}, nil
 	}
 	foundID, err := uuid.FromString(a.ID)
-	return book.Author{ID: foundID, Name: a.Name}, err
+	return book.Author{ID: foundID, Name: a.Name, Version: a.Version}, err
 }
 
 // Create stores new author
 func (r *Repository) Create(ctx context.Context, dto author.CreateDTO) (book.Author, error) {
 	id := uuid.New()
-	_, err := r.db.ExecContext(
+	_, err := sqltx.DB(ctx, r.db).ExecContext(
 		ctx,
 		fmt.Sprintf(`INSERT INTO %s.authors (id, name, created_at, updated_at, deleted_at)
 			VALUES ($1, $2, $3, $4, $5)`, r.schema),
 		id.String(), dto.Name, time.Now(), time.Time{}, time.Time{},
 	)
-	return book.Author{ID: id, Name: dto.Name}, err
+	return book.Author{ID: id, Name: dto.Name, Version: 1}, err
 }
 
-// Update updates stored non-deleted author
+// Update updates stored non-deleted author if it is still at expected version
 func (r *Repository) Update(ctx context.Context, dto book.Author) (book.Author, error) {
-	res, err := r.db.ExecContext(
+	var version uint64
+	err := sqltx.DB(ctx, r.db).QueryRowxContext(
 		ctx,
 		fmt.Sprintf(`UPDATE %s.authors
-			SET name=$3, updated_at=$4
-			WHERE id=$1 AND deleted_at=$2;`, r.schema),
-		dto.ID.String(), time.Time{}, dto.Name, time.Now(),
-	)
-	if err != nil {
-		return book.Author{}, err
+			SET name=$3, updated_at=$4, version=version+1
+			WHERE id=$1 AND deleted_at=$2 AND (version=$5 OR $5=0)
+			RETURNING version;`, r.schema),
+		dto.ID.String(), time.Time{}, dto.Name, time.Now(), dto.Version,
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return book.Author{}, r.updateError(ctx, dto.ID, dto.Version)
 	}
-	count, err := res.RowsAffected()
 	if err != nil {
 		return book.Author{}, err
 	}
-	if count == 0 {
-		return book.Author{}, &commonerrors.NotFound{What: fmt.Sprintf("Author with ID %s", dto.ID)}
+	dto.Version = version
+	return dto, nil
+}
+
+// updateError tells why conditional update of author changed nothing
+func (r *Repository) updateError(ctx context.Context, id uuid.UUID, expected uint64) error {
+	var actual uint64
+	err := sqltx.DB(ctx, r.db).GetContext(
+		ctx, &actual, fmt.Sprintf("SELECT version FROM %s.authors WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
+	)
+	if err == sql.ErrNoRows {
+		return &commonerrors.NotFound{What: fmt.Sprintf("Author with ID %s", id)}
+	}
+	if err != nil {
+		return err
 	}
-	return dto, err
+	return &commonerrors.VersionMismatch{What: fmt.Sprintf("Author with ID %s", id), Expected: expected, Actual: actual}
 }
 
 // Delete sets stored author with id as deleted
 func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Author, error) {
 	var a fromDB
-	err := r.db.QueryRowxContext(
+	err := sqltx.DB(ctx, r.db).QueryRowxContext(
 		ctx,
-		fmt.Sprintf(`SELECT id, name FROM %s.authors WHERE id=$1 AND deleted_at=$2;`, r.schema),
+		fmt.Sprintf(`SELECT id, name, version FROM %s.authors WHERE id=$1 AND deleted_at=$2;`, r.schema),
 		id.String(), time.Time{},
-	).Scan(&a.ID, &a.Name)
+	).Scan(&a.ID, &a.Name, &a.Version)
 	if err == sql.ErrNoRows {
 		return book.Author{}, &commonerrors.NotFound{What: fmt.Sprintf("Author with ID %s", id)}
 	}
 	if err != nil {
 		return book.Author{}, err
 	}
-	res, err := r.db.ExecContext(
+	res, err := sqltx.DB(ctx, r.db).ExecContext(
 		ctx,
 		fmt.Sprintf(`UPDATE %s.authors
-			SET deleted_at=$2
+			SET deleted_at=$2, version=version+1
 			WHERE id=$1 AND deleted_at=$3;`, r.schema),
 		id.String(), time.Now(), time.Time{},
 	)
@@ -143,5 +171,75 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Author, err
 	if count == 0 {
 		return book.Author{}, &commonerrors.NotFound{What: fmt.Sprintf("Author with ID %s", id)}
 	}
-	return book.Author{ID: id, Name: a.Name}, err
+	return book.Author{ID: id, Name: a.Name, Version: a.Version + 1}, err
+}
+
+// GetDeleted gets deleted authors, the latest deleted first
+func (r *Repository) GetDeleted(ctx context.Context, from, count uint) (authors []author.StoredAuthor, err error) {
+	stmt := fmt.Sprintf(`SELECT id, name, version, created_at, updated_at, deleted_at FROM %s.authors
+		WHERE deleted_at<>$1
+		ORDER BY deleted_at DESC
+		OFFSET $2 ROWS`, r.schema)
+	if count != 0 {
+		stmt += fmt.Sprintf(`
+		LIMIT %d`, count)
+	}
+	data := []deletedFromDB{}
+	err = sqltx.DB(ctx, r.db).SelectContext(ctx, &data, stmt, time.Time{}, from)
+	if err != nil {
+		return
+	}
+	var id uuid.UUID
+	authors = make([]author.StoredAuthor, len(data))
+	for i, item := range data {
+		id, err = uuid.FromString(item.ID)
+		if err != nil {
+			return
+		}
+		authors[i] = author.StoredAuthor{
+			Author: book.Author{ID: id, Name: item.Name, Version: item.Version},
+			Stored: stored.Stored{
+				CreatedAt: item.CreatedAt.Time,
+				UpdatedAt: item.UpdatedAt.Time,
+				DeletedAt: item.DeletedAt,
+			},
+		}
+	}
+	return
+}
+
+// Restore undeletes stored author with id
+func (r *Repository) Restore(ctx context.Context, id uuid.UUID) (book.Author, error) {
+	var a fromDB
+	err := sqltx.DB(ctx, r.db).QueryRowxContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %s.authors
+			SET deleted_at=$2, updated_at=$3, version=version+1
+			WHERE id=$1 AND deleted_at<>$2
+			RETURNING id, name, version;`, r.schema),
+		id.String(), time.Time{}, time.Now(),
+	).Scan(&a.ID, &a.Name, &a.Version)
+	if err == sql.ErrNoRows {
+		return book.Author{}, &commonerrors.NotFound{What: fmt.Sprintf("Deleted author with ID %s", id)}
+	}
+	if err != nil {
+		return book.Author{}, err
+	}
+	return book.Author{ID: id, Name: a.Name, Version: a.Version}, nil
+}
+
+// Purge removes authors deleted before given time, authors still referenced by books are kept
+func (r *Repository) Purge(ctx context.Context, before time.Time) (uint64, error) {
+	res, err := sqltx.DB(ctx, r.db).ExecContext(
+		ctx,
+		fmt.Sprintf(`DELETE FROM %[1]s.authors as a
+			WHERE a.deleted_at<>$1 AND a.deleted_at<$2
+			AND NOT EXISTS (SELECT 1 FROM %[1]s.books as b WHERE b.author_id=a.id);`, r.schema),
+		time.Time{}, before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	return uint64(count), err
 }
@@ -3,6 +3,7 @@ package inmemory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -41,12 +42,7 @@ func (r *Repository) Get(ctx context.Context, from, count uint, query book.Query
 				from--
 				continue
 			}
-			res = append(res, book.Book{
-				ID:         item.ID,
-				Name:       item.Name,
-				Author:     item.Author,
-				Categories: item.Categories,
-			})
+			res = append(res, item.ToBook())
 			count--
 		}
 		if count == 0 {
@@ -57,6 +53,24 @@ func (r *Repository) Get(ctx context.Context, from, count uint, query book.Query
 	return res, nil
 }
 
+// GetByIDs fetches non-deleted books with given ids, missing ones are skipped
+func (r *Repository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]book.Book, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	wanted := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		wanted[id] = true
+	}
+	res := make([]book.Book, 0, len(ids))
+	for _, item := range r.data {
+		if !item.IsDeleted() && wanted[item.ID] {
+			res = append(res, item.ToBook())
+		}
+	}
+	return res, nil
+}
+
 func matchesQuery(query book.Query, item bookrepo.StoredBook) bool {
 	if !query.ID.IsZero() && item.ID != query.ID {
 		return false
@@ -92,6 +106,7 @@ func (r *Repository) Create(ctx context.Context, dto bookrepo.CreateDTO) (book.B
 		Name:       dto.Name,
 		Author:     dto.Author,
 		Categories: dto.Categories,
+		Version:    1,
 	}
 	item := bookrepo.StoredBook{
 		Book: b,
@@ -115,11 +130,17 @@ func (r *Repository) Update(ctx context.Context, dto book.Book) (book.Book, erro
 			if item.IsDeleted() {
 				return book.Book{}, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", dto.ID)}
 			}
+			if dto.Version != 0 && dto.Version != item.Version {
+				return book.Book{}, &commonerrors.VersionMismatch{
+					What: fmt.Sprintf("Book with ID %s", dto.ID), Expected: dto.Version, Actual: item.Version,
+				}
+			}
 			b := book.Book{
 				ID:         dto.ID,
 				Name:       dto.Name,
 				Author:     dto.Author,
 				Categories: dto.Categories,
+				Version:    item.Version + 1,
 			}
 			r.data[i] = bookrepo.StoredBook{
 				Book: b,
@@ -150,6 +171,7 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Book, error
 				Name:       item.Name,
 				Author:     item.Author,
 				Categories: item.Categories,
+				Version:    item.Version + 1,
 			}
 			r.data[i] = bookrepo.StoredBook{
 				Book: b,
@@ -164,3 +186,61 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Book, error
 	}
 	return book.Book{}, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", id)}
 }
+
+// GetDeleted gets deleted items from in-memory repository, the latest deleted first
+func (r *Repository) GetDeleted(ctx context.Context, from, count uint) ([]bookrepo.StoredBook, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	deleted := make([]bookrepo.StoredBook, 0)
+	for _, item := range r.data {
+		if item.IsDeleted() {
+			deleted = append(deleted, item)
+		}
+	}
+	sort.SliceStable(deleted, func(i, j int) bool {
+		return deleted[i].DeletedAt.After(deleted[j].DeletedAt)
+	})
+	if from >= uint(len(deleted)) {
+		return []bookrepo.StoredBook{}, nil
+	}
+	deleted = deleted[from:]
+	if count != 0 && count < uint(len(deleted)) {
+		deleted = deleted[:count]
+	}
+	return deleted, nil
+}
+
+// Restore undeletes item in in-memory repository.
+// Authors are not tracked by in-memory repository, so author of restored book is not checked.
+func (r *Repository) Restore(ctx context.Context, id uuid.UUID) (book.Book, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, item := range r.data {
+		if item.ID == id && item.IsDeleted() {
+			item.Version++
+			item.UpdatedAt = time.Now()
+			item.DeletedAt = time.Time{}
+			r.data[i] = item
+			return item.ToBook(), nil
+		}
+	}
+	return book.Book{}, &commonerrors.NotFound{What: fmt.Sprintf("Deleted book with ID %s", id)}
+}
+
+// Purge removes items deleted before given time from in-memory repository
+func (r *Repository) Purge(ctx context.Context, before time.Time) (uint64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	kept := r.data[:0]
+	for _, item := range r.data {
+		if !item.IsDeleted() || !item.DeletedAt.Before(before) {
+			kept = append(kept, item)
+		}
+	}
+	purged := uint64(len(r.data) - len(kept))
+	r.data = kept
+	return purged, nil
+}
@@ -2,6 +2,7 @@ package bookrepo
 
 import (
 	"context"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/stored"
@@ -24,9 +25,19 @@ type StoredBook struct {
 // Repository of Categories
 type Repository interface {
 	Get(ctx context.Context, from, count uint, query book.Query) ([]book.Book, error)
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]book.Book, error)
 	Create(ctx context.Context, dto CreateDTO) (book.Book, error)
+	// Update updates book, returns *commonerrors.VersionMismatch if version of dto is set and does not match stored one
 	Update(ctx context.Context, dto book.Book) (book.Book, error)
+	// Delete marks book as deleted keeping its categories, so they are linked back on restore
 	Delete(ctx context.Context, id uuid.UUID) (book.Book, error)
+	// GetDeleted gets count deleted books from some number, the latest deleted first, all of them if count is 0
+	GetDeleted(ctx context.Context, from, count uint) ([]StoredBook, error)
+	// Restore undeletes book, returns *commonerrors.NotFound if there is no deleted book with id
+	// and *commonerrors.InvalidInput if author of book is deleted
+	Restore(ctx context.Context, id uuid.UUID) (book.Book, error)
+	// Purge permanently removes books deleted before given time
+	Purge(ctx context.Context, before time.Time) (uint64, error)
 }
 
 // ToBook converts stored version to actual entity
@@ -36,5 +47,6 @@ func (s StoredBook) ToBook() book.Book {
 		Name:       s.Name,
 		Author:     s.Author,
 		Categories: s.Categories,
+		Version:    s.Version,
 	}
 }
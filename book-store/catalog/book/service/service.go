@@ -2,12 +2,18 @@ package bookservice
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"time"
 
 	authorservice "github.com/Vesninovich/go-tasks/book-store/catalog/author/service"
 	bookrepo "github.com/Vesninovich/go-tasks/book-store/catalog/book"
 	categoryservice "github.com/Vesninovich/go-tasks/book-store/catalog/category/service"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 )
 
 // BookService is service for interacting with books
@@ -15,17 +21,26 @@ type BookService struct {
 	bookRepo        bookrepo.Repository
 	authorService   *authorservice.Service
 	categoryService *categoryservice.Service
+	publisher       event.Publisher
+	audit           *audit.Recorder
 }
 
 // New creates new BookService
-func New(bookRepo bookrepo.Repository, authorService *authorservice.Service, categoryService *categoryservice.Service) *BookService {
+func New(bookRepo bookrepo.Repository, authorService *authorservice.Service, categoryService *categoryservice.Service, publisher event.Publisher) *BookService {
 	return &BookService{
 		bookRepo:        bookRepo,
 		authorService:   authorService,
 		categoryService: categoryService,
+		publisher:       publisher,
 	}
 }
 
+// UseAudit makes service record every change of books with given recorder
+// Entries are saved in transactions of changes, so change fails if its entry can not be saved.
+func (s *BookService) UseAudit(r *audit.Recorder) {
+	s.audit = r
+}
+
 // GetBooks fetches count saved books from some number according to query
 func (s *BookService) GetBooks(ctx context.Context, from, count uint, query book.Query) ([]book.Book, error) {
 	if count == 0 {
@@ -34,6 +49,23 @@ func (s *BookService) GetBooks(ctx context.Context, from, count uint, query book
 	return s.bookRepo.Get(ctx, from, count, query)
 }
 
+// GetBook fetches saved book by id
+func (s *BookService) GetBook(ctx context.Context, id uuid.UUID) (book.Book, error) {
+	books, err := s.bookRepo.Get(ctx, 0, 1, book.Query{ID: id})
+	if err != nil {
+		return book.Book{}, err
+	}
+	if len(books) == 0 {
+		return book.Book{}, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", id)}
+	}
+	return books[0], nil
+}
+
+// GetBooksByIDs fetches saved books with given ids, ids of missing books are skipped
+func (s *BookService) GetBooksByIDs(ctx context.Context, ids []uuid.UUID) ([]book.Book, error) {
+	return s.bookRepo.GetByIDs(ctx, ids)
+}
+
 // CreateBook saves new book if name is not empty, listed author and all categories exist
 func (s *BookService) CreateBook(ctx context.Context, name string, aut book.Author, cats []book.Category) (book.Book, error) {
 	if name == "" {
@@ -73,9 +105,143 @@ func (s *BookService) CreateBook(ctx context.Context, name string, aut book.Auth
 			}
 		}
 	}
-	return s.bookRepo.Create(ctx, bookrepo.CreateDTO{
-		Name:       name,
-		Author:     aut,
-		Categories: cats,
+	var b book.Book
+	err := s.publisher.Publish(ctx, event.Book, event.Created, func(ctx context.Context) (changed uuid.UUID, err error) {
+		b, err = s.bookRepo.Create(ctx, bookrepo.CreateDTO{
+			Name:       name,
+			Author:     aut,
+			Categories: cats,
+		})
+		if err != nil {
+			return
+		}
+		return b.ID, s.audit.Record(ctx, event.Book.String(), b.ID.String(), audit.Create, nil, b)
+	})
+	if err != nil {
+		return book.Book{}, err
+	}
+	return b, nil
+}
+
+// UpdateBook updates stored book if name is not empty, listed author and all categories exist.
+// Non-zero version of b must match current version of book.
+func (s *BookService) UpdateBook(ctx context.Context, b book.Book) (book.Book, error) {
+	if b.Name == "" {
+		return book.Book{}, &commonerrors.InvalidInput{Reason: "name is required"}
+	}
+	before, err := s.GetBook(ctx, b.ID)
+	if err != nil {
+		return book.Book{}, err
+	}
+	aut, err := s.authorService.GetAuthor(ctx, b.Author.ID)
+	if err != nil {
+		return book.Book{}, err
+	}
+	b.Author = aut
+	for i, cat := range b.Categories {
+		c, err := s.categoryService.GetCategory(ctx, cat.ID)
+		if err != nil {
+			return book.Book{}, err
+		}
+		b.Categories[i] = c
+	}
+	var updated book.Book
+	err = s.publisher.Publish(ctx, event.Book, event.Updated, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if updated, err = s.bookRepo.Update(ctx, b); err != nil {
+			return
+		}
+		return updated.ID, s.audit.Record(ctx, event.Book.String(), updated.ID.String(), audit.Update, before, updated)
+	})
+	if err != nil {
+		return book.Book{}, err
+	}
+	return updated, nil
+}
+
+// DeleteBook deletes stored book by id
+func (s *BookService) DeleteBook(ctx context.Context, id uuid.UUID) (book.Book, error) {
+	var b book.Book
+	err := s.publisher.Publish(ctx, event.Book, event.Deleted, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if b, err = s.bookRepo.Delete(ctx, id); err != nil {
+			return
+		}
+		return b.ID, s.audit.Record(ctx, event.Book.String(), b.ID.String(), audit.Delete, b, nil)
 	})
+	if err != nil {
+		return book.Book{}, err
+	}
+	return b, nil
+}
+
+// GetDeletedBooks fetches count deleted books from some number, the latest deleted first
+func (s *BookService) GetDeletedBooks(ctx context.Context, from, count uint) ([]bookrepo.StoredBook, error) {
+	if count == 0 {
+		count = 10
+	}
+	return s.bookRepo.GetDeleted(ctx, from, count)
+}
+
+// RestoreBook undeletes stored book by id together with links to its categories.
+// Author of book must not be deleted.
+func (s *BookService) RestoreBook(ctx context.Context, id uuid.UUID) (book.Book, error) {
+	var b book.Book
+	err := s.publisher.Publish(ctx, event.Book, event.Restored, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if b, err = s.bookRepo.Restore(ctx, id); err != nil {
+			return
+		}
+		return b.ID, s.audit.Record(ctx, event.Book.String(), b.ID.String(), audit.Restore, nil, b)
+	})
+	if err != nil {
+		return book.Book{}, err
+	}
+	return b, nil
+}
+
+// PurgeResult tells how many items were permanently removed by Purge
+type PurgeResult struct {
+	Books      uint64
+	Authors    uint64
+	Categories uint64
+}
+
+// Purge permanently removes books, authors and categories deleted before given time.
+// Books go first, so authors and categories referenced only by removed books are removed too.
+func (s *BookService) Purge(ctx context.Context, before time.Time) (res PurgeResult, err error) {
+	err = s.audit.InTransaction(ctx, func(ctx context.Context) (err error) {
+		res = PurgeResult{}
+		if res.Books, err = s.bookRepo.Purge(ctx, before); err != nil {
+			return
+		}
+		if res.Books != 0 {
+			err = s.audit.Record(ctx, event.Book.String(), "", audit.Purge, nil, audit.Purged{DeletedBefore: before, Count: res.Books})
+			if err != nil {
+				return
+			}
+		}
+		if res.Authors, err = s.authorService.PurgeAuthors(ctx, before); err != nil {
+			return
+		}
+		res.Categories, err = s.categoryService.PurgeCategories(ctx, before)
+		return
+	})
+	return
+}
+
+// RunPurges purges items deleted more than `retention` ago every `interval` until context is done
+func (s *BookService) RunPurges(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			res, err := s.Purge(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Failed to purge deleted catalog items: %s", err)
+			} else if res != (PurgeResult{}) {
+				log.Printf("Purged %d books, %d authors and %d categories", res.Books, res.Authors, res.Categories)
+			}
+		}
+	}
 }
@@ -2,7 +2,9 @@ package bookservice_test
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	authorInMemory "github.com/Vesninovich/go-tasks/book-store/catalog/author/inmemory"
 	authorservice "github.com/Vesninovich/go-tasks/book-store/catalog/author/service"
@@ -10,12 +12,18 @@ import (
 	bookservice "github.com/Vesninovich/go-tasks/book-store/catalog/book/service"
 	categoryInMemory "github.com/Vesninovich/go-tasks/book-store/catalog/category/inmemory"
 	categoryservice "github.com/Vesninovich/go-tasks/book-store/catalog/category/service"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	eventInMemory "github.com/Vesninovich/go-tasks/book-store/catalog/event/inmemory"
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	auditInMemory "github.com/Vesninovich/go-tasks/book-store/common/audit/inmemory"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 )
 
 var ctx = context.Background()
+var events *eventInMemory.Repository
 
 var author = book.Author{
 	Name: "Test author",
@@ -74,9 +82,198 @@ func TestCreateNonExistingFields(t *testing.T) {
 	}
 }
 
+func TestUpdate(t *testing.T) {
+	s := setup(t)
+	created, err := s.CreateBook(ctx, "Test", author, categories)
+	if err != nil {
+		t.Fatalf("Error while creating valid book: %s", err)
+	}
+	name := "Updated"
+	res, err := s.UpdateBook(ctx, book.Book{
+		ID:         created.ID,
+		Name:       name,
+		Author:     book.Author{ID: author.ID},
+		Categories: []book.Category{{ID: categories[0].ID}},
+	})
+	if err != nil {
+		t.Fatalf("Error while updating book: %s", err)
+	}
+	if res.Name != name || res.Author.Name != author.Name || len(res.Categories) != 1 {
+		t.Errorf("Book was updated incorrectly")
+	}
+	checkLastEvent(t, event.Book, event.Updated, created.ID)
+
+	_, err = s.UpdateBook(ctx, book.Book{ID: created.ID, Name: name, Author: book.Author{ID: uuid.New()}})
+	if _, ok := err.(*commonerrors.NotFound); !ok {
+		t.Errorf("Expected to get error of not found type for non-existing author, got %T", err)
+	}
+
+	_, err = s.UpdateBook(ctx, book.Book{ID: created.ID, Name: name, Author: book.Author{ID: author.ID}, Version: created.Version})
+	if _, ok := err.(*commonerrors.VersionMismatch); !ok {
+		t.Errorf("Expected to get error of version mismatch type for stale version, got %T", err)
+	}
+	found, err := s.GetBook(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Error while getting book: %s", err)
+	}
+	if found.Version != res.Version {
+		t.Errorf("Expected book to stay at version %d, got %d", res.Version, found.Version)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s := setup(t)
+	created, err := s.CreateBook(ctx, "Test", author, categories)
+	if err != nil {
+		t.Fatalf("Error while creating valid book: %s", err)
+	}
+	checkLastEvent(t, event.Book, event.Created, created.ID)
+	_, err = s.DeleteBook(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Error while deleting book: %s", err)
+	}
+	checkLastEvent(t, event.Book, event.Deleted, created.ID)
+	_, err = s.DeleteBook(ctx, created.ID)
+	if _, ok := err.(*commonerrors.NotFound); !ok {
+		t.Errorf("Expected to get error of not found type for deleted book, got %T", err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	s := setup(t)
+	created, err := s.CreateBook(ctx, "Test", author, categories)
+	if err != nil {
+		t.Fatalf("Error while creating valid book: %s", err)
+	}
+	_, err = s.RestoreBook(ctx, created.ID)
+	if _, ok := err.(*commonerrors.NotFound); !ok {
+		t.Errorf("Expected to get error of not found type for not deleted book, got %T", err)
+	}
+	if _, err = s.DeleteBook(ctx, created.ID); err != nil {
+		t.Fatalf("Error while deleting book: %s", err)
+	}
+	deleted, err := s.GetDeletedBooks(ctx, 0, 0)
+	if err != nil || len(deleted) != 1 || deleted[0].ID != created.ID {
+		t.Fatalf("Expected to get deleted book, got %v %v", deleted, err)
+	}
+	restored, err := s.RestoreBook(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Error while restoring book: %s", err)
+	}
+	checkLastEvent(t, event.Book, event.Restored, created.ID)
+	if len(restored.Categories) != len(categories) || restored.Version != created.Version+2 {
+		t.Errorf("Expected to restore book with categories and version bumped by delete and restore, got %v", restored)
+	}
+	if _, err = s.GetBook(ctx, created.ID); err != nil {
+		t.Errorf("Error while getting restored book: %s", err)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	s := setup(t)
+	created, err := s.CreateBook(ctx, "Test", author, categories)
+	if err != nil {
+		t.Fatalf("Error while creating valid book: %s", err)
+	}
+	if _, err = s.DeleteBook(ctx, created.ID); err != nil {
+		t.Fatalf("Error while deleting book: %s", err)
+	}
+	res, err := s.Purge(ctx, time.Now().Add(-time.Minute))
+	if err != nil || res != (bookservice.PurgeResult{}) {
+		t.Errorf("Expected recently deleted book to be kept, got %+v %v", res, err)
+	}
+	res, err = s.Purge(ctx, time.Now().Add(time.Minute))
+	if err != nil || res.Books != 1 {
+		t.Errorf("Expected to purge deleted book, got %+v %v", res, err)
+	}
+	if _, err = s.RestoreBook(ctx, created.ID); err == nil {
+		t.Error("Expected purged book not to be restored")
+	}
+}
+
+func TestAudit(t *testing.T) {
+	s := setup(t)
+	recorder := audit.NewRecorder(auditInMemory.New())
+	s.UseAudit(recorder)
+	actx := auth.NewContext(ctx, auth.Principal{Subject: "admin"})
+	created, err := s.CreateBook(actx, "Test", author, categories)
+	if err != nil {
+		t.Fatalf("Error while creating valid book: %s", err)
+	}
+	_, err = s.UpdateBook(actx, book.Book{ID: created.ID, Name: "Updated", Author: book.Author{ID: author.ID}, Categories: categories})
+	if err != nil {
+		t.Fatalf("Error while updating book: %s", err)
+	}
+	if _, err = s.DeleteBook(actx, created.ID); err != nil {
+		t.Fatalf("Error while deleting book: %s", err)
+	}
+
+	entries, err := recorder.Find(ctx, audit.Filter{Entity: "book", EntityID: created.ID.String()}, 0, 0)
+	if err != nil {
+		t.Fatalf("Error while finding audit entries: %s", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries for book, got %d", len(entries))
+	}
+	for i, op := range []audit.Operation{audit.Delete, audit.Update, audit.Create} {
+		if entries[i].Operation != op || entries[i].Actor != "admin" {
+			t.Errorf("Expected entry %d to be %s by admin, got %s by %s", i, op, entries[i].Operation, entries[i].Actor)
+		}
+	}
+	upd := entries[1].Diff
+	if c, ok := upd["name"]; !ok || string(c.Before) != `"Test"` || string(c.After) != `"Updated"` {
+		t.Errorf("Expected name change in diff of update, got %v", upd)
+	}
+	if _, ok := upd["author"]; ok {
+		t.Errorf("Expected unchanged author not to be in diff of update, got %v", upd)
+	}
+}
+
+// failingAuditStore can not save entries
+type failingAuditStore struct {
+	*auditInMemory.Store
+}
+
+func (failingAuditStore) Save(ctx context.Context, e audit.Entry) (audit.Entry, error) {
+	return e, errors.New("disk is full")
+}
+
+func TestAuditFailureFailsChange(t *testing.T) {
+	s := setup(t)
+	s.UseAudit(audit.NewRecorder(failingAuditStore{auditInMemory.New()}))
+	last, err := events.LastSequence(ctx)
+	if err != nil {
+		t.Fatalf("Error while getting last event: %s", err)
+	}
+	if _, err = s.CreateBook(ctx, "Test", author, categories); err == nil {
+		t.Fatal("Expected creation to fail when audit entry is not saved")
+	}
+	if after, _ := events.LastSequence(ctx); after != last {
+		t.Errorf("Expected no event of failed creation, got events up to %d after %d", after, last)
+	}
+}
+
+func checkLastEvent(t *testing.T, entity event.Entity, typ event.Type, id uuid.UUID) {
+	t.Helper()
+	last, err := events.LastSequence(ctx)
+	if err != nil {
+		t.Fatalf("Error while getting last event: %s", err)
+	}
+	res, err := events.GetAfter(ctx, last-1, 1)
+	if err != nil || len(res) != 1 {
+		t.Fatalf("Failed to get last event: %v", err)
+	}
+	e := res[0]
+	if e.Entity != entity || e.Type != typ || e.ID != id {
+		t.Errorf("Expected last event to be %s %s of %s, got %s %s of %s", entity, typ, id, e.Entity, e.Type, e.ID)
+	}
+}
+
 func setup(t *testing.T) *bookservice.BookService {
-	as := authorservice.New(authorInMemory.New())
-	cs := categoryservice.New(categoryInMemory.New())
+	events = eventInMemory.New()
+	bus := event.NewBus(events)
+	as := authorservice.New(authorInMemory.New(), bus)
+	cs := categoryservice.New(categoryInMemory.New(), bus)
 	var err error
 	author, err = as.CreateAuthor(ctx, author.Name)
 	if err != nil {
@@ -89,5 +286,5 @@ func setup(t *testing.T) *bookservice.BookService {
 		}
 		categories[i] = created
 	}
-	return bookservice.New(inmemory.New(), as, cs)
+	return bookservice.New(inmemory.New(), as, cs, bus)
 }
@@ -10,6 +10,8 @@ import (
 	bookrepo "github.com/Vesninovich/go-tasks/book-store/catalog/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/sqltx"
+	"github.com/Vesninovich/go-tasks/book-store/common/stored"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 	"github.com/jmoiron/sqlx"
 )
@@ -25,6 +27,14 @@ type fromDB struct {
 	Name       string
 	AuthorID   string `db:"author_id"`
 	AuthorName string `db:"author_name"`
+	Version    uint64
+}
+
+type deletedFromDB struct {
+	fromDB
+	CreatedAt sql.NullTime `db:"created_at"`
+	UpdatedAt sql.NullTime `db:"updated_at"`
+	DeletedAt time.Time    `db:"deleted_at"`
 }
 
 type catsFromDB struct {
@@ -47,9 +57,12 @@ func (r *Repository) CreateTableStmt() string {
 	author_id uuid REFERENCES %[1]s.authors,
   created_at timestamp,
   updated_at timestamp,
-  deleted_at timestamp
+  deleted_at timestamp,
+  version bigint NOT NULL DEFAULT 1
 );
 
+ALTER TABLE %[1]s.books ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+
 CREATE TABLE IF NOT EXISTS %[1]s.books_categories(
 	book_id uuid REFERENCES %[1]s.books ON DELETE CASCADE,
 	category_id uuid REFERENCES %[1]s.categories ON DELETE CASCADE,
@@ -60,17 +73,53 @@ CREATE TABLE IF NOT EXISTS %[1]s.books_categories(
 // Get gets
 func (r *Repository) Get(ctx context.Context, from, count uint, query book.Query) ([]book.Book, error) {
 	data := []fromDB{}
-	err := r.db.SelectContext(
+	err := sqltx.DB(ctx, r.db).SelectContext(
 		ctx, &data, r.getSelectBooksStatement(from, count, query), time.Time{},
 	)
 	if err != nil {
 		return nil, err
 	}
+	return r.withCategories(ctx, data)
+}
+
+// GetByIDs gets non-deleted books with given ids in a single query, missing ones are skipped
+func (r *Repository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]book.Book, error) {
+	if len(ids) == 0 {
+		return []book.Book{}, nil
+	}
+	idStrs := make([]string, len(ids))
+	for i, id := range ids {
+		idStrs[i] = id.String()
+	}
+	data := []fromDB{}
+	err := sqltx.DB(ctx, r.db).SelectContext(
+		ctx,
+		&data,
+		fmt.Sprintf(`SELECT
+			b.id as id,
+			b.name as name,
+			a.id as author_id,
+			a.name as author_name,
+			b.version as version
+			FROM %[1]s.books as b
+			INNER JOIN %[1]s.authors as a
+			ON (a.id=b.author_id)
+			WHERE b.id = ANY($1::uuid[])
+			AND b.deleted_at=$2 AND a.deleted_at=$2;`, r.schema),
+		idStrs, time.Time{},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return r.withCategories(ctx, data)
+}
+
+func (r *Repository) withCategories(ctx context.Context, data []fromDB) ([]book.Book, error) {
 	if len(data) == 0 {
-		return []book.Book{}, err
+		return []book.Book{}, nil
 	}
 	catData := []catsFromDB{}
-	err = r.db.SelectContext(
+	err := sqltx.DB(ctx, r.db).SelectContext(
 		ctx, &catData, r.getSelectCategoriesStatement(data), time.Time{},
 	)
 	if err == sql.ErrNoRows {
@@ -88,7 +137,8 @@ func (r *Repository) getSelectBooksStatement(from, count uint, query book.Query)
 		b.id as id,
 		b.name as name,
 		a.id as author_id,
-		a.name as author_name
+		a.name as author_name,
+		b.version as version
 		FROM %[1]s.books as b
 		INNER JOIN %[1]s.authors as a
 		ON (a.id=b.author_id)`, r.schema)
@@ -186,6 +236,7 @@ func mapBookData(booksData []fromDB, catData []catsFromDB) (books []book.Book, e
 				Name: b.AuthorName,
 			},
 			Categories: make([]book.Category, 0),
+			Version:    b.Version,
 		}
 		booksMap[b.ID] = bk
 	}
@@ -223,43 +274,24 @@ func mapBookData(booksData []fromDB, catData []catsFromDB) (books []book.Book, e
 func (r *Repository) Create(ctx context.Context, dto bookrepo.CreateDTO) (book.Book, error) {
 	id := uuid.New()
 	idStr := id.String()
-	tx, err := r.db.BeginTxx(ctx, nil) // TODO: correct options
-	if err != nil {
-		return book.Book{}, err
-	}
-	_, err = tx.ExecContext(
-		ctx,
-		fmt.Sprintf(`INSERT INTO %s.books (id, name, author_id, created_at, updated_at, deleted_at)
-			VALUES ($1, $2, $3, $4, $5, $6)`, r.schema),
-		idStr, dto.Name, dto.Author.ID.String(), time.Now(), time.Time{}, time.Time{},
-	)
-	if err != nil {
-		rbErr := tx.Rollback()
-		if rbErr != nil {
-			err = rbErr
-		}
-		return book.Book{}, err
-	}
-	for _, cat := range dto.Categories {
+	err := sqltx.Run(ctx, r.db, func(ctx context.Context) error {
+		tx := sqltx.DB(ctx, r.db)
 		_, err := tx.ExecContext(
 			ctx,
-			fmt.Sprintf(`INSERT INTO %s.books_categories (book_id, category_id)
-				VALUES ($1, $2)`, r.schema),
-			idStr, cat.ID.String(),
+			fmt.Sprintf(`INSERT INTO %s.books (id, name, author_id, created_at, updated_at, deleted_at)
+				VALUES ($1, $2, $3, $4, $5, $6)`, r.schema),
+			idStr, dto.Name, dto.Author.ID.String(), time.Now(), time.Time{}, time.Time{},
 		)
 		if err != nil {
-			rbErr := tx.Rollback()
-			if rbErr != nil {
-				err = rbErr
-			}
-			return book.Book{}, err
+			return err
 		}
-	}
-	err = tx.Commit()
-	return book.Book{ID: id, Name: dto.Name, Author: dto.Author, Categories: dto.Categories}, err
+		return r.linkCategories(ctx, idStr, dto.Categories)
+	})
+	return book.Book{ID: id, Name: dto.Name, Author: dto.Author, Categories: dto.Categories, Version: 1}, err
 }
 
-// Update updates
+// Update updates book if it is still at expected version.
+// Version is checked by the update statement itself so concurrent updates can not overwrite each other.
 func (r *Repository) Update(ctx context.Context, dto book.Book) (b book.Book, err error) {
 	books, err := r.Get(ctx, 0, 1, book.Query{ID: dto.ID})
 	if err != nil {
@@ -269,30 +301,31 @@ func (r *Repository) Update(ctx context.Context, dto book.Book) (b book.Book, er
 		return b, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", dto.ID)}
 	}
 	b = books[0]
+	if dto.Version != 0 && dto.Version != b.Version {
+		return book.Book{}, versionMismatch(dto.ID, dto.Version, b.Version)
+	}
 	catsEq := equalCategories(dto, b)
 	if dto.Name == b.Name && dto.Author.ID == b.Author.ID && catsEq {
 		return
 	}
 	idStr := dto.ID.String()
-	tx, err := r.db.BeginTxx(ctx, nil) // TODO: correct options
-	if err != nil {
-		return
-	}
-	_, err = tx.ExecContext(
-		ctx,
-		fmt.Sprintf(`UPDATE %s.books
-			SET name=$2, author_id=$3, updated_at=$4
-			WHERE id=$1`, r.schema),
-		idStr, dto.Name, dto.Author.ID.String(), time.Now(),
-	)
-	if err != nil {
-		rbErr := tx.Rollback()
-		if rbErr != nil {
-			err = rbErr
+	var version uint64
+	err = sqltx.Run(ctx, r.db, func(ctx context.Context) error {
+		tx := sqltx.DB(ctx, r.db)
+		err := tx.QueryRowxContext(
+			ctx,
+			fmt.Sprintf(`UPDATE %s.books
+				SET name=$2, author_id=$3, updated_at=$4, version=version+1
+				WHERE id=$1 AND deleted_at=$6 AND (version=$5 OR $5=0)
+				RETURNING version`, r.schema),
+			idStr, dto.Name, dto.Author.ID.String(), time.Now(), b.Version, time.Time{},
+		).Scan(&version)
+		if err == sql.ErrNoRows {
+			return r.updateError(ctx, dto.ID, b.Version)
+		}
+		if err != nil || catsEq {
+			return err
 		}
-		return
-	}
-	if !catsEq {
 		_, err = tx.ExecContext(
 			ctx,
 			fmt.Sprintf(`DELETE FROM %s.books_categories
@@ -300,33 +333,52 @@ func (r *Repository) Update(ctx context.Context, dto book.Book) (b book.Book, er
 			idStr,
 		)
 		if err != nil {
-			rbErr := tx.Rollback()
-			if rbErr != nil {
-				err = rbErr
-			}
-			return
-		}
-		for _, cat := range dto.Categories {
-			_, err := tx.ExecContext(
-				ctx,
-				fmt.Sprintf(`INSERT INTO %s.books_categories (book_id, category_id)
-					VALUES ($1, $2)`, r.schema),
-				idStr, cat.ID.String(),
-			)
-			if err != nil {
-				rbErr := tx.Rollback()
-				if rbErr != nil {
-					err = rbErr
-				}
-				return book.Book{}, err
-			}
+			return err
 		}
+		return r.linkCategories(ctx, idStr, dto.Categories)
+	})
+	if err != nil {
+		return book.Book{}, err
 	}
 	b = dto
-	err = tx.Commit()
+	b.Version = version
 	return
 }
 
+func (r *Repository) linkCategories(ctx context.Context, bookID string, cats []book.Category) error {
+	for _, cat := range cats {
+		_, err := sqltx.DB(ctx, r.db).ExecContext(
+			ctx,
+			fmt.Sprintf(`INSERT INTO %s.books_categories (book_id, category_id)
+				VALUES ($1, $2)`, r.schema),
+			bookID, cat.ID.String(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateError tells why conditional update of book changed nothing
+func (r *Repository) updateError(ctx context.Context, id uuid.UUID, expected uint64) error {
+	var actual uint64
+	err := sqltx.DB(ctx, r.db).GetContext(
+		ctx, &actual, fmt.Sprintf("SELECT version FROM %s.books WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
+	)
+	if err == sql.ErrNoRows {
+		return &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", id)}
+	}
+	if err != nil {
+		return err
+	}
+	return versionMismatch(id, expected, actual)
+}
+
+func versionMismatch(id uuid.UUID, expected, actual uint64) *commonerrors.VersionMismatch {
+	return &commonerrors.VersionMismatch{What: fmt.Sprintf("Book with ID %s", id), Expected: expected, Actual: actual}
+}
+
 func equalCategories(a book.Book, b book.Book) bool {
 	if len(a.Categories) != len(b.Categories) {
 		return false
@@ -339,7 +391,7 @@ func equalCategories(a book.Book, b book.Book) bool {
 	return true
 }
 
-// Delete deletes
+// Delete marks book as deleted, its categories are kept to be linked back on restore
 func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Book, error) {
 	books, err := r.Get(ctx, 0, 1, book.Query{ID: id})
 	if err != nil {
@@ -348,38 +400,140 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Book, error
 	if len(books) == 0 {
 		return book.Book{}, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", id)}
 	}
-	idStr := id.String()
-	tx, err := r.db.BeginTxx(ctx, nil) // TODO: correct options
+	res, err := sqltx.DB(ctx, r.db).ExecContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %s.books
+			SET deleted_at=$2, version=version+1
+			WHERE id=$1 AND deleted_at=$3`, r.schema),
+		id.String(), time.Now(), time.Time{},
+	)
+	if err != nil {
+		return book.Book{}, err
+	}
+	count, err := res.RowsAffected()
 	if err != nil {
 		return book.Book{}, err
 	}
-	_, err = tx.ExecContext(
+	if count == 0 {
+		return book.Book{}, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", id)}
+	}
+	b := books[0]
+	b.Version++
+	return b, nil
+}
+
+// GetDeleted gets deleted books with their categories, the latest deleted first
+func (r *Repository) GetDeleted(ctx context.Context, from, count uint) ([]bookrepo.StoredBook, error) {
+	stmt := fmt.Sprintf(`SELECT
+		b.id as id,
+		b.name as name,
+		a.id as author_id,
+		a.name as author_name,
+		b.version as version,
+		b.created_at as created_at,
+		b.updated_at as updated_at,
+		b.deleted_at as deleted_at
+		FROM %[1]s.books as b
+		INNER JOIN %[1]s.authors as a
+		ON (a.id=b.author_id)
+		WHERE b.deleted_at<>$1
+		ORDER BY b.deleted_at DESC
+		OFFSET $2 ROWS`, r.schema)
+	if count != 0 {
+		stmt += fmt.Sprintf(`
+		LIMIT %d`, count)
+	}
+	data := []deletedFromDB{}
+	err := sqltx.DB(ctx, r.db).SelectContext(ctx, &data, stmt, time.Time{}, from)
+	if err != nil {
+		return nil, err
+	}
+	bookData := make([]fromDB, len(data))
+	for i, item := range data {
+		bookData[i] = item.fromDB
+	}
+	books, err := r.withCategories(ctx, bookData)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]book.Book, len(books))
+	for _, b := range books {
+		byID[b.ID] = b
+	}
+	res := make([]bookrepo.StoredBook, len(data))
+	for i, item := range data {
+		id, err := uuid.FromString(item.ID)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = bookrepo.StoredBook{
+			Book: byID[id],
+			Stored: stored.Stored{
+				CreatedAt: item.CreatedAt.Time,
+				UpdatedAt: item.UpdatedAt.Time,
+				DeletedAt: item.DeletedAt,
+			},
+		}
+	}
+	return res, nil
+}
+
+// Restore undeletes book if its author is not deleted, categories book had when it was deleted are linked back
+func (r *Repository) Restore(ctx context.Context, id uuid.UUID) (book.Book, error) {
+	res, err := sqltx.DB(ctx, r.db).ExecContext(
 		ctx,
-		fmt.Sprintf(`UPDATE %s.books
-			SET deleted_at=$2
-			WHERE id=$1`, r.schema),
-		idStr, time.Now(),
+		fmt.Sprintf(`UPDATE %[1]s.books as b
+			SET deleted_at=$2, updated_at=$3, version=version+1
+			WHERE b.id=$1 AND b.deleted_at<>$2
+			AND EXISTS (SELECT 1 FROM %[1]s.authors as a WHERE a.id=b.author_id AND a.deleted_at=$2);`, r.schema),
+		id.String(), time.Time{}, time.Now(),
 	)
 	if err != nil {
-		rbErr := tx.Rollback()
-		if rbErr != nil {
-			err = rbErr
-		}
 		return book.Book{}, err
 	}
-	_, err = tx.ExecContext(
+	count, err := res.RowsAffected()
+	if err != nil {
+		return book.Book{}, err
+	}
+	if count == 0 {
+		return book.Book{}, r.restoreError(ctx, id)
+	}
+	books, err := r.Get(ctx, 0, 1, book.Query{ID: id})
+	if err != nil {
+		return book.Book{}, err
+	}
+	if len(books) == 0 {
+		return book.Book{}, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", id)}
+	}
+	return books[0], nil
+}
+
+// restoreError tells why restore of book changed nothing
+func (r *Repository) restoreError(ctx context.Context, id uuid.UUID) error {
+	var authorID string
+	err := sqltx.DB(ctx, r.db).GetContext(
+		ctx, &authorID, fmt.Sprintf("SELECT author_id FROM %s.books WHERE id=$1 AND deleted_at<>$2;", r.schema), id.String(), time.Time{},
+	)
+	if err == sql.ErrNoRows {
+		return &commonerrors.NotFound{What: fmt.Sprintf("Deleted book with ID %s", id)}
+	}
+	if err != nil {
+		return err
+	}
+	return &commonerrors.InvalidInput{Reason: fmt.Sprintf("author %s of book is deleted, it must be restored first", authorID)}
+}
+
+// Purge removes books deleted before given time together with links to their categories
+func (r *Repository) Purge(ctx context.Context, before time.Time) (uint64, error) {
+	res, err := sqltx.DB(ctx, r.db).ExecContext(
 		ctx,
-		fmt.Sprintf(`DELETE FROM %s.books_categories
-			WHERE book_id=$1`, r.schema),
-		idStr,
+		fmt.Sprintf(`DELETE FROM %s.books
+			WHERE deleted_at<>$1 AND deleted_at<$2;`, r.schema),
+		time.Time{}, before,
 	)
 	if err != nil {
-		rbErr := tx.Rollback()
-		if rbErr != nil {
-			err = rbErr
-		}
-		return book.Book{}, err
+		return 0, err
 	}
-	err = tx.Commit()
-	return books[0], err
+	count, err := res.RowsAffected()
+	return uint64(count), err
 }
@@ -62,6 +62,10 @@ func TestGet(t *testing.T) {
 	tests.RepoGet(t, constructor)
 }
 
+func TestGetByIDs(t *testing.T) {
+	tests.RepoGetByIDs(t, constructor)
+}
+
 func TestCreate(t *testing.T) {
 	tests.RepoCreate(t, constructor)
 }
@@ -82,6 +86,10 @@ func TestUpdateWithSomeDeleted(t *testing.T) {
 	tests.RepoUpdateWithSomeDeleted(t, constructor)
 }
 
+func TestUpdateVersion(t *testing.T) {
+	tests.RepoUpdateVersion(t, constructor)
+}
+
 func TestDelete(t *testing.T) {
 	tests.RepoDelete(t, constructor)
 }
@@ -93,3 +101,23 @@ func TestDeleteTwice(t *testing.T) {
 func TestDeleteNonExisting(t *testing.T) {
 	tests.RepoDeleteNonExisting(t, constructor)
 }
+
+func TestGetDeleted(t *testing.T) {
+	tests.RepoGetDeleted(t, constructor)
+}
+
+func TestUpdateRestoredVersion(t *testing.T) {
+	tests.RepoUpdateRestoredVersion(t, constructor)
+}
+
+func TestRestore(t *testing.T) {
+	tests.RepoRestore(t, constructor)
+}
+
+func TestRestoreWithDeletedAuthor(t *testing.T) {
+	tests.RepoRestoreWithDeletedAuthor(t, constructor)
+}
+
+func TestPurge(t *testing.T) {
+	tests.RepoPurge(t, constructor)
+}
@@ -3,6 +3,7 @@ package tests
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/catalog/author"
 	bookrepo "github.com/Vesninovich/go-tasks/book-store/catalog/book"
@@ -13,6 +14,7 @@ import (
 )
 
 var aut1, aut2 book.Author
+var authorRepo author.Repository
 var cat1, cat2 book.Category
 var books = []bookrepo.CreateDTO{
 	{Name: "bookA"},
@@ -131,6 +133,52 @@ func RepoGet(t *testing.T, c Constructor) {
 	})
 }
 
+// RepoGetByIDs tests getting items by list of ids
+func RepoGetByIDs(t *testing.T, c Constructor) {
+	repo := setup(t, c)
+	stored, err := repo.Get(ctx, 0, uint(len(books)), book.Query{})
+	if err != nil {
+		t.Fatalf("Error getting all books: %s", err)
+	}
+
+	t.Run("all books", func(t *testing.T) {
+		res, err := repo.GetByIDs(ctx, []uuid.UUID{stored[0].ID, stored[1].ID})
+		if err != nil {
+			t.Fatalf("Error getting books: %s", err)
+		}
+		if len(res) != 2 {
+			t.Fatalf("Expected to get 2 books, got %d", len(res))
+		}
+		b := findByName(stored[1].Name, res, t)
+		if b.ID != stored[1].ID || len(b.Categories) != len(stored[1].Categories) {
+			t.Error("Got wrong book by ID")
+		}
+	})
+
+	t.Run("missing ids", func(t *testing.T) {
+		res, err := repo.GetByIDs(ctx, []uuid.UUID{uuid.New(), stored[0].ID})
+		if err != nil {
+			t.Fatalf("Error getting books: %s", err)
+		}
+		if len(res) != 1 {
+			t.Fatalf("Expected to get 1 book, got %d", len(res))
+		}
+		if res[0].ID != stored[0].ID {
+			t.Error("Got wrong book by ID")
+		}
+	})
+
+	t.Run("no ids", func(t *testing.T) {
+		res, err := repo.GetByIDs(ctx, []uuid.UUID{})
+		if err != nil {
+			t.Fatalf("Error getting books: %s", err)
+		}
+		if len(res) != 0 {
+			t.Fatalf("Expected to get no books, got %d", len(res))
+		}
+	})
+}
+
 // RepoCreate tests creating items
 func RepoCreate(t *testing.T, c Constructor) {
 	setup(t, c)
@@ -215,6 +263,55 @@ func RepoUpdateWithSomeDeleted(t *testing.T, c Constructor) {
 	}
 }
 
+// RepoUpdateVersion tests that item is only updated at expected version
+func RepoUpdateVersion(t *testing.T, c Constructor) {
+	repo, stored := setupMutation(t, c)
+	item := stored[0]
+	if item.Version == 0 {
+		t.Fatal("Expected stored item to have version")
+	}
+	item.Name = "renamed"
+	updated, err := repo.Update(ctx, item)
+	if err != nil {
+		t.Fatalf("Error while updating item at expected version: %s", err)
+	}
+	if updated.Version != item.Version+1 {
+		t.Errorf("Expected version to be incremented to %d, got %d", item.Version+1, updated.Version)
+	}
+	_, err = repo.Update(ctx, item)
+	if err == nil {
+		t.Fatal("Expected to get VersionMismatch error")
+	}
+	if _, typeCorrect := err.(*commonerrors.VersionMismatch); !typeCorrect {
+		t.Errorf("Expected to get error of type *commonerrors.VersionMismatch, got %T", err)
+	}
+	item.Version = 0
+	if _, err = repo.Update(ctx, item); err != nil {
+		t.Errorf("Error while updating item without version: %s", err)
+	}
+}
+
+// RepoUpdateRestoredVersion tests that item can not be updated at version it had before it was deleted and restored
+func RepoUpdateRestoredVersion(t *testing.T, c Constructor) {
+	repo, stored := setupMutation(t, c)
+	item := stored[0]
+	deleted, err := repo.Delete(ctx, item.ID)
+	if err != nil {
+		t.Fatalf("Error while deleting item: %s", err)
+	}
+	if deleted.Version != item.Version+1 {
+		t.Errorf("Expected delete to increment version to %d, got %d", item.Version+1, deleted.Version)
+	}
+	if _, err = repo.Restore(ctx, item.ID); err != nil {
+		t.Fatalf("Error while restoring item: %s", err)
+	}
+	item.Name = "renamed"
+	_, err = repo.Update(ctx, item)
+	if _, typeCorrect := err.(*commonerrors.VersionMismatch); !typeCorrect {
+		t.Errorf("Expected to get error of type *commonerrors.VersionMismatch, got %T", err)
+	}
+}
+
 // RepoDelete tests deleting item
 func RepoDelete(t *testing.T, c Constructor) {
 	repo, stored := setupMutation(t, c)
@@ -247,6 +344,93 @@ func RepoDeleteNonExisting(t *testing.T, c Constructor) {
 	checkNotFound(t, err)
 }
 
+// RepoGetDeleted tests getting deleted items with their categories
+func RepoGetDeleted(t *testing.T, c Constructor) {
+	repo, id, stored := setupAlreadyDeleted(t, c)
+	deleted, err := repo.GetDeleted(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("Error while getting deleted items: %s", err)
+	}
+	if len(deleted) != 1 || deleted[0].ID != id || !deleted[0].IsDeleted() {
+		t.Fatalf("Expected to get deleted item %s, got %v", id, deleted)
+	}
+	if len(deleted[0].Categories) != len(stored[0].Categories) {
+		t.Errorf("Expected deleted item to keep %d categories, got %d", len(stored[0].Categories), len(deleted[0].Categories))
+	}
+	deleted, err = repo.GetDeleted(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Error while getting deleted items: %s", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("Expected to get no deleted items from 1, got %d", len(deleted))
+	}
+}
+
+// RepoRestore tests restoring deleted item with its categories
+func RepoRestore(t *testing.T, c Constructor) {
+	repo, id, stored := setupAlreadyDeleted(t, c)
+	restored, err := repo.Restore(ctx, id)
+	if err != nil {
+		t.Fatalf("Error while restoring item: %s", err)
+	}
+	if restored.ID != id || restored.Version != stored[0].Version+2 {
+		t.Errorf("Expected to restore item with incremented version, got %v", restored)
+	}
+	b, err := repo.Get(ctx, 0, 1, book.Query{ID: id})
+	if err != nil {
+		t.Fatalf("Error while getting restored item: %s", err)
+	}
+	if len(b) != 1 || len(b[0].Categories) != len(stored[0].Categories) {
+		t.Errorf("Expected to get restored item with its categories, got %v", b)
+	}
+	_, err = repo.Restore(ctx, id)
+	checkNotFound(t, err)
+	_, err = repo.Restore(ctx, uuid.New())
+	checkNotFound(t, err)
+}
+
+// RepoRestoreWithDeletedAuthor tests that item is not restored while its author is deleted
+func RepoRestoreWithDeletedAuthor(t *testing.T, c Constructor) {
+	repo, id, stored := setupAlreadyDeleted(t, c)
+	if _, err := authorRepo.Delete(ctx, stored[0].Author.ID); err != nil {
+		t.Fatalf("Error while deleting author: %s", err)
+	}
+	_, err := repo.Restore(ctx, id)
+	if _, typeCorrect := err.(*commonerrors.InvalidInput); !typeCorrect {
+		t.Errorf("Expected to get error of type *commonerrors.InvalidInput, got %T", err)
+	}
+	if _, err = authorRepo.Restore(ctx, stored[0].Author.ID); err != nil {
+		t.Fatalf("Error while restoring author: %s", err)
+	}
+	if _, err = repo.Restore(ctx, id); err != nil {
+		t.Errorf("Error while restoring item after its author: %s", err)
+	}
+}
+
+// RepoPurge tests permanently removing deleted items
+func RepoPurge(t *testing.T, c Constructor) {
+	repo, id, _ := setupAlreadyDeleted(t, c)
+	purged, err := repo.Purge(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Error while purging items: %s", err)
+	}
+	if purged != 0 {
+		t.Errorf("Expected recently deleted item to be kept, got %d purged", purged)
+	}
+	purged, err = repo.Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Error while purging items: %s", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected to purge 1 item, got %d", purged)
+	}
+	_, err = repo.Restore(ctx, id)
+	checkNotFound(t, err)
+	if all, _ := repo.Get(ctx, 0, 0, book.Query{}); len(all) != len(books)-1 {
+		t.Errorf("Expected not deleted items to be kept, got %d", len(all))
+	}
+}
+
 func findByName(name string, data []book.Book, t *testing.T) book.Book {
 	for _, item := range data {
 		if item.Name == name {
@@ -258,7 +442,9 @@ func findByName(name string, data []book.Book, t *testing.T) book.Book {
 }
 
 func setup(t *testing.T, c Constructor) bookrepo.Repository {
-	authorRepo, categoryRepo, repo := c(t)
+	var categoryRepo category.Repository
+	var repo bookrepo.Repository
+	authorRepo, categoryRepo, repo = c(t)
 
 	var err error
 	aut1, err = authorRepo.Create(ctx, author.CreateDTO{Name: "authorA"})
@@ -285,7 +471,7 @@ func setup(t *testing.T, c Constructor) bookrepo.Repository {
 	for _, a := range books {
 		_, err := repo.Create(ctx, a)
 		if err != nil {
-			t.Fatalf("Error while creating category %s: %s", a, err)
+			t.Fatalf("Error while creating book %v: %s", a, err)
 		}
 	}
 	return repo
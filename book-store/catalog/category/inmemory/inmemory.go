@@ -3,6 +3,7 @@ package inmemory
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -64,6 +65,7 @@ func (r *Repository) Create(ctx context.Context, dto category.CreateDTO) (book.C
 		ID:       uuid.New(),
 		Name:     dto.Name,
 		ParentID: dto.ParentID,
+		Version:  1,
 	}
 	item := category.StoredCategory{
 		Category: c,
@@ -87,10 +89,16 @@ func (r *Repository) Update(ctx context.Context, dto book.Category) (book.Catego
 			if item.IsDeleted() {
 				return book.Category{}, &commonerrors.NotFound{What: fmt.Sprintf("Category with ID %s", dto.ID)}
 			}
+			if dto.Version != 0 && dto.Version != item.Version {
+				return book.Category{}, &commonerrors.VersionMismatch{
+					What: fmt.Sprintf("Category with ID %s", dto.ID), Expected: dto.Version, Actual: item.Version,
+				}
+			}
 			c := book.Category{
 				ID:       dto.ID,
 				Name:     dto.Name,
 				ParentID: dto.ParentID,
+				Version:  item.Version + 1,
 			}
 			r.data[i] = category.StoredCategory{
 				Category: c,
@@ -120,6 +128,7 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Category, e
 				ID:       id,
 				Name:     item.Name,
 				ParentID: item.ParentID,
+				Version:  item.Version + 1,
 			}
 			r.data[i] = category.StoredCategory{
 				Category: c,
@@ -134,3 +143,64 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Category, e
 	}
 	return book.Category{}, &commonerrors.NotFound{What: fmt.Sprintf("Category with ID %s", id)}
 }
+
+// GetDeleted gets deleted items from in-memory repository, the latest deleted first
+func (r *Repository) GetDeleted(ctx context.Context, from, count uint) ([]category.StoredCategory, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	deleted := make([]category.StoredCategory, 0)
+	for _, item := range r.data {
+		if item.IsDeleted() {
+			deleted = append(deleted, item)
+		}
+	}
+	sort.SliceStable(deleted, func(i, j int) bool {
+		return deleted[i].DeletedAt.After(deleted[j].DeletedAt)
+	})
+	if from >= uint(len(deleted)) {
+		return []category.StoredCategory{}, nil
+	}
+	deleted = deleted[from:]
+	if count != 0 && count < uint(len(deleted)) {
+		deleted = deleted[:count]
+	}
+	return deleted, nil
+}
+
+// Restore undeletes item in in-memory repository
+func (r *Repository) Restore(ctx context.Context, id uuid.UUID) (book.Category, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, item := range r.data {
+		if item.ID == id && item.IsDeleted() {
+			item.Version++
+			item.UpdatedAt = time.Now()
+			item.DeletedAt = time.Time{}
+			r.data[i] = item
+			return item.ToCategory(), nil
+		}
+	}
+	return book.Category{}, &commonerrors.NotFound{What: fmt.Sprintf("Deleted category with ID %s", id)}
+}
+
+// Purge removes items deleted before given time from in-memory repository, parents of stored items are kept
+func (r *Repository) Purge(ctx context.Context, before time.Time) (uint64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	parents := make(map[uuid.UUID]bool)
+	for _, item := range r.data {
+		parents[item.ParentID] = true
+	}
+	kept := r.data[:0]
+	for _, item := range r.data {
+		if !item.IsDeleted() || !item.DeletedAt.Before(before) || parents[item.ID] {
+			kept = append(kept, item)
+		}
+	}
+	purged := uint64(len(r.data) - len(kept))
+	r.data = kept
+	return purged, nil
+}
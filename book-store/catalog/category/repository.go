@@ -2,6 +2,7 @@ package category
 
 import (
 	"context"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/stored"
@@ -25,8 +26,16 @@ type Repository interface {
 	GetAll(ctx context.Context) ([]book.Category, error)
 	Get(ctx context.Context, id uuid.UUID) (book.Category, error)
 	Create(ctx context.Context, dto CreateDTO) (book.Category, error)
+	// Update updates category, returns *commonerrors.VersionMismatch if version of dto is set and does not match stored one
 	Update(ctx context.Context, dto book.Category) (book.Category, error)
 	Delete(ctx context.Context, id uuid.UUID) (book.Category, error)
+	// GetDeleted gets count deleted categories from some number, the latest deleted first, all of them if count is 0
+	GetDeleted(ctx context.Context, from, count uint) ([]StoredCategory, error)
+	// Restore undeletes category, returns *commonerrors.NotFound if there is no deleted category with id
+	Restore(ctx context.Context, id uuid.UUID) (book.Category, error)
+	// Purge permanently removes categories deleted before given time.
+	// Parents of stored categories and categories of stored deleted books are kept.
+	Purge(ctx context.Context, before time.Time) (uint64, error)
 }
 
 // ToCategory converts stored version to actual entity
@@ -35,5 +44,6 @@ func (s StoredCategory) ToCategory() book.Category {
 		ID:       s.ID,
 		Name:     s.Name,
 		ParentID: s.ParentID,
+		Version:  s.Version,
 	}
 }
@@ -2,8 +2,11 @@ package categoryservice
 
 import (
 	"context"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/catalog/category"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
@@ -11,12 +14,20 @@ import (
 
 // Service handles categorys manipulation
 type Service struct {
-	repo category.Repository
+	repo      category.Repository
+	publisher event.Publisher
+	audit     *audit.Recorder
 }
 
 // New creates new instance of Service
-func New(r category.Repository) *Service {
-	return &Service{r}
+func New(r category.Repository, p event.Publisher) *Service {
+	return &Service{repo: r, publisher: p}
+}
+
+// UseAudit makes service record every change of categories with given recorder
+// Entries are saved in transactions of changes, so change fails if its entry can not be saved.
+func (s *Service) UseAudit(r *audit.Recorder) {
+	s.audit = r
 }
 
 // GetCategory reads stored category by id
@@ -30,5 +41,91 @@ func (s *Service) CreateCategory(ctx context.Context, name string, parentID uuid
 	if name == "" {
 		return empty, &commonerrors.InvalidInput{Reason: "name is required"}
 	}
-	return s.repo.Create(ctx, category.CreateDTO{Name: name, ParentID: parentID})
+	var c book.Category
+	err := s.publisher.Publish(ctx, event.Category, event.Created, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if c, err = s.repo.Create(ctx, category.CreateDTO{Name: name, ParentID: parentID}); err != nil {
+			return
+		}
+		return c.ID, s.audit.Record(ctx, event.Category.String(), c.ID.String(), audit.Create, nil, c)
+	})
+	if err != nil {
+		return empty, err
+	}
+	return c, nil
+}
+
+// UpdateCategory validates data, updates category if data is valid and category is found, returns error otherwise.
+// Non-zero version must match current version of category.
+func (s *Service) UpdateCategory(ctx context.Context, id uuid.UUID, version uint64, name string, parentID uuid.UUID) (book.Category, error) {
+	var empty book.Category
+	if name == "" {
+		return empty, &commonerrors.InvalidInput{Reason: "name is required"}
+	}
+	if !parentID.IsZero() && parentID == id {
+		return empty, &commonerrors.InvalidInput{Reason: "category can not be its own parent"}
+	}
+	before, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return empty, err
+	}
+	var c book.Category
+	err = s.publisher.Publish(ctx, event.Category, event.Updated, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if c, err = s.repo.Update(ctx, book.Category{ID: id, Name: name, ParentID: parentID, Version: version}); err != nil {
+			return
+		}
+		return c.ID, s.audit.Record(ctx, event.Category.String(), c.ID.String(), audit.Update, before, c)
+	})
+	if err != nil {
+		return empty, err
+	}
+	return c, nil
+}
+
+// DeleteCategory deletes stored category by id
+func (s *Service) DeleteCategory(ctx context.Context, id uuid.UUID) (book.Category, error) {
+	var c book.Category
+	err := s.publisher.Publish(ctx, event.Category, event.Deleted, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if c, err = s.repo.Delete(ctx, id); err != nil {
+			return
+		}
+		return c.ID, s.audit.Record(ctx, event.Category.String(), c.ID.String(), audit.Delete, c, nil)
+	})
+	if err != nil {
+		return book.Category{}, err
+	}
+	return c, nil
+}
+
+// GetDeletedCategories fetches count deleted categories from some number, the latest deleted first
+func (s *Service) GetDeletedCategories(ctx context.Context, from, count uint) ([]category.StoredCategory, error) {
+	if count == 0 {
+		count = 10
+	}
+	return s.repo.GetDeleted(ctx, from, count)
+}
+
+// RestoreCategory undeletes stored category by id
+func (s *Service) RestoreCategory(ctx context.Context, id uuid.UUID) (book.Category, error) {
+	var c book.Category
+	err := s.publisher.Publish(ctx, event.Category, event.Restored, func(ctx context.Context) (changed uuid.UUID, err error) {
+		if c, err = s.repo.Restore(ctx, id); err != nil {
+			return
+		}
+		return c.ID, s.audit.Record(ctx, event.Category.String(), c.ID.String(), audit.Restore, nil, c)
+	})
+	if err != nil {
+		return book.Category{}, err
+	}
+	return c, nil
+}
+
+// PurgeCategories permanently removes categories deleted before given time, returns number of removed ones
+func (s *Service) PurgeCategories(ctx context.Context, before time.Time) (n uint64, err error) {
+	err = s.audit.InTransaction(ctx, func(ctx context.Context) (err error) {
+		if n, err = s.repo.Purge(ctx, before); err != nil || n == 0 {
+			return
+		}
+		return s.audit.Record(ctx, event.Category.String(), "", audit.Purge, nil, audit.Purged{DeletedBefore: before, Count: n})
+	})
+	return
 }
@@ -6,6 +6,8 @@ import (
 
 	"github.com/Vesninovich/go-tasks/book-store/catalog/category/inmemory"
 	categoryservice "github.com/Vesninovich/go-tasks/book-store/catalog/category/service"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	eventInMemory "github.com/Vesninovich/go-tasks/book-store/catalog/event/inmemory"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 )
@@ -29,6 +31,30 @@ func TestCreateWithEmptyName(t *testing.T) {
 	}
 }
 
+func TestRestore(t *testing.T) {
+	ctx := context.Background()
+	s := createService()
+	created, err := s.CreateCategory(ctx, "test", uuid.UUID{})
+	if err != nil {
+		t.Fatalf("Got error while creating valid category: %s", err)
+	}
+	if _, err = s.DeleteCategory(ctx, created.ID); err != nil {
+		t.Fatalf("Got error while deleting category: %s", err)
+	}
+	if deleted, _ := s.GetDeletedCategories(ctx, 0, 0); len(deleted) != 1 || deleted[0].ID != created.ID {
+		t.Errorf("Expected to get deleted category, got %v", deleted)
+	}
+	if _, err = s.RestoreCategory(ctx, created.ID); err != nil {
+		t.Fatalf("Got error while restoring category: %s", err)
+	}
+	if _, err = s.GetCategory(ctx, created.ID); err != nil {
+		t.Errorf("Got error while getting restored category: %s", err)
+	}
+	if _, err = s.RestoreCategory(ctx, created.ID); err == nil {
+		t.Error("Expected to get error while restoring not deleted category")
+	}
+}
+
 func createService() *categoryservice.Service {
-	return categoryservice.New(inmemory.New())
+	return categoryservice.New(inmemory.New(), event.NewBus(eventInMemory.New()))
 }
@@ -9,6 +9,8 @@ import (
 	"github.com/Vesninovich/go-tasks/book-store/catalog/category"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/sqltx"
+	"github.com/Vesninovich/go-tasks/book-store/common/stored"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 	"github.com/jmoiron/sqlx"
 )
@@ -23,6 +25,14 @@ type fromDB struct {
 	ID       string
 	Name     string
 	ParentID sql.NullString `db:"parent_id"`
+	Version  uint64
+}
+
+type deletedFromDB struct {
+	fromDB
+	CreatedAt sql.NullTime `db:"created_at"`
+	UpdatedAt sql.NullTime `db:"updated_at"`
+	DeletedAt time.Time    `db:"deleted_at"`
 }
 
 // New creates a new instance of SQLRepository
@@ -38,14 +48,17 @@ func (r *Repository) CreateTableStmt() string {
 	parent_id uuid REFERENCES %[1]s.categories DEFAULT NULL,
   created_at timestamp,
   updated_at timestamp,
-  deleted_at timestamp
-);`, r.schema)
+  deleted_at timestamp,
+  version bigint NOT NULL DEFAULT 1
+);
+
+ALTER TABLE %[1]s.categories ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;`, r.schema)
 }
 
 // GetAll gets all non-deleted categories
 func (r *Repository) GetAll(ctx context.Context) (categories []book.Category, err error) {
 	data := []fromDB{}
-	err = r.db.SelectContext(ctx, &data, fmt.Sprintf("SELECT id, name, parent_id FROM %s.categories WHERE deleted_at=$1;", r.schema), time.Time{})
+	err = sqltx.DB(ctx, r.db).SelectContext(ctx, &data, fmt.Sprintf("SELECT id, name, parent_id, version FROM %s.categories WHERE deleted_at=$1;", r.schema), time.Time{})
 	if err != nil {
 		return
 	}
@@ -66,6 +79,7 @@ func (r *Repository) GetAll(ctx context.Context) (categories []book.Category, er
 			ID:       id,
 			Name:     item.Name,
 			ParentID: parentID,
+			Version:  item.Version,
 		}
 	}
 	return
@@ -74,8 +88,8 @@ func (r *Repository) GetAll(ctx context.Context) (categories []book.Category, er
 // Get gets non-deleted category by ID
 func (r *Repository) Get(ctx context.Context, id uuid.UUID) (book.Category, error) {
 	a := fromDB{}
-	err := r.db.GetContext(
-		ctx, &a, fmt.Sprintf("SELECT id, name, parent_id FROM %s.categories WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
+	err := sqltx.DB(ctx, r.db).GetContext(
+		ctx, &a, fmt.Sprintf("SELECT id, name, parent_id, version FROM %s.categories WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
 	)
 	if err == sql.ErrNoRows {
 		return book.Category{}, &commonerrors.NotFound{What: fmt.Sprintf("Category with ID %s", id)}
@@ -88,7 +102,7 @@ func (r *Repository) Get(ctx context.Context, id uuid.UUID) (book.Category, erro
 		return book.Category{}, err
 	}
 	parentID, err := uuid.FromString(a.ID)
-	return book.Category{ID: foundID, Name: a.Name, ParentID: parentID}, err
+	return book.Category{ID: foundID, Name: a.Name, ParentID: parentID, Version: a.Version}, err
 }
 
 // Create stores new category
@@ -99,60 +113,74 @@ func (r *Repository) Create(ctx context.Context, dto category.CreateDTO) (book.C
 		parentID.String = dto.ParentID.String()
 		parentID.Valid = true
 	}
-	_, err := r.db.ExecContext(
+	_, err := sqltx.DB(ctx, r.db).ExecContext(
 		ctx,
 		fmt.Sprintf(`INSERT INTO %s.categories (id, name, parent_id, created_at, updated_at, deleted_at)
 			VALUES ($1, $2, $3, $4, $5, $6)`, r.schema),
 		id.String(), dto.Name, parentID, time.Now(), time.Time{}, time.Time{},
 	)
-	return book.Category{ID: id, Name: dto.Name, ParentID: dto.ParentID}, err
+	return book.Category{ID: id, Name: dto.Name, ParentID: dto.ParentID, Version: 1}, err
 }
 
-// Update updates stored non-deleted category
+// Update updates stored non-deleted category if it is still at expected version
 func (r *Repository) Update(ctx context.Context, dto book.Category) (book.Category, error) {
 	var parentID sql.NullString
 	if !dto.ParentID.IsZero() {
 		parentID.String = dto.ParentID.String()
 		parentID.Valid = true
 	}
-	res, err := r.db.ExecContext(
+	var version uint64
+	err := sqltx.DB(ctx, r.db).QueryRowxContext(
 		ctx,
 		fmt.Sprintf(`UPDATE %s.categories
-			SET name=$4, parent_id=$5, updated_at=$3
-			WHERE id=$1 AND deleted_at=$2;`, r.schema),
-		dto.ID.String(), time.Time{}, time.Now(), dto.Name, parentID,
-	)
-	if err != nil {
-		return book.Category{}, err
+			SET name=$4, parent_id=$5, updated_at=$3, version=version+1
+			WHERE id=$1 AND deleted_at=$2 AND (version=$6 OR $6=0)
+			RETURNING version;`, r.schema),
+		dto.ID.String(), time.Time{}, time.Now(), dto.Name, parentID, dto.Version,
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return book.Category{}, r.updateError(ctx, dto.ID, dto.Version)
 	}
-	count, err := res.RowsAffected()
 	if err != nil {
 		return book.Category{}, err
 	}
-	if count == 0 {
-		return book.Category{}, &commonerrors.NotFound{What: fmt.Sprintf("Category with ID %s", dto.ID)}
+	dto.Version = version
+	return dto, nil
+}
+
+// updateError tells why conditional update of category changed nothing
+func (r *Repository) updateError(ctx context.Context, id uuid.UUID, expected uint64) error {
+	var actual uint64
+	err := sqltx.DB(ctx, r.db).GetContext(
+		ctx, &actual, fmt.Sprintf("SELECT version FROM %s.categories WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
+	)
+	if err == sql.ErrNoRows {
+		return &commonerrors.NotFound{What: fmt.Sprintf("Category with ID %s", id)}
 	}
-	return dto, err
+	if err != nil {
+		return err
+	}
+	return &commonerrors.VersionMismatch{What: fmt.Sprintf("Category with ID %s", id), Expected: expected, Actual: actual}
 }
 
 // Delete sets stored category with id as deleted
 func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Category, error) {
 	var a fromDB
-	err := r.db.QueryRowxContext(
+	err := sqltx.DB(ctx, r.db).QueryRowxContext(
 		ctx,
-		fmt.Sprintf(`SELECT id, name, parent_id FROM %s.categories WHERE id=$1 AND deleted_at=$2;`, r.schema),
+		fmt.Sprintf(`SELECT id, name, parent_id, version FROM %s.categories WHERE id=$1 AND deleted_at=$2;`, r.schema),
 		id.String(), time.Time{},
-	).Scan(&a.ID, &a.Name, &a.ParentID)
+	).Scan(&a.ID, &a.Name, &a.ParentID, &a.Version)
 	if err == sql.ErrNoRows {
 		return book.Category{}, &commonerrors.NotFound{What: fmt.Sprintf("Category with ID %s", id)}
 	}
 	if err != nil {
 		return book.Category{}, err
 	}
-	res, err := r.db.ExecContext(
+	res, err := sqltx.DB(ctx, r.db).ExecContext(
 		ctx,
 		fmt.Sprintf(`UPDATE %s.categories
-			SET deleted_at=$2
+			SET deleted_at=$2, version=version+1
 			WHERE id=$1 AND deleted_at=$3;`, r.schema),
 		id.String(), time.Now(), time.Time{},
 	)
@@ -170,5 +198,95 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (book.Category, e
 	if a.ParentID.Valid {
 		parentID, err = uuid.FromString(a.ParentID.String)
 	}
-	return book.Category{ID: id, Name: a.Name, ParentID: parentID}, err
+	return book.Category{ID: id, Name: a.Name, ParentID: parentID, Version: a.Version + 1}, err
+}
+
+// GetDeleted gets deleted categories, the latest deleted first
+func (r *Repository) GetDeleted(ctx context.Context, from, count uint) (categories []category.StoredCategory, err error) {
+	stmt := fmt.Sprintf(`SELECT id, name, parent_id, version, created_at, updated_at, deleted_at FROM %s.categories
+		WHERE deleted_at<>$1
+		ORDER BY deleted_at DESC
+		OFFSET $2 ROWS`, r.schema)
+	if count != 0 {
+		stmt += fmt.Sprintf(`
+		LIMIT %d`, count)
+	}
+	data := []deletedFromDB{}
+	err = sqltx.DB(ctx, r.db).SelectContext(ctx, &data, stmt, time.Time{}, from)
+	if err != nil {
+		return
+	}
+	var c book.Category
+	categories = make([]category.StoredCategory, len(data))
+	for i, item := range data {
+		c, err = item.toCategory()
+		if err != nil {
+			return
+		}
+		categories[i] = category.StoredCategory{
+			Category: c,
+			Stored: stored.Stored{
+				CreatedAt: item.CreatedAt.Time,
+				UpdatedAt: item.UpdatedAt.Time,
+				DeletedAt: item.DeletedAt,
+			},
+		}
+	}
+	return
+}
+
+// Restore undeletes stored category with id
+func (r *Repository) Restore(ctx context.Context, id uuid.UUID) (book.Category, error) {
+	var c fromDB
+	err := sqltx.DB(ctx, r.db).QueryRowxContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %s.categories
+			SET deleted_at=$2, updated_at=$3, version=version+1
+			WHERE id=$1 AND deleted_at<>$2
+			RETURNING id, name, parent_id, version;`, r.schema),
+		id.String(), time.Time{}, time.Now(),
+	).Scan(&c.ID, &c.Name, &c.ParentID, &c.Version)
+	if err == sql.ErrNoRows {
+		return book.Category{}, &commonerrors.NotFound{What: fmt.Sprintf("Deleted category with ID %s", id)}
+	}
+	if err != nil {
+		return book.Category{}, err
+	}
+	return c.toCategory()
+}
+
+// Purge removes categories deleted before given time.
+// Parents of stored categories and categories of deleted books, which are linked back on restore of the book, are kept.
+func (r *Repository) Purge(ctx context.Context, before time.Time) (uint64, error) {
+	res, err := sqltx.DB(ctx, r.db).ExecContext(
+		ctx,
+		fmt.Sprintf(`DELETE FROM %[1]s.categories as c
+			WHERE c.deleted_at<>$1 AND c.deleted_at<$2
+			AND NOT EXISTS (SELECT 1 FROM %[1]s.categories as ch WHERE ch.parent_id=c.id)
+			AND NOT EXISTS (
+				SELECT 1
+				FROM %[1]s.books_categories as bc
+				INNER JOIN %[1]s.books as b
+				ON (b.id=bc.book_id)
+				WHERE bc.category_id=c.id AND b.deleted_at<>$1
+			);`, r.schema),
+		time.Time{}, before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	return uint64(count), err
+}
+
+func (f fromDB) toCategory() (book.Category, error) {
+	id, err := uuid.FromString(f.ID)
+	if err != nil {
+		return book.Category{}, err
+	}
+	var parentID uuid.UUID
+	if f.ParentID.Valid {
+		parentID, err = uuid.FromString(f.ParentID.String)
+	}
+	return book.Category{ID: id, Name: f.Name, ParentID: parentID, Version: f.Version}, err
 }
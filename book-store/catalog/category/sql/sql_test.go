@@ -9,6 +9,8 @@ import (
 	"os"
 	"testing"
 
+	authorsql "github.com/Vesninovich/go-tasks/book-store/catalog/author/sql"
+	booksql "github.com/Vesninovich/go-tasks/book-store/catalog/book/sql"
 	"github.com/Vesninovich/go-tasks/book-store/catalog/category"
 	categorysql "github.com/Vesninovich/go-tasks/book-store/catalog/category/sql"
 	"github.com/Vesninovich/go-tasks/book-store/catalog/category/tests"
@@ -32,6 +34,11 @@ func TestMain(m *testing.M) {
 	c := categorysql.New(db, schema)
 	log.Println(c.CreateTableStmt())
 	db.MustExec(c.CreateTableStmt())
+	// purge checks whether categories are linked to deleted books
+	a := authorsql.New(db, schema)
+	db.MustExec(a.CreateTableStmt())
+	b := booksql.New(db, schema)
+	db.MustExec(b.CreateTableStmt())
 	res := m.Run()
 	db.MustExec(fmt.Sprintf("DROP SCHEMA %s CASCADE;", schema))
 	os.Exit(res)
@@ -78,6 +85,10 @@ func TestUpdateWithSomeDeleted(t *testing.T) {
 	tests.RepoUpdateWithSomeDeleted(t, constructor)
 }
 
+func TestUpdateVersion(t *testing.T) {
+	tests.RepoUpdateVersion(t, constructor)
+}
+
 func TestDelete(t *testing.T) {
 	tests.RepoDelete(t, constructor)
 }
@@ -89,3 +100,15 @@ func TestDeleteTwice(t *testing.T) {
 func TestDeleteNonExisting(t *testing.T) {
 	tests.RepoDeleteNonExisting(t, constructor)
 }
+
+func TestGetDeleted(t *testing.T) {
+	tests.RepoGetDeleted(t, constructor)
+}
+
+func TestRestore(t *testing.T) {
+	tests.RepoRestore(t, constructor)
+}
+
+func TestPurge(t *testing.T) {
+	tests.RepoPurge(t, constructor)
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 
@@ -13,9 +15,20 @@ import (
 	booksql "github.com/Vesninovich/go-tasks/book-store/catalog/book/sql"
 	categoryservice "github.com/Vesninovich/go-tasks/book-store/catalog/category/service"
 	categorysql "github.com/Vesninovich/go-tasks/book-store/catalog/category/sql"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	eventsql "github.com/Vesninovich/go-tasks/book-store/catalog/event/sql"
 	cataloggrpc "github.com/Vesninovich/go-tasks/book-store/catalog/grpc"
 	"github.com/Vesninovich/go-tasks/book-store/catalog/rest"
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	auditsql "github.com/Vesninovich/go-tasks/book-store/common/audit/sql"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth/authgrpc"
 	pb "github.com/Vesninovich/go-tasks/book-store/common/catalog"
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
+	idempotencysql "github.com/Vesninovich/go-tasks/book-store/common/idempotency/sql"
+	"github.com/Vesninovich/go-tasks/book-store/common/sqltx"
+	"github.com/Vesninovich/go-tasks/book-store/common/stored"
+	"github.com/Vesninovich/go-tasks/book-store/common/tlsconf"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/jmoiron/sqlx"
 	"google.golang.org/grpc"
@@ -35,31 +48,73 @@ import (
 // @host localhost:8002
 // @BasePath /
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+
 // @tag.name Book
 // @tag.description Quering and creating books
 
+// @tag.name Admin
+// @tag.description Restoring and purging deleted books, authors and categories
+
 const dbURL = "postgresql://gobookstorecatalog@localhost:5432/gobookstore"
 const schema = "catalog"
 const grpcHost = "localhost:8001"
 const restHost = "localhost:8002"
 
 func main() {
-	db, ar, cr, br := initSQL()
+	db, ar, cr, br, er, is, aus := initSQL()
 	defer db.Close()
 
+	window, err := idempotency.WindowFromEnv("CATALOG")
+	if err != nil {
+		log.Fatalf("Failed to set up idempotency keys: %s", err)
+	}
+	idem := idempotency.New(is, window)
+
 	lis, err := net.Listen("tcp", grpcHost)
 	if err != nil {
 		log.Fatalf("Failed to listen due to %s", err)
 	}
 	log.Println("Listening on " + grpcHost)
 
-	grpcServer := grpc.NewServer()
-
-	as := authorservice.New(ar)
-	cs := categoryservice.New(cr)
-	bs := bookservice.New(br, as, cs)
+	tlsConf := tlsconf.FromEnv("CATALOG")
+	creds, err := tlsConf.ServerOption()
+	if err != nil {
+		log.Fatalf("Failed to set up TLS for gRPC server: %s", err)
+	}
+	grpcServer := grpc.NewServer(
+		creds,
+		grpc.ChainUnaryInterceptor(authgrpc.UnaryServerInterceptor(tlsConf.ClientNames...), idem.UnaryServerInterceptor("/catalog.Catalog/CreateBook")),
+		grpc.ChainStreamInterceptor(authgrpc.StreamServerInterceptor(tlsConf.ClientNames...)),
+	)
+
+	bus := event.NewBus(er)
+	// changes and their events are stored together, subscribers learn about them after commit
+	transactions := sqltx.New(db)
+	bus.UseTransactions(transactions)
+	as := authorservice.New(ar, bus)
+	cs := categoryservice.New(cr, bus)
+	bs := bookservice.New(br, as, cs, bus)
+	recorder := audit.NewRecorder(aus)
+	// audit entries are saved in transactions of changes they describe
+	recorder.UseTransactions(transactions)
+	as.UseAudit(recorder)
+	cs.UseAudit(recorder)
+	bs.UseAudit(recorder)
+
+	retention, purgeInterval, err := stored.PurgeFromEnv("CATALOG")
+	if err != nil {
+		log.Fatalf("Failed to set up purge of deleted items: %s", err)
+	}
+	go bs.RunPurges(context.Background(), retention, purgeInterval)
 
-	pb.RegisterCatalogServer(grpcServer, cataloggrpc.New(bs))
+	pb.RegisterCatalogServer(grpcServer, cataloggrpc.New(bs, as, cs, bus))
 	log.Println("Starting gRPC server")
 	go func() {
 		if err := grpcServer.Serve(lis); err != nil {
@@ -67,7 +122,33 @@ func main() {
 		}
 	}()
 
-	restServer := rest.New(restHost, "/book", bs)
+	restServer := rest.New(restHost, "/book", bs, as, cs)
+	restServer.UseIdempotency(idem)
+	restServer.UseAudit(recorder)
+	if tlsConf.Enabled() {
+		// client certificates are only required from services calling gRPC
+		restConf := tlsConf
+		restConf.ClientNames = nil
+		c, err := restConf.Server()
+		if err != nil {
+			log.Fatalf("Failed to set up TLS for REST server: %s", err)
+		}
+		restServer.UseTLS(c)
+	}
+	authenticator, err := auth.FromEnv("CATALOG")
+	if err != nil {
+		log.Fatalf("Failed to set up authentication for REST server: %s", err)
+	}
+	if authenticator != nil {
+		// anyone may read catalog, only admins may change it and manage deleted items
+		restServer.UseAuth(auth.NewMiddleware(authenticator,
+			auth.Rule{PathPrefix: "/admin", Roles: []string{"admin"}},
+			auth.Rule{Methods: []string{http.MethodGet}, PathPrefix: "/book", Anonymous: true},
+			auth.Rule{PathPrefix: "/book", Roles: []string{"admin"}},
+		))
+	} else {
+		log.Println("No authentication configured, REST API is open to everyone")
+	}
 	log.Println("Starting REST server on " + restHost)
 	go func() {
 		if err = restServer.Start(); err != nil {
@@ -76,7 +157,7 @@ func main() {
 	}()
 
 	// Run until interrupt
-	sig := make(chan os.Signal)
+	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 	<-sig
 	fmt.Println("SIGINT")
@@ -85,7 +166,7 @@ func main() {
 	os.Exit(0)
 }
 
-func initSQL() (*sqlx.DB, *authorsql.Repository, *categorysql.Repository, *booksql.Repository) {
+func initSQL() (*sqlx.DB, *authorsql.Repository, *categorysql.Repository, *booksql.Repository, *eventsql.Repository, *idempotencysql.Store, *auditsql.Store) {
 	db, err := sqlx.Connect("pgx", dbURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to DB at URL %s\n%s", dbURL, err)
@@ -100,6 +181,9 @@ func initSQL() (*sqlx.DB, *authorsql.Repository, *categorysql.Repository, *books
 	a := authorsql.New(db, schema)
 	c := categorysql.New(db, schema)
 	b := booksql.New(db, schema)
+	e := eventsql.New(db, schema)
+	i := idempotencysql.New(db.DB, schema)
+	au := auditsql.New(db, schema)
 
 	log.Println("Creating tables")
 	log.Println(a.CreateTableStmt())
@@ -108,7 +192,13 @@ func initSQL() (*sqlx.DB, *authorsql.Repository, *categorysql.Repository, *books
 	db.MustExec(c.CreateTableStmt())
 	log.Println(b.CreateTableStmt())
 	db.MustExec(b.CreateTableStmt())
+	log.Println(e.CreateTableStmt())
+	db.MustExec(e.CreateTableStmt())
+	log.Println(i.CreateTableStmt())
+	db.MustExec(i.CreateTableStmt())
+	log.Println(au.CreateTableStmt())
+	db.MustExec(au.CreateTableStmt())
 	log.Println("Finished setting up DB")
 
-	return db, a, c, b
+	return db, a, c, b, e, i, au
 }
@@ -0,0 +1,192 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+const subscriptionBuffer = 64
+const replayBatch = 100
+
+// Bus stores published events and delivers them to subscribers
+type Bus struct {
+	repo         Repository
+	transactions Transactor
+
+	lock sync.Mutex
+	subs map[chan Event]bool
+}
+
+// Subscription delivers events to subscriber
+type Subscription struct {
+	events chan Event
+	err    error
+}
+
+// NewBus creates new Bus storing events in given repository
+func NewBus(r Repository) *Bus {
+	return &Bus{
+		repo: r,
+		subs: make(map[chan Event]bool),
+	}
+}
+
+// UseTransactions makes bus store events in the same transaction as changes they are about.
+// Without it change and its event are stored one after another.
+func (b *Bus) UseTransactions(t Transactor) {
+	b.transactions = t
+}
+
+// Publish runs change and stores event of it, change returns ID of changed entity.
+// If either fails, error is returned and, when bus uses transactions, neither is stored.
+// Event is delivered to subscribers only after it is stored.
+func (b *Bus) Publish(ctx context.Context, entity Entity, typ Type, change func(ctx context.Context) (uuid.UUID, error)) error {
+	var e Event
+	run := func(ctx context.Context) error {
+		id, err := change(ctx)
+		if err != nil {
+			return err
+		}
+		e, err = b.repo.Append(ctx, Event{
+			Entity:    entity,
+			Type:      typ,
+			ID:        id,
+			CreatedAt: time.Now(),
+		})
+		return err
+	}
+	var err error
+	if b.transactions != nil {
+		err = b.transactions.InTransaction(ctx, run)
+	} else {
+		err = run(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	b.deliver(e)
+	return nil
+}
+
+func (b *Bus) deliver(e Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+			// subscriber does not keep up, it will catch up from repository
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// LastSequence returns sequence of the last published event
+func (b *Bus) LastSequence(ctx context.Context) (uint64, error) {
+	return b.repo.LastSequence(ctx)
+}
+
+// Subscribe subscribes to events with sequence greater than given one.
+// Stored events are replayed first, then published events are delivered as they come.
+// Events channel of subscription is closed when ctx is done or on failure to read stored events.
+func (b *Bus) Subscribe(ctx context.Context, after uint64) *Subscription {
+	s := &Subscription{events: make(chan Event)}
+	go b.serve(ctx, s, after)
+	return s
+}
+
+// Events returns channel of subscribed events
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err returns error that caused subscription to end, it is valid after events channel is closed
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+func (b *Bus) serve(ctx context.Context, s *Subscription, last uint64) {
+	defer close(s.events)
+	for {
+		live := b.register()
+		last, s.err = b.replay(ctx, s, last)
+		if s.err != nil {
+			b.unregister(live)
+			return
+		}
+		for open := true; open; {
+			var e Event
+			select {
+			case e, open = <-live:
+				if !open || e.Sequence <= last {
+					continue
+				}
+				if e.Sequence > last+1 {
+					// events stored concurrently may be delivered out of order, missed ones are read from repository
+					last, s.err = b.replay(ctx, s, last)
+					if s.err != nil {
+						b.unregister(live)
+						return
+					}
+					continue
+				}
+				select {
+				case s.events <- e:
+					last = e.Sequence
+				case <-ctx.Done():
+					b.unregister(live)
+					s.err = ctx.Err()
+					return
+				}
+			case <-ctx.Done():
+				b.unregister(live)
+				s.err = ctx.Err()
+				return
+			}
+		}
+	}
+}
+
+func (b *Bus) replay(ctx context.Context, s *Subscription, last uint64) (uint64, error) {
+	for {
+		events, err := b.repo.GetAfter(ctx, last, replayBatch)
+		if err != nil {
+			return last, err
+		}
+		for _, e := range events {
+			select {
+			case s.events <- e:
+				last = e.Sequence
+			case <-ctx.Done():
+				return last, ctx.Err()
+			}
+		}
+		if len(events) < replayBatch {
+			return last, nil
+		}
+	}
+}
+
+func (b *Bus) register() chan Event {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	ch := make(chan Event, subscriptionBuffer)
+	b.subs[ch] = true
+	return ch
+}
+
+func (b *Bus) unregister(ch chan Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.subs[ch] {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
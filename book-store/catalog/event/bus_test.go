@@ -0,0 +1,142 @@
+package event_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event/inmemory"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+var ctx = context.Background()
+
+func TestSubscribeReplaysAndDeliversLive(t *testing.T) {
+	b := event.NewBus(inmemory.New())
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	publish(t, b, event.Book, event.Created, ids[0])
+	publish(t, b, event.Author, event.Updated, ids[1])
+
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s := b.Subscribe(subCtx, 0)
+	checkNext(t, s, 1, ids[0])
+	checkNext(t, s, 2, ids[1])
+
+	publish(t, b, event.Category, event.Deleted, ids[2])
+	e := checkNext(t, s, 3, ids[2])
+	if e.Entity != event.Category || e.Type != event.Deleted {
+		t.Errorf("Got wrong event %s %s", e.Entity, e.Type)
+	}
+
+	cancel()
+	select {
+	case _, open := <-s.Events():
+		if open {
+			t.Error("Did not expect to get event after cancel")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected events channel to be closed after cancel")
+	}
+	if s.Err() != context.Canceled {
+		t.Errorf("Expected subscription to end with cancel error, got %v", s.Err())
+	}
+}
+
+func TestSubscribeResumesFromSequence(t *testing.T) {
+	b := event.NewBus(inmemory.New())
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for _, id := range ids {
+		publish(t, b, event.Book, event.Updated, id)
+	}
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s := b.Subscribe(subCtx, 2)
+	checkNext(t, s, 3, ids[2])
+}
+
+func TestSlowSubscriberCatchesUp(t *testing.T) {
+	b := event.NewBus(inmemory.New())
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s := b.Subscribe(subCtx, 0)
+
+	count := 300
+	ids := make([]uuid.UUID, count)
+	for i := range ids {
+		ids[i] = uuid.New()
+		publish(t, b, event.Book, event.Created, ids[i])
+	}
+	for i, id := range ids {
+		checkNext(t, s, uint64(i+1), id)
+	}
+}
+
+func TestFailedChangeIsNotPublished(t *testing.T) {
+	b := event.NewBus(inmemory.New())
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	s := b.Subscribe(subCtx, 0)
+
+	failure := errors.New("failure")
+	err := b.Publish(ctx, event.Book, event.Created, func(context.Context) (uuid.UUID, error) {
+		return uuid.UUID{}, failure
+	})
+	if err != failure {
+		t.Errorf("Expected failure of change to be returned, got %v", err)
+	}
+	if last, _ := b.LastSequence(ctx); last != 0 {
+		t.Errorf("Expected event of failed change not to be stored, got sequence %d", last)
+	}
+	id := uuid.New()
+	publish(t, b, event.Book, event.Created, id)
+	checkNext(t, s, 1, id)
+}
+
+type transactor struct {
+	runs int
+}
+
+func (tr *transactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	tr.runs++
+	return fn(ctx)
+}
+
+func TestPublishInTransaction(t *testing.T) {
+	b := event.NewBus(inmemory.New())
+	tr := &transactor{}
+	b.UseTransactions(tr)
+	publish(t, b, event.Author, event.Deleted, uuid.New())
+	if tr.runs != 1 {
+		t.Errorf("Expected change and event to be stored in transaction, got %d transactions", tr.runs)
+	}
+}
+
+func publish(t *testing.T, b *event.Bus, entity event.Entity, typ event.Type, id uuid.UUID) {
+	t.Helper()
+	err := b.Publish(ctx, entity, typ, func(context.Context) (uuid.UUID, error) {
+		return id, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to publish event: %s", err)
+	}
+}
+
+func checkNext(t *testing.T, s *event.Subscription, sequence uint64, id uuid.UUID) event.Event {
+	t.Helper()
+	select {
+	case e, open := <-s.Events():
+		if !open {
+			t.Fatalf("Subscription ended unexpectedly: %v", s.Err())
+		}
+		if e.Sequence != sequence || e.ID != id {
+			t.Fatalf("Expected to get event %d, got %d", sequence, e.Sequence)
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for event %d", sequence)
+	}
+	return event.Event{}
+}
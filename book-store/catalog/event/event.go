@@ -0,0 +1,88 @@
+package event
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+// Entity is type of changed catalog entity
+type Entity uint
+
+// Possible Entities
+const (
+	Book Entity = iota
+	Author
+	Category
+)
+
+// Type is type of change
+type Type uint
+
+// Possible Types
+const (
+	Created Type = iota
+	Updated
+	Deleted
+	Restored
+)
+
+// Event represents change of catalog entity
+type Event struct {
+	Sequence  uint64
+	Entity    Entity
+	Type      Type
+	ID        uuid.UUID
+	CreatedAt time.Time
+}
+
+// Repository of Events
+type Repository interface {
+	// Append stores event assigning next sequence number to it
+	Append(ctx context.Context, e Event) (Event, error)
+	// GetAfter gets up to count events with sequence greater than given in order of sequence
+	GetAfter(ctx context.Context, sequence uint64, count uint) ([]Event, error)
+	// LastSequence returns sequence of the last stored event, 0 if there are none
+	LastSequence(ctx context.Context) (uint64, error)
+}
+
+// Publisher publishes events about changes of catalog entities
+type Publisher interface {
+	// Publish runs change of entity and stores event of it, change returns ID of changed entity
+	Publish(ctx context.Context, entity Entity, typ Type, change func(ctx context.Context) (uuid.UUID, error)) error
+}
+
+// Transactor runs changes of repositories and storing of their events in one transaction
+type Transactor interface {
+	// InTransaction runs fn in transaction committed if fn succeeds and rolled back otherwise
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+func (e Entity) String() string {
+	switch e {
+	case Book:
+		return "book"
+	case Author:
+		return "author"
+	case Category:
+		return "category"
+	default:
+		return ""
+	}
+}
+
+func (t Type) String() string {
+	switch t {
+	case Created:
+		return "created"
+	case Updated:
+		return "updated"
+	case Deleted:
+		return "deleted"
+	case Restored:
+		return "restored"
+	default:
+		return ""
+	}
+}
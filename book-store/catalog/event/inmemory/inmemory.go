@@ -0,0 +1,57 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+)
+
+// Repository represents in-memory repository of events
+type Repository struct {
+	data []event.Event
+	lock sync.RWMutex
+}
+
+// New creates new in-memory repository of events
+func New() *Repository {
+	return &Repository{
+		data: make([]event.Event, 0),
+	}
+}
+
+// Append stores event assigning next sequence number to it
+func (r *Repository) Append(ctx context.Context, e event.Event) (event.Event, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	e.Sequence = uint64(len(r.data)) + 1
+	r.data = append(r.data, e)
+	return e, nil
+}
+
+// GetAfter gets up to count events with sequence greater than given in order of sequence
+func (r *Repository) GetAfter(ctx context.Context, sequence uint64, count uint) ([]event.Event, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	from := sort.Search(len(r.data), func(i int) bool {
+		return r.data[i].Sequence > sequence
+	})
+	to := len(r.data)
+	if count != 0 && from+int(count) < to {
+		to = from + int(count)
+	}
+	res := make([]event.Event, to-from)
+	copy(res, r.data[from:to])
+	return res, nil
+}
+
+// LastSequence returns sequence of the last stored event, 0 if there are none
+func (r *Repository) LastSequence(ctx context.Context) (uint64, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	return uint64(len(r.data)), nil
+}
@@ -0,0 +1,21 @@
+package inmemory_test
+
+import (
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event/inmemory"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event/tests"
+)
+
+func constructor(t *testing.T) event.Repository {
+	return inmemory.New()
+}
+
+func TestAppend(t *testing.T) {
+	tests.RepoAppend(t, constructor)
+}
+
+func TestGetAfter(t *testing.T) {
+	tests.RepoGetAfter(t, constructor)
+}
@@ -0,0 +1,107 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	"github.com/Vesninovich/go-tasks/book-store/common/sqltx"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Repository provides access to relational DB storage of events
+type Repository struct {
+	db     *sqlx.DB
+	schema string
+}
+
+type fromDB struct {
+	Sequence  uint64
+	Entity    event.Entity
+	Type      event.Type
+	EntityID  string    `db:"entity_id"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// New creates a new instance of Repository
+func New(db *sqlx.DB, schema string) *Repository {
+	return &Repository{db, schema}
+}
+
+// CreateTableStmt of events
+func (r *Repository) CreateTableStmt() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.events(
+  sequence bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  entity integer NOT NULL,
+  type integer NOT NULL,
+  entity_id uuid NOT NULL,
+  created_at timestamp
+);`, r.schema)
+}
+
+// Append stores event assigning next sequence number to it.
+// Table of events is locked until transaction ends, so that events become visible in order of their sequence
+// and subscribers resuming from the last seen sequence do not skip any.
+func (r *Repository) Append(ctx context.Context, e event.Event) (event.Event, error) {
+	err := sqltx.Run(ctx, r.db, func(ctx context.Context) error {
+		q := sqltx.DB(ctx, r.db)
+		_, err := q.ExecContext(ctx, fmt.Sprintf("LOCK TABLE %s.events IN EXCLUSIVE MODE;", r.schema))
+		if err != nil {
+			return err
+		}
+		return q.QueryRowxContext(
+			ctx,
+			fmt.Sprintf(`INSERT INTO %s.events (entity, type, entity_id, created_at)
+				VALUES ($1, $2, $3, $4)
+				RETURNING sequence`, r.schema),
+			e.Entity, e.Type, e.ID.String(), e.CreatedAt,
+		).Scan(&e.Sequence)
+	})
+	return e, err
+}
+
+// GetAfter gets up to count events with sequence greater than given in order of sequence
+func (r *Repository) GetAfter(ctx context.Context, sequence uint64, count uint) (events []event.Event, err error) {
+	stmt := fmt.Sprintf(`SELECT sequence, entity, type, entity_id, created_at
+		FROM %s.events
+		WHERE sequence > $1
+		ORDER BY sequence`, r.schema)
+	if count != 0 {
+		stmt += fmt.Sprintf(`
+		LIMIT %d`, count)
+	}
+	data := []fromDB{}
+	err = sqltx.DB(ctx, r.db).SelectContext(ctx, &data, stmt, sequence)
+	if err != nil {
+		return
+	}
+	events = make([]event.Event, len(data))
+	for i, item := range data {
+		events[i], err = item.toEvent()
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// LastSequence returns sequence of the last stored event, 0 if there are none
+func (r *Repository) LastSequence(ctx context.Context) (sequence uint64, err error) {
+	err = sqltx.DB(ctx, r.db).GetContext(
+		ctx, &sequence, fmt.Sprintf("SELECT COALESCE(MAX(sequence), 0) FROM %s.events;", r.schema),
+	)
+	return
+}
+
+func (f fromDB) toEvent() (event.Event, error) {
+	id, err := uuid.FromString(f.EntityID)
+	return event.Event{
+		Sequence:  f.Sequence,
+		Entity:    f.Entity,
+		Type:      f.Type,
+		ID:        id,
+		CreatedAt: f.CreatedAt,
+	}, err
+}
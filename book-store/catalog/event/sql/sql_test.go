@@ -0,0 +1,55 @@
+// +build sql
+
+package sql_test
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	eventsql "github.com/Vesninovich/go-tasks/book-store/catalog/event/sql"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event/tests"
+	_ "github.com/jackc/pgx/v4/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+const dbURL = "postgresql://gobookstorecatalog@localhost:5432/gobookstore"
+const schema = "catalog_events_test"
+
+var db *sqlx.DB
+
+func TestMain(m *testing.M) {
+	var err error
+	db, err = sqlx.Connect("pgx", dbURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to DB at URL %s\n%s", dbURL, err)
+	}
+	defer db.Close()
+	db.MustExec("CREATE SCHEMA IF NOT EXISTS " + schema)
+	r := eventsql.New(db, schema)
+	log.Println(r.CreateTableStmt())
+	db.MustExec(r.CreateTableStmt())
+	res := m.Run()
+	db.MustExec(fmt.Sprintf("DROP SCHEMA %s CASCADE;", schema))
+	os.Exit(res)
+}
+
+func constructor(t *testing.T) event.Repository {
+	t.Cleanup(clear)
+	return eventsql.New(db, schema)
+}
+
+func clear() {
+	db.MustExecContext(context.Background(), fmt.Sprintf("DELETE FROM %s.events;", schema))
+}
+
+func TestAppend(t *testing.T) {
+	tests.RepoAppend(t, constructor)
+}
+
+func TestGetAfter(t *testing.T) {
+	tests.RepoGetAfter(t, constructor)
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+var events = []event.Event{
+	{Entity: event.Author, Type: event.Created, ID: uuid.New()},
+	{Entity: event.Book, Type: event.Created, ID: uuid.New()},
+	{Entity: event.Book, Type: event.Updated, ID: uuid.New()},
+	{Entity: event.Category, Type: event.Deleted, ID: uuid.New()},
+}
+var ctx = context.Background()
+
+// Constructor is function that constructs repository to test
+type Constructor func(*testing.T) event.Repository
+
+// RepoAppend tests appending events
+func RepoAppend(t *testing.T, c Constructor) {
+	repo := c(t)
+	last, err := repo.LastSequence(ctx)
+	if err != nil {
+		t.Fatalf("Error while getting last sequence: %s", err)
+	}
+	if last != 0 {
+		t.Errorf("Expected last sequence of empty repository to be 0, got %d", last)
+	}
+	stored := setup(t, repo)
+	for i := 1; i < len(stored); i++ {
+		if stored[i].Sequence <= stored[i-1].Sequence {
+			t.Fatal("Expected sequence of appended events to increase")
+		}
+	}
+	last, err = repo.LastSequence(ctx)
+	if err != nil {
+		t.Fatalf("Error while getting last sequence: %s", err)
+	}
+	if last != stored[len(stored)-1].Sequence {
+		t.Errorf("Expected last sequence to be %d, got %d", stored[len(stored)-1].Sequence, last)
+	}
+}
+
+// RepoGetAfter tests getting events after sequence
+func RepoGetAfter(t *testing.T, c Constructor) {
+	repo := c(t)
+	stored := setup(t, repo)
+
+	t.Run("all events", func(t *testing.T) {
+		res, err := repo.GetAfter(ctx, 0, 0)
+		if err != nil {
+			t.Fatalf("Error while getting events: %s", err)
+		}
+		if len(res) != len(stored) {
+			t.Fatalf("Expected to get %d events, got %d", len(stored), len(res))
+		}
+		for i, e := range res {
+			if e.Sequence != stored[i].Sequence ||
+				e.ID != stored[i].ID ||
+				e.Entity != stored[i].Entity ||
+				e.Type != stored[i].Type {
+				t.Errorf("Got wrong event at %d", i)
+			}
+		}
+	})
+
+	t.Run("events after sequence", func(t *testing.T) {
+		res, err := repo.GetAfter(ctx, stored[1].Sequence, 1)
+		if err != nil {
+			t.Fatalf("Error while getting events: %s", err)
+		}
+		if len(res) != 1 {
+			t.Fatalf("Expected to get 1 event, got %d", len(res))
+		}
+		if res[0].Sequence != stored[2].Sequence {
+			t.Error("Got wrong event")
+		}
+	})
+
+	t.Run("no events", func(t *testing.T) {
+		res, err := repo.GetAfter(ctx, stored[len(stored)-1].Sequence, 0)
+		if err != nil {
+			t.Fatalf("Error while getting events: %s", err)
+		}
+		if len(res) != 0 {
+			t.Fatalf("Expected to get no events, got %d", len(res))
+		}
+	})
+}
+
+func setup(t *testing.T, repo event.Repository) []event.Event {
+	stored := make([]event.Event, len(events))
+	for i, e := range events {
+		e.CreatedAt = time.Now()
+		s, err := repo.Append(ctx, e)
+		if err != nil {
+			t.Fatalf("Error while appending event: %s", err)
+		}
+		stored[i] = s
+	}
+	return stored
+}
@@ -2,24 +2,45 @@ package grpc
 
 import (
 	"context"
+	"time"
 
+	authorservice "github.com/Vesninovich/go-tasks/book-store/catalog/author/service"
 	bookservice "github.com/Vesninovich/go-tasks/book-store/catalog/book/service"
+	categoryservice "github.com/Vesninovich/go-tasks/book-store/catalog/category/service"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth/authgrpc"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/catalog"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// adminRole is role principal must have to call admin RPCs
+const adminRole = "admin"
+
 // Server implements catalog gRPC server
 type Server struct {
 	catalog.UnimplementedCatalogServer
 
-	bookService *bookservice.BookService
+	bookService     *bookservice.BookService
+	authorService   *authorservice.Service
+	categoryService *categoryservice.Service
+	bus             *event.Bus
 }
 
 // New creates Server object
-func New(bookService *bookservice.BookService) *Server {
+func New(
+	bookService *bookservice.BookService,
+	authorService *authorservice.Service,
+	categoryService *categoryservice.Service,
+	bus *event.Bus,
+) *Server {
 	return &Server{
-		bookService: bookService,
+		bookService:     bookService,
+		authorService:   authorService,
+		categoryService: categoryService,
+		bus:             bus,
 	}
 }
 
@@ -54,6 +75,28 @@ func (s *Server) GetBooks(q *catalog.BooksQuery, stream catalog.Catalog_GetBooks
 	return
 }
 
+// GetBooksByIDs godoc
+func (s *Server) GetBooksByIDs(q *catalog.BookIDs, stream catalog.Catalog_GetBooksByIDsServer) (err error) {
+	ids := make([]uuid.UUID, len(q.Ids))
+	for i, id := range q.Ids {
+		ids[i], err = uuid.FromBytes(id)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid book ID at %d: %s", i, err)
+		}
+	}
+	data, err := s.bookService.GetBooksByIDs(stream.Context(), ids)
+	if err != nil {
+		return
+	}
+	for _, item := range data {
+		err = stream.Send(makeBookResponse(item))
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
 // CreateBook godoc
 func (s *Server) CreateBook(ctx context.Context, dto *catalog.BookCreateDTO) (*catalog.Book, error) {
 	var autID, cID, cPID uuid.UUID
@@ -89,6 +132,154 @@ func (s *Server) CreateBook(ctx context.Context, dto *catalog.BookCreateDTO) (*c
 	return makeBookResponse(b), err
 }
 
+// WatchBooks godoc
+func (s *Server) WatchBooks(q *catalog.WatchQuery, stream catalog.Catalog_WatchBooksServer) (err error) {
+	ctx := stream.Context()
+	var after uint64
+	if q.AfterSequence == nil {
+		after, err = s.bus.LastSequence(ctx)
+		if err != nil {
+			return
+		}
+	} else {
+		after = *q.AfterSequence
+	}
+	sub := s.bus.Subscribe(ctx, after)
+	for e := range sub.Events() {
+		err = stream.Send(&catalog.Event{
+			Sequence:  e.Sequence,
+			Entity:    catalog.Event_Entity(e.Entity),
+			Type:      catalog.Event_Type(e.Type),
+			Id:        e.ID[:],
+			CreatedAt: e.CreatedAt.Unix(),
+		})
+		if err != nil {
+			return
+		}
+	}
+	if sub.Err() == ctx.Err() {
+		return nil
+	}
+	return sub.Err()
+}
+
+// GetDeletedBooks godoc
+func (s *Server) GetDeletedBooks(q *catalog.DeletedQuery, stream catalog.Catalog_GetDeletedBooksServer) error {
+	ctx := stream.Context()
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return err
+	}
+	data, err := s.bookService.GetDeletedBooks(ctx, uint(q.GetFrom()), uint(q.GetCount()))
+	if err != nil {
+		return err
+	}
+	for _, item := range data {
+		err = stream.Send(&catalog.DeletedBook{Book: makeBookResponse(item.ToBook()), DeletedAt: item.DeletedAt.Unix()})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDeletedAuthors godoc
+func (s *Server) GetDeletedAuthors(q *catalog.DeletedQuery, stream catalog.Catalog_GetDeletedAuthorsServer) error {
+	ctx := stream.Context()
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return err
+	}
+	data, err := s.authorService.GetDeletedAuthors(ctx, uint(q.GetFrom()), uint(q.GetCount()))
+	if err != nil {
+		return err
+	}
+	for _, item := range data {
+		err = stream.Send(&catalog.DeletedAuthor{Author: makeAuthorResponse(item.ToAuthor()), DeletedAt: item.DeletedAt.Unix()})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetDeletedCategories godoc
+func (s *Server) GetDeletedCategories(q *catalog.DeletedQuery, stream catalog.Catalog_GetDeletedCategoriesServer) error {
+	ctx := stream.Context()
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return err
+	}
+	data, err := s.categoryService.GetDeletedCategories(ctx, uint(q.GetFrom()), uint(q.GetCount()))
+	if err != nil {
+		return err
+	}
+	for _, item := range data {
+		err = stream.Send(&catalog.DeletedCategory{Category: makeCategoryResponse(item.ToCategory()), DeletedAt: item.DeletedAt.Unix()})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreBook godoc
+func (s *Server) RestoreBook(ctx context.Context, req *catalog.EntityID) (*catalog.Book, error) {
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return nil, err
+	}
+	id, err := uuid.FromBytes(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.bookService.RestoreBook(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return makeBookResponse(b), nil
+}
+
+// RestoreAuthor godoc
+func (s *Server) RestoreAuthor(ctx context.Context, req *catalog.EntityID) (*catalog.Author, error) {
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return nil, err
+	}
+	id, err := uuid.FromBytes(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.authorService.RestoreAuthor(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return makeAuthorResponse(a), nil
+}
+
+// RestoreCategory godoc
+func (s *Server) RestoreCategory(ctx context.Context, req *catalog.EntityID) (*catalog.Category, error) {
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return nil, err
+	}
+	id, err := uuid.FromBytes(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	c, err := s.categoryService.RestoreCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return makeCategoryResponse(c), nil
+}
+
+// Purge godoc
+func (s *Server) Purge(ctx context.Context, req *catalog.PurgeRequest) (*catalog.PurgeResult, error) {
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return nil, err
+	}
+	res, err := s.bookService.Purge(ctx, time.Unix(req.Before, 0))
+	if err != nil {
+		return nil, err
+	}
+	return &catalog.PurgeResult{Books: res.Books, Authors: res.Authors, Categories: res.Categories}, nil
+}
+
 func getUUIDs(bID []byte, author []byte, categories [][]byte) (bookID uuid.UUID, autID uuid.UUID, catIDs []uuid.UUID, err error) {
 	if bID != nil && len(bID) != 0 {
 		bookID, err = uuid.FromBytes(bID)
@@ -117,20 +308,28 @@ func getUUIDs(bID []byte, author []byte, categories [][]byte) (bookID uuid.UUID,
 func makeBookResponse(item book.Book) *catalog.Book {
 	categories := make([]*catalog.Category, len(item.Categories))
 	for i, cat := range item.Categories {
-		categories[i] = &catalog.Category{
-			Id:       cat.ID[:],
-			Name:     cat.Name,
-			ParentId: cat.ParentID[:],
-		}
+		categories[i] = makeCategoryResponse(cat)
 	}
 
 	return &catalog.Book{
+		Id:         item.ID[:],
+		Name:       item.Name,
+		Author:     makeAuthorResponse(item.Author),
+		Categories: categories,
+	}
+}
+
+func makeAuthorResponse(item book.Author) *catalog.Author {
+	return &catalog.Author{
 		Id:   item.ID[:],
 		Name: item.Name,
-		Author: &catalog.Author{
-			Id:   item.Author.ID[:],
-			Name: item.Author.Name,
-		},
-		Categories: categories,
+	}
+}
+
+func makeCategoryResponse(item book.Category) *catalog.Category {
+	return &catalog.Category{
+		Id:       item.ID[:],
+		Name:     item.Name,
+		ParentId: item.ParentID[:],
 	}
 }
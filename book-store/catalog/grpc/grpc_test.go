@@ -6,6 +6,7 @@ import (
 	"log"
 	"net"
 	"testing"
+	"time"
 
 	authorInMemory "github.com/Vesninovich/go-tasks/book-store/catalog/author/inmemory"
 	authorservice "github.com/Vesninovich/go-tasks/book-store/catalog/author/service"
@@ -13,12 +14,20 @@ import (
 	bookservice "github.com/Vesninovich/go-tasks/book-store/catalog/book/service"
 	categoryInMemory "github.com/Vesninovich/go-tasks/book-store/catalog/category/inmemory"
 	categoryservice "github.com/Vesninovich/go-tasks/book-store/catalog/category/service"
+	"github.com/Vesninovich/go-tasks/book-store/catalog/event"
+	eventInMemory "github.com/Vesninovich/go-tasks/book-store/catalog/event/inmemory"
 	cataloggrpc "github.com/Vesninovich/go-tasks/book-store/catalog/grpc"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth/authgrpc"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/catalog"
 	pb "github.com/Vesninovich/go-tasks/book-store/common/catalog"
+	"github.com/Vesninovich/go-tasks/book-store/common/tlsconf/tlstest"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
@@ -34,6 +43,7 @@ var cats = []book.Category{
 var as *authorservice.Service
 var bs *bookservice.BookService
 var cs *categoryservice.Service
+var bus *event.Bus
 
 func TestCreateBook(t *testing.T) {
 	s := setup(t)
@@ -264,13 +274,257 @@ func TestGetBooks(t *testing.T) {
 	}
 }
 
-func setup(t *testing.T) *grpc.Server {
+func TestGetBooksByIDs(t *testing.T) {
+	s := setup(t)
+	defer s.GracefulStop()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %s", err)
+	}
+	defer conn.Close()
+	client := pb.NewCatalogClient(conn)
+
+	b0, err := bs.CreateBook(ctx, "TestA", aut, []book.Category{cats[0]})
+	if err != nil {
+		t.Fatalf("Failed to create valid book: %s", err)
+	}
+	b1, err := bs.CreateBook(ctx, "TestB", aut, []book.Category{cats[1]})
+	if err != nil {
+		t.Fatalf("Failed to create valid book: %s", err)
+	}
+	_, err = bs.CreateBook(ctx, "TestC", aut, []book.Category{})
+	if err != nil {
+		t.Fatalf("Failed to create valid book: %s", err)
+	}
+	missing := uuid.New()
+
+	stream, err := client.GetBooksByIDs(ctx, &pb.BookIDs{
+		Ids: [][]byte{b0.ID[:], missing[:], b1.ID[:]},
+	})
+	if err != nil {
+		t.Fatalf("Failed to get books by ids: %s", err)
+	}
+	found := make(map[uuid.UUID]bool)
+	for {
+		b, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to read book from stream: %s", err)
+		}
+		id, err := uuid.FromBytes(b.Id)
+		if err != nil {
+			t.Fatalf("Failed to get uuid of book: %s", err)
+		}
+		found[id] = true
+	}
+	if len(found) != 2 || !found[b0.ID] || !found[b1.ID] {
+		t.Errorf("Expected to get 2 requested books, got %d", len(found))
+	}
+
+	stream, err = client.GetBooksByIDs(ctx, &pb.BookIDs{
+		Ids: [][]byte{append(b0.ID[:], 0)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to get books by ids: %s", err)
+	}
+	_, err = stream.Recv()
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected to get InvalidArgument for invalid book UUID, got %v", err)
+	}
+}
+
+func TestWatchBooks(t *testing.T) {
+	s := setup(t)
+	defer s.GracefulStop()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %s", err)
+	}
+	defer conn.Close()
+	client := pb.NewCatalogClient(conn)
+
+	b, err := bs.CreateBook(ctx, "TestA", aut, []book.Category{cats[0]})
+	if err != nil {
+		t.Fatalf("Failed to create valid book: %s", err)
+	}
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// setup created author and 2 categories, so book creation is 4th event
+	after := uint64(3)
+	replayed, err := client.WatchBooks(watchCtx, &pb.WatchQuery{AfterSequence: &after})
+	if err != nil {
+		t.Fatalf("Failed to watch books: %s", err)
+	}
+	e, err := replayed.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive event: %s", err)
+	}
+	if e.Sequence != 4 || e.Entity != pb.Event_BOOK || e.Type != pb.Event_CREATED {
+		t.Errorf("Got wrong replayed event %d %s %s", e.Sequence, e.Entity, e.Type)
+	}
+
+	live, err := client.WatchBooks(watchCtx, &pb.WatchQuery{})
+	if err != nil {
+		t.Fatalf("Failed to watch books: %s", err)
+	}
+	// make sure subscription is established before change
+	time.Sleep(50 * time.Millisecond)
+	_, err = bs.DeleteBook(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("Failed to delete book: %s", err)
+	}
+	for _, stream := range []pb.Catalog_WatchBooksClient{replayed, live} {
+		e, err = stream.Recv()
+		if err != nil {
+			t.Fatalf("Failed to receive event: %s", err)
+		}
+		id, err := uuid.FromBytes(e.Id)
+		if err != nil {
+			t.Fatalf("Failed to get uuid of book: %s", err)
+		}
+		if e.Sequence != 5 || e.Entity != pb.Event_BOOK || e.Type != pb.Event_DELETED || id != b.ID {
+			t.Errorf("Got wrong live event %d %s %s", e.Sequence, e.Entity, e.Type)
+		}
+	}
+}
+
+func TestForgedAdminRole(t *testing.T) {
+	s := setup(t)
+	defer s.GracefulStop()
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %s", err)
+	}
+	defer conn.Close()
+	client := pb.NewCatalogClient(conn)
+
+	b, err := bs.CreateBook(ctx, "TestA", aut, nil)
+	if err != nil {
+		t.Fatalf("Failed to create valid book: %s", err)
+	}
+	if _, err = bs.DeleteBook(ctx, b.ID); err != nil {
+		t.Fatalf("Failed to delete book: %s", err)
+	}
+	// client without certificate of trusted service claims admin role in metadata
+	forged := metadata.AppendToOutgoingContext(ctx, "x-auth-subject", "mallory", "x-auth-roles", "admin")
+	if _, err = client.Purge(forged, &pb.PurgeRequest{Before: time.Now().Add(time.Minute).Unix()}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected purge with forged role to be denied, got %v", err)
+	}
+	if _, err = client.RestoreBook(forged, &pb.EntityID{Id: b.ID[:]}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected restore with forged role to be denied, got %v", err)
+	}
+	deleted, err := client.GetDeletedBooks(forged, &pb.DeletedQuery{})
+	if err == nil {
+		_, err = deleted.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected reading deleted books with forged role to be denied, got %v", err)
+	}
+	if _, err = bs.RestoreBook(ctx, b.ID); err != nil {
+		t.Errorf("Expected book to stay in trash, got %s", err)
+	}
+}
+
+func TestRestoreAndPurge(t *testing.T) {
+	ca, err := tlstest.NewCA(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConf, err := ca.Issue("catalog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientConf, err := ca.Issue("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := serverConf.ServerOption()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := setup(t, creds)
+	defer s.GracefulStop()
+	// principal is only trusted when sent by client with certificate of trusted service
+	dialCreds, err := clientConf.DialOption("localhost")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), dialCreds)
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %s", err)
+	}
+	defer conn.Close()
+	client := pb.NewCatalogClient(conn)
+
+	b, err := bs.CreateBook(ctx, "TestA", aut, []book.Category{cats[0], cats[1]})
+	if err != nil {
+		t.Fatalf("Failed to create valid book: %s", err)
+	}
+	if _, err = bs.DeleteBook(ctx, b.ID); err != nil {
+		t.Fatalf("Failed to delete book: %s", err)
+	}
+
+	_, err = client.RestoreBook(ctx, &pb.EntityID{Id: b.ID[:]})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected restore without principal to be unauthenticated, got %v", err)
+	}
+	reader := authgrpc.OutgoingContext(auth.NewContext(ctx, auth.Principal{Subject: "reader"}))
+	_, err = client.RestoreBook(reader, &pb.EntityID{Id: b.ID[:]})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected restore without admin role to be denied, got %v", err)
+	}
+
+	admin := authgrpc.OutgoingContext(auth.NewContext(ctx, auth.Principal{Subject: "admin", Roles: []string{"admin"}}))
+	deleted, err := client.GetDeletedBooks(admin, &pb.DeletedQuery{})
+	if err != nil {
+		t.Fatalf("Failed to get deleted books: %s", err)
+	}
+	d, err := deleted.Recv()
+	if err != nil {
+		t.Fatalf("Failed to receive deleted book: %s", err)
+	}
+	if id, _ := uuid.FromBytes(d.Book.Id); id != b.ID || d.DeletedAt == 0 || len(d.Book.Categories) != 2 {
+		t.Errorf("Got wrong deleted book %v", d)
+	}
+	restored, err := client.RestoreBook(admin, &pb.EntityID{Id: b.ID[:]})
+	if err != nil {
+		t.Fatalf("Failed to restore book: %s", err)
+	}
+	if len(restored.Categories) != 2 {
+		t.Errorf("Expected restored book to have its categories, got %v", restored.Categories)
+	}
+
+	if _, err = bs.DeleteBook(ctx, b.ID); err != nil {
+		t.Fatalf("Failed to delete book: %s", err)
+	}
+	res, err := client.Purge(admin, &pb.PurgeRequest{Before: time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatalf("Failed to purge: %s", err)
+	}
+	if res.Books != 1 || res.Authors != 0 || res.Categories != 0 {
+		t.Errorf("Expected to purge deleted book only, got %v", res)
+	}
+	if _, err = client.RestoreBook(admin, &pb.EntityID{Id: b.ID[:]}); err == nil {
+		t.Error("Expected purged book not to be restored")
+	}
+}
+
+// setup starts server trusting principal sent by "orders" service, with given options, e.g. TLS credentials
+func setup(t *testing.T, opts ...grpc.ServerOption) *grpc.Server {
 	lis = bufconn.Listen(bufsize)
-	s := grpc.NewServer()
+	s := grpc.NewServer(append(
+		opts,
+		grpc.ChainUnaryInterceptor(authgrpc.UnaryServerInterceptor("orders")),
+		grpc.ChainStreamInterceptor(authgrpc.StreamServerInterceptor("orders")),
+	)...)
 
-	as = authorservice.New(authorInMemory.New())
-	cs = categoryservice.New(categoryInMemory.New())
-	bs = bookservice.New(bookInMemory.New(), as, cs)
+	bus = event.NewBus(eventInMemory.New())
+	as = authorservice.New(authorInMemory.New(), bus)
+	cs = categoryservice.New(categoryInMemory.New(), bus)
+	bs = bookservice.New(bookInMemory.New(), as, cs, bus)
 
 	var err error
 	aut, err = as.CreateAuthor(ctx, "TestA")
@@ -286,7 +540,7 @@ func setup(t *testing.T) *grpc.Server {
 		t.Fatalf("Failed to create category: %s", err)
 	}
 
-	pb.RegisterCatalogServer(s, cataloggrpc.New(bs))
+	pb.RegisterCatalogServer(s, cataloggrpc.New(bs, as, cs, bus))
 	go func() {
 		if err = s.Serve(lis); err != nil {
 			log.Fatalf("Failed to start gRPC server: %s", err)
@@ -0,0 +1,362 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	"github.com/Vesninovich/go-tasks/book-store/common/book"
+	"github.com/Vesninovich/go-tasks/book-store/common/etag"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+// adminURL is base URL of endpoints managing deleted items
+const adminURL = "/admin"
+
+type deletedAPIModel struct {
+	apiModel
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+type authorAPIModel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type deletedAuthorAPIModel struct {
+	authorAPIModel
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+type categoryAPIModel struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	ParentID string `json:"parentID,omitempty"`
+}
+
+type deletedCategoryAPIModel struct {
+	categoryAPIModel
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+type purgeAPIModel struct {
+	Books      uint64 `json:"books"`
+	Authors    uint64 `json:"authors"`
+	Categories uint64 `json:"categories"`
+}
+
+func (s *Server) handleAdminEndpoints(serveMux *http.ServeMux) {
+	restorePath := regexp.MustCompile(adminURL + "/(book|author|category)/" + uuid.REGEX + "/restore$")
+	serveMux.HandleFunc(adminURL+"/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == adminURL+"/book/deleted":
+			s.getDeletedBooks(w, r)
+		case r.Method == http.MethodGet && r.URL.Path == adminURL+"/author/deleted":
+			s.getDeletedAuthors(w, r)
+		case r.Method == http.MethodGet && r.URL.Path == adminURL+"/category/deleted":
+			s.getDeletedCategories(w, r)
+		case r.Method == http.MethodGet && r.URL.Path == adminURL+"/audit" && s.audit != nil:
+			s.getAudit(w, r)
+		case r.Method == http.MethodPost && r.URL.Path == adminURL+"/purge":
+			s.purge(w, r)
+		case r.Method == http.MethodPost && restorePath.MatchString(r.URL.Path):
+			switch strings.Split(r.URL.Path, "/")[2] {
+			case "book":
+				s.restoreBook(w, r)
+			case "author":
+				s.restoreAuthor(w, r)
+			default:
+				s.restoreCategory(w, r)
+			}
+		default:
+			writeNotFound(w)
+		}
+	})
+}
+
+// getDeletedBooks godoc
+// @Summary get deleted books
+// @Description get deleted books with categories they are linked back to on restore, the latest deleted first
+// @Tags Admin
+// @Produce json
+// @Param from query string false "results start"
+// @Param count query string false "results count, 10 by default"
+// @Success 200 {object} []deletedAPIModel "results"
+// @Failure 400 {string} string "malformed query"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/book/deleted [get]
+func (s *Server) getDeletedBooks(w http.ResponseWriter, r *http.Request) {
+	from, count, err := parsePagination(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	books, err := s.service.GetDeletedBooks(r.Context(), from, count)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	models := make([]deletedAPIModel, len(books))
+	for i, b := range books {
+		models[i] = deletedAPIModel{toResponse(b.ToBook()), b.DeletedAt}
+	}
+	writeJSON(w, models)
+}
+
+// getDeletedAuthors godoc
+// @Summary get deleted authors
+// @Description get deleted authors, the latest deleted first
+// @Tags Admin
+// @Produce json
+// @Param from query string false "results start"
+// @Param count query string false "results count, 10 by default"
+// @Success 200 {object} []deletedAuthorAPIModel "results"
+// @Failure 400 {string} string "malformed query"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/author/deleted [get]
+func (s *Server) getDeletedAuthors(w http.ResponseWriter, r *http.Request) {
+	from, count, err := parsePagination(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	authors, err := s.authors.GetDeletedAuthors(r.Context(), from, count)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	models := make([]deletedAuthorAPIModel, len(authors))
+	for i, a := range authors {
+		models[i] = deletedAuthorAPIModel{toAuthorResponse(a.ToAuthor()), a.DeletedAt}
+	}
+	writeJSON(w, models)
+}
+
+// getDeletedCategories godoc
+// @Summary get deleted categories
+// @Description get deleted categories, the latest deleted first
+// @Tags Admin
+// @Produce json
+// @Param from query string false "results start"
+// @Param count query string false "results count, 10 by default"
+// @Success 200 {object} []deletedCategoryAPIModel "results"
+// @Failure 400 {string} string "malformed query"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/category/deleted [get]
+func (s *Server) getDeletedCategories(w http.ResponseWriter, r *http.Request) {
+	from, count, err := parsePagination(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	categories, err := s.categories.GetDeletedCategories(r.Context(), from, count)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	models := make([]deletedCategoryAPIModel, len(categories))
+	for i, c := range categories {
+		models[i] = deletedCategoryAPIModel{toCategoryResponse(c.ToCategory()), c.DeletedAt}
+	}
+	writeJSON(w, models)
+}
+
+// restoreBook godoc
+// @Summary restore book
+// @Description undelete book linking it back to categories it had, author of book must be restored first
+// @Tags Admin
+// @Produce json
+// @Param id path string true "book id"
+// @Success 200 {object} apiModel "restored book"
+// @Header 200 {string} ETag "book version"
+// @Failure 400 {string} string "malformed id or author of book is deleted"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 404 {string} string "deleted book not found"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/book/{id}/restore [post]
+func (s *Server) restoreBook(w http.ResponseWriter, r *http.Request) {
+	id, err := getRestoredID(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	b, err := s.service.RestoreBook(r.Context(), id)
+	writeResponse(w, b, err)
+}
+
+// restoreAuthor godoc
+// @Summary restore author
+// @Description undelete author
+// @Tags Admin
+// @Produce json
+// @Param id path string true "author id"
+// @Success 200 {object} authorAPIModel "restored author"
+// @Header 200 {string} ETag "author version"
+// @Failure 400 {string} string "malformed id"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 404 {string} string "deleted author not found"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/author/{id}/restore [post]
+func (s *Server) restoreAuthor(w http.ResponseWriter, r *http.Request) {
+	id, err := getRestoredID(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	a, err := s.authors.RestoreAuthor(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag.Format(a.Version))
+	writeJSON(w, toAuthorResponse(a))
+}
+
+// restoreCategory godoc
+// @Summary restore category
+// @Description undelete category
+// @Tags Admin
+// @Produce json
+// @Param id path string true "category id"
+// @Success 200 {object} categoryAPIModel "restored category"
+// @Header 200 {string} ETag "category version"
+// @Failure 400 {string} string "malformed id"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 404 {string} string "deleted category not found"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/category/{id}/restore [post]
+func (s *Server) restoreCategory(w http.ResponseWriter, r *http.Request) {
+	id, err := getRestoredID(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	c, err := s.categories.RestoreCategory(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag.Format(c.Version))
+	writeJSON(w, toCategoryResponse(c))
+}
+
+// purge godoc
+// @Summary purge deleted items
+// @Description permanently remove books, authors and categories deleted before given time.
+// @Description Authors of remaining books, parents of remaining categories and categories of remaining deleted books are kept.
+// @Tags Admin
+// @Produce json
+// @Param before query string true "RFC 3339 time, items deleted before it are removed"
+// @Success 200 {object} purgeAPIModel "numbers of removed items"
+// @Failure 400 {string} string "malformed time"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/purge [post]
+func (s *Server) purge(w http.ResponseWriter, r *http.Request) {
+	param := r.URL.Query().Get("before")
+	if param == "" {
+		writeError(w, http.StatusBadRequest, errors.New("before is required"))
+		return
+	}
+	before, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	res, err := s.service.Purge(r.Context(), before)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, purgeAPIModel{Books: res.Books, Authors: res.Authors, Categories: res.Categories})
+}
+
+// getAudit godoc
+// @Summary get audit trail
+// @Description get changes of books, authors and categories with who made them and changed fields, the latest first
+// @Tags Admin
+// @Produce json
+// @Param entity query string false "book, author or category"
+// @Param id query string false "entity id"
+// @Param since query string false "RFC 3339 time, changes made before it are skipped"
+// @Param until query string false "RFC 3339 time, changes made at it or later are skipped"
+// @Param from query string false "results start"
+// @Param count query string false "results count, 10 by default"
+// @Success 200 {object} []audit.Entry "results"
+// @Failure 400 {string} string "malformed query"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/audit [get]
+func (s *Server) getAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, count, err := parsePagination(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	f, err := audit.FilterFromQuery(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries, err := s.audit.Find(r.Context(), f, from, count)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, entries)
+}
+
+// getRestoredID gets id from path of restore endpoint
+func getRestoredID(path string) (uuid.UUID, error) {
+	return getUUIDFromURL(strings.TrimSuffix(path, "/restore"))
+}
+
+func toAuthorResponse(a book.Author) authorAPIModel {
+	return authorAPIModel{
+		ID:   a.ID.String(),
+		Name: a.Name,
+	}
+}
+
+func toCategoryResponse(c book.Category) categoryAPIModel {
+	var parentID string
+	if !c.ParentID.IsZero() {
+		parentID = c.ParentID.String()
+	}
+	return categoryAPIModel{
+		ID:       c.ID.String(),
+		Name:     c.Name,
+		ParentID: parentID,
+	}
+}
@@ -1,29 +1,40 @@
 package rest
 
 import (
-	"context"
+	"crypto/tls"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 
+	authorservice "github.com/Vesninovich/go-tasks/book-store/catalog/author/service"
 	bookservice "github.com/Vesninovich/go-tasks/book-store/catalog/book/service"
+	categoryservice "github.com/Vesninovich/go-tasks/book-store/catalog/category/service"
 	_ "github.com/Vesninovich/go-tasks/book-store/catalog/docs" // generated docs
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/etag"
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-var ctx = context.Background()
-
-// Server of orders
+// Server of catalog
 type Server struct {
-	service *bookservice.BookService
-	baseURL string
-	host    string
+	service    *bookservice.BookService
+	authors    *authorservice.Service
+	categories *categoryservice.Service
+	baseURL    string
+	host       string
+	tls        *tls.Config
+	auth       *auth.Middleware
+	idem       *idempotency.Guard
+	audit      *audit.Recorder
 }
 
 type apiModel struct {
@@ -33,6 +44,12 @@ type apiModel struct {
 	Categories []string `json:"categories"`
 }
 
+type updateAPIModel struct {
+	Name       string   `json:"name"`
+	Author     string   `json:"author"`
+	Categories []string `json:"categories"`
+}
+
 type createAPIModel struct {
 	Name   string `json:"name"`
 	Author struct {
@@ -47,22 +64,56 @@ type createAPIModel struct {
 }
 
 // New creates Server
-func New(host, baseURL string, s *bookservice.BookService) *Server {
+func New(host, baseURL string, s *bookservice.BookService, as *authorservice.Service, cs *categoryservice.Service) *Server {
 	return &Server{
-		service: s,
-		baseURL: baseURL,
-		host:    host,
+		service:    s,
+		authors:    as,
+		categories: cs,
+		baseURL:    baseURL,
+		host:       host,
 	}
 }
 
+// UseTLS makes server serve HTTPS with given config
+func (s *Server) UseTLS(c *tls.Config) {
+	s.tls = c
+}
+
+// UseAuth makes server authenticate and authorize requests with given middleware
+func (s *Server) UseAuth(m *auth.Middleware) {
+	s.auth = m
+}
+
+// UseIdempotency makes server replay responses to create requests repeated with the same Idempotency-Key header
+func (s *Server) UseIdempotency(g *idempotency.Guard) {
+	s.idem = g
+}
+
+// UseAudit makes server serve audit trail saved by given recorder
+func (s *Server) UseAudit(r *audit.Recorder) {
+	s.audit = r
+}
+
 // Start builds HTTP server for application and attempts to start it on given host.
 // Created server serves requests starting from given `baseURL`.
 func (s *Server) Start() error {
 	serveMux := http.NewServeMux()
 	s.handleTaskEndpoints(serveMux)
+	s.handleAdminEndpoints(serveMux)
 	var server http.Server
 	server.Handler = serveMux
+	if s.idem != nil {
+		// only creation of entities is guarded, admin actions are idempotent by themselves
+		server.Handler = s.idem.Middleware(s.baseURL)(server.Handler)
+	}
+	if s.auth != nil {
+		server.Handler = s.auth.Wrap(server.Handler)
+	}
 	server.Addr = s.host
+	if s.tls != nil {
+		server.TLSConfig = s.tls
+		return server.ListenAndServeTLS("", "")
+	}
 	err := server.ListenAndServe()
 	return err
 }
@@ -79,22 +130,22 @@ func (s *Server) handleTaskEndpoints(serveMux *http.ServeMux) {
 		}
 	})
 
-	// validPath := regexp.MustCompile(s.baseURL + "/" + uuid.REGEX + "$")
-	// serveMux.HandleFunc(s.baseURL+"/", func(w http.ResponseWriter, r *http.Request) {
-	// 	m := validPath.FindStringSubmatch(r.URL.Path)
-	// 	if m == nil {
-	// 		writeNotFound(w)
-	// 		return
-	// 	}
-	// 	switch r.Method {
-	// 	case http.MethodPut:
-	// 		s.updateDescription(w, r)
-	// 	case http.MethodDelete:
-	// 		s.removeOrder(w, r)
-	// 	default:
-	// 		writeNotFound(w)
-	// 	}
-	// })
+	validPath := regexp.MustCompile(s.baseURL + "/" + uuid.REGEX + "$")
+	serveMux.HandleFunc(s.baseURL+"/", func(w http.ResponseWriter, r *http.Request) {
+		m := validPath.FindStringSubmatch(r.URL.Path)
+		if m == nil {
+			writeNotFound(w)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			s.getBook(w, r)
+		case http.MethodPut:
+			s.updateBook(w, r)
+		default:
+			writeNotFound(w)
+		}
+	})
 
 	// TODO: move to separate server
 	serveMux.HandleFunc(s.baseURL+"/swagger/", httpSwagger.Handler(httpSwagger.URL(s.baseURL+"/swagger/doc.json")))
@@ -120,7 +171,7 @@ func (s *Server) getBooks(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	books, err := s.service.GetBooks(ctx, from, count, query)
+	books, err := s.service.GetBooks(r.Context(), from, count, query)
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, err)
 	}
@@ -139,15 +190,22 @@ func (s *Server) getBooks(w http.ResponseWriter, r *http.Request) {
 
 // createBook godoc
 // @Summary create book
-// @Description create book
+// @Description create book, response is replayed to requests repeated with the same Idempotency-Key header
 // @Tags Book
 // @Accept json
 // @Produce json
+// @Param Idempotency-Key header string false "key making retries of request safe"
 // @Param order body createAPIModel true "book data"
 // @Success 200 {object} book.Book "created book"
 // @Failure 400 {string} string "malformed data"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
 // @Failure 404 {string} string "nested author or category not found"
+// @Failure 409 {string} string "request with the same idempotency key is in progress"
+// @Failure 422 {string} string "idempotency key was used for another request"
 // @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /book [post]
 func (s *Server) createBook(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
@@ -190,7 +248,89 @@ func (s *Server) createBook(w http.ResponseWriter, r *http.Request) {
 			cats[i].ParentID = id
 		}
 	}
-	b, err := s.service.CreateBook(ctx, data.Name, aut, cats)
+	b, err := s.service.CreateBook(r.Context(), data.Name, aut, cats)
+	writeResponse(w, b, err)
+}
+
+// getBook godoc
+// @Summary get book
+// @Description get book by id, version of book is sent in ETag header
+// @Tags Book
+// @Produce json
+// @Param id path string true "book id"
+// @Success 200 {object} apiModel "requested book"
+// @Header 200 {string} ETag "book version"
+// @Failure 400 {string} string "malformed id"
+// @Failure 404 {string} string "requested book not found"
+// @Failure 500 {string} string "internal error"
+// @Router /book/{id} [get]
+func (s *Server) getBook(w http.ResponseWriter, r *http.Request) {
+	id, err := getUUIDFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	b, err := s.service.GetBook(r.Context(), id)
+	writeResponse(w, b, err)
+}
+
+// updateBook godoc
+// @Summary update book
+// @Description update book, it is only updated if it still has version from If-Match header when the header is set
+// @Tags Book
+// @Accept json
+// @Produce json
+// @Param id path string true "book id"
+// @Param If-Match header string false "expected book version from ETag"
+// @Param book body updateAPIModel true "book data"
+// @Success 200 {object} apiModel "updated book"
+// @Header 200 {string} ETag "book version"
+// @Failure 400 {string} string "malformed data"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 404 {string} string "book, author or category not found"
+// @Failure 412 {string} string "book was changed since expected version"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /book/{id} [put]
+func (s *Server) updateBook(w http.ResponseWriter, r *http.Request) {
+	id, err := getUUIDFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	version, err := etag.ParseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var data updateAPIModel
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	b := book.Book{ID: id, Name: data.Name, Version: version}
+	b.Author.ID, err = uuid.FromString(data.Author)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	b.Categories = make([]book.Category, len(data.Categories))
+	for i, cat := range data.Categories {
+		b.Categories[i].ID, err = uuid.FromString(cat)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+	}
+	b, err = s.service.UpdateBook(r.Context(), b)
 	writeResponse(w, b, err)
 }
 
@@ -201,25 +341,12 @@ func getUUIDFromURL(path string) (uuid.UUID, error) {
 
 // TODO: split (or not)
 func parseQuery(params url.Values) (from, count uint, query book.Query, err error) {
-	var val uint64
 	var id uuid.UUID
-	param := params.Get("from")
-	if param != "" {
-		val, err = strconv.ParseUint(param, 10, 64)
-		if err != nil {
-			return
-		}
-		from = uint(val)
-	}
-	param = params.Get("count")
-	if param != "" {
-		val, err = strconv.ParseUint(param, 10, 64)
-		if err != nil {
-			return
-		}
-		count = uint(val)
+	from, count, err = parsePagination(params)
+	if err != nil {
+		return
 	}
-	param = params.Get("id")
+	param := params.Get("id")
 	if param != "" {
 		id, err = uuid.FromString(param)
 		if err != nil {
@@ -250,14 +377,30 @@ func parseQuery(params url.Values) (from, count uint, query book.Query, err erro
 	return
 }
 
+func parsePagination(params url.Values) (from, count uint, err error) {
+	var val uint64
+	param := params.Get("from")
+	if param != "" {
+		val, err = strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return
+		}
+		from = uint(val)
+	}
+	param = params.Get("count")
+	if param != "" {
+		val, err = strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return
+		}
+		count = uint(val)
+	}
+	return
+}
+
 func writeResponse(w http.ResponseWriter, b book.Book, err error) {
 	if err != nil {
-		switch err.(type) {
-		case *commonerrors.NotFound:
-			writeError(w, http.StatusNotFound, err)
-		default:
-			writeError(w, http.StatusInternalServerError, err)
-		}
+		writeServiceError(w, err)
 		return
 	}
 	res, err := json.Marshal(toResponse(b))
@@ -266,6 +409,30 @@ func writeResponse(w http.ResponseWriter, b book.Book, err error) {
 		return
 	}
 	w.Header().Add("Content-Type", "application/json")
+	w.Header().Set("ETag", etag.Format(b.Version))
+	w.Write(res)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *commonerrors.NotFound:
+		writeError(w, http.StatusNotFound, err)
+	case *commonerrors.VersionMismatch:
+		writeError(w, http.StatusPreconditionFailed, err)
+	case *commonerrors.InvalidInput:
+		writeError(w, http.StatusBadRequest, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	res, err := json.Marshal(data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
 	w.Write(res)
 }
 
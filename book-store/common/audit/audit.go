@@ -0,0 +1,189 @@
+// Package audit records who changed what: every mutation of stored entity is saved as Entry
+// with principal who made it and changed fields
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+)
+
+// Operation is type of mutation
+type Operation string
+
+// Possible Operations
+const (
+	Create  Operation = "create"
+	Update  Operation = "update"
+	Delete  Operation = "delete"
+	Restore Operation = "restore"
+	Purge   Operation = "purge"
+)
+
+// Change of single field, Before is not set for added field and After for removed one
+type Change struct {
+	Before json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After  json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+}
+
+// Entry of audit trail
+type Entry struct {
+	// ID is assigned by Store in order entries are saved
+	ID uint64 `json:"id"`
+	// Actor is subject of principal who made change, empty for anonymous requests and scheduled purges
+	Actor     string    `json:"actor"`
+	Entity    string    `json:"entity"`
+	EntityID  string    `json:"entityID,omitempty"`
+	Operation Operation `json:"operation"`
+	// Diff maps names of changed fields to their values before and after change
+	Diff      map[string]Change `json:"diff"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Purged is data of purge entry, it has no entity ID
+type Purged struct {
+	DeletedBefore time.Time `json:"deletedBefore"`
+	Count         uint64    `json:"count"`
+}
+
+// Filter of entries, zero fields are not checked
+type Filter struct {
+	Entity   string
+	EntityID string
+	// Since and Until limit time of change, Since is inclusive and Until is exclusive
+	Since time.Time
+	Until time.Time
+}
+
+// FilterFromQuery reads filter from `entity`, `id`, `since` and `until` query parameters, times are in RFC 3339
+func FilterFromQuery(q url.Values) (f Filter, err error) {
+	f.Entity = q.Get("entity")
+	f.EntityID = q.Get("id")
+	if v := q.Get("since"); v != "" {
+		if f.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		f.Until, err = time.Parse(time.RFC3339, v)
+	}
+	return
+}
+
+// Store keeps audit entries
+type Store interface {
+	// Save stores entry assigning next ID to it
+	Save(ctx context.Context, e Entry) (Entry, error)
+	// Find gets count entries matching filter from some number, the latest first, all of them if count is 0
+	Find(ctx context.Context, f Filter, from, count uint) ([]Entry, error)
+}
+
+// Transactor runs functions in transactions, so that entries are committed together with changes they are about
+type Transactor interface {
+	InTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Recorder saves entries about mutations to Store
+type Recorder struct {
+	store        Store
+	transactions Transactor
+	now          func() time.Time
+}
+
+// NewRecorder creates Recorder saving entries to given store
+func NewRecorder(s Store) *Recorder {
+	return &Recorder{store: s, now: time.Now}
+}
+
+// UseTransactions makes InTransaction run changes and saving of their entries in transactions of given Transactor.
+// Store must save entries in the transaction passed in context then.
+func (r *Recorder) UseTransactions(t Transactor) {
+	r.transactions = t
+}
+
+// InTransaction runs fn, which makes change and records it, in transaction if Recorder uses transactions,
+// so that change is rolled back if its entry can not be saved.
+// Running with nil Recorder just calls fn.
+func (r *Recorder) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if r == nil || r.transactions == nil {
+		return fn(ctx)
+	}
+	return r.transactions.InTransaction(ctx, fn)
+}
+
+// Record saves entry about mutation of entity made by principal from context.
+// `before` and `after` are states of entity marshalled to JSON, nil if entity did not exist or was removed.
+// It should be called in the same transaction as mutation, so that mutation fails if entry is not saved,
+// see InTransaction. Recording with nil Recorder does nothing, so auditing is optional.
+func (r *Recorder) Record(ctx context.Context, entity, entityID string, op Operation, before, after interface{}) error {
+	if r == nil {
+		return nil
+	}
+	e := Entry{
+		Entity:    entity,
+		EntityID:  entityID,
+		Operation: op,
+		CreatedAt: r.now(),
+	}
+	if p, ok := auth.FromContext(ctx); ok {
+		e.Actor = p.Subject
+	}
+	var err error
+	if e.Diff, err = Diff(before, after); err == nil {
+		_, err = r.store.Save(ctx, e)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record %s of %s %s: %w", op, entity, entityID, err)
+	}
+	return nil
+}
+
+// Find gets count entries matching filter from some number, the latest first, 10 by default
+func (r *Recorder) Find(ctx context.Context, f Filter, from, count uint) ([]Entry, error) {
+	if count == 0 {
+		count = 10
+	}
+	return r.store.Find(ctx, f, from, count)
+}
+
+// Diff compares JSON objects `before` and `after` are marshalled to and returns changed fields
+func Diff(before, after interface{}) (map[string]Change, error) {
+	b, err := fields(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := fields(after)
+	if err != nil {
+		return nil, err
+	}
+	diff := make(map[string]Change)
+	for name, v := range b {
+		if av, ok := a[name]; !ok || !bytes.Equal(v, av) {
+			diff[name] = Change{Before: v, After: av}
+		}
+	}
+	for name, v := range a {
+		if _, ok := b[name]; !ok {
+			diff[name] = Change{After: v}
+		}
+	}
+	return diff, nil
+}
+
+func fields(v interface{}) (map[string]json.RawMessage, error) {
+	res := make(map[string]json.RawMessage)
+	if v == nil {
+		return res, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(data, &res)
+	return res, err
+}
@@ -0,0 +1,132 @@
+package audit_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	"github.com/Vesninovich/go-tasks/book-store/common/audit/inmemory"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/book"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+func TestDiff(t *testing.T) {
+	id := uuid.New()
+	before := book.Author{ID: id, Name: "Ann", Version: 1}
+	after := book.Author{ID: id, Name: "Bob", Version: 2}
+
+	diff, err := audit.Diff(before, after)
+	if err != nil {
+		t.Fatalf("Error comparing authors: %s", err)
+	}
+	if len(diff) != 2 {
+		t.Errorf("Expected name and version to change, got %v", diff)
+	}
+	if c := diff["name"]; string(c.Before) != `"Ann"` || string(c.After) != `"Bob"` {
+		t.Errorf("Wrong change of name: %s -> %s", c.Before, c.After)
+	}
+
+	diff, err = audit.Diff(nil, after)
+	if err != nil {
+		t.Fatalf("Error comparing created author: %s", err)
+	}
+	if c := diff["id"]; len(diff) != 3 || c.Before != nil || string(c.After) != `"`+id.String()+`"` {
+		t.Errorf("Expected all fields of created author to be added, got %v", diff)
+	}
+
+	diff, _ = audit.Diff(after, nil)
+	if c := diff["name"]; len(diff) != 3 || c.After != nil || string(c.Before) != `"Bob"` {
+		t.Errorf("Expected all fields of deleted author to be removed, got %v", diff)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	r := audit.NewRecorder(inmemory.New())
+	id := uuid.New()
+	a := book.Author{ID: id, Name: "Ann", Version: 1}
+
+	if err := r.Record(ctx, "author", id.String(), audit.Create, nil, a); err != nil {
+		t.Fatalf("Error recording creation: %s", err)
+	}
+	r.Record(context.Background(), "author", id.String(), audit.Delete, a, nil)
+
+	entries, err := r.Find(ctx, audit.Filter{EntityID: id.String()}, 0, 0)
+	if err != nil {
+		t.Fatalf("Error finding entries: %s", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if e := entries[1]; e.Actor != "alice" || e.Operation != audit.Create || e.Entity != "author" || e.CreatedAt.IsZero() {
+		t.Errorf("Wrong entry of creation: %+v", e)
+	}
+	if e := entries[0]; e.Actor != "" || e.Operation != audit.Delete {
+		t.Errorf("Expected anonymous deletion to be the latest entry, got %+v", e)
+	}
+
+	var none *audit.Recorder
+	if err := none.Record(ctx, "author", id.String(), audit.Update, a, a); err != nil {
+		t.Errorf("Expected recording with nil recorder to do nothing, got %s", err)
+	}
+}
+
+// failingStore can not save entries
+type failingStore struct {
+	*inmemory.Store
+}
+
+func (failingStore) Save(ctx context.Context, e audit.Entry) (audit.Entry, error) {
+	return e, errors.New("disk is full")
+}
+
+// fakeTransactor tells whether transaction was committed
+type fakeTransactor struct {
+	committed bool
+}
+
+func (t *fakeTransactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(ctx)
+	t.committed = err == nil
+	return err
+}
+
+func TestRecorderFailsChange(t *testing.T) {
+	r := audit.NewRecorder(failingStore{inmemory.New()})
+	tx := &fakeTransactor{}
+	r.UseTransactions(tx)
+	changed := false
+	err := r.InTransaction(context.Background(), func(ctx context.Context) error {
+		changed = true
+		return r.Record(ctx, "author", "1", audit.Delete, nil, nil)
+	})
+	if err == nil || !changed || tx.committed {
+		t.Errorf("Expected change to be rolled back when its entry can not be saved, got %v", err)
+	}
+
+	var none *audit.Recorder
+	if err = none.InTransaction(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Errorf("Expected nil recorder to run change, got %s", err)
+	}
+}
+
+func TestFilterFromQuery(t *testing.T) {
+	q := url.Values{}
+	q.Set("entity", "book")
+	q.Set("id", "1")
+	q.Set("since", "2021-01-02T03:04:05Z")
+	f, err := audit.FilterFromQuery(q)
+	if err != nil {
+		t.Fatalf("Error reading filter: %s", err)
+	}
+	if f.Entity != "book" || f.EntityID != "1" || f.Since.Year() != 2021 || !f.Until.IsZero() {
+		t.Errorf("Wrong filter: %+v", f)
+	}
+	q.Set("until", "yesterday")
+	if _, err = audit.FilterFromQuery(q); err == nil {
+		t.Error("Expected error for malformed time")
+	}
+}
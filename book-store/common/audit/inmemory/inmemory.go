@@ -0,0 +1,57 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+)
+
+// Store represents in-memory store of audit entries
+type Store struct {
+	data []audit.Entry
+	lock sync.RWMutex
+}
+
+// New creates new in-memory store of audit entries
+func New() *Store {
+	return &Store{}
+}
+
+// Save stores entry assigning next ID to it
+func (s *Store) Save(ctx context.Context, e audit.Entry) (audit.Entry, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e.ID = uint64(len(s.data)) + 1
+	s.data = append(s.data, e)
+	return e, nil
+}
+
+// Find gets count entries matching filter from some number, the latest first, all of them if count is 0
+func (s *Store) Find(ctx context.Context, f audit.Filter, from, count uint) ([]audit.Entry, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	res := make([]audit.Entry, 0)
+	var skipped uint
+	for i := len(s.data) - 1; i >= 0 && (count == 0 || uint(len(res)) < count); i-- {
+		e := s.data[i]
+		if !matches(e, f) {
+			continue
+		}
+		if skipped < from {
+			skipped++
+			continue
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+func matches(e audit.Entry, f audit.Filter) bool {
+	return (f.Entity == "" || e.Entity == f.Entity) &&
+		(f.EntityID == "" || e.EntityID == f.EntityID) &&
+		(f.Since.IsZero() || !e.CreatedAt.Before(f.Since)) &&
+		(f.Until.IsZero() || e.CreatedAt.Before(f.Until))
+}
@@ -0,0 +1,42 @@
+package inmemory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	"github.com/Vesninovich/go-tasks/book-store/common/audit/inmemory"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := inmemory.New()
+	now := time.Now()
+	for i, e := range []audit.Entry{
+		{Entity: "book", EntityID: "1", Operation: audit.Create, CreatedAt: now.Add(-2 * time.Hour)},
+		{Entity: "author", EntityID: "2", Operation: audit.Create, CreatedAt: now.Add(-time.Hour)},
+		{Entity: "book", EntityID: "1", Operation: audit.Update, CreatedAt: now},
+	} {
+		saved, err := s.Save(ctx, e)
+		if err != nil {
+			t.Fatalf("Error saving entry: %s", err)
+		}
+		if saved.ID != uint64(i+1) {
+			t.Errorf("Expected entry to get ID %d, got %d", i+1, saved.ID)
+		}
+	}
+
+	found, _ := s.Find(ctx, audit.Filter{}, 0, 0)
+	if len(found) != 3 || found[0].ID != 3 {
+		t.Errorf("Expected all entries, the latest first, got %+v", found)
+	}
+	found, _ = s.Find(ctx, audit.Filter{Entity: "book", EntityID: "1"}, 1, 1)
+	if len(found) != 1 || found[0].ID != 1 {
+		t.Errorf("Expected the second entry of book, got %+v", found)
+	}
+	found, _ = s.Find(ctx, audit.Filter{Since: now.Add(-time.Hour), Until: now}, 0, 0)
+	if len(found) != 1 || found[0].ID != 2 {
+		t.Errorf("Expected only entry within time range, got %+v", found)
+	}
+}
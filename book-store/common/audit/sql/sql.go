@@ -0,0 +1,115 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	"github.com/Vesninovich/go-tasks/book-store/common/sqltx"
+	"github.com/jmoiron/sqlx"
+)
+
+// Store provides access to relational DB storage of audit entries.
+// Entries are saved in transaction carried by context if there is one, see sqltx.
+type Store struct {
+	db     *sqlx.DB
+	schema string
+}
+
+// New creates a new instance of Store keeping entries in given schema
+func New(db *sqlx.DB, schema string) *Store {
+	return &Store{db, schema}
+}
+
+// CreateTableStmt of audit entries
+func (s *Store) CreateTableStmt() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s.audit_log(
+  id bigserial PRIMARY KEY,
+  actor varchar NOT NULL,
+  entity varchar NOT NULL,
+  entity_id varchar NOT NULL,
+  operation varchar NOT NULL,
+  diff jsonb NOT NULL,
+  created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_log_entity_idx ON %[1]s.audit_log(entity, entity_id);
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON %[1]s.audit_log(created_at);`, s.schema)
+}
+
+// Save stores entry assigning next ID to it
+func (s *Store) Save(ctx context.Context, e audit.Entry) (audit.Entry, error) {
+	diff, err := json.Marshal(e.Diff)
+	if err != nil {
+		return e, err
+	}
+	err = sqltx.DB(ctx, s.db).QueryRowxContext(
+		ctx,
+		fmt.Sprintf(`INSERT INTO %s.audit_log (actor, entity, entity_id, operation, diff, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id;`, s.schema),
+		e.Actor, e.Entity, e.EntityID, string(e.Operation), string(diff), e.CreatedAt,
+	).Scan(&e.ID)
+	return e, err
+}
+
+// Find gets count entries matching filter from some number, the latest first, all of them if count is 0
+func (s *Store) Find(ctx context.Context, f audit.Filter, from, count uint) ([]audit.Entry, error) {
+	conds := make([]string, 0, 4)
+	args := make([]interface{}, 0, 6)
+	cond := func(c string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(c, len(args)))
+	}
+	if f.Entity != "" {
+		cond("entity=$%d", f.Entity)
+	}
+	if f.EntityID != "" {
+		cond("entity_id=$%d", f.EntityID)
+	}
+	if !f.Since.IsZero() {
+		cond("created_at>=$%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		cond("created_at<$%d", f.Until)
+	}
+	where := ""
+	if len(conds) != 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+	limit := ""
+	if count != 0 {
+		args = append(args, count)
+		limit = fmt.Sprintf("LIMIT $%d", len(args))
+	}
+	args = append(args, from)
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf(`SELECT id, actor, entity, entity_id, operation, diff, created_at
+			FROM %s.audit_log
+			%s
+			ORDER BY id DESC
+			%s OFFSET $%d;`, s.schema, where, limit, len(args)),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make([]audit.Entry, 0)
+	for rows.Next() {
+		var e audit.Entry
+		var op string
+		var diff []byte
+		if err = rows.Scan(&e.ID, &e.Actor, &e.Entity, &e.EntityID, &op, &diff, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Operation = audit.Operation(op)
+		if err = json.Unmarshal(diff, &e.Diff); err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, rows.Err()
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+)
+
+// APIKeyHeader is header carrying API key
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys authenticates requests by API key in X-API-Key header
+type APIKeys map[string]Principal
+
+// Authenticate implements Authenticator
+func (k APIKeys) Authenticate(r *http.Request) (Principal, error) {
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return Principal{}, ErrNoCredentials
+	}
+	// compare with every key so that time does not depend on which key matched
+	var found Principal
+	ok := false
+	for known, p := range k {
+		if subtle.ConstantTimeCompare([]byte(known), []byte(key)) == 1 {
+			found, ok = p, true
+		}
+	}
+	if !ok {
+		return Principal{}, errors.New("unknown API key")
+	}
+	return found, nil
+}
@@ -0,0 +1,62 @@
+// Package auth authenticates REST requests with API keys or JWT bearer tokens
+// and checks role based rules, propagation of principal via gRPC is in authgrpc
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrNoCredentials is returned by Authenticator when request carries no credentials it understands
+var ErrNoCredentials = errors.New("no credentials")
+
+// Principal is authenticated caller
+type Principal struct {
+	Subject string
+	Roles   []string
+}
+
+// HasRole reports whether principal has given role
+func (p Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator extracts principal from request credentials.
+// Returns ErrNoCredentials if request has no credentials for this authenticator
+// and other error if credentials are present but invalid.
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// Chain tries authenticators in order until one finds credentials in request
+type Chain []Authenticator
+
+// Authenticate implements Authenticator
+func (c Chain) Authenticate(r *http.Request) (Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if err != ErrNoCredentials {
+			return p, err
+		}
+	}
+	return Principal{}, ErrNoCredentials
+}
+
+type principalKey struct{}
+
+// NewContext returns context carrying principal
+func NewContext(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns principal carried by context, if any
+func FromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var secret = []byte("test secret")
+var now = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func sign(t *testing.T, alg string, claims map[string]interface{}, key interface{}) string {
+	h, err := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(c)
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sum := sha256.Sum256([]byte(signed))
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, sum[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func bearer(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+func TestJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := &JWT{
+		HMACSecret: secret,
+		RSAKey:     &rsaKey.PublicKey,
+		Issuer:     "issuer",
+		Audience:   "catalog",
+		now:        func() time.Time { return now },
+	}
+	valid := map[string]interface{}{
+		"sub":   "alice",
+		"iss":   "issuer",
+		"aud":   []string{"orders", "catalog"},
+		"exp":   now.Add(time.Minute).Unix(),
+		"roles": []string{"admin"},
+	}
+	with := func(key string, value interface{}) map[string]interface{} {
+		c := map[string]interface{}{}
+		for k, v := range valid {
+			c[k] = v
+		}
+		if value == nil {
+			delete(c, key)
+		} else {
+			c[key] = value
+		}
+		return c
+	}
+
+	t.Run("HS256", func(t *testing.T) {
+		p, err := j.Authenticate(bearer(sign(t, "HS256", valid, secret)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Subject != "alice" || !p.HasRole("admin") {
+			t.Errorf("Wrong principal %+v", p)
+		}
+	})
+	t.Run("RS256", func(t *testing.T) {
+		p, err := j.Authenticate(bearer(sign(t, "RS256", with("roles", "reader writer"), rsaKey)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !p.HasRole("reader") || !p.HasRole("writer") {
+			t.Errorf("Wrong roles %v", p.Roles)
+		}
+	})
+	invalid := map[string]string{
+		"wrong secret":   sign(t, "HS256", valid, []byte("other")),
+		"unsupported":    sign(t, "none", valid, []byte{}),
+		"expired":        sign(t, "HS256", with("exp", now.Add(-time.Minute).Unix()), secret),
+		"no expiration":  sign(t, "HS256", with("exp", nil), secret),
+		"not yet valid":  sign(t, "HS256", with("nbf", now.Add(time.Minute).Unix()), secret),
+		"wrong issuer":   sign(t, "HS256", with("iss", "other"), secret),
+		"wrong audience": sign(t, "HS256", with("aud", "orders"), secret),
+		"no subject":     sign(t, "HS256", with("sub", nil), secret),
+		"malformed":      "not.a.token",
+	}
+	for name, token := range invalid {
+		token := token
+		t.Run(name, func(t *testing.T) {
+			if _, err := j.Authenticate(bearer(token)); err == nil || err == ErrNoCredentials {
+				t.Errorf("Expected token to be rejected, got %v", err)
+			}
+		})
+	}
+	t.Run("No token", func(t *testing.T) {
+		if _, err := j.Authenticate(httptest.NewRequest(http.MethodGet, "/", nil)); err != ErrNoCredentials {
+			t.Errorf("Expected ErrNoCredentials, got %v", err)
+		}
+	})
+}
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys("k1=alice:admin|writer, k2=bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := keys["k1"]; p.Subject != "alice" || !p.HasRole("admin") || !p.HasRole("writer") {
+		t.Errorf("Wrong principal for k1: %+v", p)
+	}
+	if p := keys["k2"]; p.Subject != "bob" || len(p.Roles) != 0 {
+		t.Errorf("Wrong principal for k2: %+v", p)
+	}
+	if _, err = ParseAPIKeys("nokey"); err == nil {
+		t.Error("Expected error for entry without key")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	keys := APIKeys{
+		"admin-key":  {Subject: "alice", Roles: []string{"admin"}},
+		"reader-key": {Subject: "bob"},
+	}
+	m := NewMiddleware(Chain{keys, &JWT{HMACSecret: secret}},
+		Rule{Methods: []string{http.MethodGet}, PathPrefix: "/book", Anonymous: true},
+		Rule{PathPrefix: "/book", Roles: []string{"admin"}},
+	)
+	var got Principal
+	h := m.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = FromContext(r.Context())
+	}))
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		status int
+	}{
+		{"Anonymous read", http.MethodGet, "/book", "", http.StatusOK},
+		{"Anonymous write", http.MethodPost, "/book", "", http.StatusUnauthorized},
+		{"Admin write", http.MethodPost, "/book", "admin-key", http.StatusOK},
+		{"Non admin write", http.MethodPost, "/book", "reader-key", http.StatusForbidden},
+		{"Unknown key", http.MethodGet, "/book", "unknown", http.StatusUnauthorized},
+		{"No rule anonymous", http.MethodGet, "/other", "", http.StatusUnauthorized},
+		{"No rule authenticated", http.MethodGet, "/other", "reader-key", http.StatusOK},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got = Principal{}
+			r := httptest.NewRequest(c.method, c.path, nil)
+			if c.key != "" {
+				r.Header.Set(APIKeyHeader, c.key)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != c.status {
+				t.Errorf("Expected status %d, got %d", c.status, w.Code)
+			}
+			if c.status == http.StatusOK && c.key != "" && got.Subject != keys[c.key].Subject {
+				t.Errorf("Expected principal %s in context, got %+v", keys[c.key].Subject, got)
+			}
+		})
+	}
+}
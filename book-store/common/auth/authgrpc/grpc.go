@@ -0,0 +1,118 @@
+// Package authgrpc propagates authenticated principal between services via gRPC metadata
+package authgrpc
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/tlsconf"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadata keys carrying principal between services
+const (
+	subjectKey = "x-auth-subject"
+	rolesKey   = "x-auth-roles"
+)
+
+// OutgoingContext returns context which makes gRPC calls carry principal from given context, if any
+func OutgoingContext(ctx context.Context) context.Context {
+	p, ok := auth.FromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	return metadata.AppendToOutgoingContext(ctx, subjectKey, p.Subject, rolesKey, strings.Join(p.Roles, ","))
+}
+
+// untrustedKey marks context of call which carried principal in metadata of untrusted peer
+type untrustedKey struct{}
+
+// IncomingContext returns context carrying principal received in gRPC metadata, if any.
+// Principal is only taken from peers presenting verified TLS certificate with one of trusted names,
+// principal sent by any other peer is ignored and calls requiring roles are denied.
+func IncomingContext(ctx context.Context, trusted []string) context.Context {
+	p, ok := metadataPrincipal(ctx)
+	if !ok {
+		return ctx
+	}
+	if !tlsconf.PeerHasName(ctx, trusted) {
+		return context.WithValue(ctx, untrustedKey{}, true)
+	}
+	return auth.NewContext(ctx, p)
+}
+
+func metadataPrincipal(ctx context.Context) (auth.Principal, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return auth.Principal{}, false
+	}
+	subj := md.Get(subjectKey)
+	if len(subj) == 0 || subj[0] == "" {
+		return auth.Principal{}, false
+	}
+	p := auth.Principal{Subject: subj[0]}
+	if roles := md.Get(rolesKey); len(roles) > 0 && roles[0] != "" {
+		p.Roles = strings.Split(roles[0], ",")
+	}
+	return p, true
+}
+
+// RequireRole returns nil if context carries principal with given role,
+// Unauthenticated status error if it carries no principal and PermissionDenied one otherwise,
+// including calls with principal sent by untrusted peer
+func RequireRole(ctx context.Context, role string) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok && ctx.Value(untrustedKey{}) != nil {
+		return status.Error(codes.PermissionDenied, "principal sent by untrusted peer")
+	}
+	if !ok {
+		return status.Error(codes.Unauthenticated, "authentication required")
+	}
+	if !p.HasRole(role) {
+		return status.Errorf(codes.PermissionDenied, "role %s required", role)
+	}
+	return nil
+}
+
+// UnaryClientInterceptor propagates principal into metadata of unary calls
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(OutgoingContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor propagates principal into metadata of streaming calls
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(OutgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
+
+// UnaryServerInterceptor puts principal received in metadata from trusted clients into context of unary handlers.
+// Trusted clients are the ones presenting verified TLS certificate with one of given names.
+func UnaryServerInterceptor(trusted ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(IncomingContext(ctx, trusted), req)
+	}
+}
+
+// StreamServerInterceptor puts principal received in metadata from trusted clients into context of streaming handlers,
+// as UnaryServerInterceptor does
+func StreamServerInterceptor(trusted ...string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &principalStream{ss, IncomingContext(ss.Context(), trusted)})
+	}
+}
+
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,83 @@
+package authgrpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCPropagation(t *testing.T) {
+	sent := auth.Principal{Subject: "alice", Roles: []string{"admin", "writer"}}
+	ctx := auth.NewContext(context.Background(), sent)
+	var received auth.Principal
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ := metadata.FromOutgoingContext(ctx)
+		// server side sees outgoing metadata of client as incoming
+		_, err := UnaryServerInterceptor("orders")(metadata.NewIncomingContext(tlsPeer("orders"), md), nil, nil,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				received, _ = auth.FromContext(ctx)
+				return nil, nil
+			})
+		return err
+	}
+	if err := UnaryClientInterceptor()(ctx, "/method", nil, nil, nil, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if received.Subject != sent.Subject || !received.HasRole("admin") || !received.HasRole("writer") {
+		t.Errorf("Expected to receive %+v, got %+v", sent, received)
+	}
+
+	if _, ok := auth.FromContext(IncomingContext(tlsPeer("orders"), []string{"orders"})); ok {
+		t.Error("Expected no principal without metadata")
+	}
+}
+
+func TestGRPCUntrustedPeer(t *testing.T) {
+	md := metadata.Pairs(subjectKey, "mallory", rolesKey, "admin")
+	peers := map[string]context.Context{
+		"Plain connection":   peer.NewContext(context.Background(), &peer.Peer{}),
+		"Not allowed client": tlsPeer("other"),
+		"No peer":            context.Background(),
+	}
+	for name, ctx := range peers {
+		t.Run(name, func(t *testing.T) {
+			ctx := IncomingContext(metadata.NewIncomingContext(ctx, md), []string{"orders"})
+			if p, ok := auth.FromContext(ctx); ok {
+				t.Errorf("Expected principal of untrusted peer to be ignored, got %+v", p)
+			}
+			if status.Code(RequireRole(ctx, "admin")) != codes.PermissionDenied {
+				t.Error("Expected PermissionDenied for forged role")
+			}
+		})
+	}
+}
+
+// tlsPeer returns context of call from peer presenting verified certificate with given name
+func tlsPeer(name string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: name}}
+	info := credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: info})
+}
+
+func TestRequireRole(t *testing.T) {
+	ctx := context.Background()
+	if status.Code(RequireRole(ctx, "admin")) != codes.Unauthenticated {
+		t.Error("Expected Unauthenticated without principal")
+	}
+	if status.Code(RequireRole(auth.NewContext(ctx, auth.Principal{Subject: "bob", Roles: []string{"reader"}}), "admin")) != codes.PermissionDenied {
+		t.Error("Expected PermissionDenied without role")
+	}
+	if err := RequireRole(auth.NewContext(ctx, auth.Principal{Subject: "alice", Roles: []string{"admin"}}), "admin"); err != nil {
+		t.Errorf("Expected principal with role to be allowed, got %s", err)
+	}
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// FromEnv builds Authenticator from environment variables:
+//
+// <prefix>_API_KEYS - comma separated `key=subject:role1|role2` entries;
+// <prefix>_JWT_HS256_SECRET - secret for HS256 tokens;
+// <prefix>_JWT_RS256_KEY - path to PEM encoded public key for RS256 tokens;
+// <prefix>_JWT_ISSUER, <prefix>_JWT_AUDIENCE - expected token issuer and audience.
+//
+// Returns nil Authenticator if neither API keys nor JWT keys are configured.
+func FromEnv(prefix string) (Authenticator, error) {
+	var chain Chain
+	keys, err := ParseAPIKeys(os.Getenv(prefix + "_API_KEYS"))
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) > 0 {
+		chain = append(chain, keys)
+	}
+	j := &JWT{
+		Issuer:   os.Getenv(prefix + "_JWT_ISSUER"),
+		Audience: os.Getenv(prefix + "_JWT_AUDIENCE"),
+	}
+	if secret := os.Getenv(prefix + "_JWT_HS256_SECRET"); secret != "" {
+		j.HMACSecret = []byte(secret)
+	}
+	if keyFile := os.Getenv(prefix + "_JWT_RS256_KEY"); keyFile != "" {
+		if j.RSAKey, err = loadRSAKey(keyFile); err != nil {
+			return nil, err
+		}
+	}
+	if j.HMACSecret != nil || j.RSAKey != nil {
+		chain = append(chain, j)
+	}
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+// ParseAPIKeys parses comma separated `key=subject:role1|role2` entries
+func ParseAPIKeys(s string) (APIKeys, error) {
+	keys := APIKeys{}
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		eq := strings.Index(entry, "=")
+		if eq <= 0 {
+			return nil, fmt.Errorf("malformed API key entry %q", entry)
+		}
+		key, rest := entry[:eq], entry[eq+1:]
+		p := Principal{Subject: rest}
+		if colon := strings.Index(rest, ":"); colon >= 0 {
+			p.Subject = rest[:colon]
+			if roles := rest[colon+1:]; roles != "" {
+				p.Roles = strings.Split(roles, "|")
+			}
+		}
+		if p.Subject == "" {
+			return nil, fmt.Errorf("no subject for API key in entry %q", entry)
+		}
+		keys[key] = p
+	}
+	return keys, nil
+}
+
+func loadRSAKey(path string) (*rsa.PublicKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read RSA key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in %s", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		if rsaKey, rsaErr := x509.ParsePKCS1PublicKey(block.Bytes); rsaErr == nil {
+			return rsaKey, nil
+		}
+		return nil, fmt.Errorf("failed to parse RSA key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("key is not an RSA public key")
+	}
+	return rsaKey, nil
+}
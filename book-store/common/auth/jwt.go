@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// JWT authenticates requests by HS256 or RS256 signed bearer token in Authorization header.
+// Subject is taken from `sub` claim and roles from `roles` claim (array or space separated string).
+// Tokens must have `exp` claim.
+type JWT struct {
+	// HMACSecret enables HS256 tokens
+	HMACSecret []byte
+	// RSAKey enables RS256 tokens
+	RSAKey *rsa.PublicKey
+	// Issuer and Audience are checked against `iss` and `aud` claims when set
+	Issuer   string
+	Audience string
+	// Leeway allowed for `exp` and `nbf` checks
+	Leeway time.Duration
+
+	now func() time.Time
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+type claims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Roles     json.RawMessage `json:"roles"`
+}
+
+// Authenticate implements Authenticator
+func (j *JWT) Authenticate(r *http.Request) (Principal, error) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return Principal{}, ErrNoCredentials
+	}
+	return j.Verify(strings.TrimSpace(h[7:]))
+}
+
+// Verify checks token signature and claims and returns principal described by it
+func (j *JWT) Verify(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, errors.New("malformed token")
+	}
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return Principal{}, fmt.Errorf("malformed token header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("malformed token signature: %w", err)
+	}
+	if err = j.verifySignature(h.Alg, parts[0]+"."+parts[1], sig); err != nil {
+		return Principal{}, err
+	}
+	var c claims
+	if err = decodeSegment(parts[1], &c); err != nil {
+		return Principal{}, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if err = j.verifyClaims(c); err != nil {
+		return Principal{}, err
+	}
+	roles, err := parseStrings(c.Roles, true)
+	if err != nil {
+		return Principal{}, fmt.Errorf("malformed roles claim: %w", err)
+	}
+	return Principal{Subject: c.Subject, Roles: roles}, nil
+}
+
+func (j *JWT) verifySignature(alg, signed string, sig []byte) error {
+	switch alg {
+	case "HS256":
+		if j.HMACSecret == nil {
+			return errors.New("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, j.HMACSecret)
+		mac.Write([]byte(signed))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return errors.New("invalid token signature")
+		}
+	case "RS256":
+		if j.RSAKey == nil {
+			return errors.New("RS256 tokens are not accepted")
+		}
+		sum := sha256.Sum256([]byte(signed))
+		if rsa.VerifyPKCS1v15(j.RSAKey, crypto.SHA256, sum[:], sig) != nil {
+			return errors.New("invalid token signature")
+		}
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	return nil
+}
+
+func (j *JWT) verifyClaims(c claims) error {
+	now := time.Now()
+	if j.now != nil {
+		now = j.now()
+	}
+	// tokens without expiration would be valid forever
+	if c.ExpiresAt == nil {
+		return errors.New("token has no expiration")
+	}
+	if now.After(time.Unix(*c.ExpiresAt, 0).Add(j.Leeway)) {
+		return errors.New("token is expired")
+	}
+	if c.NotBefore != nil && now.Before(time.Unix(*c.NotBefore, 0).Add(-j.Leeway)) {
+		return errors.New("token is not valid yet")
+	}
+	if c.Subject == "" {
+		return errors.New("token has no subject")
+	}
+	if j.Issuer != "" && c.Issuer != j.Issuer {
+		return fmt.Errorf("unexpected token issuer %q", c.Issuer)
+	}
+	if j.Audience != "" {
+		aud, err := parseStrings(c.Audience, false)
+		if err != nil {
+			return fmt.Errorf("malformed audience claim: %w", err)
+		}
+		for _, a := range aud {
+			if a == j.Audience {
+				return nil
+			}
+		}
+		return errors.New("token is not intended for this service")
+	}
+	return nil
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// parseStrings parses claim being either array of strings or single string,
+// which is split by spaces if `split` is set
+func parseStrings(raw json.RawMessage, split bool) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	if split {
+		return strings.Fields(s), nil
+	}
+	return []string{s}, nil
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Rule restricts access to requests matching its methods and path prefix.
+// First rule matching request decides whether request is allowed.
+type Rule struct {
+	// Methods rule applies to, any method if empty
+	Methods []string
+	// PathPrefix rule applies to
+	PathPrefix string
+	// Anonymous allows requests without credentials
+	Anonymous bool
+	// Roles principal must have one of, any authenticated principal if empty
+	Roles []string
+}
+
+func (r Rule) matches(req *http.Request) bool {
+	if !strings.HasPrefix(req.URL.Path, r.PathPrefix) {
+		return false
+	}
+	if len(r.Methods) == 0 {
+		return true
+	}
+	for _, m := range r.Methods {
+		if m == req.Method {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) allows(p Principal) bool {
+	if len(r.Roles) == 0 {
+		return true
+	}
+	for _, role := range r.Roles {
+		if p.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware authenticates requests and checks them against rules.
+// Requests not matching any rule require authentication.
+type Middleware struct {
+	auth  Authenticator
+	rules []Rule
+}
+
+// NewMiddleware creates Middleware
+func NewMiddleware(a Authenticator, rules ...Rule) *Middleware {
+	return &Middleware{auth: a, rules: rules}
+}
+
+// Wrap returns handler passing allowed requests to `next` with principal put into request context
+func (m *Middleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := m.auth.Authenticate(r)
+		authenticated := err == nil
+		if err != nil && err != ErrNoCredentials {
+			writeUnauthorized(w, err.Error())
+			return
+		}
+		rule := Rule{}
+		for _, candidate := range m.rules {
+			if candidate.matches(r) {
+				rule = candidate
+				break
+			}
+		}
+		if !authenticated {
+			if !rule.Anonymous {
+				writeUnauthorized(w, "authentication required")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !rule.allows(p) {
+			w.Header().Add("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Forbidden"))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+	})
+}
+
+func writeUnauthorized(w http.ResponseWriter, reason string) {
+	w.Header().Add("Content-Type", "text/plain")
+	w.Header().Add("WWW-Authenticate", "Bearer")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write([]byte(reason))
+}
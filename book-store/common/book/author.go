@@ -6,6 +6,8 @@ import (
 
 // Author is author data
 type Author struct {
-	ID   uuid.UUID
-	Name string
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	// Version is incremented on every change of Author, on update it is expected current version, zero skips the check
+	Version uint64 `json:"version"`
 }
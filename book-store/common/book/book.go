@@ -6,8 +6,10 @@ import (
 
 // Book is book data
 type Book struct {
-	ID         uuid.UUID
-	Name       string
-	Author     Author
-	Categories []Category
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Author     Author     `json:"author"`
+	Categories []Category `json:"categories"`
+	// Version is incremented on every change of Book, on update it is expected current version, zero skips the check
+	Version uint64 `json:"version"`
 }
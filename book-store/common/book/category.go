@@ -6,7 +6,9 @@ import (
 
 // Category is book category data
 type Category struct {
-	ID       uuid.UUID
-	Name     string
-	ParentID uuid.UUID
+	ID       uuid.UUID `json:"id"`
+	Name     string    `json:"name"`
+	ParentID uuid.UUID `json:"parentID"`
+	// Version is incremented on every change of Category, on update it is expected current version, zero skips the check
+	Version uint64 `json:"version"`
 }
@@ -19,7 +19,17 @@ const _ = grpc.SupportPackageIsVersion7
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type CatalogClient interface {
 	GetBooks(ctx context.Context, in *BooksQuery, opts ...grpc.CallOption) (Catalog_GetBooksClient, error)
+	GetBooksByIDs(ctx context.Context, in *BookIDs, opts ...grpc.CallOption) (Catalog_GetBooksByIDsClient, error)
 	CreateBook(ctx context.Context, in *BookCreateDTO, opts ...grpc.CallOption) (*Book, error)
+	WatchBooks(ctx context.Context, in *WatchQuery, opts ...grpc.CallOption) (Catalog_WatchBooksClient, error)
+	// admin RPCs below require principal with admin role
+	GetDeletedBooks(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (Catalog_GetDeletedBooksClient, error)
+	GetDeletedAuthors(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (Catalog_GetDeletedAuthorsClient, error)
+	GetDeletedCategories(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (Catalog_GetDeletedCategoriesClient, error)
+	RestoreBook(ctx context.Context, in *EntityID, opts ...grpc.CallOption) (*Book, error)
+	RestoreAuthor(ctx context.Context, in *EntityID, opts ...grpc.CallOption) (*Author, error)
+	RestoreCategory(ctx context.Context, in *EntityID, opts ...grpc.CallOption) (*Category, error)
+	Purge(ctx context.Context, in *PurgeRequest, opts ...grpc.CallOption) (*PurgeResult, error)
 }
 
 type catalogClient struct {
@@ -62,6 +72,38 @@ func (x *catalogGetBooksClient) Recv() (*Book, error) {
 	return m, nil
 }
 
+func (c *catalogClient) GetBooksByIDs(ctx context.Context, in *BookIDs, opts ...grpc.CallOption) (Catalog_GetBooksByIDsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Catalog_ServiceDesc.Streams[1], "/catalog.Catalog/GetBooksByIDs", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &catalogGetBooksByIDsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Catalog_GetBooksByIDsClient interface {
+	Recv() (*Book, error)
+	grpc.ClientStream
+}
+
+type catalogGetBooksByIDsClient struct {
+	grpc.ClientStream
+}
+
+func (x *catalogGetBooksByIDsClient) Recv() (*Book, error) {
+	m := new(Book)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *catalogClient) CreateBook(ctx context.Context, in *BookCreateDTO, opts ...grpc.CallOption) (*Book, error) {
 	out := new(Book)
 	err := c.cc.Invoke(ctx, "/catalog.Catalog/CreateBook", in, out, opts...)
@@ -71,12 +113,186 @@ func (c *catalogClient) CreateBook(ctx context.Context, in *BookCreateDTO, opts
 	return out, nil
 }
 
+func (c *catalogClient) WatchBooks(ctx context.Context, in *WatchQuery, opts ...grpc.CallOption) (Catalog_WatchBooksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Catalog_ServiceDesc.Streams[2], "/catalog.Catalog/WatchBooks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &catalogWatchBooksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Catalog_WatchBooksClient interface {
+	Recv() (*Event, error)
+	grpc.ClientStream
+}
+
+type catalogWatchBooksClient struct {
+	grpc.ClientStream
+}
+
+func (x *catalogWatchBooksClient) Recv() (*Event, error) {
+	m := new(Event)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *catalogClient) GetDeletedBooks(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (Catalog_GetDeletedBooksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Catalog_ServiceDesc.Streams[3], "/catalog.Catalog/GetDeletedBooks", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &catalogGetDeletedBooksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Catalog_GetDeletedBooksClient interface {
+	Recv() (*DeletedBook, error)
+	grpc.ClientStream
+}
+
+type catalogGetDeletedBooksClient struct {
+	grpc.ClientStream
+}
+
+func (x *catalogGetDeletedBooksClient) Recv() (*DeletedBook, error) {
+	m := new(DeletedBook)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *catalogClient) GetDeletedAuthors(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (Catalog_GetDeletedAuthorsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Catalog_ServiceDesc.Streams[4], "/catalog.Catalog/GetDeletedAuthors", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &catalogGetDeletedAuthorsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Catalog_GetDeletedAuthorsClient interface {
+	Recv() (*DeletedAuthor, error)
+	grpc.ClientStream
+}
+
+type catalogGetDeletedAuthorsClient struct {
+	grpc.ClientStream
+}
+
+func (x *catalogGetDeletedAuthorsClient) Recv() (*DeletedAuthor, error) {
+	m := new(DeletedAuthor)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *catalogClient) GetDeletedCategories(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (Catalog_GetDeletedCategoriesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Catalog_ServiceDesc.Streams[5], "/catalog.Catalog/GetDeletedCategories", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &catalogGetDeletedCategoriesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Catalog_GetDeletedCategoriesClient interface {
+	Recv() (*DeletedCategory, error)
+	grpc.ClientStream
+}
+
+type catalogGetDeletedCategoriesClient struct {
+	grpc.ClientStream
+}
+
+func (x *catalogGetDeletedCategoriesClient) Recv() (*DeletedCategory, error) {
+	m := new(DeletedCategory)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *catalogClient) RestoreBook(ctx context.Context, in *EntityID, opts ...grpc.CallOption) (*Book, error) {
+	out := new(Book)
+	err := c.cc.Invoke(ctx, "/catalog.Catalog/RestoreBook", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogClient) RestoreAuthor(ctx context.Context, in *EntityID, opts ...grpc.CallOption) (*Author, error) {
+	out := new(Author)
+	err := c.cc.Invoke(ctx, "/catalog.Catalog/RestoreAuthor", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogClient) RestoreCategory(ctx context.Context, in *EntityID, opts ...grpc.CallOption) (*Category, error) {
+	out := new(Category)
+	err := c.cc.Invoke(ctx, "/catalog.Catalog/RestoreCategory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *catalogClient) Purge(ctx context.Context, in *PurgeRequest, opts ...grpc.CallOption) (*PurgeResult, error) {
+	out := new(PurgeResult)
+	err := c.cc.Invoke(ctx, "/catalog.Catalog/Purge", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CatalogServer is the server API for Catalog service.
 // All implementations must embed UnimplementedCatalogServer
 // for forward compatibility
 type CatalogServer interface {
 	GetBooks(*BooksQuery, Catalog_GetBooksServer) error
+	GetBooksByIDs(*BookIDs, Catalog_GetBooksByIDsServer) error
 	CreateBook(context.Context, *BookCreateDTO) (*Book, error)
+	WatchBooks(*WatchQuery, Catalog_WatchBooksServer) error
+	// admin RPCs below require principal with admin role
+	GetDeletedBooks(*DeletedQuery, Catalog_GetDeletedBooksServer) error
+	GetDeletedAuthors(*DeletedQuery, Catalog_GetDeletedAuthorsServer) error
+	GetDeletedCategories(*DeletedQuery, Catalog_GetDeletedCategoriesServer) error
+	RestoreBook(context.Context, *EntityID) (*Book, error)
+	RestoreAuthor(context.Context, *EntityID) (*Author, error)
+	RestoreCategory(context.Context, *EntityID) (*Category, error)
+	Purge(context.Context, *PurgeRequest) (*PurgeResult, error)
 	mustEmbedUnimplementedCatalogServer()
 }
 
@@ -87,9 +303,36 @@ type UnimplementedCatalogServer struct {
 func (UnimplementedCatalogServer) GetBooks(*BooksQuery, Catalog_GetBooksServer) error {
 	return status.Errorf(codes.Unimplemented, "method GetBooks not implemented")
 }
+func (UnimplementedCatalogServer) GetBooksByIDs(*BookIDs, Catalog_GetBooksByIDsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetBooksByIDs not implemented")
+}
 func (UnimplementedCatalogServer) CreateBook(context.Context, *BookCreateDTO) (*Book, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateBook not implemented")
 }
+func (UnimplementedCatalogServer) WatchBooks(*WatchQuery, Catalog_WatchBooksServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchBooks not implemented")
+}
+func (UnimplementedCatalogServer) GetDeletedBooks(*DeletedQuery, Catalog_GetDeletedBooksServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetDeletedBooks not implemented")
+}
+func (UnimplementedCatalogServer) GetDeletedAuthors(*DeletedQuery, Catalog_GetDeletedAuthorsServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetDeletedAuthors not implemented")
+}
+func (UnimplementedCatalogServer) GetDeletedCategories(*DeletedQuery, Catalog_GetDeletedCategoriesServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetDeletedCategories not implemented")
+}
+func (UnimplementedCatalogServer) RestoreBook(context.Context, *EntityID) (*Book, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreBook not implemented")
+}
+func (UnimplementedCatalogServer) RestoreAuthor(context.Context, *EntityID) (*Author, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreAuthor not implemented")
+}
+func (UnimplementedCatalogServer) RestoreCategory(context.Context, *EntityID) (*Category, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreCategory not implemented")
+}
+func (UnimplementedCatalogServer) Purge(context.Context, *PurgeRequest) (*PurgeResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Purge not implemented")
+}
 func (UnimplementedCatalogServer) mustEmbedUnimplementedCatalogServer() {}
 
 // UnsafeCatalogServer may be embedded to opt out of forward compatibility for this service.
@@ -124,6 +367,27 @@ func (x *catalogGetBooksServer) Send(m *Book) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _Catalog_GetBooksByIDs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BookIDs)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CatalogServer).GetBooksByIDs(m, &catalogGetBooksByIDsServer{stream})
+}
+
+type Catalog_GetBooksByIDsServer interface {
+	Send(*Book) error
+	grpc.ServerStream
+}
+
+type catalogGetBooksByIDsServer struct {
+	grpc.ServerStream
+}
+
+func (x *catalogGetBooksByIDsServer) Send(m *Book) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _Catalog_CreateBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(BookCreateDTO)
 	if err := dec(in); err != nil {
@@ -142,6 +406,162 @@ func _Catalog_CreateBook_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Catalog_WatchBooks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CatalogServer).WatchBooks(m, &catalogWatchBooksServer{stream})
+}
+
+type Catalog_WatchBooksServer interface {
+	Send(*Event) error
+	grpc.ServerStream
+}
+
+type catalogWatchBooksServer struct {
+	grpc.ServerStream
+}
+
+func (x *catalogWatchBooksServer) Send(m *Event) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Catalog_GetDeletedBooks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DeletedQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CatalogServer).GetDeletedBooks(m, &catalogGetDeletedBooksServer{stream})
+}
+
+type Catalog_GetDeletedBooksServer interface {
+	Send(*DeletedBook) error
+	grpc.ServerStream
+}
+
+type catalogGetDeletedBooksServer struct {
+	grpc.ServerStream
+}
+
+func (x *catalogGetDeletedBooksServer) Send(m *DeletedBook) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Catalog_GetDeletedAuthors_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DeletedQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CatalogServer).GetDeletedAuthors(m, &catalogGetDeletedAuthorsServer{stream})
+}
+
+type Catalog_GetDeletedAuthorsServer interface {
+	Send(*DeletedAuthor) error
+	grpc.ServerStream
+}
+
+type catalogGetDeletedAuthorsServer struct {
+	grpc.ServerStream
+}
+
+func (x *catalogGetDeletedAuthorsServer) Send(m *DeletedAuthor) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Catalog_GetDeletedCategories_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DeletedQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CatalogServer).GetDeletedCategories(m, &catalogGetDeletedCategoriesServer{stream})
+}
+
+type Catalog_GetDeletedCategoriesServer interface {
+	Send(*DeletedCategory) error
+	grpc.ServerStream
+}
+
+type catalogGetDeletedCategoriesServer struct {
+	grpc.ServerStream
+}
+
+func (x *catalogGetDeletedCategoriesServer) Send(m *DeletedCategory) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Catalog_RestoreBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntityID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServer).RestoreBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/catalog.Catalog/RestoreBook",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServer).RestoreBook(ctx, req.(*EntityID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Catalog_RestoreAuthor_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntityID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServer).RestoreAuthor(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/catalog.Catalog/RestoreAuthor",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServer).RestoreAuthor(ctx, req.(*EntityID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Catalog_RestoreCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EntityID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServer).RestoreCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/catalog.Catalog/RestoreCategory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServer).RestoreCategory(ctx, req.(*EntityID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Catalog_Purge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CatalogServer).Purge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/catalog.Catalog/Purge",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CatalogServer).Purge(ctx, req.(*PurgeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Catalog_ServiceDesc is the grpc.ServiceDesc for Catalog service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -153,6 +573,22 @@ var Catalog_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "CreateBook",
 			Handler:    _Catalog_CreateBook_Handler,
 		},
+		{
+			MethodName: "RestoreBook",
+			Handler:    _Catalog_RestoreBook_Handler,
+		},
+		{
+			MethodName: "RestoreAuthor",
+			Handler:    _Catalog_RestoreAuthor_Handler,
+		},
+		{
+			MethodName: "RestoreCategory",
+			Handler:    _Catalog_RestoreCategory_Handler,
+		},
+		{
+			MethodName: "Purge",
+			Handler:    _Catalog_Purge_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -160,6 +596,31 @@ var Catalog_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _Catalog_GetBooks_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "GetBooksByIDs",
+			Handler:       _Catalog_GetBooksByIDs_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchBooks",
+			Handler:       _Catalog_WatchBooks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetDeletedBooks",
+			Handler:       _Catalog_GetDeletedBooks_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetDeletedAuthors",
+			Handler:       _Catalog_GetDeletedAuthors_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "GetDeletedCategories",
+			Handler:       _Catalog_GetDeletedCategories_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "catalog/catalog.proto",
 }
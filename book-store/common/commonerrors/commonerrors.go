@@ -1,5 +1,7 @@
 package commonerrors
 
+import "fmt"
+
 // NotFound represents error that something was not found and that's an error
 type NotFound struct {
 	What string
@@ -17,3 +19,24 @@ type InvalidInput struct {
 func (e InvalidInput) Error() string {
 	return "Invalid input: " + e.Reason
 }
+
+// Unavailable represents error that some dependency can not be reached at the moment
+type Unavailable struct {
+	What   string
+	Reason string
+}
+
+func (e Unavailable) Error() string {
+	return e.What + " is unavailable: " + e.Reason
+}
+
+// VersionMismatch represents error that something was changed since the version client based its change on
+type VersionMismatch struct {
+	What     string
+	Expected uint64
+	Actual   uint64
+}
+
+func (e VersionMismatch) Error() string {
+	return fmt.Sprintf("%s has version %d, expected %d", e.What, e.Actual, e.Expected)
+}
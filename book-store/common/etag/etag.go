@@ -0,0 +1,29 @@
+package etag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format formats version of entity as strong entity tag
+func Format(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// ParseIfMatch parses expected version of entity from value of `If-Match` header.
+// Returns zero if header is empty or "*", only single entity tag is supported.
+func ParseIfMatch(header string) (uint64, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return 0, nil
+	}
+	if len(header) < 2 || header[0] != '"' || header[len(header)-1] != '"' {
+		return 0, fmt.Errorf("malformed If-Match header %s", header)
+	}
+	version, err := strconv.ParseUint(header[1:len(header)-1], 10, 64)
+	if err != nil || version == 0 {
+		return 0, fmt.Errorf("malformed If-Match header %s", header)
+	}
+	return version, nil
+}
@@ -0,0 +1,30 @@
+package etag_test
+
+import (
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/etag"
+)
+
+func TestRoundTrip(t *testing.T) {
+	version, err := etag.ParseIfMatch(etag.Format(42))
+	if err != nil {
+		t.Fatalf("Got error parsing formatted tag: %s", err)
+	}
+	if version != 42 {
+		t.Errorf("Expected version 42, got %d", version)
+	}
+}
+
+func TestParseIfMatch(t *testing.T) {
+	for _, h := range []string{"", "*", " * "} {
+		if version, err := etag.ParseIfMatch(h); err != nil || version != 0 {
+			t.Errorf("Expected %q to skip the check, got %d, %v", h, version, err)
+		}
+	}
+	for _, h := range []string{`W/"1"`, `"1", "2"`, `"0"`, `"abc"`, `1`, `"`} {
+		if _, err := etag.ParseIfMatch(h); err == nil {
+			t.Errorf("Expected error parsing %q", h)
+		}
+	}
+}
@@ -3,6 +3,7 @@ module github.com/Vesninovich/go-tasks/book-store/common
 go 1.16
 
 require (
+	github.com/jmoiron/sqlx v1.3.4
 	google.golang.org/grpc v1.38.0
 	google.golang.org/protobuf v1.26.0
 )
@@ -0,0 +1,121 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// MetadataKey carrying idempotency key of gRPC call
+const MetadataKey = "idempotency-key"
+
+// OutgoingContext returns context which makes gRPC call carry given idempotency key
+func OutgoingContext(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, key)
+}
+
+// UnaryServerInterceptor makes calls of given methods carrying idempotency key in metadata idempotent.
+// Methods are full names as in grpc.UnaryServerInfo, e.g. "/orders.Orders/CreateOrder".
+// Response is saved unless call fails, then it may be retried with the same key.
+// Call reusing key with different request fails with InvalidArgument, call repeated while the first one is in progress fails with Aborted.
+// Principal should already be put into context, so interceptor must be chained after auth one.
+func (g *Guard) UnaryServerInterceptor(methods ...string) grpc.UnaryServerInterceptor {
+	guarded := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		guarded[m] = true
+	}
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		key := incomingKey(ctx)
+		reqMsg, ok := req.(proto.Message)
+		if !guarded[info.FullMethod] || key == "" || !ok {
+			return handler(ctx, req)
+		}
+		data, err := proto.MarshalOptions{Deterministic: true}.Marshal(reqMsg)
+		if err != nil {
+			return nil, err
+		}
+		rec, replay, err := g.Begin(ctx, key, Fingerprint([]byte(info.FullMethod), data))
+		switch err.(type) {
+		case nil:
+		case *KeyReused, *commonerrors.InvalidInput:
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		case *InProgress:
+			return nil, status.Error(codes.Aborted, err.Error())
+		default:
+			return nil, err
+		}
+		if replay {
+			return replayResponse(info.FullMethod, rec)
+		}
+
+		res, err := handler(ctx, req)
+		if err != nil {
+			if relErr := g.Release(ctx, rec); relErr != nil {
+				log.Printf("Failed to release idempotency key %s: %s", key, relErr)
+			}
+			return res, err
+		}
+		if resMsg, ok := res.(proto.Message); ok {
+			rec.Status = int(codes.OK)
+			rec.Body, err = proto.Marshal(resMsg)
+			if err == nil {
+				err = g.Complete(ctx, rec)
+			}
+		}
+		if err != nil {
+			log.Printf("Failed to save response for idempotency key %s: %s", key, err)
+		}
+		return res, nil
+	}
+}
+
+func incomingKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if v := md.Get(MetadataKey); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// replayResponse restores saved response of method, its type is looked up in registry of generated messages
+func replayResponse(method string, rec Record) (interface{}, error) {
+	// method is "/package.Service/Method"
+	parts := strings.Split(strings.TrimPrefix(method, "/"), "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed method name %s", method)
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(parts[0]))
+	if err != nil {
+		return nil, err
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", parts[0])
+	}
+	md := sd.Methods().ByName(protoreflect.Name(parts[1]))
+	if md == nil {
+		return nil, fmt.Errorf("method %s not found", method)
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(md.Output().FullName())
+	if err != nil {
+		return nil, err
+	}
+	res := mt.New().Interface()
+	if err = proto.Unmarshal(rec.Body, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
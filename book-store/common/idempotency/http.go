@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+)
+
+// Header carrying idempotency key of HTTP request
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from Store
+const ReplayedHeader = "Idempotent-Replayed"
+
+// Middleware returns middleware making POST requests to given paths with Idempotency-Key header idempotent,
+// requests to other paths are passed through as is.
+// Response is saved unless it has 5xx status or handler panics, then request may be retried with the same key.
+// Request reusing key with different path or body gets 422, request repeated while the first one is in progress gets 409.
+// Principal should already be put into context, so middleware must be applied after auth one.
+func (g *Guard) Middleware(paths ...string) func(http.Handler) http.Handler {
+	guarded := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		guarded[p] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return g.wrap(next, guarded)
+	}
+}
+
+func (g *Guard) wrap(next http.Handler, guarded map[string]bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(Header)
+		if r.Method != http.MethodPost || key == "" || !guarded[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+		rec, replay, err := g.Begin(r.Context(), key, Fingerprint([]byte(r.URL.Path), body))
+		switch err.(type) {
+		case nil:
+		case *KeyReused:
+			writeError(w, http.StatusUnprocessableEntity, err)
+			return
+		case *InProgress:
+			writeError(w, http.StatusConflict, err)
+			return
+		case *commonerrors.InvalidInput:
+			writeError(w, http.StatusBadRequest, err)
+			return
+		default:
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if replay {
+			for k, v := range rec.Header {
+				w.Header()[k] = v
+			}
+			w.Header().Set(ReplayedHeader, "true")
+			w.WriteHeader(rec.Status)
+			w.Write(rec.Body)
+			return
+		}
+
+		rw := &recorder{ResponseWriter: w}
+		defer func() {
+			// key of request that failed with panic is not left in progress until window expires
+			if p := recover(); p != nil {
+				if err := g.Release(r.Context(), rec); err != nil {
+					log.Printf("Failed to release idempotency key %s: %s", key, err)
+				}
+				panic(p)
+			}
+		}()
+		next.ServeHTTP(rw, r)
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		if rw.status >= http.StatusInternalServerError {
+			err = g.Release(r.Context(), rec)
+		} else {
+			rec.Status = rw.status
+			rec.Header = w.Header().Clone()
+			rec.Body = rw.body.Bytes()
+			err = g.Complete(r.Context(), rec)
+		}
+		if err != nil {
+			log.Printf("Failed to save response for idempotency key %s: %s", key, err)
+		}
+	})
+}
+
+// recorder copies response written to it
+type recorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Add("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	w.Write([]byte(err.Error()))
+}
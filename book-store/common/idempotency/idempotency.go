@@ -0,0 +1,155 @@
+// Package idempotency makes create requests safe to retry: request carrying idempotency key is handled once
+// and its response is replayed to retries with the same key and data within configured window
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+)
+
+// DefaultWindow is how long responses are kept for replay unless configured otherwise
+const DefaultWindow = 24 * time.Hour
+
+// MaxKeyLength limits length of idempotency keys
+const MaxKeyLength = 255
+
+// Record of request made with idempotency key
+type Record struct {
+	// Scope keys are unique in, subject of principal who made request
+	Scope string
+	Key   string
+	// Fingerprint of request data, request with the same key and different data is rejected
+	Fingerprint string
+	// Done is set once response is saved
+	Done bool
+	// Status of response, HTTP status or gRPC code
+	Status int
+	// Header of HTTP response
+	Header map[string][]string
+	// Body of response
+	Body      []byte
+	CreatedAt time.Time
+}
+
+// Store keeps records of requests made with idempotency keys
+type Store interface {
+	// Reserve saves record of request in progress if there is no record with the same scope and key created after `since`,
+	// otherwise returns found record and false. Records created before `since` are replaced.
+	Reserve(ctx context.Context, rec Record, since time.Time) (Record, bool, error)
+	// Complete saves response of reserved request
+	Complete(ctx context.Context, rec Record) error
+	// Release removes record of request, so request with the same key may be made again
+	Release(ctx context.Context, scope, key string) error
+}
+
+// KeyReused represents error that idempotency key was already used for request with different data
+type KeyReused struct {
+	Key string
+}
+
+func (e KeyReused) Error() string {
+	return fmt.Sprintf("Idempotency key %s was already used for another request", e.Key)
+}
+
+// InProgress represents error that request with the same idempotency key is still being handled
+type InProgress struct {
+	Key string
+}
+
+func (e InProgress) Error() string {
+	return fmt.Sprintf("Request with idempotency key %s is still in progress", e.Key)
+}
+
+// Guard checks requests with idempotency keys against Store
+type Guard struct {
+	store  Store
+	window time.Duration
+	now    func() time.Time
+}
+
+// New creates Guard keeping responses in store for given window
+func New(s Store, window time.Duration) *Guard {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return &Guard{s, window, time.Now}
+}
+
+// WindowFromEnv reads window from <prefix>_IDEMPOTENCY_WINDOW environment variable, DefaultWindow if it is not set
+func WindowFromEnv(prefix string) (time.Duration, error) {
+	v := os.Getenv(prefix + "_IDEMPOTENCY_WINDOW")
+	if v == "" {
+		return DefaultWindow, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("malformed %s_IDEMPOTENCY_WINDOW: %w", prefix, err)
+	}
+	return d, nil
+}
+
+// Begin reserves key for request with given fingerprint made by caller from context.
+// Fingerprint is bound to the caller, so response is never replayed to request of other principal.
+// If request with the same key was already completed, its record is returned with `replay` set.
+// Returns KeyReused if key was used for request with different fingerprint
+// and InProgress if request with the same key is not completed yet.
+func (g *Guard) Begin(ctx context.Context, key, fingerprint string) (rec Record, replay bool, err error) {
+	if len(key) > MaxKeyLength {
+		return rec, false, &commonerrors.InvalidInput{Reason: fmt.Sprintf("idempotency key must be at most %d characters long", MaxKeyLength)}
+	}
+	now := g.now()
+	sc := scope(ctx)
+	fingerprint = Fingerprint([]byte(sc), []byte(fingerprint))
+	rec = Record{
+		Scope:       sc,
+		Key:         key,
+		Fingerprint: fingerprint,
+		CreatedAt:   now,
+	}
+	found, created, err := g.store.Reserve(ctx, rec, now.Add(-g.window))
+	if err != nil || created {
+		return rec, false, err
+	}
+	if found.Fingerprint != fingerprint {
+		return rec, false, &KeyReused{Key: key}
+	}
+	if !found.Done {
+		return rec, false, &InProgress{Key: key}
+	}
+	return found, true, nil
+}
+
+// Complete saves response of request reserved with Begin
+func (g *Guard) Complete(ctx context.Context, rec Record) error {
+	rec.Done = true
+	return g.store.Complete(ctx, rec)
+}
+
+// Release forgets request reserved with Begin, e.g. when it failed and may be retried with the same key
+func (g *Guard) Release(ctx context.Context, rec Record) error {
+	return g.store.Release(ctx, rec.Scope, rec.Key)
+}
+
+// Fingerprint hashes parts of request
+func Fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	for _, p := range parts {
+		fmt.Fprintf(h, "%d:", len(p))
+		h.Write(p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func scope(ctx context.Context) string {
+	if p, ok := auth.FromContext(ctx); ok {
+		return p.Subject
+	}
+	return ""
+}
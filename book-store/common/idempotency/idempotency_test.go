@@ -0,0 +1,231 @@
+package idempotency_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency/inmemory"
+	"github.com/Vesninovich/go-tasks/book-store/common/orders"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// counting handler creates new entity on every call
+type counting struct {
+	calls  int
+	status int
+}
+
+func (c *counting) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.calls++
+	body, _ := ioutil.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("ETag", `"1"`)
+	if c.status != 0 {
+		w.WriteHeader(c.status)
+	}
+	fmt.Fprintf(w, "%d:%s", c.calls, body)
+}
+
+func post(h http.Handler, path, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(idempotency.Header, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHTTPReplay(t *testing.T) {
+	next := &counting{status: http.StatusCreated}
+	h := idempotency.New(inmemory.New(), time.Hour).Middleware("/book", "/order")(next)
+
+	first := post(h, "/book", "k1", "data")
+	if first.Code != http.StatusCreated || first.Body.String() != "1:data" {
+		t.Fatalf("Unexpected first response %d %s", first.Code, first.Body)
+	}
+	replay := post(h, "/book", "k1", "data")
+	if replay.Code != http.StatusCreated || replay.Body.String() != "1:data" {
+		t.Errorf("Expected replay of first response, got %d %s", replay.Code, replay.Body)
+	}
+	if replay.Header().Get("ETag") != `"1"` || replay.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Errorf("Expected headers of first response to be replayed, got %v", replay.Header())
+	}
+	if next.calls != 1 {
+		t.Errorf("Expected request to be handled once, got %d calls", next.calls)
+	}
+
+	if w := post(h, "/book", "k1", "other"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 on key reused with different body, got %d", w.Code)
+	}
+	if w := post(h, "/order", "k1", "data"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected 422 on key reused with different path, got %d", w.Code)
+	}
+	if w := post(h, "/book", "k2", "data"); w.Body.String() != "2:data" {
+		t.Errorf("Expected request with new key to be handled, got %s", w.Body)
+	}
+	post(h, "/book", "", "data")
+	post(h, "/book", "", "data")
+	if next.calls != 4 {
+		t.Errorf("Expected requests without key to be handled every time, got %d calls", next.calls)
+	}
+	post(h, "/book/restore", "k1", "data")
+	post(h, "/book/restore", "k1", "data")
+	if next.calls != 6 {
+		t.Errorf("Expected requests to paths that are not guarded to be passed through, got %d calls", next.calls)
+	}
+	if w := post(h, "/book", strings.Repeat("k", idempotency.MaxKeyLength+1), "data"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 on too long key, got %d", w.Code)
+	}
+}
+
+func TestHTTPScope(t *testing.T) {
+	next := &counting{}
+	h := idempotency.New(inmemory.New(), time.Hour).Middleware("/book", "/order")(next)
+	for _, subject := range []string{"alice", "bob"} {
+		r := httptest.NewRequest(http.MethodPost, "/book", strings.NewReader("data"))
+		r = r.WithContext(auth.NewContext(r.Context(), auth.Principal{Subject: subject}))
+		r.Header.Set(idempotency.Header, "k")
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	if next.calls != 2 {
+		t.Errorf("Expected keys of different principals not to collide, got %d calls", next.calls)
+	}
+}
+
+// unscopedStore keeps keys of all principals together, like store shared by several services could
+type unscopedStore struct {
+	*inmemory.Store
+}
+
+func (s unscopedStore) Reserve(ctx context.Context, rec idempotency.Record, since time.Time) (idempotency.Record, bool, error) {
+	rec.Scope = ""
+	return s.Store.Reserve(ctx, rec, since)
+}
+
+func TestFingerprintBoundToCaller(t *testing.T) {
+	g := idempotency.New(unscopedStore{inmemory.New()}, time.Hour)
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	rec, _, err := g.Begin(alice, "k", "data")
+	if err != nil {
+		t.Fatalf("Error on first request: %s", err)
+	}
+	rec.Status = http.StatusCreated
+	g.Complete(alice, rec)
+
+	bob := auth.NewContext(context.Background(), auth.Principal{Subject: "bob"})
+	if _, replay, err := g.Begin(bob, "k", "data"); replay || err == nil {
+		t.Errorf("Expected response of one principal not to be replayed to other, got replay %t, error %v", replay, err)
+	}
+}
+
+func TestHTTPFailureReleasesKey(t *testing.T) {
+	next := &counting{status: http.StatusServiceUnavailable}
+	h := idempotency.New(inmemory.New(), time.Hour).Middleware("/book", "/order")(next)
+	post(h, "/order", "k", "data")
+	next.status = http.StatusOK
+	if w := post(h, "/order", "k", "data"); w.Code != http.StatusOK || next.calls != 2 {
+		t.Errorf("Expected failed request to be retried, got %d after %d calls", w.Code, next.calls)
+	}
+}
+
+func TestHTTPPanicReleasesKey(t *testing.T) {
+	panics := true
+	h := idempotency.New(inmemory.New(), time.Hour).Middleware("/order")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if panics {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Expected panic of handler to be passed on")
+			}
+		}()
+		post(h, "/order", "k", "data")
+	}()
+	panics = false
+	if w := post(h, "/order", "k", "data"); w.Code != http.StatusCreated {
+		t.Errorf("Expected request failed with panic to be retried with the same key, got %d", w.Code)
+	}
+}
+
+func TestHTTPInProgress(t *testing.T) {
+	g := idempotency.New(inmemory.New(), time.Hour)
+	var nested *httptest.ResponseRecorder
+	var h http.Handler
+	h = g.Middleware("/order")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if nested == nil {
+			nested = post(h, "/order", "k", "data")
+		}
+	}))
+	post(h, "/order", "k", "data")
+	if nested.Code != http.StatusConflict {
+		t.Errorf("Expected 409 on request repeated while first is in progress, got %d", nested.Code)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	next := &counting{}
+	g := idempotency.New(inmemory.New(), time.Millisecond)
+	h := g.Middleware("/book")(next)
+	post(h, "/book", "k", "data")
+	time.Sleep(5 * time.Millisecond)
+	if w := post(h, "/book", "k", "other"); w.Body.String() != "2:other" {
+		t.Errorf("Expected key to be reusable after window, got %d %s", w.Code, w.Body)
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	const method = "/orders.Orders/CreateOrder"
+	intercept := idempotency.New(inmemory.New(), time.Hour).UnaryServerInterceptor(method)
+	calls := 0
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		calls++
+		return &orders.Order{Id: []byte{byte(calls)}, Description: req.(*orders.CreateDTO).Description}, nil
+	}
+	call := func(m, key, desc string) (*orders.Order, error) {
+		ctx := context.Background()
+		if key != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(idempotency.MetadataKey, key))
+		}
+		res, err := intercept(ctx, &orders.CreateDTO{Description: desc}, &grpc.UnaryServerInfo{FullMethod: m}, handler)
+		if err != nil {
+			return nil, err
+		}
+		return res.(*orders.Order), nil
+	}
+
+	first, err := call(method, "k", "d")
+	if err != nil {
+		t.Fatalf("Error on first call: %s", err)
+	}
+	replay, err := call(method, "k", "d")
+	if err != nil {
+		t.Fatalf("Error on replay: %s", err)
+	}
+	if !proto.Equal(first, replay) || calls != 1 {
+		t.Errorf("Expected replay of %v, got %v after %d calls", first, replay, calls)
+	}
+	if _, err = call(method, "k", "other"); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument on key reused with different request, got %v", err)
+	}
+	call(method, "", "d")
+	call("/orders.Orders/GetOrder", "k", "d")
+	if calls != 3 {
+		t.Errorf("Expected calls without key or of other methods to pass through, got %d calls", calls)
+	}
+}
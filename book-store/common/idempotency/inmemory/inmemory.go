@@ -0,0 +1,72 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
+)
+
+type key struct {
+	scope string
+	key   string
+}
+
+// Store represents in-memory store of idempotency records
+type Store struct {
+	data map[key]idempotency.Record
+	lock sync.Mutex
+}
+
+// New creates new in-memory store of idempotency records
+func New() *Store {
+	return &Store{
+		data: make(map[key]idempotency.Record),
+	}
+}
+
+// Reserve saves record of request in progress unless there is one with the same scope and key created after `since`
+func (s *Store) Reserve(ctx context.Context, rec idempotency.Record, since time.Time) (idempotency.Record, bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	k := key{rec.Scope, rec.Key}
+	if found, ok := s.data[k]; ok && !found.CreatedAt.Before(since) {
+		return found, false, nil
+	}
+	rec.Done = false
+	s.data[k] = rec
+	s.purge(since)
+	return rec, true, nil
+}
+
+// Complete saves response of reserved request
+func (s *Store) Complete(ctx context.Context, rec idempotency.Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	k := key{rec.Scope, rec.Key}
+	if found, ok := s.data[k]; ok && !found.Done && found.Fingerprint == rec.Fingerprint {
+		s.data[k] = rec
+	}
+	return nil
+}
+
+// Release removes record of request
+func (s *Store) Release(ctx context.Context, scope, k string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.data, key{scope, k})
+	return nil
+}
+
+// purge removes records created before given time
+func (s *Store) purge(before time.Time) {
+	for k, rec := range s.data {
+		if rec.CreatedAt.Before(before) {
+			delete(s.data, k)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package inmemory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency/inmemory"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	s := inmemory.New()
+	now := time.Now()
+	rec := idempotency.Record{Scope: "alice", Key: "k", Fingerprint: "f", CreatedAt: now}
+
+	if _, created, err := s.Reserve(ctx, rec, now.Add(-time.Hour)); err != nil || !created {
+		t.Fatalf("Expected new key to be reserved, got %v %v", created, err)
+	}
+	found, created, _ := s.Reserve(ctx, rec, now.Add(-time.Hour))
+	if created || found.Done {
+		t.Errorf("Expected reserved record in progress to be found, got %+v", found)
+	}
+
+	rec.Done, rec.Status, rec.Body = true, 201, []byte("body")
+	if err := s.Complete(ctx, rec); err != nil {
+		t.Fatalf("Error completing record: %s", err)
+	}
+	found, _, _ = s.Reserve(ctx, rec, now.Add(-time.Hour))
+	if !found.Done || found.Status != 201 || string(found.Body) != "body" {
+		t.Errorf("Expected completed record to be found, got %+v", found)
+	}
+
+	if _, created, _ = s.Reserve(ctx, rec, now.Add(time.Second)); !created {
+		t.Error("Expected expired record to be replaced")
+	}
+	if err := s.Release(ctx, rec.Scope, rec.Key); err != nil {
+		t.Fatalf("Error releasing record: %s", err)
+	}
+	if _, created, _ = s.Reserve(ctx, rec, now.Add(-time.Hour)); !created {
+		t.Error("Expected released key to be reserved again")
+	}
+}
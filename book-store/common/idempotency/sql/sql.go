@@ -0,0 +1,113 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
+)
+
+// Store provides access to relational DB storage of idempotency records
+type Store struct {
+	db     *sql.DB
+	schema string
+}
+
+// New creates a new instance of Store keeping records in given schema
+func New(db *sql.DB, schema string) *Store {
+	return &Store{db, schema}
+}
+
+// CreateTableStmt of idempotency records
+func (s *Store) CreateTableStmt() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s.idempotency_keys(
+  scope varchar NOT NULL,
+  key varchar NOT NULL,
+  fingerprint varchar NOT NULL,
+  done boolean NOT NULL DEFAULT false,
+  status integer NOT NULL DEFAULT 0,
+  header text,
+  body bytea,
+  created_at timestamptz NOT NULL,
+  PRIMARY KEY (scope, key)
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON %[1]s.idempotency_keys(created_at);`, s.schema)
+}
+
+// Reserve saves record of request in progress unless there is one with the same scope and key created after `since`.
+// Records created before `since` are deleted.
+func (s *Store) Reserve(ctx context.Context, rec idempotency.Record, since time.Time) (idempotency.Record, bool, error) {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s.idempotency_keys WHERE created_at < $1;", s.schema), since)
+	if err != nil {
+		return rec, false, err
+	}
+	// record found on conflict may be released right before it is read, then key is tried again
+	for attempt := 0; ; attempt++ {
+		res, err := s.db.ExecContext(
+			ctx,
+			fmt.Sprintf(`INSERT INTO %s.idempotency_keys (scope, key, fingerprint, created_at)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (scope, key) DO NOTHING;`, s.schema),
+			rec.Scope, rec.Key, rec.Fingerprint, rec.CreatedAt,
+		)
+		if err != nil {
+			return rec, false, err
+		}
+		if count, err := res.RowsAffected(); err != nil || count > 0 {
+			return rec, err == nil, err
+		}
+		found, err := s.get(ctx, rec.Scope, rec.Key)
+		if err == sql.ErrNoRows && attempt == 0 {
+			continue
+		}
+		return found, false, err
+	}
+}
+
+func (s *Store) get(ctx context.Context, scope, key string) (idempotency.Record, error) {
+	rec := idempotency.Record{Scope: scope, Key: key}
+	var header sql.NullString
+	err := s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`SELECT fingerprint, done, status, header, body, created_at
+			FROM %s.idempotency_keys
+			WHERE scope=$1 AND key=$2;`, s.schema),
+		scope, key,
+	).Scan(&rec.Fingerprint, &rec.Done, &rec.Status, &header, &rec.Body, &rec.CreatedAt)
+	if err != nil {
+		return rec, err
+	}
+	if header.Valid {
+		err = json.Unmarshal([]byte(header.String), &rec.Header)
+	}
+	return rec, err
+}
+
+// Complete saves response of reserved request
+func (s *Store) Complete(ctx context.Context, rec idempotency.Record) error {
+	header, err := json.Marshal(rec.Header)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %s.idempotency_keys
+			SET done=true, status=$4, header=$5, body=$6
+			WHERE scope=$1 AND key=$2 AND fingerprint=$3 AND NOT done;`, s.schema),
+		rec.Scope, rec.Key, rec.Fingerprint, rec.Status, string(header), rec.Body,
+	)
+	return err
+}
+
+// Release removes record of request
+func (s *Store) Release(ctx context.Context, scope, key string) error {
+	_, err := s.db.ExecContext(
+		ctx,
+		fmt.Sprintf("DELETE FROM %s.idempotency_keys WHERE scope=$1 AND key=$2;", s.schema),
+		scope, key,
+	)
+	return err
+}
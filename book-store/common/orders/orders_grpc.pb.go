@@ -22,6 +22,10 @@ type OrdersClient interface {
 	CreateOrder(ctx context.Context, in *CreateDTO, opts ...grpc.CallOption) (*Order, error)
 	UpdateOrderDescription(ctx context.Context, in *DescriptionUpdate, opts ...grpc.CallOption) (*Order, error)
 	RemoveOrder(ctx context.Context, in *ID, opts ...grpc.CallOption) (*Order, error)
+	// admin RPCs below require principal with admin role
+	GetDeletedOrders(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (Orders_GetDeletedOrdersClient, error)
+	RestoreOrder(ctx context.Context, in *ID, opts ...grpc.CallOption) (*Order, error)
+	PurgeOrders(ctx context.Context, in *PurgeRequest, opts ...grpc.CallOption) (*PurgeResult, error)
 }
 
 type ordersClient struct {
@@ -68,6 +72,56 @@ func (c *ordersClient) RemoveOrder(ctx context.Context, in *ID, opts ...grpc.Cal
 	return out, nil
 }
 
+func (c *ordersClient) GetDeletedOrders(ctx context.Context, in *DeletedQuery, opts ...grpc.CallOption) (Orders_GetDeletedOrdersClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Orders_ServiceDesc.Streams[0], "/orders.Orders/GetDeletedOrders", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &ordersGetDeletedOrdersClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Orders_GetDeletedOrdersClient interface {
+	Recv() (*DeletedOrder, error)
+	grpc.ClientStream
+}
+
+type ordersGetDeletedOrdersClient struct {
+	grpc.ClientStream
+}
+
+func (x *ordersGetDeletedOrdersClient) Recv() (*DeletedOrder, error) {
+	m := new(DeletedOrder)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *ordersClient) RestoreOrder(ctx context.Context, in *ID, opts ...grpc.CallOption) (*Order, error) {
+	out := new(Order)
+	err := c.cc.Invoke(ctx, "/orders.Orders/RestoreOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ordersClient) PurgeOrders(ctx context.Context, in *PurgeRequest, opts ...grpc.CallOption) (*PurgeResult, error) {
+	out := new(PurgeResult)
+	err := c.cc.Invoke(ctx, "/orders.Orders/PurgeOrders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // OrdersServer is the server API for Orders service.
 // All implementations must embed UnimplementedOrdersServer
 // for forward compatibility
@@ -76,6 +130,10 @@ type OrdersServer interface {
 	CreateOrder(context.Context, *CreateDTO) (*Order, error)
 	UpdateOrderDescription(context.Context, *DescriptionUpdate) (*Order, error)
 	RemoveOrder(context.Context, *ID) (*Order, error)
+	// admin RPCs below require principal with admin role
+	GetDeletedOrders(*DeletedQuery, Orders_GetDeletedOrdersServer) error
+	RestoreOrder(context.Context, *ID) (*Order, error)
+	PurgeOrders(context.Context, *PurgeRequest) (*PurgeResult, error)
 	mustEmbedUnimplementedOrdersServer()
 }
 
@@ -95,6 +153,15 @@ func (UnimplementedOrdersServer) UpdateOrderDescription(context.Context, *Descri
 func (UnimplementedOrdersServer) RemoveOrder(context.Context, *ID) (*Order, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveOrder not implemented")
 }
+func (UnimplementedOrdersServer) GetDeletedOrders(*DeletedQuery, Orders_GetDeletedOrdersServer) error {
+	return status.Errorf(codes.Unimplemented, "method GetDeletedOrders not implemented")
+}
+func (UnimplementedOrdersServer) RestoreOrder(context.Context, *ID) (*Order, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreOrder not implemented")
+}
+func (UnimplementedOrdersServer) PurgeOrders(context.Context, *PurgeRequest) (*PurgeResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeOrders not implemented")
+}
 func (UnimplementedOrdersServer) mustEmbedUnimplementedOrdersServer() {}
 
 // UnsafeOrdersServer may be embedded to opt out of forward compatibility for this service.
@@ -180,6 +247,63 @@ func _Orders_RemoveOrder_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Orders_GetDeletedOrders_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(DeletedQuery)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(OrdersServer).GetDeletedOrders(m, &ordersGetDeletedOrdersServer{stream})
+}
+
+type Orders_GetDeletedOrdersServer interface {
+	Send(*DeletedOrder) error
+	grpc.ServerStream
+}
+
+type ordersGetDeletedOrdersServer struct {
+	grpc.ServerStream
+}
+
+func (x *ordersGetDeletedOrdersServer) Send(m *DeletedOrder) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Orders_RestoreOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ID)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrdersServer).RestoreOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orders.Orders/RestoreOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrdersServer).RestoreOrder(ctx, req.(*ID))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Orders_PurgeOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(OrdersServer).PurgeOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orders.Orders/PurgeOrders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(OrdersServer).PurgeOrders(ctx, req.(*PurgeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Orders_ServiceDesc is the grpc.ServiceDesc for Orders service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -203,7 +327,21 @@ var Orders_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveOrder",
 			Handler:    _Orders_RemoveOrder_Handler,
 		},
+		{
+			MethodName: "RestoreOrder",
+			Handler:    _Orders_RestoreOrder_Handler,
+		},
+		{
+			MethodName: "PurgeOrders",
+			Handler:    _Orders_PurgeOrders_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "GetDeletedOrders",
+			Handler:       _Orders_GetDeletedOrders_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "orders/orders.proto",
 }
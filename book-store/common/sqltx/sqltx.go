@@ -0,0 +1,65 @@
+// Package sqltx runs changes of several SQL repositories in one transaction,
+// the transaction is passed to repositories in context
+package sqltx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type txKey struct{}
+
+// Querier executes statements either in transaction or directly in DB
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// Transactor runs functions in transactions of DB
+type Transactor struct {
+	db *sqlx.DB
+}
+
+// New creates new Transactor of given DB
+func New(db *sqlx.DB) *Transactor {
+	return &Transactor{db}
+}
+
+// InTransaction runs fn in transaction, see Run
+func (t *Transactor) InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return Run(ctx, t.db, fn)
+}
+
+// Run runs fn in transaction that is committed if fn succeeds and rolled back otherwise.
+// Repositories given context passed to fn execute their statements in the transaction.
+// If ctx already carries transaction, fn joins it and it is left to the outer call to finish it.
+func Run(ctx context.Context, db *sqlx.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	err = fn(context.WithValue(ctx, txKey{}, tx))
+	if err != nil {
+		rbErr := tx.Rollback()
+		if rbErr != nil {
+			err = rbErr
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+// DB returns transaction carried by ctx, or db itself if there is none
+func DB(ctx context.Context, db *sqlx.DB) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return tx
+	}
+	return db
+}
@@ -0,0 +1,40 @@
+package stored
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultRetention is how long deleted items are kept before they are purged
+const DefaultRetention = 30 * 24 * time.Hour
+
+// DefaultPurgeInterval is how often deleted items are purged
+const DefaultPurgeInterval = time.Hour
+
+// PurgeFromEnv reads how long deleted items are kept from <prefix>_DELETED_RETENTION
+// and how often they are purged from <prefix>_PURGE_INTERVAL environment variables,
+// DefaultRetention and DefaultPurgeInterval are used for unset ones
+func PurgeFromEnv(prefix string) (retention, interval time.Duration, err error) {
+	retention, err = durationFromEnv(prefix+"_DELETED_RETENTION", DefaultRetention)
+	if err != nil {
+		return
+	}
+	interval, err = durationFromEnv(prefix+"_PURGE_INTERVAL", DefaultPurgeInterval)
+	return
+}
+
+func durationFromEnv(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("malformed %s: %w", name, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return d, nil
+}
@@ -0,0 +1,34 @@
+package stored
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestPurgeFromEnv(t *testing.T) {
+	os.Unsetenv("TEST_DELETED_RETENTION")
+	os.Unsetenv("TEST_PURGE_INTERVAL")
+	retention, interval, err := PurgeFromEnv("TEST")
+	if err != nil || retention != DefaultRetention || interval != DefaultPurgeInterval {
+		t.Errorf("Expected defaults, got %s %s %v", retention, interval, err)
+	}
+
+	os.Setenv("TEST_DELETED_RETENTION", "72h")
+	os.Setenv("TEST_PURGE_INTERVAL", "10m")
+	defer os.Unsetenv("TEST_DELETED_RETENTION")
+	defer os.Unsetenv("TEST_PURGE_INTERVAL")
+	retention, interval, err = PurgeFromEnv("TEST")
+	if err != nil || retention != 72*time.Hour || interval != 10*time.Minute {
+		t.Errorf("Expected values from environment, got %s %s %v", retention, interval, err)
+	}
+
+	os.Setenv("TEST_PURGE_INTERVAL", "0s")
+	if _, _, err = PurgeFromEnv("TEST"); err == nil {
+		t.Error("Expected error for zero interval")
+	}
+	os.Setenv("TEST_PURGE_INTERVAL", "often")
+	if _, _, err = PurgeFromEnv("TEST"); err == nil {
+		t.Error("Expected error for malformed interval")
+	}
+}
@@ -0,0 +1,196 @@
+package tlsconf
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Config describes TLS setup of a service.
+// Service is considered to use TLS when CertFile is set.
+type Config struct {
+	// CertFile and KeyFile are paths to PEM encoded certificate of the service and its key.
+	// Certificate is presented to clients by servers and to servers by clients.
+	CertFile string
+	KeyFile  string
+	// CAFile is path to PEM encoded certificate of CA used to verify peers.
+	// System roots are used for verification of servers when it is empty.
+	CAFile string
+	// ClientNames are names (common name or DNS SAN) of clients allowed to connect to server.
+	// When not empty server requires clients to present certificate signed by CA and carrying one of these names.
+	ClientNames []string
+}
+
+// FromEnv reads Config from environment variables
+// <prefix>_TLS_CERT, <prefix>_TLS_KEY, <prefix>_TLS_CA and <prefix>_TLS_CLIENT_NAMES (comma separated).
+func FromEnv(prefix string) Config {
+	c := Config{
+		CertFile: os.Getenv(prefix + "_TLS_CERT"),
+		KeyFile:  os.Getenv(prefix + "_TLS_KEY"),
+		CAFile:   os.Getenv(prefix + "_TLS_CA"),
+	}
+	for _, name := range strings.Split(os.Getenv(prefix+"_TLS_CLIENT_NAMES"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			c.ClientNames = append(c.ClientNames, name)
+		}
+	}
+	return c
+}
+
+// Enabled reports whether TLS is configured
+func (c Config) Enabled() bool {
+	return c.CertFile != ""
+}
+
+// Server builds tls.Config for server side of connection
+func (c Config) Server() (*tls.Config, error) {
+	if !c.Enabled() {
+		return nil, errors.New("no certificate configured for server")
+	}
+	cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if c.CAFile != "" {
+		pool, err := loadPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.ClientCAs = pool
+		conf.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	if len(c.ClientNames) > 0 {
+		if conf.ClientCAs == nil {
+			return nil, errors.New("CA is required to verify client names")
+		}
+		conf.ClientAuth = tls.RequireAndVerifyClientCert
+		conf.VerifyPeerCertificate = verifyNames(c.ClientNames)
+	}
+	return conf, nil
+}
+
+// Client builds tls.Config for client side of connection to server with given name.
+// Certificate of the service, if configured, is presented to the server.
+func (c Config) Client(serverName string) (*tls.Config, error) {
+	conf := &tls.Config{
+		ServerName: serverName,
+		MinVersion: tls.VersionTLS12,
+	}
+	if c.CAFile != "" {
+		pool, err := loadPool(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = pool
+	}
+	if c.Enabled() {
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+// ServerOption builds gRPC server option with TLS credentials.
+// Returns no-op option if TLS is not configured.
+func (c Config) ServerOption() (grpc.ServerOption, error) {
+	if !c.Enabled() {
+		return grpc.EmptyServerOption{}, nil
+	}
+	conf, err := c.Server()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Creds(credentials.NewTLS(conf)), nil
+}
+
+// DialOption builds gRPC dial option for connection to server with given name.
+// Connection is insecure if neither certificate nor CA are configured.
+func (c Config) DialOption(serverName string) (grpc.DialOption, error) {
+	if !c.Enabled() && c.CAFile == "" {
+		return grpc.WithInsecure(), nil
+	}
+	conf, err := c.Client(serverName)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(conf)), nil
+}
+
+func loadPool(caFile string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", caFile)
+	}
+	return pool, nil
+}
+
+// verifyNames checks that verified peer certificate carries one of allowed names
+func verifyNames(allowed []string) func([][]byte, [][]*x509.Certificate) error {
+	return func(_ [][]byte, chains [][]*x509.Certificate) error {
+		for _, chain := range chains {
+			if len(chain) == 0 {
+				continue
+			}
+			leaf := chain[0]
+			if hasName(leaf, allowed) {
+				return nil
+			}
+			return fmt.Errorf("client certificate %q is not allowed", leaf.Subject.CommonName)
+		}
+		return errors.New("no verified client certificate")
+	}
+}
+
+// PeerHasName reports whether gRPC peer of the call in context presented certificate verified by server
+// and carrying one of given names. It is false for calls over connections without TLS.
+func PeerHasName(ctx context.Context, names []string) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok || len(names) == 0 {
+		return false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return false
+	}
+	for _, chain := range info.State.VerifiedChains {
+		if len(chain) > 0 && hasName(chain[0], names) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasName reports whether certificate carries one of names as common name or DNS SAN
+func hasName(cert *x509.Certificate, names []string) bool {
+	for _, name := range names {
+		if cert.Subject.CommonName == name {
+			return true
+		}
+		for _, dns := range cert.DNSNames {
+			if dns == name {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package tlsconf_test
+
+import (
+	"crypto/tls"
+	"os"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/tlsconf"
+	"github.com/Vesninovich/go-tasks/book-store/common/tlsconf/tlstest"
+)
+
+func TestHandshake(t *testing.T) {
+	ca, err := tlstest.NewCA(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := issue(t, ca, "server")
+	allowed := issue(t, ca, "allowed")
+	denied := issue(t, ca, "denied")
+
+	mutual := server
+	mutual.ClientNames = []string{"allowed"}
+
+	t.Run("Server only", func(t *testing.T) {
+		err := handshake(t, server, tlsconf.Config{CAFile: ca.CAFile()})
+		if err != nil {
+			t.Errorf("Expected handshake to succeed, got %s", err)
+		}
+	})
+	t.Run("Allowed client", func(t *testing.T) {
+		err := handshake(t, mutual, allowed)
+		if err != nil {
+			t.Errorf("Expected handshake to succeed, got %s", err)
+		}
+	})
+	t.Run("Client with not allowed name", func(t *testing.T) {
+		err := handshake(t, mutual, denied)
+		if err == nil {
+			t.Error("Expected handshake to fail")
+		}
+	})
+	t.Run("Client without certificate", func(t *testing.T) {
+		err := handshake(t, mutual, tlsconf.Config{CAFile: ca.CAFile()})
+		if err == nil {
+			t.Error("Expected handshake to fail")
+		}
+	})
+	t.Run("Client not trusting server", func(t *testing.T) {
+		other, err := tlstest.NewCA(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = handshake(t, server, tlsconf.Config{CAFile: other.CAFile()})
+		if err == nil {
+			t.Error("Expected handshake to fail")
+		}
+	})
+}
+
+func TestClientNamesWithoutCA(t *testing.T) {
+	ca, err := tlstest.NewCA(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := issue(t, ca, "server")
+	c.CAFile = ""
+	c.ClientNames = []string{"client"}
+	if _, err = c.Server(); err == nil {
+		t.Error("Expected error when client names are set without CA")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	setenv(t, "TEST_TLS_CERT", "cert.pem")
+	setenv(t, "TEST_TLS_KEY", "key.pem")
+	setenv(t, "TEST_TLS_CA", "ca.pem")
+	setenv(t, "TEST_TLS_CLIENT_NAMES", "orders, admin,")
+	c := tlsconf.FromEnv("TEST")
+	if c.CertFile != "cert.pem" || c.KeyFile != "key.pem" || c.CAFile != "ca.pem" {
+		t.Errorf("Wrong files read from env: %+v", c)
+	}
+	if len(c.ClientNames) != 2 || c.ClientNames[0] != "orders" || c.ClientNames[1] != "admin" {
+		t.Errorf("Wrong client names read from env: %v", c.ClientNames)
+	}
+	if !c.Enabled() {
+		t.Error("Expected config to be enabled")
+	}
+	if tlsconf.FromEnv("NOT_SET").Enabled() {
+		t.Error("Expected config without certificate to be disabled")
+	}
+}
+
+func setenv(t *testing.T, key, value string) {
+	os.Setenv(key, value)
+	t.Cleanup(func() { os.Unsetenv(key) })
+}
+
+func issue(t *testing.T, ca *tlstest.CA, name string) tlsconf.Config {
+	c, err := ca.Issue(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// handshake connects client to server and returns first error encountered by either side
+func handshake(t *testing.T, server, client tlsconf.Config) error {
+	sConf, err := server.Server()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cConf, err := client.Client("server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", sConf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		if err = conn.(*tls.Conn).Handshake(); err != nil {
+			serverErr <- err
+			return
+		}
+		_, err = conn.Write([]byte{1})
+		serverErr <- err
+	}()
+
+	conn, err := tls.Dial("tcp", lis.Addr().String(), cConf)
+	if err == nil {
+		// with TLS 1.3 client learns about rejected certificate only on read
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	if sErr := <-serverErr; sErr != nil {
+		return sErr
+	}
+	return err
+}
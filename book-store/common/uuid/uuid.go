@@ -82,6 +82,17 @@ func (uuid UUID) String() string {
 	return string(res[:])
 }
 
+// MarshalText makes UUID appear as string in JSON
+func (uuid UUID) MarshalText() ([]byte, error) {
+	return []byte(uuid.String()), nil
+}
+
+// UnmarshalText reads UUID from string
+func (uuid *UUID) UnmarshalText(text []byte) (err error) {
+	*uuid, err = FromString(string(text))
+	return
+}
+
 var zero UUID
 
 // IsZero checks if UUID is zero value
@@ -1,6 +1,7 @@
 package uuid_test
 
 import (
+	"encoding/json"
 	"strings"
 	"testing"
 
@@ -47,3 +48,21 @@ func TestFromInvalidString(t *testing.T) {
 		}
 	})
 }
+
+func TestJSON(t *testing.T) {
+	id := uuid.New()
+	data, err := json.Marshal(id)
+	if err != nil {
+		t.Fatalf("Failed to marshal UUID: %s", err)
+	}
+	if string(data) != `"`+id.String()+`"` {
+		t.Errorf("Expected UUID to be marshalled as string, got %s", data)
+	}
+	var res uuid.UUID
+	if err = json.Unmarshal(data, &res); err != nil || res != id {
+		t.Errorf("Wrong UUID after marshalling/unmarshalling:\n\tsource %s\n\tresult %s (%v)", id, res, err)
+	}
+	if err = json.Unmarshal([]byte(`"not uuid"`), &res); err == nil {
+		t.Error("Expected to get error on malformed UUID string")
+	}
+}
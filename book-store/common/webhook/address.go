@@ -0,0 +1,87 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// privateNetworks are ranges of addresses not reachable from the Internet, deliveries are not made to them,
+// so that subscriptions can not be used to reach services of internal network
+var privateNetworks = mustParseNetworks("10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,100.64.0.0/10,0.0.0.0/8,fc00::/7")
+
+// ParseNetworks parses comma separated list of networks in CIDR notation, e.g. "10.1.0.0/16,fd00::/8"
+func ParseNetworks(s string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(s, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, n)
+	}
+	return networks, nil
+}
+
+func mustParseNetworks(s string) []*net.IPNet {
+	networks, err := ParseNetworks(s)
+	if err != nil {
+		panic(err)
+	}
+	return networks
+}
+
+// checkIP returns error if ip is loopback, link-local, private or unspecified address, unless it is in allowed networks
+func checkIP(ip net.IP, allowed []*net.IPNet) error {
+	if inNetworks(ip, allowed) {
+		return nil
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() || inNetworks(ip, privateNetworks) {
+		return fmt.Errorf("address %s is not public", ip)
+	}
+	return nil
+}
+
+func inNetworks(ip net.IP, networks []*net.IPNet) bool {
+	for _, n := range networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost resolves host and returns error if any of its addresses may not be delivered to
+func checkHost(ctx context.Context, host string, allowed []*net.IPNet) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		if err = checkIP(a.IP, allowed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dialControl checks address right before connection is made, so that host resolving to other address
+// after subscription was made (or redirect) does not reach internal network
+func dialControl(allowed []*net.IPNet) func(network, address string, c syscall.RawConn) error {
+	return func(network, address string, c syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("address %s is not IP", host)
+		}
+		return checkIP(ip, allowed)
+	}
+}
@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// DefaultInterval is how often deliveries due for retry are attempted
+const DefaultInterval = 10 * time.Second
+
+// OptionsFromEnv reads options of delivery from <prefix>_WEBHOOK_MAX_ATTEMPTS, <prefix>_WEBHOOK_BACKOFF,
+// <prefix>_WEBHOOK_MAX_BACKOFF, <prefix>_WEBHOOK_TIMEOUT and <prefix>_WEBHOOK_ALLOWED_NETWORKS (comma separated CIDRs)
+// environment variables and interval of retries from <prefix>_WEBHOOK_INTERVAL, defaults are used for unset ones
+func OptionsFromEnv(prefix string, events []string) (opts Options, interval time.Duration, err error) {
+	opts.Events = events
+	if v := os.Getenv(prefix + "_WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		if opts.MaxAttempts, err = strconv.Atoi(v); err != nil {
+			return opts, 0, fmt.Errorf("malformed %s_WEBHOOK_MAX_ATTEMPTS: %w", prefix, err)
+		}
+	}
+	if opts.Backoff, err = durationFromEnv(prefix+"_WEBHOOK_BACKOFF", DefaultBackoff); err != nil {
+		return
+	}
+	if opts.MaxBackoff, err = durationFromEnv(prefix+"_WEBHOOK_MAX_BACKOFF", DefaultMaxBackoff); err != nil {
+		return
+	}
+	if opts.Timeout, err = durationFromEnv(prefix+"_WEBHOOK_TIMEOUT", DefaultTimeout); err != nil {
+		return
+	}
+	if opts.AllowedNetworks, err = ParseNetworks(os.Getenv(prefix + "_WEBHOOK_ALLOWED_NETWORKS")); err != nil {
+		return opts, 0, fmt.Errorf("malformed %s_WEBHOOK_ALLOWED_NETWORKS: %w", prefix, err)
+	}
+	interval, err = durationFromEnv(prefix+"_WEBHOOK_INTERVAL", DefaultInterval)
+	return
+}
+
+func durationFromEnv(name string, def time.Duration) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("malformed %s: %w", name, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%s must be positive", name)
+	}
+	return d, nil
+}
@@ -0,0 +1,182 @@
+package inmemory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+)
+
+// Store represents in-memory store of webhook subscriptions and deliveries
+type Store struct {
+	subs       []webhook.Subscription
+	deliveries []webhook.Delivery
+	lastSubID  uint64
+	lastDelID  uint64
+	lock       sync.Mutex
+}
+
+// New creates new in-memory store of webhook subscriptions and deliveries
+func New() *Store {
+	return &Store{}
+}
+
+// CreateSubscription stores subscription assigning next ID to it
+func (s *Store) CreateSubscription(ctx context.Context, sub webhook.Subscription) (webhook.Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.lastSubID++
+	sub.ID = s.lastSubID
+	s.subs = append(s.subs, sub)
+	return sub, nil
+}
+
+// GetSubscription reads subscription by ID
+func (s *Store) GetSubscription(ctx context.Context, id uint64) (webhook.Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, sub := range s.subs {
+		if sub.ID == id {
+			return sub, nil
+		}
+	}
+	return webhook.Subscription{}, &commonerrors.NotFound{What: fmt.Sprintf("Subscription with ID %d", id)}
+}
+
+// ListSubscriptions reads subscriptions of owner in order they were created
+func (s *Store) ListSubscriptions(ctx context.Context, owner string) ([]webhook.Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := make([]webhook.Subscription, 0)
+	for _, sub := range s.subs {
+		if sub.Owner == owner {
+			res = append(res, sub)
+		}
+	}
+	return res, nil
+}
+
+// Subscribed reads subscriptions to event of given type
+func (s *Store) Subscribed(ctx context.Context, event string) ([]webhook.Subscription, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var res []webhook.Subscription
+	for _, sub := range s.subs {
+		for _, e := range sub.Events {
+			if e == event {
+				res = append(res, sub)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// DeleteSubscription deletes subscription with its deliveries
+func (s *Store) DeleteSubscription(ctx context.Context, id uint64) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	subs := s.subs[:0]
+	for _, sub := range s.subs {
+		if sub.ID != id {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) == len(s.subs) {
+		return &commonerrors.NotFound{What: fmt.Sprintf("Subscription with ID %d", id)}
+	}
+	s.subs = subs
+	deliveries := s.deliveries[:0]
+	for _, d := range s.deliveries {
+		if d.SubscriptionID != id {
+			deliveries = append(deliveries, d)
+		}
+	}
+	s.deliveries = deliveries
+	return nil
+}
+
+// CreateDeliveries stores deliveries assigning next IDs to them
+func (s *Store) CreateDeliveries(ctx context.Context, ds []webhook.Delivery) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, d := range ds {
+		s.lastDelID++
+		d.ID = s.lastDelID
+		s.deliveries = append(s.deliveries, d)
+	}
+	return nil
+}
+
+// Claim reads up to count pending deliveries due at `now`, the oldest first, and postpones their next attempt to `until`
+func (s *Store) Claim(ctx context.Context, now, until time.Time, count uint) ([]webhook.Delivery, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var res []webhook.Delivery
+	for i := range s.deliveries {
+		d := &s.deliveries[i]
+		if uint(len(res)) == count {
+			break
+		}
+		if d.Status != webhook.Pending || d.NextAttemptAt.After(now) {
+			continue
+		}
+		d.NextAttemptAt = until
+		res = append(res, copyDelivery(*d))
+	}
+	return res, nil
+}
+
+// SaveAttempt appends attempt to delivery and saves its status and next attempt time
+func (s *Store) SaveAttempt(ctx context.Context, d webhook.Delivery, a webhook.Attempt) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for i := range s.deliveries {
+		stored := &s.deliveries[i]
+		if stored.ID == d.ID {
+			stored.Attempts = append(stored.Attempts, a)
+			stored.Status = d.Status
+			stored.NextAttemptAt = d.NextAttemptAt
+			return nil
+		}
+	}
+	return &commonerrors.NotFound{What: fmt.Sprintf("Delivery with ID %d", d.ID)}
+}
+
+// ListDeliveries reads count deliveries of subscription from some number, the latest first, all of them if count is 0
+func (s *Store) ListDeliveries(ctx context.Context, subscriptionID uint64, from, count uint) ([]webhook.Delivery, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	res := make([]webhook.Delivery, 0)
+	var skipped uint
+	for i := len(s.deliveries) - 1; i >= 0 && (count == 0 || uint(len(res)) < count); i-- {
+		d := s.deliveries[i]
+		if d.SubscriptionID != subscriptionID {
+			continue
+		}
+		if skipped < from {
+			skipped++
+			continue
+		}
+		res = append(res, copyDelivery(d))
+	}
+	return res, nil
+}
+
+// copyDelivery copies attempts of delivery, so appending to them does not change stored ones
+func copyDelivery(d webhook.Delivery) webhook.Delivery {
+	d.Attempts = append([]webhook.Attempt(nil), d.Attempts...)
+	return d
+}
@@ -0,0 +1,50 @@
+package inmemory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook/inmemory"
+)
+
+func TestClaim(t *testing.T) {
+	ctx := context.Background()
+	s := inmemory.New()
+	now := time.Now()
+	sub, _ := s.CreateSubscription(ctx, webhook.Subscription{Owner: "alice", URL: "http://localhost", Events: []string{"done"}})
+	s.CreateDeliveries(ctx, []webhook.Delivery{
+		{SubscriptionID: sub.ID, Event: "done", Status: webhook.Pending, NextAttemptAt: now},
+		{SubscriptionID: sub.ID, Event: "done", Status: webhook.Pending, NextAttemptAt: now.Add(time.Minute)},
+		{SubscriptionID: sub.ID, Event: "done", Status: webhook.Delivered, NextAttemptAt: now},
+	})
+
+	claimed, err := s.Claim(ctx, now, now.Add(time.Second), 10)
+	if err != nil || len(claimed) != 1 || claimed[0].ID != 1 {
+		t.Fatalf("Expected due pending delivery to be claimed, got %+v, %v", claimed, err)
+	}
+	if again, _ := s.Claim(ctx, now, now.Add(time.Second), 10); len(again) != 0 {
+		t.Errorf("Expected claimed delivery not to be claimed again, got %+v", again)
+	}
+
+	d := claimed[0]
+	d.NextAttemptAt = now.Add(time.Hour)
+	if err = s.SaveAttempt(ctx, d, webhook.Attempt{StatusCode: 500}); err != nil {
+		t.Fatalf("Got error saving attempt: %s", err)
+	}
+	ds, _ := s.ListDeliveries(ctx, sub.ID, 0, 0)
+	if len(ds) != 3 || ds[2].ID != 1 || len(ds[2].Attempts) != 1 || !ds[2].NextAttemptAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected attempt to be saved, got %+v", ds)
+	}
+	if ds, _ = s.ListDeliveries(ctx, sub.ID, 1, 1); len(ds) != 1 || ds[0].ID != 2 {
+		t.Errorf("Expected paginated deliveries, got %+v", ds)
+	}
+
+	if err = s.DeleteSubscription(ctx, sub.ID); err != nil {
+		t.Fatalf("Got error deleting subscription: %s", err)
+	}
+	if ds, _ = s.ListDeliveries(ctx, sub.ID, 0, 0); len(ds) != 0 {
+		t.Errorf("Expected deliveries to be deleted with subscription, got %+v", ds)
+	}
+}
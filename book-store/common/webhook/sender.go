@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxErrorBody limits size of response body of failed delivery kept in attempt
+const maxErrorBody = 1024
+
+// sender makes delivery requests
+type sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+// newSender creates sender connecting only to public addresses and addresses in allowed networks.
+// Proxies are not used, as addresses could not be checked then.
+func newSender(timeout time.Duration, now func() time.Time, allowed []*net.IPNet) *sender {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout: timeout,
+		Control: dialControl(allowed),
+	}).DialContext
+	return &sender{&http.Client{Timeout: timeout, Transport: transport}, now}
+}
+
+// send POSTs payload of delivery to URL of subscription, returned attempt has status code or error of request
+func (s *sender) send(ctx context.Context, sub Subscription, d Delivery) (a Attempt) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		a.Error = err.Error()
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(d.ID, 10))
+	// every attempt is signed anew, so receivers may reject requests signed long ago as replays
+	timestamp := s.now().Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, timestamp, d.Payload))
+	res, err := s.client.Do(req)
+	if err != nil {
+		a.Error = err.Error()
+		return
+	}
+	defer res.Body.Close()
+	a.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+		a.Error = string(body)
+	}
+	// body is drained, so connection is reused
+	io.Copy(ioutil.Discard, res.Body)
+	return
+}
@@ -0,0 +1,252 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+)
+
+// Store provides access to relational DB storage of webhook subscriptions and deliveries
+type Store struct {
+	db     *sql.DB
+	schema string
+}
+
+// New creates a new instance of Store keeping subscriptions and deliveries in given schema
+func New(db *sql.DB, schema string) *Store {
+	return &Store{db, schema}
+}
+
+// CreateTableStmt of subscriptions and deliveries
+func (s *Store) CreateTableStmt() string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s.webhook_subscriptions(
+  id bigserial PRIMARY KEY,
+  owner varchar NOT NULL DEFAULT '',
+  url varchar NOT NULL,
+  events jsonb NOT NULL,
+  secret varchar NOT NULL,
+  created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_owner_idx ON %[1]s.webhook_subscriptions(owner);
+CREATE TABLE IF NOT EXISTS %[1]s.webhook_deliveries(
+  id bigserial PRIMARY KEY,
+  subscription_id bigint NOT NULL REFERENCES %[1]s.webhook_subscriptions(id) ON DELETE CASCADE,
+  event varchar NOT NULL,
+  payload bytea NOT NULL,
+  status varchar NOT NULL,
+  attempts jsonb NOT NULL DEFAULT '[]',
+  next_attempt_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON %[1]s.webhook_deliveries(subscription_id, id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON %[1]s.webhook_deliveries(next_attempt_at) WHERE status = 'pending';`, s.schema)
+}
+
+// CreateSubscription stores subscription assigning next ID to it
+func (s *Store) CreateSubscription(ctx context.Context, sub webhook.Subscription) (webhook.Subscription, error) {
+	events, err := json.Marshal(sub.Events)
+	if err != nil {
+		return sub, err
+	}
+	err = s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`INSERT INTO %s.webhook_subscriptions (owner, url, events, secret, created_at)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id;`, s.schema),
+		sub.Owner, sub.URL, string(events), sub.Secret, sub.CreatedAt,
+	).Scan(&sub.ID)
+	return sub, err
+}
+
+// GetSubscription reads subscription by ID
+func (s *Store) GetSubscription(ctx context.Context, id uint64) (webhook.Subscription, error) {
+	sub, err := scanSubscription(s.db.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`SELECT id, owner, url, events, secret, created_at FROM %s.webhook_subscriptions WHERE id=$1;`, s.schema),
+		id,
+	))
+	if err == sql.ErrNoRows {
+		return sub, &commonerrors.NotFound{What: fmt.Sprintf("Subscription with ID %d", id)}
+	}
+	return sub, err
+}
+
+// ListSubscriptions reads subscriptions of owner in order they were created
+func (s *Store) ListSubscriptions(ctx context.Context, owner string) ([]webhook.Subscription, error) {
+	return s.querySubscriptions(
+		ctx,
+		fmt.Sprintf(`SELECT id, owner, url, events, secret, created_at FROM %s.webhook_subscriptions WHERE owner=$1 ORDER BY id;`, s.schema),
+		owner,
+	)
+}
+
+// Subscribed reads subscriptions to event of given type
+func (s *Store) Subscribed(ctx context.Context, event string) ([]webhook.Subscription, error) {
+	return s.querySubscriptions(
+		ctx,
+		fmt.Sprintf(`SELECT id, owner, url, events, secret, created_at FROM %s.webhook_subscriptions WHERE events ? $1 ORDER BY id;`, s.schema),
+		event,
+	)
+}
+
+func (s *Store) querySubscriptions(ctx context.Context, query string, args ...interface{}) ([]webhook.Subscription, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make([]webhook.Subscription, 0)
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, sub)
+	}
+	return res, rows.Err()
+}
+
+// DeleteSubscription deletes subscription, its deliveries are deleted by cascade
+func (s *Store) DeleteSubscription(ctx context.Context, id uint64) error {
+	res, err := s.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s.webhook_subscriptions WHERE id=$1;", s.schema), id)
+	if err != nil {
+		return err
+	}
+	if count, err := res.RowsAffected(); err != nil || count == 0 {
+		if err == nil {
+			err = &commonerrors.NotFound{What: fmt.Sprintf("Subscription with ID %d", id)}
+		}
+		return err
+	}
+	return nil
+}
+
+// CreateDeliveries stores deliveries assigning next IDs to them
+func (s *Store) CreateDeliveries(ctx context.Context, ds []webhook.Delivery) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, d := range ds {
+		_, err = tx.ExecContext(
+			ctx,
+			fmt.Sprintf(`INSERT INTO %s.webhook_deliveries (subscription_id, event, payload, status, next_attempt_at, created_at)
+				VALUES ($1, $2, $3, $4, $5, $6);`, s.schema),
+			d.SubscriptionID, d.Event, d.Payload, string(d.Status), d.NextAttemptAt, d.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Claim reads up to count pending deliveries due at `now`, the oldest first, and postpones their next attempt to `until`.
+// Deliveries claimed by other instances at the same time are skipped.
+func (s *Store) Claim(ctx context.Context, now, until time.Time, count uint) ([]webhook.Delivery, error) {
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %[1]s.webhook_deliveries SET next_attempt_at=$2
+			WHERE id IN (
+				SELECT id FROM %[1]s.webhook_deliveries
+				WHERE status='pending' AND next_attempt_at<=$1
+				ORDER BY id
+				LIMIT $3
+				FOR UPDATE SKIP LOCKED
+			)
+			RETURNING id, subscription_id, event, payload, status, attempts, next_attempt_at, created_at;`, s.schema),
+		now, until, count,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var res []webhook.Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+// SaveAttempt appends attempt to delivery and saves its status and next attempt time
+func (s *Store) SaveAttempt(ctx context.Context, d webhook.Delivery, a webhook.Attempt) error {
+	attempt, err := json.Marshal([]webhook.Attempt{a})
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %s.webhook_deliveries
+			SET attempts = attempts || $2::jsonb, status=$3, next_attempt_at=$4
+			WHERE id=$1;`, s.schema),
+		d.ID, string(attempt), string(d.Status), d.NextAttemptAt,
+	)
+	return err
+}
+
+// ListDeliveries reads count deliveries of subscription from some number, the latest first, all of them if count is 0
+func (s *Store) ListDeliveries(ctx context.Context, subscriptionID uint64, from, count uint) ([]webhook.Delivery, error) {
+	var limit interface{}
+	if count != 0 {
+		limit = count
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		fmt.Sprintf(`SELECT id, subscription_id, event, payload, status, attempts, next_attempt_at, created_at
+			FROM %s.webhook_deliveries
+			WHERE subscription_id=$1
+			ORDER BY id DESC
+			LIMIT $2 OFFSET $3;`, s.schema),
+		subscriptionID, limit, from,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	res := make([]webhook.Delivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, d)
+	}
+	return res, rows.Err()
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSubscription(row scanner) (webhook.Subscription, error) {
+	var sub webhook.Subscription
+	var events []byte
+	if err := row.Scan(&sub.ID, &sub.Owner, &sub.URL, &events, &sub.Secret, &sub.CreatedAt); err != nil {
+		return sub, err
+	}
+	err := json.Unmarshal(events, &sub.Events)
+	return sub, err
+}
+
+func scanDelivery(row scanner) (webhook.Delivery, error) {
+	var d webhook.Delivery
+	var status string
+	var attempts []byte
+	err := row.Scan(&d.ID, &d.SubscriptionID, &d.Event, &d.Payload, &status, &attempts, &d.NextAttemptAt, &d.CreatedAt)
+	if err != nil {
+		return d, err
+	}
+	d.Status = webhook.Status(status)
+	err = json.Unmarshal(attempts, &d.Attempts)
+	return d, err
+}
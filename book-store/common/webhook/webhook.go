@@ -0,0 +1,413 @@
+// Package webhook notifies other services about events: event is POSTed as JSON to URL of every subscription to it
+// signed with secret of the subscription, failed deliveries are retried with backoff and every attempt is recorded
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+)
+
+// Headers of delivery requests
+const (
+	// SignatureHeader carries "sha256=" followed by hex encoded HMAC-SHA256 keyed with secret of subscription
+	// of TimestampHeader value, "." and request body
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader carries Unix time request was signed at
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// SignatureTolerance is how far from current time timestamp of request may be for Verify to accept it.
+// Requests signed earlier are rejected as replays, so receivers do not have to remember all requests they got.
+const SignatureTolerance = 5 * time.Minute
+
+// Status of Delivery
+type Status string
+
+// Possible Statuses
+const (
+	// Pending delivery is waiting for the next attempt
+	Pending   Status = "pending"
+	Delivered Status = "delivered"
+	// Failed delivery is not attempted anymore
+	Failed Status = "failed"
+)
+
+// Subscription of URL to events
+type Subscription struct {
+	ID uint64
+	// Owner is user who registered subscription
+	Owner  string
+	URL    string
+	Events []string
+	// Secret signs delivered payloads
+	Secret    string
+	CreatedAt time.Time
+}
+
+// Delivery of event to subscription
+type Delivery struct {
+	ID             uint64
+	SubscriptionID uint64
+	Event          string
+	Payload        []byte
+	Status         Status
+	// Attempts made so far, in order they were made
+	Attempts      []Attempt
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+}
+
+// Attempt to deliver event
+type Attempt struct {
+	// StatusCode of response, zero if request failed
+	StatusCode int
+	// Error of request or response body of failed one
+	Error     string
+	Duration  time.Duration
+	CreatedAt time.Time
+}
+
+// Event to notify subscribers about
+type Event struct {
+	Type string
+	// Data is sent as `data` field of payload
+	Data interface{}
+	// Recipients are owners of notified subscriptions, subscriptions of all owners are notified if empty
+	Recipients []string
+	CreatedAt  time.Time
+}
+
+// Store keeps subscriptions and their deliveries
+type Store interface {
+	// CreateSubscription stores subscription assigning next ID to it
+	CreateSubscription(ctx context.Context, s Subscription) (Subscription, error)
+	// GetSubscription reads subscription by ID
+	GetSubscription(ctx context.Context, id uint64) (Subscription, error)
+	// ListSubscriptions reads subscriptions of owner in order they were created
+	ListSubscriptions(ctx context.Context, owner string) ([]Subscription, error)
+	// Subscribed reads subscriptions to event of given type
+	Subscribed(ctx context.Context, event string) ([]Subscription, error)
+	// DeleteSubscription deletes subscription with its deliveries
+	DeleteSubscription(ctx context.Context, id uint64) error
+	// CreateDeliveries stores deliveries assigning next IDs to them
+	CreateDeliveries(ctx context.Context, ds []Delivery) error
+	// Claim reads up to count pending deliveries due at `now`, the oldest first, and postpones their next attempt
+	// to `until`, so they are not attempted twice at the same time
+	Claim(ctx context.Context, now, until time.Time, count uint) ([]Delivery, error)
+	// SaveAttempt appends attempt to delivery and saves its status and next attempt time
+	SaveAttempt(ctx context.Context, d Delivery, a Attempt) error
+	// ListDeliveries reads count deliveries of subscription with their attempts from some number,
+	// the latest first, all of them if count is 0
+	ListDeliveries(ctx context.Context, subscriptionID uint64, from, count uint) ([]Delivery, error)
+}
+
+// Options of Dispatcher
+type Options struct {
+	// Events subscriptions may be made to
+	Events []string
+	// MaxAttempts to deliver event, DefaultMaxAttempts is used if not set
+	MaxAttempts int
+	// Backoff is delay before the second attempt, it doubles with every next one up to MaxBackoff.
+	// DefaultBackoff and DefaultMaxBackoff are used if not set.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout of delivery request, DefaultTimeout is used if not set
+	Timeout time.Duration
+	// Now returns current time, time.Now is used if not set
+	Now func() time.Time
+	// AllowedNetworks may be delivered to even if they are private, e.g. to reach receivers in the same network.
+	// Otherwise URLs resolving to loopback, link-local, private or unspecified addresses are refused.
+	AllowedNetworks []*net.IPNet
+}
+
+// Defaults of Options
+const (
+	DefaultMaxAttempts = 8
+	DefaultBackoff     = 30 * time.Second
+	DefaultMaxBackoff  = time.Hour
+	DefaultTimeout     = 10 * time.Second
+)
+
+// claimBatch is number of deliveries attempted at once
+const claimBatch = 100
+
+// Dispatcher registers subscriptions and delivers events to them
+type Dispatcher struct {
+	store  Store
+	opts   Options
+	sender *sender
+	// wake is signalled when new deliveries are created
+	wake chan struct{}
+}
+
+// New creates Dispatcher keeping subscriptions and deliveries in given store
+func New(s Store, opts Options) *Dispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = DefaultBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultMaxBackoff
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Dispatcher{s, opts, newSender(opts.Timeout, opts.Now, opts.AllowedNetworks), make(chan struct{}, 1)}
+}
+
+// Subscribe validates and stores subscription. Random secret is generated if it is not set.
+// Host of URL must resolve to public addresses or addresses in allowed networks,
+// it is checked again on every delivery as it may resolve to other addresses later.
+func (d *Dispatcher) Subscribe(ctx context.Context, s Subscription) (Subscription, error) {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return s, &commonerrors.InvalidInput{Reason: "url must be absolute http or https URL"}
+	}
+	if len(s.Events) == 0 {
+		return s, &commonerrors.InvalidInput{Reason: "at least one event is required"}
+	}
+	for _, e := range s.Events {
+		if !contains(d.opts.Events, e) {
+			return s, &commonerrors.InvalidInput{Reason: fmt.Sprintf(`unknown event "%s"`, e)}
+		}
+	}
+	if err = checkHost(ctx, u.Hostname(), d.opts.AllowedNetworks); err != nil {
+		return s, &commonerrors.InvalidInput{Reason: fmt.Sprintf("url must point to public address: %s", err)}
+	}
+	if s.Secret == "" {
+		if s.Secret, err = randomSecret(); err != nil {
+			return s, err
+		}
+	}
+	s.CreatedAt = d.opts.Now()
+	return d.store.CreateSubscription(ctx, s)
+}
+
+// Subscriptions reads subscriptions of owner
+func (d *Dispatcher) Subscriptions(ctx context.Context, owner string) ([]Subscription, error) {
+	return d.store.ListSubscriptions(ctx, owner)
+}
+
+// Unsubscribe deletes subscription of owner with its deliveries
+func (d *Dispatcher) Unsubscribe(ctx context.Context, owner string, id uint64) error {
+	if _, err := d.subscription(ctx, owner, id); err != nil {
+		return err
+	}
+	return d.store.DeleteSubscription(ctx, id)
+}
+
+// Deliveries reads count deliveries of subscription of owner from some number, the latest first, 10 by default
+func (d *Dispatcher) Deliveries(ctx context.Context, owner string, id uint64, from, count uint) ([]Delivery, error) {
+	if _, err := d.subscription(ctx, owner, id); err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		count = 10
+	}
+	return d.store.ListDeliveries(ctx, id, from, count)
+}
+
+// subscription reads subscription of owner, subscriptions of others are not found
+func (d *Dispatcher) subscription(ctx context.Context, owner string, id uint64) (Subscription, error) {
+	s, err := d.store.GetSubscription(ctx, id)
+	if err == nil && s.Owner != owner {
+		return s, &commonerrors.NotFound{What: fmt.Sprintf("Subscription with ID %d", id)}
+	}
+	return s, err
+}
+
+// Publish creates deliveries of events to subscriptions and wakes up delivery worker.
+// Events have already happened, so failure to create deliveries is logged instead of being returned.
+// Publishing with nil Dispatcher does nothing, so webhooks are optional.
+func (d *Dispatcher) Publish(ctx context.Context, events ...Event) {
+	if d == nil {
+		return
+	}
+	var deliveries []Delivery
+	now := d.opts.Now()
+	for _, e := range events {
+		subs, err := d.store.Subscribed(ctx, e.Type)
+		if err != nil {
+			log.Printf("Failed to publish %s event: %s", e.Type, err)
+			continue
+		}
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = now
+		}
+		payload, err := json.Marshal(payloadOf(e))
+		if err != nil {
+			log.Printf("Failed to publish %s event: %s", e.Type, err)
+			continue
+		}
+		for _, s := range subs {
+			if len(e.Recipients) != 0 && !contains(e.Recipients, s.Owner) {
+				continue
+			}
+			deliveries = append(deliveries, Delivery{
+				SubscriptionID: s.ID,
+				Event:          e.Type,
+				Payload:        payload,
+				Status:         Pending,
+				NextAttemptAt:  now,
+				CreatedAt:      now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return
+	}
+	if err := d.store.CreateDeliveries(ctx, deliveries); err != nil {
+		log.Printf("Failed to create %d deliveries: %s", len(deliveries), err)
+		return
+	}
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run attempts due deliveries every `interval` and right after events are published until context is done
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.DeliverDue(ctx); err != nil {
+			log.Printf("Failed to deliver webhooks: %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue attempts all deliveries which are due now
+func (d *Dispatcher) DeliverDue(ctx context.Context) error {
+	for {
+		now := d.opts.Now()
+		// claimed deliveries are attempted again if this attempt is not saved in time, e.g. on restart
+		ds, err := d.store.Claim(ctx, now, now.Add(2*d.opts.Timeout), claimBatch)
+		if err != nil || len(ds) == 0 {
+			return err
+		}
+		var wg sync.WaitGroup
+		for _, dl := range ds {
+			wg.Add(1)
+			go func(dl Delivery) {
+				defer wg.Done()
+				if err := d.attempt(ctx, dl); err != nil {
+					log.Printf("Failed to save attempt of delivery %d: %s", dl.ID, err)
+				}
+			}(dl)
+		}
+		wg.Wait()
+		if len(ds) < claimBatch {
+			return nil
+		}
+	}
+}
+
+// attempt sends delivery to its subscription and saves result
+func (d *Dispatcher) attempt(ctx context.Context, dl Delivery) error {
+	s, err := d.store.GetSubscription(ctx, dl.SubscriptionID)
+	if err != nil {
+		return err
+	}
+	started := d.opts.Now()
+	a := d.sender.send(ctx, s, dl)
+	a.CreatedAt = started
+	a.Duration = d.opts.Now().Sub(started)
+	dl.Attempts = append(dl.Attempts, a)
+	switch {
+	case a.StatusCode >= 200 && a.StatusCode < 300:
+		dl.Status = Delivered
+	case len(dl.Attempts) >= d.opts.MaxAttempts:
+		dl.Status = Failed
+	default:
+		dl.NextAttemptAt = started.Add(d.backoff(len(dl.Attempts)))
+	}
+	return d.store.SaveAttempt(ctx, dl, a)
+}
+
+// backoff returns delay after given number of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.opts.Backoff
+	for i := 1; i < attempts && delay < d.opts.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.opts.MaxBackoff {
+		delay = d.opts.MaxBackoff
+	}
+	return delay
+}
+
+// Sign returns value of SignatureHeader for body sent at given Unix time signed with secret
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks that signature of body and timestamp is made with secret and that timestamp is within
+// SignatureTolerance of now, receivers may use it to check requests with values of TimestampHeader and SignatureHeader
+func Verify(secret string, body []byte, timestamp, signature string, now time.Time) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > SignatureTolerance || age < -SignatureTolerance {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, ts, body)), []byte(signature))
+}
+
+type payload struct {
+	Event string `json:"event"`
+	// Time is Unix time of event
+	Time int64       `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+func payloadOf(e Event) payload {
+	return payload{e.Type, e.CreatedAt.Unix(), e.Data}
+}
+
+func randomSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package webhook_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook/inmemory"
+)
+
+// loopback is allowed for deliveries to test servers
+var loopback, _ = webhook.ParseNetworks("127.0.0.0/8,::1/128")
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// receiver records requests and answers them with its status
+type receiver struct {
+	lock     sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.requests = append(r.requests, req)
+	r.bodies = append(r.bodies, body)
+	w.WriteHeader(r.status)
+	w.Write([]byte("answer"))
+}
+
+func TestDelivery(t *testing.T) {
+	ctx := context.Background()
+	rec := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	clock := &fakeClock{time.Unix(1600000000, 0)}
+	d := webhook.New(inmemory.New(), webhook.Options{Events: []string{"created", "done"}, Now: clock.Now, AllowedNetworks: loopback})
+
+	sub, err := d.Subscribe(ctx, webhook.Subscription{Owner: "alice", URL: server.URL, Events: []string{"done"}})
+	if err != nil {
+		t.Fatalf("Got error subscribing: %s", err)
+	}
+	if len(sub.Secret) != 64 {
+		t.Errorf("Expected secret to be generated, got %q", sub.Secret)
+	}
+	d.Subscribe(ctx, webhook.Subscription{Owner: "bob", URL: server.URL, Events: []string{"done"}})
+
+	d.Publish(ctx,
+		webhook.Event{Type: "created", Data: 1},
+		webhook.Event{Type: "done", Data: map[string]int{"taskID": 1}, Recipients: []string{"alice"}},
+	)
+	if err = d.DeliverDue(ctx); err != nil {
+		t.Fatalf("Got error delivering: %s", err)
+	}
+	if len(rec.requests) != 1 {
+		t.Fatalf("Expected single delivery to recipient subscribed to event, got %d", len(rec.requests))
+	}
+	req, body := rec.requests[0], rec.bodies[0]
+	if string(body) != `{"event":"done","time":1600000000,"data":{"taskID":1}}` {
+		t.Errorf("Wrong payload %s", body)
+	}
+	if req.Header.Get(webhook.EventHeader) != "done" || req.Header.Get(webhook.DeliveryHeader) != "1" {
+		t.Errorf("Wrong headers %v", req.Header)
+	}
+	timestamp, signature := req.Header.Get(webhook.TimestampHeader), req.Header.Get(webhook.SignatureHeader)
+	if timestamp != "1600000000" {
+		t.Errorf("Expected request to have time it was signed at, got %q", timestamp)
+	}
+	if !webhook.Verify(sub.Secret, body, timestamp, signature, clock.now.Add(time.Minute)) {
+		t.Errorf("Expected payload to be signed with secret, got %s", signature)
+	}
+	if webhook.Verify("other", body, timestamp, signature, clock.now) {
+		t.Error("Expected signature not to be verified with other secret")
+	}
+	if webhook.Verify(sub.Secret, body, "1600000001", signature, clock.now) {
+		t.Error("Expected signature not to be verified with other timestamp")
+	}
+	if webhook.Verify(sub.Secret, body, timestamp, signature, clock.now.Add(webhook.SignatureTolerance+time.Second)) {
+		t.Error("Expected signature not to be verified after tolerance")
+	}
+
+	ds, _ := d.Deliveries(ctx, "alice", sub.ID, 0, 0)
+	if len(ds) != 1 || ds[0].Status != webhook.Delivered || len(ds[0].Attempts) != 1 || ds[0].Attempts[0].StatusCode != 204 {
+		t.Errorf("Expected delivered delivery with attempt, got %+v", ds)
+	}
+	if _, err = d.Deliveries(ctx, "bob", sub.ID, 0, 0); !isNotFound(err) {
+		t.Errorf("Expected deliveries of other user to be not found, got %v", err)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	ctx := context.Background()
+	rec := &receiver{status: http.StatusInternalServerError}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	clock := &fakeClock{time.Unix(1600000000, 0)}
+	d := webhook.New(inmemory.New(), webhook.Options{
+		Events:          []string{"done"},
+		MaxAttempts:     4,
+		Backoff:         time.Minute,
+		MaxBackoff:      3 * time.Minute,
+		Now:             clock.Now,
+		AllowedNetworks: loopback,
+	})
+	sub, _ := d.Subscribe(ctx, webhook.Subscription{URL: server.URL, Events: []string{"done"}, Secret: "secret"})
+	d.Publish(ctx, webhook.Event{Type: "done"})
+
+	// delays after attempts are 1, 2 and 3 (capped) minutes
+	for i, delay := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute} {
+		d.DeliverDue(ctx)
+		ds, _ := d.Deliveries(ctx, "", sub.ID, 0, 0)
+		if ds[0].Status != webhook.Pending || len(ds[0].Attempts) != i+1 || !ds[0].NextAttemptAt.Equal(clock.now.Add(delay)) {
+			t.Fatalf("Expected attempt %d to be retried after %s, got %+v", i+1, delay, ds[0])
+		}
+		if ds[0].Attempts[i].StatusCode != 500 || ds[0].Attempts[i].Error != "answer" {
+			t.Errorf("Expected failed attempt to be recorded, got %+v", ds[0].Attempts[i])
+		}
+		d.DeliverDue(ctx)
+		if len(rec.requests) != i+1 {
+			t.Errorf("Expected no attempts before backoff, got %d", len(rec.requests))
+		}
+		clock.now = clock.now.Add(delay)
+	}
+	d.DeliverDue(ctx)
+	ds, _ := d.Deliveries(ctx, "", sub.ID, 0, 0)
+	if ds[0].Status != webhook.Failed || len(ds[0].Attempts) != 4 {
+		t.Errorf("Expected delivery to fail after max attempts, got %+v", ds[0])
+	}
+	clock.now = clock.now.Add(time.Hour)
+	if d.DeliverDue(ctx); len(rec.requests) != 4 {
+		t.Errorf("Expected failed delivery not to be attempted, got %d requests", len(rec.requests))
+	}
+
+	if err := d.Unsubscribe(ctx, "", sub.ID); err != nil {
+		t.Fatalf("Got error unsubscribing: %s", err)
+	}
+	if _, err := d.Deliveries(ctx, "", sub.ID, 0, 0); !isNotFound(err) {
+		t.Errorf("Expected deleted subscription to be not found, got %v", err)
+	}
+}
+
+func TestSubscribeInvalid(t *testing.T) {
+	ctx := context.Background()
+	d := webhook.New(inmemory.New(), webhook.Options{Events: []string{"done"}})
+	subs := []webhook.Subscription{
+		{URL: "ftp://example.com", Events: []string{"done"}},
+		{URL: "/relative", Events: []string{"done"}},
+		{URL: "http://example.com"},
+		{URL: "http://example.com", Events: []string{"unknown"}},
+		{URL: "http://localhost:8080", Events: []string{"done"}},
+		{URL: "http://127.0.0.1", Events: []string{"done"}},
+		{URL: "http://[::1]", Events: []string{"done"}},
+		{URL: "http://169.254.169.254/latest/meta-data", Events: []string{"done"}},
+		{URL: "http://10.1.2.3", Events: []string{"done"}},
+		{URL: "https://192.168.0.1", Events: []string{"done"}},
+		{URL: "http://0.0.0.0", Events: []string{"done"}},
+	}
+	for _, s := range subs {
+		if _, err := d.Subscribe(ctx, s); !isInvalidInput(err) {
+			t.Errorf("Expected %+v to be rejected, got %v", s, err)
+		}
+	}
+}
+
+func TestDeliveryToPrivateAddress(t *testing.T) {
+	ctx := context.Background()
+	rec := &receiver{status: http.StatusNoContent}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+	store := inmemory.New()
+	d := webhook.New(store, webhook.Options{Events: []string{"done"}})
+	if _, err := d.Subscribe(ctx, webhook.Subscription{URL: server.URL, Events: []string{"done"}}); !isInvalidInput(err) {
+		t.Errorf("Expected subscription to loopback address to be rejected, got %v", err)
+	}
+
+	// host of subscription may resolve to private address after it is made
+	sub, _ := store.CreateSubscription(ctx, webhook.Subscription{URL: server.URL, Events: []string{"done"}, Secret: "secret"})
+	d.Publish(ctx, webhook.Event{Type: "done"})
+	d.DeliverDue(ctx)
+	if len(rec.requests) != 0 {
+		t.Errorf("Expected no requests to loopback address, got %d", len(rec.requests))
+	}
+	ds, _ := d.Deliveries(ctx, "", sub.ID, 0, 0)
+	if len(ds) != 1 || len(ds[0].Attempts) != 1 || !strings.Contains(ds[0].Attempts[0].Error, "is not public") {
+		t.Errorf("Expected attempt to fail before connecting, got %+v", ds)
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(*commonerrors.NotFound)
+	return ok
+}
+
+func isInvalidInput(err error) bool {
+	_, ok := err.(*commonerrors.InvalidInput)
+	return ok
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/book"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+// CacheOptions configures cache of catalog books
+type CacheOptions struct {
+	// Size is maximum number of cached books, cache is disabled if it is 0
+	Size int
+	// TTL is time during which cached book is considered fresh
+	TTL time.Duration
+	// StaleTTL is time after TTL during which stale book is still returned
+	// while it is revalidated in background, stale-while-revalidate is disabled if it is 0
+	StaleTTL time.Duration
+	// FetchTimeout limits fetches not bound to context of single caller: batched fetches and revalidations,
+	// DefaultFetchTimeout is used if it is 0
+	FetchTimeout time.Duration
+}
+
+// DefaultFetchTimeout is enough for client to make all retries of fetch with default options
+const DefaultFetchTimeout = 10 * time.Second
+
+// DefaultCacheOptions are reasonable cache options
+var DefaultCacheOptions = CacheOptions{
+	Size:         1000,
+	TTL:          time.Minute,
+	StaleTTL:     5 * time.Minute,
+	FetchTimeout: DefaultFetchTimeout,
+}
+
+// CacheStats contains cache metrics
+type CacheStats struct {
+	Hits      uint64
+	StaleHits uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+type entryState int
+
+const (
+	missing entryState = iota
+	fresh
+	stale
+)
+
+type cacheEntry struct {
+	book         book.Book
+	storedAt     time.Time
+	revalidating bool
+}
+
+// fetch is started fetch of book, its result is cached only if book is not invalidated while it is fetched
+type fetch struct {
+	id  uuid.UUID
+	gen uint64
+}
+
+// fetches of the same book in progress, generation is bumped when book is invalidated
+type pendingFetches struct {
+	gen   uint64
+	count int
+}
+
+// cache is LRU cache of books with expiration
+type cache struct {
+	opts CacheOptions
+	now  func() time.Time
+
+	lock    sync.Mutex
+	entries map[uuid.UUID]*list.Element
+	order   *list.List // front is most recently used
+	fetches map[uuid.UUID]*pendingFetches
+
+	hits, staleHits, misses, evictions uint64
+}
+
+func newCache(opts CacheOptions) *cache {
+	return &cache{
+		opts:    opts,
+		now:     time.Now,
+		entries: make(map[uuid.UUID]*list.Element),
+		order:   list.New(),
+		fetches: make(map[uuid.UUID]*pendingFetches),
+	}
+}
+
+// get returns cached book and its state, stale book is marked as revalidating,
+// so revalidate is true only for first caller that gets it
+func (c *cache) get(id uuid.UUID) (b book.Book, state entryState, revalidate bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	el, found := c.entries[id]
+	if !found {
+		atomic.AddUint64(&c.misses, 1)
+		return b, missing, false
+	}
+	e := el.Value.(*cacheEntry)
+	age := c.now().Sub(e.storedAt)
+	switch {
+	case age < c.opts.TTL:
+		c.order.MoveToFront(el)
+		atomic.AddUint64(&c.hits, 1)
+		return e.book, fresh, false
+	case age < c.opts.TTL+c.opts.StaleTTL:
+		c.order.MoveToFront(el)
+		atomic.AddUint64(&c.staleHits, 1)
+		revalidate = !e.revalidating
+		e.revalidating = true
+		return e.book, stale, revalidate
+	default:
+		c.remove(el)
+		atomic.AddUint64(&c.misses, 1)
+		return b, missing, false
+	}
+}
+
+// startFetch registers fetch of book, it must be finished with finishFetch
+func (c *cache) startFetch(id uuid.UUID) fetch {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	p, found := c.fetches[id]
+	if !found {
+		p = &pendingFetches{}
+		c.fetches[id] = p
+	}
+	p.count++
+	return fetch{id, p.gen}
+}
+
+// finishFetch puts fetched book unless it was invalidated after fetch started,
+// otherwise the book may be older than the change that invalidated it. Nil book means that fetch failed.
+func (c *cache) finishFetch(f fetch, b *book.Book) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	p := c.fetches[f.id]
+	if p.count--; p.count == 0 {
+		delete(c.fetches, f.id)
+	}
+	if b != nil && p.gen == f.gen {
+		c.store(*b)
+	}
+}
+
+func (c *cache) put(b book.Book) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.store(b)
+}
+
+// store puts book, must be called with lock held
+func (c *cache) store(b book.Book) {
+	if c.opts.Size <= 0 {
+		return
+	}
+	if el, found := c.entries[b.ID]; found {
+		el.Value = &cacheEntry{book: b, storedAt: c.now()}
+		c.order.MoveToFront(el)
+		return
+	}
+	c.entries[b.ID] = c.order.PushFront(&cacheEntry{book: b, storedAt: c.now()})
+	for c.order.Len() > c.opts.Size {
+		c.remove(c.order.Back())
+		atomic.AddUint64(&c.evictions, 1)
+	}
+}
+
+// failedRevalidation allows stale book to be revalidated again
+func (c *cache) failedRevalidation(id uuid.UUID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if el, found := c.entries[id]; found {
+		el.Value.(*cacheEntry).revalidating = false
+	}
+}
+
+func (c *cache) invalidate(id uuid.UUID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if el, found := c.entries[id]; found {
+		c.remove(el)
+	}
+	if p, found := c.fetches[id]; found {
+		p.gen++
+	}
+}
+
+// invalidateRelated removes books that have author or category with given id
+func (c *cache) invalidateRelated(id uuid.UUID) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, el := range c.entries {
+		b := el.Value.(*cacheEntry).book
+		related := b.Author.ID == id
+		for _, cat := range b.Categories {
+			related = related || cat.ID == id
+		}
+		if related {
+			c.remove(el)
+		}
+	}
+	// books being fetched are not known yet, so they might be related too
+	c.invalidateFetches()
+}
+
+func (c *cache) invalidateAll() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.entries = make(map[uuid.UUID]*list.Element)
+	c.order.Init()
+	c.invalidateFetches()
+}
+
+// invalidateFetches makes all fetches in progress not cache their results, must be called with lock held
+func (c *cache) invalidateFetches() {
+	for _, p := range c.fetches {
+		p.gen++
+	}
+}
+
+// remove removes element, must be called with lock held
+func (c *cache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).book.ID)
+}
+
+func (c *cache) stats() CacheStats {
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		StaleHits: atomic.LoadUint64(&c.staleHits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+	}
+}
@@ -0,0 +1,213 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/book"
+	"github.com/Vesninovich/go-tasks/book-store/common/catalog"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time {
+	return c.t
+}
+
+func newTestCache(opts CacheOptions) (*cache, *fakeClock) {
+	clock := &fakeClock{t: time.Now()}
+	c := newCache(opts)
+	c.now = clock.now
+	return c, clock
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c, _ := newTestCache(CacheOptions{Size: 2, TTL: time.Minute})
+	b0, b1, b2 := book.Book{ID: uuid.New()}, book.Book{ID: uuid.New()}, book.Book{ID: uuid.New()}
+	c.put(b0)
+	c.put(b1)
+	c.get(b0.ID)
+	c.put(b2)
+	if _, state, _ := c.get(b1.ID); state != missing {
+		t.Error("Expected least recently used book to be evicted")
+	}
+	if _, state, _ := c.get(b0.ID); state != fresh {
+		t.Error("Expected recently used book to stay in cache")
+	}
+	if _, state, _ := c.get(b2.ID); state != fresh {
+		t.Error("Expected last added book to stay in cache")
+	}
+	if stats := c.stats(); stats.Evictions != 1 || stats.Hits != 3 || stats.Misses != 1 {
+		t.Errorf("Got wrong cache stats: %+v", stats)
+	}
+}
+
+func TestCacheExpiration(t *testing.T) {
+	c, clock := newTestCache(CacheOptions{Size: 2, TTL: time.Minute, StaleTTL: time.Minute})
+	b := book.Book{ID: uuid.New()}
+	c.put(b)
+
+	clock.t = clock.t.Add(90 * time.Second)
+	_, state, revalidate := c.get(b.ID)
+	if state != stale || !revalidate {
+		t.Error("Expected to get stale book to revalidate")
+	}
+	_, state, revalidate = c.get(b.ID)
+	if state != stale || revalidate {
+		t.Error("Expected stale book to be revalidated only once")
+	}
+	c.failedRevalidation(b.ID)
+	if _, _, revalidate = c.get(b.ID); !revalidate {
+		t.Error("Expected stale book to be revalidated again after failure")
+	}
+
+	clock.t = clock.t.Add(time.Minute)
+	if _, state, _ = c.get(b.ID); state != missing {
+		t.Error("Expected book to expire")
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c, _ := newTestCache(CacheOptions{})
+	b := book.Book{ID: uuid.New()}
+	c.put(b)
+	if _, state, _ := c.get(b.ID); state != missing {
+		t.Error("Expected disabled cache to store nothing")
+	}
+}
+
+func TestGetBookUsesCache(t *testing.T) {
+	c, ids := newFakeCatalog(2)
+	s := New(c, DefaultCacheOptions)
+	for i := 0; i < 3; i++ {
+		if _, err := s.GetBook(ctx, ids[0]); err != nil {
+			t.Fatalf("Error while getting book: %s", err)
+		}
+	}
+	if c.calls != 1 {
+		t.Errorf("Expected to make 1 call to catalog, made %d", c.calls)
+	}
+	books, err := s.GetBooks(ctx, ids)
+	if err != nil {
+		t.Fatalf("Error while getting books: %s", err)
+	}
+	if len(books) != 2 || c.calls != 2 {
+		t.Errorf("Expected to fetch only uncached book, made %d calls", c.calls)
+	}
+
+	s.Invalidate(ids[0])
+	if _, err := s.GetBook(ctx, ids[0]); err != nil {
+		t.Fatalf("Error while getting book: %s", err)
+	}
+	if c.calls != 3 {
+		t.Errorf("Expected to fetch invalidated book, made %d calls", c.calls)
+	}
+	if stats := s.CacheStats(); stats.Hits != 3 || stats.Misses != 3 {
+		t.Errorf("Got wrong cache stats: %+v", stats)
+	}
+}
+
+func TestGetBookServesStaleWhenCatalogIsUnavailable(t *testing.T) {
+	c, ids := newFakeCatalog(1)
+	s := New(c, CacheOptions{Size: 10, TTL: time.Minute, StaleTTL: time.Hour})
+	clock := &fakeClock{t: time.Now()}
+	s.cache.now = clock.now
+	revalidated := make(chan struct{}, 1)
+	s.loader = newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]book.Book, error) {
+		defer func() { revalidated <- struct{}{} }()
+		return nil, errors.New("catalog is down")
+	}, time.Millisecond, maxBatchSize, time.Second)
+	s.cache.put(book.Book{ID: ids[0], Name: "cached"})
+
+	clock.t = clock.t.Add(2 * time.Minute)
+	b, err := s.GetBook(ctx, ids[0])
+	if err != nil {
+		t.Fatalf("Expected to get stale book, got error: %s", err)
+	}
+	if b.Name != "cached" {
+		t.Error("Got wrong stale book")
+	}
+	<-revalidated
+}
+
+func TestInvalidateDuringFetch(t *testing.T) {
+	c, ids := newFakeCatalog(1)
+	s := New(c, DefaultCacheOptions)
+	started, release := make(chan struct{}), make(chan struct{})
+	fetches := 0
+	s.loader = newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]book.Book, error) {
+		fetches++
+		if fetches == 1 {
+			close(started)
+			<-release
+		}
+		return s.fetchBooks(ctx, ids)
+	}, time.Millisecond, maxBatchSize, time.Second)
+
+	go func() {
+		<-started
+		// book changes while its old version is being fetched
+		s.Invalidate(ids[0])
+		close(release)
+	}()
+	if _, err := s.GetBook(ctx, ids[0]); err != nil {
+		t.Fatalf("Error while getting book: %s", err)
+	}
+	if _, err := s.GetBook(ctx, ids[0]); err != nil {
+		t.Fatalf("Error while getting book: %s", err)
+	}
+	if fetches != 2 {
+		t.Errorf("Expected book fetched before invalidation not to be cached, made %d fetches", fetches)
+	}
+	if _, err := s.GetBook(ctx, ids[0]); err != nil || fetches != 2 {
+		t.Errorf("Expected book fetched after invalidation to be cached, made %d fetches", fetches)
+	}
+	if len(s.cache.fetches) != 0 {
+		t.Errorf("Expected finished fetches to be forgotten, got %d", len(s.cache.fetches))
+	}
+}
+
+func TestWatchInvalidatesCache(t *testing.T) {
+	c, ids := newFakeCatalog(2)
+	s := New(c, DefaultCacheOptions)
+	aID, _ := uuid.FromBytes(c.books[ids[1]].Author.Id)
+	if _, err := s.GetBooks(ctx, ids); err != nil {
+		t.Fatalf("Error while getting books: %s", err)
+	}
+
+	watchRetryDelay = time.Millisecond
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		s.Watch(watchCtx)
+		close(done)
+	}()
+
+	if q := <-c.watches; q.AfterSequence != nil {
+		t.Errorf("Expected to watch only new events at start")
+	}
+	c.events <- &catalog.Event{Sequence: 7, Entity: catalog.Event_BOOK, Type: catalog.Event_UPDATED, Id: ids[0][:]}
+	c.events <- &catalog.Event{Sequence: 8, Entity: catalog.Event_AUTHOR, Type: catalog.Event_UPDATED, Id: aID[:]}
+
+	// break the stream, watch must resume after last received event
+	close(c.events)
+	q := <-c.watches
+	if q.AfterSequence == nil || *q.AfterSequence != 8 {
+		t.Errorf("Expected to resume watching after event 8")
+	}
+	if _, state, _ := s.cache.get(ids[0]); state != missing {
+		t.Error("Expected updated book to be invalidated")
+	}
+	if _, state, _ := s.cache.get(ids[1]); state != missing {
+		t.Error("Expected book of updated author to be invalidated")
+	}
+
+	cancel()
+	<-done
+}
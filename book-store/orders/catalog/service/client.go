@@ -0,0 +1,307 @@
+package service
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/catalog"
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ClientOptions configures resilience of catalog client
+type ClientOptions struct {
+	// CallTimeout is deadline of single attempt of a call
+	CallTimeout time.Duration
+	// MaxRetries is number of retries of idempotent calls after failed attempt
+	MaxRetries int
+	// Backoff is delay before first retry, it is doubled for every next retry up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// BreakerThreshold is number of consecutive failed calls after which circuit is opened
+	BreakerThreshold int
+	// BreakerCooldown is time during which calls fail fast after circuit is opened
+	BreakerCooldown time.Duration
+}
+
+// DefaultClientOptions are reasonable client options
+var DefaultClientOptions = ClientOptions{
+	CallTimeout:      2 * time.Second,
+	MaxRetries:       3,
+	Backoff:          100 * time.Millisecond,
+	MaxBackoff:       time.Second,
+	BreakerThreshold: 5,
+	BreakerCooldown:  10 * time.Second,
+}
+
+// Client is catalog client with per-call deadlines, retries of idempotent calls and circuit breaker.
+// Calls fail with *commonerrors.Unavailable when catalog can not be reached.
+type Client struct {
+	cc      catalog.CatalogClient
+	opts    ClientOptions
+	breaker *breaker
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+// NewClient wraps catalog client
+func NewClient(cc catalog.CatalogClient, opts ClientOptions) *Client {
+	return &Client{
+		cc:      cc,
+		opts:    opts,
+		breaker: newBreaker(opts.BreakerThreshold, opts.BreakerCooldown),
+		sleep:   sleep,
+	}
+}
+
+// GetBooks reads whole stream of books with retries, so returned stream is already received
+func (c *Client) GetBooks(ctx context.Context, in *catalog.BooksQuery, opts ...grpc.CallOption) (catalog.Catalog_GetBooksClient, error) {
+	books, err := c.readBooks(ctx, func(ctx context.Context) (booksReceiver, error) {
+		return c.cc.GetBooks(ctx, in, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &receivedBooks{books: books}, nil
+}
+
+// GetBooksByIDs reads whole stream of books with retries, so returned stream is already received
+func (c *Client) GetBooksByIDs(ctx context.Context, in *catalog.BookIDs, opts ...grpc.CallOption) (catalog.Catalog_GetBooksByIDsClient, error) {
+	books, err := c.readBooks(ctx, func(ctx context.Context) (booksReceiver, error) {
+		return c.cc.GetBooksByIDs(ctx, in, opts...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &receivedBooks{books: books}, nil
+}
+
+// CreateBook is not idempotent, so it is never retried
+func (c *Client) CreateBook(ctx context.Context, in *catalog.BookCreateDTO, opts ...grpc.CallOption) (res *catalog.Book, err error) {
+	err = c.call(ctx, 0, func(ctx context.Context) (err error) {
+		res, err = c.cc.CreateBook(ctx, in, opts...)
+		return
+	})
+	return
+}
+
+// WatchBooks is long-living stream resumed by its consumer, so it is passed through as is
+func (c *Client) WatchBooks(ctx context.Context, in *catalog.WatchQuery, opts ...grpc.CallOption) (catalog.Catalog_WatchBooksClient, error) {
+	return c.cc.WatchBooks(ctx, in, opts...)
+}
+
+// GetDeletedBooks and other admin calls are not used by orders, so they are passed through as is
+func (c *Client) GetDeletedBooks(ctx context.Context, in *catalog.DeletedQuery, opts ...grpc.CallOption) (catalog.Catalog_GetDeletedBooksClient, error) {
+	return c.cc.GetDeletedBooks(ctx, in, opts...)
+}
+
+// GetDeletedAuthors is passed through as is
+func (c *Client) GetDeletedAuthors(ctx context.Context, in *catalog.DeletedQuery, opts ...grpc.CallOption) (catalog.Catalog_GetDeletedAuthorsClient, error) {
+	return c.cc.GetDeletedAuthors(ctx, in, opts...)
+}
+
+// GetDeletedCategories is passed through as is
+func (c *Client) GetDeletedCategories(ctx context.Context, in *catalog.DeletedQuery, opts ...grpc.CallOption) (catalog.Catalog_GetDeletedCategoriesClient, error) {
+	return c.cc.GetDeletedCategories(ctx, in, opts...)
+}
+
+// RestoreBook is passed through as is
+func (c *Client) RestoreBook(ctx context.Context, in *catalog.EntityID, opts ...grpc.CallOption) (*catalog.Book, error) {
+	return c.cc.RestoreBook(ctx, in, opts...)
+}
+
+// RestoreAuthor is passed through as is
+func (c *Client) RestoreAuthor(ctx context.Context, in *catalog.EntityID, opts ...grpc.CallOption) (*catalog.Author, error) {
+	return c.cc.RestoreAuthor(ctx, in, opts...)
+}
+
+// RestoreCategory is passed through as is
+func (c *Client) RestoreCategory(ctx context.Context, in *catalog.EntityID, opts ...grpc.CallOption) (*catalog.Category, error) {
+	return c.cc.RestoreCategory(ctx, in, opts...)
+}
+
+// Purge is passed through as is
+func (c *Client) Purge(ctx context.Context, in *catalog.PurgeRequest, opts ...grpc.CallOption) (*catalog.PurgeResult, error) {
+	return c.cc.Purge(ctx, in, opts...)
+}
+
+type booksReceiver interface {
+	Recv() (*catalog.Book, error)
+}
+
+func (c *Client) readBooks(ctx context.Context, open func(ctx context.Context) (booksReceiver, error)) (books []*catalog.Book, err error) {
+	err = c.call(ctx, c.opts.MaxRetries, func(ctx context.Context) error {
+		books = make([]*catalog.Book, 0)
+		stream, err := open(ctx)
+		if err != nil {
+			return err
+		}
+		for {
+			b, err := stream.Recv()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			books = append(books, b)
+		}
+	})
+	return
+}
+
+// call makes attempts of call with deadline until it succeeds, fails with non-transient error or retries are exhausted
+func (c *Client) call(ctx context.Context, retries int, attempt func(ctx context.Context) error) error {
+	backoff := c.opts.Backoff
+	for i := 0; ; i++ {
+		if !c.breaker.allow() {
+			return unavailable("circuit is open")
+		}
+		callCtx, cancel := context.WithTimeout(ctx, c.opts.CallTimeout)
+		err := attempt(callCtx)
+		cancel()
+		if err == nil {
+			c.breaker.success()
+			return nil
+		}
+		if ctx.Err() != nil {
+			// caller gave up or ran out of its own deadline, it tells nothing about catalog
+			c.breaker.abort()
+			return ctx.Err()
+		}
+		if !isTransient(err) {
+			c.breaker.success()
+			return err
+		}
+		c.breaker.failure()
+		if i >= retries {
+			return unavailable(err.Error())
+		}
+		if err := c.sleep(ctx, backoff); err != nil {
+			return err
+		}
+		backoff *= 2
+		if backoff > c.opts.MaxBackoff {
+			backoff = c.opts.MaxBackoff
+		}
+	}
+}
+
+// isTransient tells if error is caused by catalog being unreachable or overloaded
+func isTransient(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	default:
+		return false
+	}
+}
+
+func unavailable(reason string) *commonerrors.Unavailable {
+	return &commonerrors.Unavailable{What: "Catalog", Reason: reason}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type receivedBooks struct {
+	grpc.ClientStream
+	books []*catalog.Book
+}
+
+func (s *receivedBooks) Recv() (*catalog.Book, error) {
+	if len(s.books) == 0 {
+		return nil, io.EOF
+	}
+	b := s.books[0]
+	s.books = s.books[1:]
+	return b, nil
+}
+
+type breakerState int
+
+const (
+	closed breakerState = iota
+	open
+	halfOpen
+)
+
+// breaker is circuit breaker that opens after threshold consecutive failures
+// and lets single trial call through after cooldown
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	lock     sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+func (b *breaker) allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch b.state {
+	case open:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = halfOpen
+		return true
+	case halfOpen:
+		// trial call is in progress
+		return false
+	default:
+		return true
+	}
+}
+
+func (b *breaker) success() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.state = closed
+	b.failures = 0
+}
+
+func (b *breaker) failure() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.failures++
+	if b.state == halfOpen || (b.threshold > 0 && b.failures >= b.threshold) {
+		b.state = open
+		b.openedAt = b.now()
+	}
+}
+
+// abort finishes call that tells nothing about catalog, abandoned trial call lets next one through
+func (b *breaker) abort() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == halfOpen {
+		b.state = open
+	}
+}
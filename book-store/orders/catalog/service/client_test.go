@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/catalog"
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyCatalog fails first `failures` calls with err
+type flakyCatalog struct {
+	*fakeCatalog
+	ids       []uuid.UUID
+	failures  int
+	err       error
+	attempts  int
+	deadlines []bool
+	// giveUp is called on every attempt to make caller give up while call is in flight
+	giveUp func()
+}
+
+func (c *flakyCatalog) GetBooksByIDs(ctx context.Context, in *catalog.BookIDs, opts ...grpc.CallOption) (catalog.Catalog_GetBooksByIDsClient, error) {
+	_, hasDeadline := ctx.Deadline()
+	c.deadlines = append(c.deadlines, hasDeadline)
+	c.attempts++
+	if c.giveUp != nil {
+		c.giveUp()
+	}
+	if c.attempts <= c.failures {
+		return nil, c.err
+	}
+	return c.fakeCatalog.GetBooksByIDs(ctx, in, opts...)
+}
+
+func (c *flakyCatalog) CreateBook(ctx context.Context, in *catalog.BookCreateDTO, opts ...grpc.CallOption) (*catalog.Book, error) {
+	c.attempts++
+	return nil, c.err
+}
+
+var testClientOptions = ClientOptions{
+	CallTimeout:      time.Second,
+	MaxRetries:       2,
+	Backoff:          time.Millisecond,
+	MaxBackoff:       time.Millisecond,
+	BreakerThreshold: 4,
+	BreakerCooldown:  time.Minute,
+}
+
+func newTestClient(failures int, err error) (*Client, *flakyCatalog, *fakeClock) {
+	fake, ids := newFakeCatalog(1)
+	cc := &flakyCatalog{fakeCatalog: fake, ids: ids, failures: failures, err: err}
+	c := NewClient(cc, testClientOptions)
+	clock := &fakeClock{t: time.Now()}
+	c.breaker.now = clock.now
+	return c, cc, clock
+}
+
+func TestClientRetriesTransientErrors(t *testing.T) {
+	c, cc, _ := newTestClient(2, status.Error(codes.Unavailable, "down"))
+	id := cc.ids[0]
+	stream, err := c.GetBooksByIDs(ctx, &catalog.BookIDs{Ids: [][]byte{id[:]}})
+	if err != nil {
+		t.Fatalf("Expected call to succeed after retries, got %s", err)
+	}
+	if cc.attempts != 3 {
+		t.Errorf("Expected to make 3 attempts, made %d", cc.attempts)
+	}
+	for i, hasDeadline := range cc.deadlines {
+		if !hasDeadline {
+			t.Errorf("Expected attempt %d to have deadline", i)
+		}
+	}
+	if _, err = stream.Recv(); err != nil {
+		t.Errorf("Expected to receive book, got %s", err)
+	}
+	if _, err = stream.Recv(); err != io.EOF {
+		t.Errorf("Expected stream to end, got %v", err)
+	}
+}
+
+func TestClientGivesUpWithUnavailable(t *testing.T) {
+	c, cc, _ := newTestClient(10, status.Error(codes.Unavailable, "down"))
+	_, err := c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	if _, ok := err.(*commonerrors.Unavailable); !ok {
+		t.Errorf("Expected to get error of unavailable type, got %T", err)
+	}
+	if cc.attempts != 3 {
+		t.Errorf("Expected to make 3 attempts, made %d", cc.attempts)
+	}
+}
+
+func TestClientDoesNotRetryPermanentErrors(t *testing.T) {
+	c, cc, _ := newTestClient(10, status.Error(codes.InvalidArgument, "bad id"))
+	_, err := c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected to get original error, got %v", err)
+	}
+	if cc.attempts != 1 {
+		t.Errorf("Expected to make 1 attempt, made %d", cc.attempts)
+	}
+}
+
+func TestClientDoesNotRetryCreate(t *testing.T) {
+	c, cc, _ := newTestClient(10, status.Error(codes.Unavailable, "down"))
+	_, err := c.CreateBook(ctx, &catalog.BookCreateDTO{})
+	if _, ok := err.(*commonerrors.Unavailable); !ok {
+		t.Errorf("Expected to get error of unavailable type, got %T", err)
+	}
+	if cc.attempts != 1 {
+		t.Errorf("Expected to make 1 attempt, made %d", cc.attempts)
+	}
+}
+
+func TestClientCircuitBreaker(t *testing.T) {
+	c, cc, clock := newTestClient(5, status.Error(codes.Unavailable, "down"))
+	c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	if cc.attempts != 4 {
+		t.Fatalf("Expected circuit to open after 4 failed attempts, made %d", cc.attempts)
+	}
+
+	_, err := c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	if _, ok := err.(*commonerrors.Unavailable); !ok {
+		t.Errorf("Expected to fail fast with error of unavailable type, got %T", err)
+	}
+	if cc.attempts != 4 {
+		t.Errorf("Expected to fail fast without attempts while circuit is open")
+	}
+
+	// trial call fails, so circuit is opened again
+	clock.t = clock.t.Add(time.Minute)
+	c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	if cc.attempts != 5 {
+		t.Errorf("Expected to make single trial attempt after cooldown, made %d", cc.attempts-4)
+	}
+
+	clock.t = clock.t.Add(time.Minute)
+	_, err = c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	if err != nil {
+		t.Errorf("Expected circuit to close after successful trial call, got %s", err)
+	}
+	_, err = c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	if err != nil {
+		t.Errorf("Expected closed circuit to let calls through, got %s", err)
+	}
+}
+
+func TestClientCallerGivesUp(t *testing.T) {
+	c, cc, clock := newTestClient(10, status.Error(codes.DeadlineExceeded, "deadline exceeded"))
+	for i := 0; i < 5; i++ {
+		callCtx, cancel := context.WithCancel(ctx)
+		cc.giveUp = cancel
+		_, err := c.GetBooksByIDs(callCtx, &catalog.BookIDs{})
+		if err != context.Canceled {
+			t.Errorf("Expected to get error of caller context, got %v", err)
+		}
+	}
+	if cc.attempts != 5 {
+		t.Errorf("Expected not to retry calls caller gave up on, made %d attempts", cc.attempts)
+	}
+
+	// calls caller gave up on do not open circuit
+	cc.giveUp = nil
+	cc.attempts = 0
+	c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	if cc.attempts != 3 {
+		t.Errorf("Expected circuit to stay closed, made %d attempts", cc.attempts)
+	}
+
+	// abandoned trial call does not leave circuit half-open
+	c.GetBooksByIDs(ctx, &catalog.BookIDs{})
+	clock.t = clock.t.Add(time.Minute)
+	callCtx, cancel := context.WithCancel(ctx)
+	cc.giveUp = cancel
+	c.GetBooksByIDs(callCtx, &catalog.BookIDs{})
+	cc.giveUp = nil
+	cc.failures = 0
+	if _, err := c.GetBooksByIDs(ctx, &catalog.BookIDs{}); err != nil {
+		t.Errorf("Expected next call to be let through as trial, got %s", err)
+	}
+}
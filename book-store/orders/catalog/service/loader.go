@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/book"
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+type fetchFunc func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]book.Book, error)
+
+// loader coalesces concurrent lookups of single books into batched fetches
+type loader struct {
+	fetch    fetchFunc
+	wait     time.Duration
+	maxBatch int
+	timeout  time.Duration
+
+	lock  sync.Mutex
+	batch *batch
+}
+
+type batch struct {
+	ids     []uuid.UUID
+	wanted  map[uuid.UUID]bool
+	started bool
+	done    chan struct{}
+	res     map[uuid.UUID]book.Book
+	err     error
+}
+
+func newLoader(fetch fetchFunc, wait time.Duration, maxBatch int, timeout time.Duration) *loader {
+	return &loader{
+		fetch:    fetch,
+		wait:     wait,
+		maxBatch: maxBatch,
+		timeout:  timeout,
+	}
+}
+
+// load waits for current batch to be fetched and returns book with given id from it
+func (l *loader) load(ctx context.Context, id uuid.UUID) (book.Book, error) {
+	l.lock.Lock()
+	b := l.batch
+	if b == nil {
+		b = &batch{
+			ids:    make([]uuid.UUID, 0, l.maxBatch),
+			wanted: make(map[uuid.UUID]bool),
+			done:   make(chan struct{}),
+		}
+		l.batch = b
+		time.AfterFunc(l.wait, func() {
+			l.lock.Lock()
+			started := b.started
+			l.detach(b)
+			l.lock.Unlock()
+			if !started {
+				l.run(b)
+			}
+		})
+	}
+	if !b.wanted[id] {
+		b.wanted[id] = true
+		b.ids = append(b.ids, id)
+	}
+	if len(b.ids) >= l.maxBatch {
+		l.detach(b)
+		go l.run(b)
+	}
+	l.lock.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		return book.Book{}, ctx.Err()
+	}
+	if b.err != nil {
+		return book.Book{}, b.err
+	}
+	bk, found := b.res[id]
+	if !found {
+		return book.Book{}, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", id)}
+	}
+	return bk, nil
+}
+
+// detach closes batch for new ids, must be called with lock held
+func (l *loader) detach(b *batch) {
+	b.started = true
+	if l.batch == b {
+		l.batch = nil
+	}
+}
+
+func (l *loader) run(b *batch) {
+	// batch is shared by several callers, so it is not bound to context of any of them,
+	// but it is limited by its own timeout so that hanging fetch does not keep them all waiting
+	ctx, cancel := context.WithTimeout(context.Background(), l.timeout)
+	defer cancel()
+	b.res, b.err = l.fetch(ctx, b.ids)
+	close(b.done)
+}
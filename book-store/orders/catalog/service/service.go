@@ -2,8 +2,9 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"io"
+	"log"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/catalog"
@@ -11,34 +12,198 @@ import (
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 )
 
+const batchWait = 2 * time.Millisecond
+const maxBatchSize = 100
+
+var watchRetryDelay = 5 * time.Second
+
 // Service for accessing the catalog
 type Service struct {
 	catClient catalog.CatalogClient
-	cache     []book.Book // used for in-memory testing, may be used later
+	cache     *cache
+	loader    *loader
 }
 
-// New creates service
-func New(c catalog.CatalogClient) *Service {
-	return &Service{catClient: c, cache: make([]book.Book, 0)}
+// New creates service, books are cached according to given options
+func New(c catalog.CatalogClient, cacheOpts CacheOptions) *Service {
+	if cacheOpts.FetchTimeout <= 0 {
+		cacheOpts.FetchTimeout = DefaultFetchTimeout
+	}
+	s := &Service{catClient: c, cache: newCache(cacheOpts)}
+	s.loader = newLoader(s.fetchBooks, batchWait, maxBatchSize, cacheOpts.FetchTimeout)
+	return s
 }
 
-// GetBook gets book from catalog
-func (s *Service) GetBook(ctx context.Context, id uuid.UUID) (bk book.Book, err error) {
-	cl, err := s.catClient.GetBooks(ctx, &catalog.BooksQuery{
-		Id: id[:],
-	})
+// GetBook gets book from cache or from catalog if it is not cached.
+// Concurrent calls for uncached books are coalesced into single batched request to catalog.
+// Stale cached book is returned right away and is revalidated in background.
+func (s *Service) GetBook(ctx context.Context, id uuid.UUID) (book.Book, error) {
+	bk, state, revalidate := s.cache.get(id)
+	switch state {
+	case fresh:
+		return bk, nil
+	case stale:
+		if revalidate {
+			go s.revalidate(id)
+		}
+		return bk, nil
+	}
+	f := s.cache.startFetch(id)
+	bk, err := s.loader.load(ctx, id)
 	if err != nil {
-		return
+		s.cache.finishFetch(f, nil)
+		return bk, err
+	}
+	s.cache.finishFetch(f, &bk)
+	return bk, nil
+}
+
+// GetBooks gets books with given ids from cache, uncached books are fetched from catalog in single request.
+// Books that were not found are absent in resulting map.
+func (s *Service) GetBooks(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]book.Book, error) {
+	books := make(map[uuid.UUID]book.Book, len(ids))
+	uncached := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		bk, state, revalidate := s.cache.get(id)
+		if state == missing {
+			uncached = append(uncached, id)
+			continue
+		}
+		if revalidate {
+			go s.revalidate(id)
+		}
+		books[id] = bk
+	}
+	fetches := make([]fetch, len(uncached))
+	for i, id := range uncached {
+		fetches[i] = s.cache.startFetch(id)
+	}
+	fetched, err := s.fetchBooks(ctx, uncached)
+	for _, f := range fetches {
+		bk, found := fetched[f.id]
+		if !found {
+			s.cache.finishFetch(f, nil)
+			continue
+		}
+		s.cache.finishFetch(f, &bk)
+		books[f.id] = bk
+	}
+	if err != nil {
+		return nil, err
+	}
+	return books, nil
+}
+
+// Invalidate removes book from cache, should be called when book changes in catalog
+func (s *Service) Invalidate(id uuid.UUID) {
+	s.cache.invalidate(id)
+}
+
+// InvalidateAll clears cache, should be called when changes in catalog might have been missed
+func (s *Service) InvalidateAll() {
+	s.cache.invalidateAll()
+}
+
+// Watch keeps cache consistent with catalog by invalidating books on catalog change events.
+// Stream of events is resumed from the last received event on failures.
+// It blocks until ctx is done.
+func (s *Service) Watch(ctx context.Context) {
+	var after *uint64
+	for {
+		var err error
+		after, err = s.watch(ctx, after)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Catalog events stream failed: %s", err)
+		if after == nil {
+			// there is nothing to resume from, so changes might have been missed
+			s.InvalidateAll()
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
+
+func (s *Service) watch(ctx context.Context, after *uint64) (*uint64, error) {
+	cl, err := s.catClient.WatchBooks(ctx, &catalog.WatchQuery{AfterSequence: after})
+	if err != nil {
+		return after, err
+	}
+	for {
+		e, err := cl.Recv()
+		if err != nil {
+			return after, err
+		}
+		id, err := uuid.FromBytes(e.Id)
+		if err != nil {
+			log.Printf("Malformed ID in catalog event %d: %s", e.Sequence, err)
+		} else if e.Entity == catalog.Event_BOOK {
+			s.cache.invalidate(id)
+		} else {
+			s.cache.invalidateRelated(id)
+		}
+		seq := e.Sequence
+		after = &seq
 	}
-	res, err := cl.Recv()
-	if err == io.EOF {
-		return bk, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", id)}
+}
+
+// CacheStats returns metrics of books cache
+func (s *Service) CacheStats() CacheStats {
+	return s.cache.stats()
+}
+
+func (s *Service) revalidate(id uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cache.opts.FetchTimeout)
+	defer cancel()
+	f := s.cache.startFetch(id)
+	bk, err := s.loader.load(ctx, id)
+	if _, notFound := err.(*commonerrors.NotFound); notFound {
+		s.cache.finishFetch(f, nil)
+		s.cache.invalidate(id)
+		return
 	}
 	if err != nil {
+		s.cache.finishFetch(f, nil)
+		// stale book is served until catalog is available again or it expires
+		s.cache.failedRevalidation(id)
 		return
 	}
-	bk, err = resToBook(res)
-	return
+	s.cache.finishFetch(f, &bk)
+}
+
+func (s *Service) fetchBooks(ctx context.Context, ids []uuid.UUID) (books map[uuid.UUID]book.Book, err error) {
+	books = make(map[uuid.UUID]book.Book, len(ids))
+	if len(ids) == 0 {
+		return
+	}
+	req := &catalog.BookIDs{Ids: make([][]byte, len(ids))}
+	for i := range ids {
+		req.Ids[i] = ids[i][:]
+	}
+	cl, err := s.catClient.GetBooksByIDs(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	var res *catalog.Book
+	var bk book.Book
+	for {
+		res, err = cl.Recv()
+		if err == io.EOF {
+			return books, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		bk, err = resToBook(res)
+		if err != nil {
+			return nil, err
+		}
+		books[bk.ID] = bk
+	}
 }
 
 func resToBook(res *catalog.Book) (bk book.Book, err error) {
@@ -78,25 +243,3 @@ func resToBook(res *catalog.Book) (bk book.Book, err error) {
 	}
 	return
 }
-
-// GetBookFromCache gets book from cache
-func (s *Service) getBookFromCache(ctx context.Context, id uuid.UUID) (book.Book, error) {
-	for _, b := range s.cache {
-		if b.ID == id {
-			return b, nil
-		}
-	}
-	return book.Book{}, &commonerrors.NotFound{What: fmt.Sprintf("Book with ID %s", id)}
-}
-
-// CheckBookInCache check if book in cache
-func (s *Service) checkBookInCache(ctx context.Context, id uuid.UUID) (bool, error) {
-	_, err := s.getBookFromCache(ctx, id)
-	if err == nil {
-		return true, err
-	}
-	if _, notFound := err.(*commonerrors.NotFound); notFound {
-		return false, nil
-	}
-	return false, err
-}
@@ -0,0 +1,244 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/book"
+	"github.com/Vesninovich/go-tasks/book-store/common/catalog"
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+	"google.golang.org/grpc"
+)
+
+var ctx = context.Background()
+
+type fakeCatalog struct {
+	books   map[uuid.UUID]*catalog.Book
+	calls   int32
+	events  chan *catalog.Event
+	watches chan *catalog.WatchQuery
+}
+
+type eventsStream struct {
+	grpc.ClientStream
+	ctx    context.Context
+	events chan *catalog.Event
+}
+
+type booksStream struct {
+	grpc.ClientStream
+	books []*catalog.Book
+}
+
+func (c *fakeCatalog) GetBooks(ctx context.Context, in *catalog.BooksQuery, opts ...grpc.CallOption) (catalog.Catalog_GetBooksClient, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCatalog) GetBooksByIDs(ctx context.Context, in *catalog.BookIDs, opts ...grpc.CallOption) (catalog.Catalog_GetBooksByIDsClient, error) {
+	atomic.AddInt32(&c.calls, 1)
+	s := &booksStream{books: make([]*catalog.Book, 0, len(in.Ids))}
+	for _, rawID := range in.Ids {
+		id, err := uuid.FromBytes(rawID)
+		if err != nil {
+			return nil, err
+		}
+		if b, found := c.books[id]; found {
+			s.books = append(s.books, b)
+		}
+	}
+	return s, nil
+}
+
+func (c *fakeCatalog) CreateBook(ctx context.Context, in *catalog.BookCreateDTO, opts ...grpc.CallOption) (*catalog.Book, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCatalog) GetDeletedBooks(ctx context.Context, in *catalog.DeletedQuery, opts ...grpc.CallOption) (catalog.Catalog_GetDeletedBooksClient, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCatalog) GetDeletedAuthors(ctx context.Context, in *catalog.DeletedQuery, opts ...grpc.CallOption) (catalog.Catalog_GetDeletedAuthorsClient, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCatalog) GetDeletedCategories(ctx context.Context, in *catalog.DeletedQuery, opts ...grpc.CallOption) (catalog.Catalog_GetDeletedCategoriesClient, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCatalog) RestoreBook(ctx context.Context, in *catalog.EntityID, opts ...grpc.CallOption) (*catalog.Book, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCatalog) RestoreAuthor(ctx context.Context, in *catalog.EntityID, opts ...grpc.CallOption) (*catalog.Author, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCatalog) RestoreCategory(ctx context.Context, in *catalog.EntityID, opts ...grpc.CallOption) (*catalog.Category, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCatalog) Purge(ctx context.Context, in *catalog.PurgeRequest, opts ...grpc.CallOption) (*catalog.PurgeResult, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeCatalog) WatchBooks(ctx context.Context, in *catalog.WatchQuery, opts ...grpc.CallOption) (catalog.Catalog_WatchBooksClient, error) {
+	select {
+	case c.watches <- in:
+	default:
+	}
+	return &eventsStream{ctx: ctx, events: c.events}, nil
+}
+
+func (s *eventsStream) Recv() (*catalog.Event, error) {
+	select {
+	case e, open := <-s.events:
+		if !open {
+			return nil, errors.New("stream broken")
+		}
+		return e, nil
+	case <-s.ctx.Done():
+		return nil, s.ctx.Err()
+	}
+}
+
+func (s *booksStream) Recv() (*catalog.Book, error) {
+	if len(s.books) == 0 {
+		return nil, io.EOF
+	}
+	b := s.books[0]
+	s.books = s.books[1:]
+	return b, nil
+}
+
+func newFakeCatalog(count int) (*fakeCatalog, []uuid.UUID) {
+	c := &fakeCatalog{
+		books:   make(map[uuid.UUID]*catalog.Book),
+		events:  make(chan *catalog.Event),
+		watches: make(chan *catalog.WatchQuery, 10),
+	}
+	ids := make([]uuid.UUID, count)
+	for i := range ids {
+		id, aID := uuid.New(), uuid.New()
+		ids[i] = id
+		c.books[id] = &catalog.Book{
+			Id:     id[:],
+			Name:   "test",
+			Author: &catalog.Author{Id: aID[:], Name: "author"},
+		}
+	}
+	return c, ids
+}
+
+func TestGetBooks(t *testing.T) {
+	c, ids := newFakeCatalog(3)
+	s := New(c, DefaultCacheOptions)
+	missing := uuid.New()
+	res, err := s.GetBooks(ctx, []uuid.UUID{ids[0], missing, ids[2]})
+	if err != nil {
+		t.Fatalf("Error while getting books: %s", err)
+	}
+	if len(res) != 2 {
+		t.Fatalf("Expected to get 2 books, got %d", len(res))
+	}
+	if res[ids[0]].ID != ids[0] || res[ids[2]].ID != ids[2] {
+		t.Error("Got wrong books")
+	}
+	if c.calls != 1 {
+		t.Errorf("Expected to make 1 call to catalog, made %d", c.calls)
+	}
+}
+
+func TestGetBookNotFound(t *testing.T) {
+	c, _ := newFakeCatalog(1)
+	s := New(c, DefaultCacheOptions)
+	_, err := s.GetBook(ctx, uuid.New())
+	if _, ok := err.(*commonerrors.NotFound); !ok {
+		t.Errorf("Expected to get error of not found type, got %T", err)
+	}
+}
+
+func TestLoaderCoalescesConcurrentLookups(t *testing.T) {
+	c, ids := newFakeCatalog(10)
+	s := New(c, DefaultCacheOptions)
+	s.loader = newLoader(s.GetBooks, 50*time.Millisecond, maxBatchSize, time.Second)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		for _, id := range ids {
+			wg.Add(1)
+			go func(id uuid.UUID) {
+				defer wg.Done()
+				b, err := s.GetBook(ctx, id)
+				if err != nil {
+					t.Errorf("Error while getting book: %s", err)
+					return
+				}
+				if b.ID != id {
+					t.Error("Got wrong book")
+				}
+			}(id)
+		}
+	}
+	wg.Wait()
+	if c.calls != 1 {
+		t.Errorf("Expected to make 1 call to catalog, made %d", c.calls)
+	}
+}
+
+func TestLoaderSplitsFullBatches(t *testing.T) {
+	var lock sync.Mutex
+	sizes := make([]int, 0)
+	l := newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]book.Book, error) {
+		lock.Lock()
+		sizes = append(sizes, len(ids))
+		lock.Unlock()
+		res := make(map[uuid.UUID]book.Book, len(ids))
+		for _, id := range ids {
+			res[id] = book.Book{ID: id}
+		}
+		return res, nil
+	}, time.Second, 2, time.Second)
+
+	var wg sync.WaitGroup
+	for _, id := range []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()} {
+		wg.Add(1)
+		go func(id uuid.UUID) {
+			defer wg.Done()
+			if _, err := l.load(ctx, id); err != nil {
+				t.Errorf("Error while loading book: %s", err)
+			}
+		}(id)
+	}
+	wg.Wait()
+	if len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 2 {
+		t.Errorf("Expected to fetch 2 batches of 2 books, got %v", sizes)
+	}
+}
+
+func TestLoaderError(t *testing.T) {
+	fetchErr := errors.New("catalog is down")
+	l := newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]book.Book, error) {
+		return nil, fetchErr
+	}, time.Millisecond, maxBatchSize, time.Second)
+	_, err := l.load(ctx, uuid.New())
+	if err != fetchErr {
+		t.Errorf("Expected to get fetch error, got %v", err)
+	}
+}
+
+func TestLoaderTimeout(t *testing.T) {
+	l := newLoader(func(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]book.Book, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}, time.Millisecond, maxBatchSize, 10*time.Millisecond)
+	_, err := l.load(ctx, uuid.New())
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected hanging fetch to time out, got %v", err)
+	}
+}
@@ -5,12 +5,23 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
-	"time"
 
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	auditsql "github.com/Vesninovich/go-tasks/book-store/common/audit/sql"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth/authgrpc"
 	"github.com/Vesninovich/go-tasks/book-store/common/catalog"
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
+	idempotencysql "github.com/Vesninovich/go-tasks/book-store/common/idempotency/sql"
 	"github.com/Vesninovich/go-tasks/book-store/common/orders"
+	"github.com/Vesninovich/go-tasks/book-store/common/sqltx"
+	"github.com/Vesninovich/go-tasks/book-store/common/stored"
+	"github.com/Vesninovich/go-tasks/book-store/common/tlsconf"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+	webhooksql "github.com/Vesninovich/go-tasks/book-store/common/webhook/sql"
 	catalogservice "github.com/Vesninovich/go-tasks/book-store/orders/catalog/service"
 	ordergrpc "github.com/Vesninovich/go-tasks/book-store/orders/grpc"
 	orderservice "github.com/Vesninovich/go-tasks/book-store/orders/order/service"
@@ -35,11 +46,23 @@ import (
 // @host localhost:8004
 // @BasePath /
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+
 // @tag.name Order
 // @tag.description Requesting and placing orders
 
+// @tag.name Admin
+// @tag.description Restoring and purging deleted orders, audit trail and webhooks
+
 const dbURL = "postgresql://gobookstoreorders@localhost:5432/gobookstore"
 const catalogURL = "localhost:8001"
+const catalogServerName = "localhost"
 const grpcHost = "localhost:8003"
 const restHost = "localhost:8004"
 const schema = "orders"
@@ -48,15 +71,32 @@ const bufsize = 1024 * 1024
 var ctx = context.Background()
 
 func main() {
-	db, r := initSQL()
+	db, r, is, aus, ws := initSQL()
 	defer db.Close()
 
-	cConn, err := grpc.Dial(catalogURL, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(5*time.Second))
+	window, err := idempotency.WindowFromEnv("ORDERS")
+	if err != nil {
+		log.Fatalf("Failed to set up idempotency keys: %s", err)
+	}
+	idem := idempotency.New(is, window)
+
+	tlsConf := tlsconf.FromEnv("ORDERS")
+	catalogCreds, err := tlsConf.DialOption(catalogServerName)
 	if err != nil {
-		log.Fatalf("Failed to connect to catalog service on %s: %s", catalogURL, err)
+		log.Fatalf("Failed to set up TLS for connection to catalog service: %s", err)
+	}
+	// dial is not blocking, so orders can start before catalog and connect to it later
+	// principal of REST request is passed on to catalog
+	cConn, err := grpc.Dial(
+		catalogURL,
+		catalogCreds,
+		grpc.WithChainUnaryInterceptor(authgrpc.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(authgrpc.StreamClientInterceptor()),
+	)
+	if err != nil {
+		log.Fatalf("Failed to set up connection to catalog service on %s: %s", catalogURL, err)
 	}
 	defer cConn.Close()
-	log.Println("Connected to catalog gRPC service")
 
 	lis, err := net.Listen("tcp", grpcHost)
 	if err != nil {
@@ -64,11 +104,40 @@ func main() {
 	}
 	log.Println("Listening on " + grpcHost)
 
-	cc := catalog.NewCatalogClient(cConn)
-	c := catalogservice.New(cc)
+	cc := catalogservice.NewClient(catalog.NewCatalogClient(cConn), catalogservice.DefaultClientOptions)
+	c := catalogservice.New(cc, catalogservice.DefaultCacheOptions)
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	go c.Watch(watchCtx)
 	s := orderservice.New(r, c)
+	recorder := audit.NewRecorder(aus)
+	// audit entries are saved in transactions of changes they describe
+	recorder.UseTransactions(sqltx.New(db))
+	s.UseAudit(recorder)
+
+	retention, purgeInterval, err := stored.PurgeFromEnv("ORDERS")
+	if err != nil {
+		log.Fatalf("Failed to set up purge of deleted orders: %s", err)
+	}
+	go s.RunPurges(ctx, retention, purgeInterval)
 
-	grpcServer := grpc.NewServer()
+	hookOpts, hookInterval, err := webhook.OptionsFromEnv("ORDERS", orderservice.WebhookEvents)
+	if err != nil {
+		log.Fatalf("Failed to set up webhooks: %s", err)
+	}
+	hooks := webhook.New(ws, hookOpts)
+	s.UseWebhooks(hooks)
+	go hooks.Run(ctx, hookInterval)
+
+	creds, err := tlsConf.ServerOption()
+	if err != nil {
+		log.Fatalf("Failed to set up TLS for gRPC server: %s", err)
+	}
+	grpcServer := grpc.NewServer(
+		creds,
+		grpc.ChainUnaryInterceptor(authgrpc.UnaryServerInterceptor(tlsConf.ClientNames...), idem.UnaryServerInterceptor("/orders.Orders/CreateOrder")),
+		grpc.ChainStreamInterceptor(authgrpc.StreamServerInterceptor(tlsConf.ClientNames...)),
+	)
 	orders.RegisterOrdersServer(grpcServer, ordergrpc.New(s))
 	log.Println("Starting gRPC server")
 	go func() {
@@ -78,6 +147,34 @@ func main() {
 	}()
 
 	restServer := rest.New(restHost, "/order", s)
+	restServer.UseIdempotency(idem)
+	restServer.UseAudit(recorder)
+	restServer.UseWebhooks(hooks)
+	restServer.UseCatalogCache(c)
+	if tlsConf.Enabled() {
+		// client certificates are only required from services calling gRPC
+		restConf := tlsConf
+		restConf.ClientNames = nil
+		c, err := restConf.Server()
+		if err != nil {
+			log.Fatalf("Failed to set up TLS for REST server: %s", err)
+		}
+		restServer.UseTLS(c)
+	}
+	authenticator, err := auth.FromEnv("ORDERS")
+	if err != nil {
+		log.Fatalf("Failed to set up authentication for REST server: %s", err)
+	}
+	if authenticator != nil {
+		// orders are available to any authenticated user, only admins may delete them and manage deleted ones
+		restServer.UseAuth(auth.NewMiddleware(authenticator,
+			auth.Rule{PathPrefix: "/admin", Roles: []string{"admin"}},
+			auth.Rule{Methods: []string{http.MethodGet}, PathPrefix: "/order/swagger/", Anonymous: true},
+			auth.Rule{Methods: []string{http.MethodDelete}, PathPrefix: "/order", Roles: []string{"admin"}},
+		))
+	} else {
+		log.Println("No authentication configured, REST API is open to everyone")
+	}
 	log.Println("Starting REST server on " + restHost)
 	go func() {
 		if err = restServer.Start(); err != nil {
@@ -86,16 +183,17 @@ func main() {
 	}()
 
 	// Run until interrupt
-	sig := make(chan os.Signal)
+	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 	<-sig
 	fmt.Println("SIGINT")
+	stopWatch()
 	grpcServer.GracefulStop()
 	db.Close()
 	os.Exit(0)
 }
 
-func initSQL() (*sqlx.DB, *ordersql.Repository) {
+func initSQL() (*sqlx.DB, *ordersql.Repository, *idempotencysql.Store, *auditsql.Store, *webhooksql.Store) {
 	db, err := sqlx.Connect("pgx", dbURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to DB at URL %s\n%s", dbURL, err)
@@ -108,11 +206,20 @@ func initSQL() (*sqlx.DB, *ordersql.Repository) {
 	db.MustExec(s)
 
 	r := ordersql.New(db, schema)
+	i := idempotencysql.New(db.DB, schema)
+	au := auditsql.New(db, schema)
+	ws := webhooksql.New(db.DB, schema)
 
 	log.Println("Creating tables")
 	log.Println(r.CreateTableStmt())
 	db.MustExec(r.CreateTableStmt())
+	log.Println(i.CreateTableStmt())
+	db.MustExec(i.CreateTableStmt())
+	log.Println(au.CreateTableStmt())
+	db.MustExec(au.CreateTableStmt())
+	log.Println(ws.CreateTableStmt())
+	db.MustExec(ws.CreateTableStmt())
 	log.Println("Finished setting up DB")
 
-	return db, r
+	return db, r, i, au, ws
 }
@@ -5,13 +5,19 @@ package main_test
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/common/catalog"
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
+	idempotencysql "github.com/Vesninovich/go-tasks/book-store/common/idempotency/sql"
 	"github.com/Vesninovich/go-tasks/book-store/common/orders"
+	"github.com/Vesninovich/go-tasks/book-store/common/tlsconf"
+	"github.com/Vesninovich/go-tasks/book-store/common/tlsconf/tlstest"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 	catalogservice "github.com/Vesninovich/go-tasks/book-store/orders/catalog/service"
 	ordergrpc "github.com/Vesninovich/go-tasks/book-store/orders/grpc"
@@ -20,11 +26,14 @@ import (
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/jmoiron/sqlx"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
 )
 
 const dbURL = "postgresql://gobookstoreorders@localhost:5432/gobookstore"
 const catalogURL = "localhost:8001"
+const catalogServerName = "localhost"
 const schema = "orders_test"
 const bufsize = 1024 * 1024
 
@@ -33,22 +42,31 @@ var ctx = context.Background()
 var client orders.OrdersClient
 var cc catalog.CatalogClient
 var bk *catalog.Book
+var certDir string
 
 // TestMain tests
 func TestMain(m *testing.M) {
-	db, r := initSQL()
+	db, r, is := initSQL()
 
-	cConn, err := grpc.Dial(catalogURL, grpc.WithInsecure())
+	// catalog is started separately, so it is reached with certificates configured in environment
+	catalogCreds, err := tlsconf.FromEnv("ORDERS").DialOption(catalogServerName)
+	if err != nil {
+		log.Fatalf("Failed to set up TLS for connection to catalog service: %s", err)
+	}
+	cConn, err := grpc.Dial(catalogURL, catalogCreds)
 	if err != nil {
 		log.Fatalf("Failed to connect to catalog service on %s: %s", catalogURL, err)
 	}
 
 	cc = catalog.NewCatalogClient(cConn)
-	c := catalogservice.New(cc)
+	c := catalogservice.New(cc, catalogservice.DefaultCacheOptions)
 	s := orderservice.New(r, c)
 
+	serverCreds, clientCreds := setUpTLS()
+
 	lis = bufconn.Listen(bufsize)
-	grpcServer := grpc.NewServer()
+	idem := idempotency.New(is, time.Hour)
+	grpcServer := grpc.NewServer(serverCreds, grpc.ChainUnaryInterceptor(idem.UnaryServerInterceptor("/orders.Orders/CreateOrder")))
 	orders.RegisterOrdersServer(grpcServer, ordergrpc.New(s))
 	log.Println("Starting gRPC server")
 	go func() {
@@ -57,7 +75,7 @@ func TestMain(m *testing.M) {
 		}
 	}()
 
-	oConn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), grpc.WithInsecure())
+	oConn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(bufDialer), clientCreds)
 	if err != nil {
 		log.Fatalf("Failed to dial bufnet: %s", err)
 	}
@@ -69,6 +87,7 @@ func TestMain(m *testing.M) {
 	cConn.Close()
 	grpcServer.GracefulStop()
 	db.Close()
+	os.RemoveAll(certDir)
 	os.Exit(res)
 }
 
@@ -113,13 +132,91 @@ func TestOrders(t *testing.T) {
 	if resID != createdID || res.Description != created.Description || resBID != createdBID {
 		t.Error("Created and queried orders are not equal")
 	}
+
+	expected := res.Version
+	upd := &orders.DescriptionUpdate{Id: created.Id, Description: "Changed order", ExpectedVersion: &expected}
+	updated, err := client.UpdateOrderDescription(ctx, upd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != expected+1 {
+		t.Errorf("Expected updated order to have version %d, got %d", expected+1, updated.Version)
+	}
+	upd.Description = "Stale change"
+	_, err = client.UpdateOrderDescription(ctx, upd)
+	if status.Code(err) != codes.Aborted {
+		t.Errorf("Expected update at stale version to be aborted, got %v", err)
+	}
+}
+
+func TestIdempotentCreate(t *testing.T) {
+	bk, err := cc.CreateBook(ctx, &catalog.BookCreateDTO{
+		Name: "idempotent book",
+		Author: &catalog.Author{
+			Name: "test author",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := uuid.New().String()
+	keyCtx := idempotency.OutgoingContext(ctx, key)
+	dto := &orders.CreateDTO{Description: "Retried order", Book: bk.Id}
+	created, err := client.CreateOrder(keyCtx, dto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retried, err := client.CreateOrder(keyCtx, dto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(retried.Id) != string(created.Id) {
+		t.Error("Expected retry with the same idempotency key to return created order")
+	}
+	dto.Description = "Another order"
+	_, err = client.CreateOrder(keyCtx, dto)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected idempotency key reused with different order to be rejected, got %v", err)
+	}
+}
+
+// setUpTLS issues throwaway certificates for orders server and its test client
+// and makes server accept only that client
+func setUpTLS() (grpc.ServerOption, grpc.DialOption) {
+	var err error
+	certDir, err = ioutil.TempDir("", "orders-test-certs")
+	if err != nil {
+		log.Fatalf("Failed to create directory for certificates: %s", err)
+	}
+	ca, err := tlstest.NewCA(certDir)
+	if err != nil {
+		log.Fatalf("Failed to create CA: %s", err)
+	}
+	server, err := ca.Issue("orders")
+	if err != nil {
+		log.Fatalf("Failed to issue server certificate: %s", err)
+	}
+	client, err := ca.Issue("orders-test-client")
+	if err != nil {
+		log.Fatalf("Failed to issue client certificate: %s", err)
+	}
+	server.ClientNames = []string{"orders-test-client"}
+	serverCreds, err := server.ServerOption()
+	if err != nil {
+		log.Fatalf("Failed to set up TLS for gRPC server: %s", err)
+	}
+	clientCreds, err := client.DialOption("orders")
+	if err != nil {
+		log.Fatalf("Failed to set up TLS for gRPC client: %s", err)
+	}
+	return serverCreds, clientCreds
 }
 
 func bufDialer(context.Context, string) (net.Conn, error) {
 	return lis.Dial()
 }
 
-func initSQL() (*sqlx.DB, *ordersql.Repository) {
+func initSQL() (*sqlx.DB, *ordersql.Repository, *idempotencysql.Store) {
 	db, err := sqlx.Connect("pgx", dbURL)
 	if err != nil {
 		log.Fatalf("Failed to connect to DB at URL %s\n%s", dbURL, err)
@@ -132,11 +229,14 @@ func initSQL() (*sqlx.DB, *ordersql.Repository) {
 	db.MustExec(s)
 
 	r := ordersql.New(db, schema)
+	i := idempotencysql.New(db.DB, schema)
 
 	log.Println("Creating tables")
 	log.Println(r.CreateTableStmt())
 	db.MustExec(r.CreateTableStmt())
+	log.Println(i.CreateTableStmt())
+	db.MustExec(i.CreateTableStmt())
 	log.Println("Finished setting up DB")
 
-	return db, r
+	return db, r, i
 }
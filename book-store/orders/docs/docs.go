@@ -32,9 +32,603 @@ var doc = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/admin/audit": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get changes of orders with who made them and changed fields, the latest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "get audit trail",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "order id",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, changes made before it are skipped",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, changes made at it or later are skipped",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "results start",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "results count, 10 by default",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "results",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/audit.Entry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "malformed query",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/catalog/cache": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get counters of cache of catalog books since start of service",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "get catalog cache stats",
+                "responses": {
+                    "200": {
+                        "description": "cache stats",
+                        "schema": {
+                            "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.cacheStatsAPIModel"
+                        }
+                    },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/order/deleted": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get deleted orders, the latest deleted first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "get deleted orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "results start",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "results count, 10 by default",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "results",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.deletedAPIModel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "malformed query",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/order/{id}/restore": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "undelete order",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "restore order",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "order id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "restored order",
+                        "schema": {
+                            "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.apiModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "order version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "malformed id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "deleted order not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/purge": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "permanently remove orders deleted before given time",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "purge deleted orders",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "RFC 3339 time, orders deleted before it are removed",
+                        "name": "before",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "number of removed orders",
+                        "schema": {
+                            "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.purgeAPIModel"
+                        }
+                    },
+                    "400": {
+                        "description": "malformed time",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhook": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get webhook subscriptions to events of orders, their secrets are not sent",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "get webhooks",
+                "responses": {
+                    "200": {
+                        "description": "subscriptions",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.webhookAPIModel"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "subscribe URL to events of orders: order.created, order.updated, order.deleted or order.restored.\nPayloads are signed with secret, it is generated if not set and is only sent in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "create webhook",
+                "parameters": [
+                    {
+                        "description": "subscription data",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.createWebhookAPIModel"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "created subscription",
+                        "schema": {
+                            "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.webhookAPIModel"
+                        }
+                    },
+                    "400": {
+                        "description": "bad data",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhook/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "delete webhook subscription with its deliveries",
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "delete webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "subscription id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "subscription deleted"
+                    },
+                    "400": {
+                        "description": "malformed id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "subscription not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/webhook/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get log of deliveries of webhook subscription with all attempts, the latest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Admin"
+                ],
+                "summary": "get webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "subscription id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "results start",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "results count, 10 by default",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "results",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.deliveryAPIModel"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "malformed id or query",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "subscription not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/order": {
             "post": {
-                "description": "place new book order",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "place new book order, response is replayed to requests repeated with the same Idempotency-Key header",
                 "consumes": [
                     "application/json"
                 ],
@@ -53,6 +647,12 @@ var doc = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "key making retries of request safe",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
                     {
                         "description": "order data",
                         "name": "order",
@@ -76,24 +676,62 @@ var doc = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "requested book not found",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "409": {
+                        "description": "request with the same idempotency key is in progress",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "422": {
+                        "description": "idempotency key was used for another request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "internal error",
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "catalog is unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
         "/order/{id}": {
             "get": {
-                "description": "get order by id",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "get order by id, version of order is sent in ETag header",
                 "produces": [
                     "application/json"
                 ],
@@ -115,6 +753,12 @@ var doc = `{
                         "description": "requested order",
                         "schema": {
                             "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.apiModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "order version"
+                            }
                         }
                     },
                     "400": {
@@ -123,6 +767,18 @@ var doc = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "requested order not found",
                         "schema": {
@@ -134,11 +790,25 @@ var doc = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "503": {
+                        "description": "catalog is unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
             "put": {
-                "description": "change order description",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "change order description, it is only changed if order still has version from If-Match header when the header is set",
                 "consumes": [
                     "application/json"
                 ],
@@ -157,6 +827,12 @@ var doc = `{
                         "in": "path",
                         "required": true
                     },
+                    {
+                        "type": "string",
+                        "description": "expected order version from ETag",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
                     {
                         "description": "new description",
                         "name": "description",
@@ -172,6 +848,12 @@ var doc = `{
                         "description": "updated order",
                         "schema": {
                             "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.apiModel"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "order version"
+                            }
                         }
                     },
                     "400": {
@@ -180,12 +862,30 @@ var doc = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "requested order not found",
                         "schema": {
                             "type": "string"
                         }
                     },
+                    "412": {
+                        "description": "order was changed since expected version",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "internal error",
                         "schema": {
@@ -195,6 +895,14 @@ var doc = `{
                 }
             },
             "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    },
+                    {
+                        "BearerAuth": []
+                    }
+                ],
                 "description": "remove order",
                 "produces": [
                     "application/json"
@@ -225,6 +933,18 @@ var doc = `{
                             "type": "string"
                         }
                     },
+                    "401": {
+                        "description": "not authenticated",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "not allowed",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "404": {
                         "description": "requested order not found",
                         "schema": {
@@ -242,6 +962,49 @@ var doc = `{
         }
     },
     "definitions": {
+        "audit.Change": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                }
+            }
+        },
+        "audit.Entry": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Actor is subject of principal who made change, empty for anonymous requests and scheduled purges",
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "diff": {
+                    "description": "Diff maps names of changed fields to their values before and after change",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/audit.Change"
+                    }
+                },
+                "entity": {
+                    "type": "string"
+                },
+                "entityID": {
+                    "type": "string"
+                },
+                "id": {
+                    "description": "ID is assigned by Store in order entries are saved",
+                    "type": "integer"
+                },
+                "operation": {
+                    "type": "string"
+                }
+            }
+        },
         "github.com_Vesninovich_go-tasks_book-store_orders_rest.apiModel": {
             "type": "object",
             "properties": {
@@ -256,6 +1019,42 @@ var doc = `{
                 }
             }
         },
+        "github.com_Vesninovich_go-tasks_book-store_orders_rest.attemptAPIModel": {
+            "type": "object",
+            "properties": {
+                "durationMs": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "statusCode": {
+                    "description": "StatusCode of response, not set if request failed",
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "github.com_Vesninovich_go-tasks_book-store_orders_rest.cacheStatsAPIModel": {
+            "type": "object",
+            "properties": {
+                "evictions": {
+                    "type": "integer"
+                },
+                "hits": {
+                    "type": "integer"
+                },
+                "misses": {
+                    "type": "integer"
+                },
+                "staleHits": {
+                    "description": "StaleHits are stale books returned while they are revalidated",
+                    "type": "integer"
+                }
+            }
+        },
         "github.com_Vesninovich_go-tasks_book-store_orders_rest.createAPIModel": {
             "type": "object",
             "properties": {
@@ -267,6 +1066,71 @@ var doc = `{
                 }
             }
         },
+        "github.com_Vesninovich_go-tasks_book-store_orders_rest.createWebhookAPIModel": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Secret signing payloads, generated if not set",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "github.com_Vesninovich_go-tasks_book-store_orders_rest.deletedAPIModel": {
+            "type": "object",
+            "properties": {
+                "bookID": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "github.com_Vesninovich_go-tasks_book-store_orders_rest.deliveryAPIModel": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/github.com_Vesninovich_go-tasks_book-store_orders_rest.attemptAPIModel"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "nextAttemptAt": {
+                    "description": "NextAttemptAt is only set for pending deliveries",
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "github.com_Vesninovich_go-tasks_book-store_orders_rest.descUpdAPIModel": {
             "type": "object",
             "properties": {
@@ -274,12 +1138,208 @@ var doc = `{
                     "type": "string"
                 }
             }
+        },
+        "github.com_Vesninovich_go-tasks_book-store_orders_rest.purgeAPIModel": {
+            "type": "object",
+            "properties": {
+                "orders": {
+                    "type": "integer"
+                }
+            }
+        },
+        "github.com_Vesninovich_go-tasks_book-store_orders_rest.webhookAPIModel": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Secret is only sent in response to subscription",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.apiModel": {
+            "type": "object",
+            "properties": {
+                "bookID": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.attemptAPIModel": {
+            "type": "object",
+            "properties": {
+                "durationMs": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "statusCode": {
+                    "description": "StatusCode of response, not set if request failed",
+                    "type": "integer"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.createAPIModel": {
+            "type": "object",
+            "properties": {
+                "bookID": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.createWebhookAPIModel": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "Secret signing payloads, generated if not set",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.deletedAPIModel": {
+            "type": "object",
+            "properties": {
+                "bookID": {
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.deliveryAPIModel": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rest.attemptAPIModel"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "nextAttemptAt": {
+                    "description": "NextAttemptAt is only set for pending deliveries",
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.descUpdAPIModel": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                }
+            }
+        },
+        "rest.purgeAPIModel": {
+            "type": "object",
+            "properties": {
+                "orders": {
+                    "type": "integer"
+                }
+            }
+        },
+        "rest.webhookAPIModel": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "Secret is only sent in response to subscription",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
         }
     },
     "tags": [
         {
             "description": "Requesting and placing orders",
             "name": "Order"
+        },
+        {
+            "description": "Restoring and purging deleted orders, audit trail and webhooks",
+            "name": "Admin"
         }
     ]
 }`
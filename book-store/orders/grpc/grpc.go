@@ -2,13 +2,21 @@ package grpc
 
 import (
 	"context"
+	"time"
 
+	"github.com/Vesninovich/go-tasks/book-store/common/auth/authgrpc"
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
 	"github.com/Vesninovich/go-tasks/book-store/common/orders"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 	"github.com/Vesninovich/go-tasks/book-store/orders/order"
 	orderservice "github.com/Vesninovich/go-tasks/book-store/orders/order/service"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// adminRole is role principal must have to call admin RPCs
+const adminRole = "admin"
+
 // Server of orders
 type Server struct {
 	orders.UnimplementedOrdersServer
@@ -52,8 +60,8 @@ func (s *Server) CreateOrder(ctx context.Context, dto *orders.CreateDTO) (*order
 	return orderToResponse(o), nil
 }
 
-// UpdateDescription godoc
-func (s *Server) UpdateDescription(ctx context.Context, dto *orders.DescriptionUpdate) (*orders.Order, error) {
+// UpdateOrderDescription godoc
+func (s *Server) UpdateOrderDescription(ctx context.Context, dto *orders.DescriptionUpdate) (*orders.Order, error) {
 	id, err := uuid.FromBytes(dto.Id)
 	if err != nil {
 		return nil, err
@@ -61,7 +69,12 @@ func (s *Server) UpdateDescription(ctx context.Context, dto *orders.DescriptionU
 	o, err := s.service.UpdateDescription(ctx, order.Order{
 		ID:          id,
 		Description: dto.Description,
+		Version:     dto.GetExpectedVersion(),
 	})
+	if _, ok := err.(*commonerrors.VersionMismatch); ok {
+		// client should read order again and retry its change
+		return nil, status.Error(codes.Aborted, err.Error())
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -81,10 +94,67 @@ func (s *Server) RemoveOrder(ctx context.Context, id *orders.ID) (*orders.Order,
 	return orderToResponse(o), nil
 }
 
+// GetDeletedOrders godoc
+func (s *Server) GetDeletedOrders(q *orders.DeletedQuery, stream orders.Orders_GetDeletedOrdersServer) error {
+	ctx := stream.Context()
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return err
+	}
+	data, err := s.service.GetDeletedOrders(ctx, uint(q.GetFrom()), uint(q.GetCount()))
+	if err != nil {
+		return err
+	}
+	for _, item := range data {
+		o := item.ToOrderDTO()
+		err = stream.Send(&orders.DeletedOrder{
+			Order: &orders.Order{
+				Id:          o.ID[:],
+				Description: o.Description,
+				Book:        o.BookID[:],
+				Version:     o.Version,
+			},
+			DeletedAt: item.DeletedAt.Unix(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestoreOrder godoc
+func (s *Server) RestoreOrder(ctx context.Context, id *orders.ID) (*orders.Order, error) {
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return nil, err
+	}
+	oid, err := uuid.FromBytes(id.Id)
+	if err != nil {
+		return nil, err
+	}
+	o, err := s.service.RestoreOrder(ctx, oid)
+	if err != nil {
+		return nil, err
+	}
+	return orderToResponse(o), nil
+}
+
+// PurgeOrders godoc
+func (s *Server) PurgeOrders(ctx context.Context, req *orders.PurgeRequest) (*orders.PurgeResult, error) {
+	if err := authgrpc.RequireRole(ctx, adminRole); err != nil {
+		return nil, err
+	}
+	n, err := s.service.PurgeOrders(ctx, time.Unix(req.Before, 0))
+	if err != nil {
+		return nil, err
+	}
+	return &orders.PurgeResult{Orders: n}, nil
+}
+
 func orderToResponse(o order.Order) *orders.Order {
 	return &orders.Order{
 		Id:          o.ID[:],
 		Description: o.Description,
 		Book:        o.Book.ID[:],
+		Version:     o.Version,
 	}
 }
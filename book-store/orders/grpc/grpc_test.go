@@ -0,0 +1,55 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth/authgrpc"
+	"github.com/Vesninovich/go-tasks/book-store/common/orders"
+	ordergrpc "github.com/Vesninovich/go-tasks/book-store/orders/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestForgedAdminRole(t *testing.T) {
+	lis := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(authgrpc.UnaryServerInterceptor("catalog")),
+		grpc.ChainStreamInterceptor(authgrpc.StreamServerInterceptor("catalog")),
+	)
+	// admin RPCs check role before using service
+	orders.RegisterOrdersServer(s, ordergrpc.New(nil))
+	go s.Serve(lis)
+	defer s.GracefulStop()
+
+	ctx := context.Background()
+	dialer := func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}
+	conn, err := grpc.DialContext(ctx, "bufnet", grpc.WithContextDialer(dialer), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Failed to dial bufnet: %s", err)
+	}
+	defer conn.Close()
+	client := orders.NewOrdersClient(conn)
+
+	// client without certificate of trusted service claims admin role in metadata
+	forged := metadata.AppendToOutgoingContext(ctx, "x-auth-subject", "mallory", "x-auth-roles", "admin")
+	if _, err = client.PurgeOrders(forged, &orders.PurgeRequest{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected purge with forged role to be denied, got %v", err)
+	}
+	if _, err = client.RestoreOrder(forged, &orders.ID{}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected restore with forged role to be denied, got %v", err)
+	}
+	deleted, err := client.GetDeletedOrders(forged, &orders.DeletedQuery{})
+	if err == nil {
+		_, err = deleted.Recv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected reading deleted orders with forged role to be denied, got %v", err)
+	}
+}
@@ -10,4 +10,6 @@ type Order struct {
 	ID          uuid.UUID
 	Description string
 	Book        book.Book
+	// Version is incremented on every change of Order, on update it is expected current version, zero skips the check
+	Version uint64
 }
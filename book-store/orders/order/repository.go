@@ -2,6 +2,7 @@ package order
 
 import (
 	"context"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/common/stored"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
@@ -9,14 +10,16 @@ import (
 
 // CreateDTO is DTO for creating order
 type CreateDTO struct {
-	Description string
-	BookID      uuid.UUID
+	Description string    `json:"description"`
+	BookID      uuid.UUID `json:"bookID"`
 }
 
 // DTO is DTO of order
 type DTO struct {
-	ID uuid.UUID
+	ID uuid.UUID `json:"id"`
 	CreateDTO
+	// Version is incremented on every change of order, on update it is expected current version, zero skips the check
+	Version uint64 `json:"version"`
 }
 
 // StoredOrderDTO is order that is stored
@@ -30,8 +33,15 @@ type Repository interface {
 	GetAll(ctx context.Context) ([]DTO, error)
 	Get(ctx context.Context, id uuid.UUID) (DTO, error)
 	Create(ctx context.Context, dto CreateDTO) (DTO, error)
+	// Update updates order, returns *commonerrors.VersionMismatch if version of dto is set and does not match stored one
 	Update(ctx context.Context, dto DTO) (DTO, error)
 	Delete(ctx context.Context, id uuid.UUID) (DTO, error)
+	// GetDeleted gets count deleted orders from some number, the latest deleted first, all of them if count is 0
+	GetDeleted(ctx context.Context, from, count uint) ([]StoredOrderDTO, error)
+	// Restore undeletes order, returns *commonerrors.NotFound if there is no deleted order with id
+	Restore(ctx context.Context, id uuid.UUID) (DTO, error)
+	// Purge permanently removes orders deleted before given time
+	Purge(ctx context.Context, before time.Time) (uint64, error)
 }
 
 // ToOrderDTO converts stored version to general DTO
@@ -42,5 +52,6 @@ func (s StoredOrderDTO) ToOrderDTO() DTO {
 			Description: s.Description,
 			BookID:      s.BookID,
 		},
+		Version: s.Version,
 	}
 }
@@ -2,10 +2,15 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"time"
 
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
 	"github.com/Vesninovich/go-tasks/book-store/common/book"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
 	catalogservice "github.com/Vesninovich/go-tasks/book-store/orders/catalog/service"
 	"github.com/Vesninovich/go-tasks/book-store/orders/order"
 )
@@ -14,11 +19,22 @@ import (
 type Service struct {
 	repo    order.Repository
 	catalog *catalogservice.Service
+	audit   *audit.Recorder
+	hooks   *webhook.Dispatcher
 }
 
+// auditEntity is name of orders in audit trail
+const auditEntity = "order"
+
 // New creates new instance of Service
 func New(r order.Repository, c *catalogservice.Service) *Service {
-	return &Service{r, c}
+	return &Service{repo: r, catalog: c}
+}
+
+// UseAudit makes service record every change of orders with given recorder
+// Entries are saved in transactions of changes, so change fails if its entry can not be saved.
+func (s *Service) UseAudit(r *audit.Recorder) {
+	s.audit = r
 }
 
 // GetOrder reads stored order by id
@@ -40,6 +56,7 @@ func (s *Service) GetOrder(ctx context.Context, id uuid.UUID) (order.Order, erro
 		ID:          dto.ID,
 		Description: dto.Description,
 		Book:        book,
+		Version:     dto.Version,
 	}, err
 }
 
@@ -56,18 +73,26 @@ func (s *Service) CreateOrder(ctx context.Context, data order.CreateDTO) (order.
 	if err != nil {
 		return empty, err
 	}
-	res, err := s.repo.Create(ctx, data)
+	var res order.DTO
+	err = s.audit.InTransaction(ctx, func(ctx context.Context) (err error) {
+		if res, err = s.repo.Create(ctx, data); err != nil {
+			return
+		}
+		return s.audit.Record(ctx, auditEntity, res.ID.String(), audit.Create, nil, res)
+	})
 	if err != nil {
 		return empty, err
 	}
+	s.hooks.Publish(ctx, webhook.Event{Type: EventCreated, Data: res})
 	return order.Order{
 		ID:          res.ID,
 		Description: res.Description,
 		Book:        b,
+		Version:     res.Version,
 	}, err
 }
 
-// UpdateDescription updates description
+// UpdateDescription updates description, non-zero version of data must match current version of order
 func (s *Service) UpdateDescription(ctx context.Context, data order.Order) (order.Order, error) {
 	var empty order.Order
 	if data.ID.IsZero() {
@@ -80,27 +105,43 @@ func (s *Service) UpdateDescription(ctx context.Context, data order.Order) (orde
 	if err != nil {
 		return empty, err
 	}
+	if data.Version != 0 && data.Version != o.Version {
+		return empty, &commonerrors.VersionMismatch{
+			What: fmt.Sprintf("Order with ID %s", o.ID), Expected: data.Version, Actual: o.Version,
+		}
+	}
 	if o.Description == data.Description {
 		return order.Order{
 			ID:          o.ID,
 			Description: o.Description,
 			Book:        book.Book{ID: o.BookID},
+			Version:     o.Version,
 		}, err
 	}
-	res, err := s.repo.Update(ctx, order.DTO{
-		ID: data.ID,
-		CreateDTO: order.CreateDTO{
-			Description: data.Description,
-			BookID:      o.BookID,
-		},
+	var res order.DTO
+	err = s.audit.InTransaction(ctx, func(ctx context.Context) (err error) {
+		res, err = s.repo.Update(ctx, order.DTO{
+			ID: data.ID,
+			CreateDTO: order.CreateDTO{
+				Description: data.Description,
+				BookID:      o.BookID,
+			},
+			Version: data.Version,
+		})
+		if err != nil {
+			return
+		}
+		return s.audit.Record(ctx, auditEntity, res.ID.String(), audit.Update, o, res)
 	})
 	if err != nil {
 		return empty, err
 	}
+	s.hooks.Publish(ctx, webhook.Event{Type: EventUpdated, Data: res})
 	return order.Order{
 		ID:          res.ID,
 		Description: res.Description,
 		Book:        book.Book{ID: res.BookID},
+		Version:     res.Version,
 	}, err
 }
 
@@ -110,13 +151,84 @@ func (s *Service) RemoveOrder(ctx context.Context, id uuid.UUID) (order.Order, e
 	if id.IsZero() {
 		return empty, &commonerrors.InvalidInput{Reason: "ID is required"}
 	}
-	res, err := s.repo.Delete(ctx, id)
+	var res order.DTO
+	err := s.audit.InTransaction(ctx, func(ctx context.Context) (err error) {
+		if res, err = s.repo.Delete(ctx, id); err != nil {
+			return
+		}
+		return s.audit.Record(ctx, auditEntity, res.ID.String(), audit.Delete, res, nil)
+	})
+	if err != nil {
+		return empty, err
+	}
+	s.hooks.Publish(ctx, webhook.Event{Type: EventDeleted, Data: res})
+	return order.Order{
+		ID:          res.ID,
+		Description: res.Description,
+		Book:        book.Book{ID: res.BookID},
+		Version:     res.Version,
+	}, err
+}
+
+// GetDeletedOrders fetches count deleted orders from some number, the latest deleted first
+func (s *Service) GetDeletedOrders(ctx context.Context, from, count uint) ([]order.StoredOrderDTO, error) {
+	if count == 0 {
+		count = 10
+	}
+	return s.repo.GetDeleted(ctx, from, count)
+}
+
+// RestoreOrder undeletes stored order by id
+func (s *Service) RestoreOrder(ctx context.Context, id uuid.UUID) (order.Order, error) {
+	var empty order.Order
+	if id.IsZero() {
+		return empty, &commonerrors.InvalidInput{Reason: "ID is required"}
+	}
+	var res order.DTO
+	err := s.audit.InTransaction(ctx, func(ctx context.Context) (err error) {
+		if res, err = s.repo.Restore(ctx, id); err != nil {
+			return
+		}
+		return s.audit.Record(ctx, auditEntity, res.ID.String(), audit.Restore, nil, res)
+	})
 	if err != nil {
 		return empty, err
 	}
+	s.hooks.Publish(ctx, webhook.Event{Type: EventRestored, Data: res})
 	return order.Order{
 		ID:          res.ID,
 		Description: res.Description,
 		Book:        book.Book{ID: res.BookID},
+		Version:     res.Version,
 	}, err
 }
+
+// PurgeOrders permanently removes orders deleted before given time
+func (s *Service) PurgeOrders(ctx context.Context, before time.Time) (n uint64, err error) {
+	err = s.audit.InTransaction(ctx, func(ctx context.Context) (err error) {
+		if n, err = s.repo.Purge(ctx, before); err != nil || n == 0 {
+			return
+		}
+		return s.audit.Record(ctx, auditEntity, "", audit.Purge, nil, audit.Purged{DeletedBefore: before, Count: n})
+	})
+	return
+}
+
+// RunPurges purges orders deleted more than `retention` ago every `interval` until context is done
+func (s *Service) RunPurges(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.PurgeOrders(ctx, time.Now().Add(-retention))
+			if err != nil {
+				log.Printf("Failed to purge deleted orders: %s", err)
+			} else if n != 0 {
+				log.Printf("Purged %d orders", n)
+			}
+		}
+	}
+}
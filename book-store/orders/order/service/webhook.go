@@ -0,0 +1,19 @@
+package service
+
+import "github.com/Vesninovich/go-tasks/book-store/common/webhook"
+
+// Webhook events of orders
+const (
+	EventCreated  = "order.created"
+	EventUpdated  = "order.updated"
+	EventDeleted  = "order.deleted"
+	EventRestored = "order.restored"
+)
+
+// WebhookEvents are events webhook subscriptions may be made to
+var WebhookEvents = []string{EventCreated, EventUpdated, EventDeleted, EventRestored}
+
+// UseWebhooks makes service notify webhook subscriptions about every change of orders with given dispatcher
+func (s *Service) UseWebhooks(d *webhook.Dispatcher) {
+	s.hooks = d
+}
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/sqltx"
+	"github.com/Vesninovich/go-tasks/book-store/common/stored"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
 	"github.com/Vesninovich/go-tasks/book-store/orders/order"
 	"github.com/jmoiron/sqlx"
@@ -22,6 +24,14 @@ type fromDB struct {
 	ID          string
 	Description string
 	BookID      string `db:"book_id"`
+	Version     uint64
+}
+
+type deletedFromDB struct {
+	fromDB
+	CreatedAt sql.NullTime `db:"created_at"`
+	UpdatedAt sql.NullTime `db:"updated_at"`
+	DeletedAt time.Time    `db:"deleted_at"`
 }
 
 // New creates a new instance of Repository
@@ -31,20 +41,23 @@ func New(db *sqlx.DB, schema string) *Repository {
 
 // CreateTableStmt of orders
 func (r *Repository) CreateTableStmt() string {
-	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s.orders(
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s.orders(
   id uuid PRIMARY KEY,
   description text NOT NULL,
 	book_id uuid,
   created_at timestamp,
   updated_at timestamp,
-  deleted_at timestamp
-);`, r.schema)
+  deleted_at timestamp,
+  version bigint NOT NULL DEFAULT 1
+);
+
+ALTER TABLE %[1]s.orders ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;`, r.schema)
 }
 
 // GetAll gets all non-deleted orders
 func (r *Repository) GetAll(ctx context.Context) (orders []order.DTO, err error) {
 	data := []fromDB{}
-	err = r.db.SelectContext(ctx, &data, fmt.Sprintf("SELECT id, description, book_id FROM %s.orders WHERE deleted_at=$1;", r.schema), time.Time{})
+	err = sqltx.DB(ctx, r.db).SelectContext(ctx, &data, fmt.Sprintf("SELECT id, description, book_id, version FROM %s.orders WHERE deleted_at=$1;", r.schema), time.Time{})
 	if err != nil {
 		return
 	}
@@ -63,8 +76,8 @@ func (r *Repository) GetAll(ctx context.Context) (orders []order.DTO, err error)
 // Get gets non-deleted order by ID
 func (r *Repository) Get(ctx context.Context, id uuid.UUID) (order.DTO, error) {
 	o := fromDB{}
-	err := r.db.GetContext(
-		ctx, &o, fmt.Sprintf("SELECT id, description, book_id FROM %s.orders WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
+	err := sqltx.DB(ctx, r.db).GetContext(
+		ctx, &o, fmt.Sprintf("SELECT id, description, book_id, version FROM %s.orders WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
 	)
 	if err == sql.ErrNoRows {
 		return order.DTO{}, &commonerrors.NotFound{What: fmt.Sprintf("Order with ID %s", id)}
@@ -78,7 +91,7 @@ func (r *Repository) Get(ctx context.Context, id uuid.UUID) (order.DTO, error) {
 // Create stores new order
 func (r *Repository) Create(ctx context.Context, dto order.CreateDTO) (order.DTO, error) {
 	id := uuid.New()
-	_, err := r.db.ExecContext(
+	_, err := sqltx.DB(ctx, r.db).ExecContext(
 		ctx,
 		fmt.Sprintf(`INSERT INTO %s.orders (id, description, book_id, created_at, updated_at, deleted_at)
 			VALUES ($1, $2, $3, $4, $5, $6)`, r.schema),
@@ -87,38 +100,53 @@ func (r *Repository) Create(ctx context.Context, dto order.CreateDTO) (order.DTO
 	return order.DTO{
 		ID:        id,
 		CreateDTO: dto,
+		Version:   1,
 	}, err
 }
 
-// Update updates stored non-deleted order
+// Update updates stored non-deleted order if it is still at expected version
 func (r *Repository) Update(ctx context.Context, dto order.DTO) (order.DTO, error) {
-	res, err := r.db.ExecContext(
+	var version uint64
+	err := sqltx.DB(ctx, r.db).QueryRowxContext(
 		ctx,
 		fmt.Sprintf(`UPDATE %s.orders
-			SET description=$3, book_id=$4, updated_at=$5
-			WHERE id=$1 AND deleted_at=$2;`, r.schema),
-		dto.ID.String(), time.Time{}, dto.Description, dto.BookID.String(), time.Now(),
-	)
-	if err != nil {
-		return order.DTO{}, err
+			SET description=$3, book_id=$4, updated_at=$5, version=version+1
+			WHERE id=$1 AND deleted_at=$2 AND (version=$6 OR $6=0)
+			RETURNING version;`, r.schema),
+		dto.ID.String(), time.Time{}, dto.Description, dto.BookID.String(), time.Now(), dto.Version,
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return order.DTO{}, r.updateError(ctx, dto.ID, dto.Version)
 	}
-	count, err := res.RowsAffected()
 	if err != nil {
 		return order.DTO{}, err
 	}
-	if count == 0 {
-		return order.DTO{}, &commonerrors.NotFound{What: fmt.Sprintf("Order with ID %s", dto.ID)}
+	dto.Version = version
+	return dto, nil
+}
+
+// updateError tells why conditional update of order changed nothing
+func (r *Repository) updateError(ctx context.Context, id uuid.UUID, expected uint64) error {
+	var actual uint64
+	err := sqltx.DB(ctx, r.db).GetContext(
+		ctx, &actual, fmt.Sprintf("SELECT version FROM %s.orders WHERE id=$1 AND deleted_at=$2;", r.schema), id.String(), time.Time{},
+	)
+	if err == sql.ErrNoRows {
+		return &commonerrors.NotFound{What: fmt.Sprintf("Order with ID %s", id)}
+	}
+	if err != nil {
+		return err
 	}
-	return dto, err
+	return &commonerrors.VersionMismatch{What: fmt.Sprintf("Order with ID %s", id), Expected: expected, Actual: actual}
 }
 
 // Delete sets stored order with id as deleted
 func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (order.DTO, error) {
 	var o fromDB
-	err := r.db.GetContext(
+	err := sqltx.DB(ctx, r.db).GetContext(
 		ctx,
 		&o,
-		fmt.Sprintf(`SELECT id, description, book_id FROM %s.orders WHERE id=$1 AND deleted_at=$2;`, r.schema),
+		fmt.Sprintf(`SELECT id, description, book_id, version FROM %s.orders WHERE id=$1 AND deleted_at=$2;`, r.schema),
 		id.String(), time.Time{},
 	)
 	if err == sql.ErrNoRows {
@@ -127,10 +155,10 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (order.DTO, error
 	if err != nil {
 		return order.DTO{}, err
 	}
-	res, err := r.db.ExecContext(
+	res, err := sqltx.DB(ctx, r.db).ExecContext(
 		ctx,
 		fmt.Sprintf(`UPDATE %s.orders
-			SET deleted_at=$2
+			SET deleted_at=$2, version=version+1
 			WHERE id=$1 AND deleted_at=$3;`, r.schema),
 		id.String(), time.Now(), time.Time{},
 	)
@@ -144,9 +172,79 @@ func (r *Repository) Delete(ctx context.Context, id uuid.UUID) (order.DTO, error
 	if count == 0 {
 		return order.DTO{}, &commonerrors.NotFound{What: fmt.Sprintf("Order with ID %s", id)}
 	}
+	o.Version++
+	return o.toDTO()
+}
+
+// GetDeleted gets deleted orders, the latest deleted first
+func (r *Repository) GetDeleted(ctx context.Context, from, count uint) (orders []order.StoredOrderDTO, err error) {
+	stmt := fmt.Sprintf(`SELECT id, description, book_id, version, created_at, updated_at, deleted_at FROM %s.orders
+		WHERE deleted_at<>$1
+		ORDER BY deleted_at DESC
+		OFFSET $2 ROWS`, r.schema)
+	if count != 0 {
+		stmt += fmt.Sprintf(`
+		LIMIT %d`, count)
+	}
+	data := []deletedFromDB{}
+	err = sqltx.DB(ctx, r.db).SelectContext(ctx, &data, stmt, time.Time{}, from)
+	if err != nil {
+		return
+	}
+	var dto order.DTO
+	orders = make([]order.StoredOrderDTO, len(data))
+	for i, item := range data {
+		dto, err = item.toDTO()
+		if err != nil {
+			return
+		}
+		orders[i] = order.StoredOrderDTO{
+			DTO: dto,
+			Stored: stored.Stored{
+				CreatedAt: item.CreatedAt.Time,
+				UpdatedAt: item.UpdatedAt.Time,
+				DeletedAt: item.DeletedAt,
+			},
+		}
+	}
+	return
+}
+
+// Restore undeletes stored order with id
+func (r *Repository) Restore(ctx context.Context, id uuid.UUID) (order.DTO, error) {
+	var o fromDB
+	err := sqltx.DB(ctx, r.db).QueryRowxContext(
+		ctx,
+		fmt.Sprintf(`UPDATE %s.orders
+			SET deleted_at=$2, updated_at=$3, version=version+1
+			WHERE id=$1 AND deleted_at<>$2
+			RETURNING id, description, book_id, version;`, r.schema),
+		id.String(), time.Time{}, time.Now(),
+	).Scan(&o.ID, &o.Description, &o.BookID, &o.Version)
+	if err == sql.ErrNoRows {
+		return order.DTO{}, &commonerrors.NotFound{What: fmt.Sprintf("Deleted order with ID %s", id)}
+	}
+	if err != nil {
+		return order.DTO{}, err
+	}
 	return o.toDTO()
 }
 
+// Purge removes orders deleted before given time
+func (r *Repository) Purge(ctx context.Context, before time.Time) (uint64, error) {
+	res, err := sqltx.DB(ctx, r.db).ExecContext(
+		ctx,
+		fmt.Sprintf(`DELETE FROM %s.orders
+			WHERE deleted_at<>$1 AND deleted_at<$2;`, r.schema),
+		time.Time{}, before,
+	)
+	if err != nil {
+		return 0, err
+	}
+	count, err := res.RowsAffected()
+	return uint64(count), err
+}
+
 func (f fromDB) toDTO() (order.DTO, error) {
 	id, err := uuid.FromString(f.ID)
 	if err != nil {
@@ -159,5 +257,6 @@ func (f fromDB) toDTO() (order.DTO, error) {
 			Description: f.Description,
 			BookID:      bID,
 		},
+		Version: f.Version,
 	}, err
 }
@@ -3,6 +3,7 @@ package tests
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
@@ -129,6 +130,34 @@ func RepoUpdateWithSomeDeleted(t *testing.T, c Constructor) {
 	}
 }
 
+// RepoUpdateVersion tests that item is only updated at expected version
+func RepoUpdateVersion(t *testing.T, c Constructor) {
+	repo, stored := setupMutation(t, c)
+	item := stored[0]
+	if item.Version == 0 {
+		t.Fatal("Expected stored item to have version")
+	}
+	item.Description = "changed"
+	updated, err := repo.Update(ctx, item)
+	if err != nil {
+		t.Fatalf("Error while updating item at expected version: %s", err)
+	}
+	if updated.Version != item.Version+1 {
+		t.Errorf("Expected version to be incremented to %d, got %d", item.Version+1, updated.Version)
+	}
+	_, err = repo.Update(ctx, item)
+	if err == nil {
+		t.Fatal("Expected to get VersionMismatch error")
+	}
+	if _, typeCorrect := err.(*commonerrors.VersionMismatch); !typeCorrect {
+		t.Errorf("Expected to get error of type *commonerrors.VersionMismatch, got %T", err)
+	}
+	item.Version = 0
+	if _, err = repo.Update(ctx, item); err != nil {
+		t.Errorf("Error while updating item without version: %s", err)
+	}
+}
+
 // RepoDelete tests deleting item
 func RepoDelete(t *testing.T, c Constructor) {
 	repo, stored := setupMutation(t, c)
@@ -156,6 +185,68 @@ func RepoDeleteNonExisting(t *testing.T, c Constructor) {
 	checkNotFound(t, err)
 }
 
+// RepoGetDeleted tests getting deleted items
+func RepoGetDeleted(t *testing.T, c Constructor) {
+	repo, id, _ := setupAlreadyDeleted(t, c)
+	deleted, err := repo.GetDeleted(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("Error while getting deleted items: %s", err)
+	}
+	if len(deleted) != 1 || deleted[0].ID != id || !deleted[0].IsDeleted() {
+		t.Errorf("Expected to get deleted item %s, got %v", id, deleted)
+	}
+	deleted, err = repo.GetDeleted(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Error while getting deleted items: %s", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("Expected to get no deleted items from 1, got %d", len(deleted))
+	}
+}
+
+// RepoRestore tests restoring deleted item
+func RepoRestore(t *testing.T, c Constructor) {
+	repo, id, stored := setupAlreadyDeleted(t, c)
+	restored, err := repo.Restore(ctx, id)
+	if err != nil {
+		t.Fatalf("Error while restoring item: %s", err)
+	}
+	if restored.ID != id || restored.BookID != stored[0].BookID || restored.Version != stored[0].Version+2 {
+		t.Errorf("Expected to restore item with incremented version, got %v", restored)
+	}
+	if _, err = repo.Get(ctx, id); err != nil {
+		t.Errorf("Error while getting restored item: %s", err)
+	}
+	_, err = repo.Restore(ctx, id)
+	checkNotFound(t, err)
+	_, err = repo.Restore(ctx, uuid.New())
+	checkNotFound(t, err)
+}
+
+// RepoPurge tests permanently removing deleted items
+func RepoPurge(t *testing.T, c Constructor) {
+	repo, id, _ := setupAlreadyDeleted(t, c)
+	purged, err := repo.Purge(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Error while purging items: %s", err)
+	}
+	if purged != 0 {
+		t.Errorf("Expected recently deleted item to be kept, got %d purged", purged)
+	}
+	purged, err = repo.Purge(ctx, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Error while purging items: %s", err)
+	}
+	if purged != 1 {
+		t.Errorf("Expected to purge 1 item, got %d", purged)
+	}
+	_, err = repo.Restore(ctx, id)
+	checkNotFound(t, err)
+	if all, _ := repo.GetAll(ctx); len(all) != len(orders)-1 {
+		t.Errorf("Expected not deleted items to be kept, got %d", len(all))
+	}
+}
+
 func findByDescription(name string, data []order.DTO, t *testing.T) order.DTO {
 	for _, item := range data {
 		if item.Description == name {
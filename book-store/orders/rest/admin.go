@@ -0,0 +1,214 @@
+package rest
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+)
+
+// adminURL is base URL of endpoints managing deleted orders, audit trail, webhooks and catalog cache
+const adminURL = "/admin"
+
+type deletedAPIModel struct {
+	apiModel
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+type purgeAPIModel struct {
+	Orders uint64 `json:"orders"`
+}
+
+func (s *Server) handleAdminEndpoints(serveMux *http.ServeMux) {
+	restorePath := regexp.MustCompile(adminURL + "/order/" + uuid.REGEX + "/restore$")
+	webhookPath := regexp.MustCompile(adminURL + "/webhook/\\d+$")
+	deliveriesPath := regexp.MustCompile(adminURL + "/webhook/\\d+/deliveries$")
+	serveMux.HandleFunc(adminURL+"/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == adminURL+"/order/deleted":
+			s.getDeletedOrders(w, r)
+		case r.Method == http.MethodGet && r.URL.Path == adminURL+"/audit" && s.audit != nil:
+			s.getAudit(w, r)
+		case r.Method == http.MethodPost && r.URL.Path == adminURL+"/purge":
+			s.purge(w, r)
+		case r.Method == http.MethodPost && restorePath.MatchString(r.URL.Path):
+			s.restoreOrder(w, r)
+		case r.Method == http.MethodGet && r.URL.Path == adminURL+"/webhook" && s.hooks != nil:
+			s.getWebhooks(w, r)
+		case r.Method == http.MethodPost && r.URL.Path == adminURL+"/webhook" && s.hooks != nil:
+			s.createWebhook(w, r)
+		case r.Method == http.MethodDelete && webhookPath.MatchString(r.URL.Path) && s.hooks != nil:
+			s.deleteWebhook(w, r)
+		case r.Method == http.MethodGet && deliveriesPath.MatchString(r.URL.Path) && s.hooks != nil:
+			s.getDeliveries(w, r)
+		case r.Method == http.MethodGet && r.URL.Path == adminURL+"/catalog/cache" && s.catalog != nil:
+			s.getCacheStats(w, r)
+		default:
+			writeNotFound(w)
+		}
+	})
+}
+
+// getDeletedOrders godoc
+// @Summary get deleted orders
+// @Description get deleted orders, the latest deleted first
+// @Tags Admin
+// @Produce json
+// @Param from query string false "results start"
+// @Param count query string false "results count, 10 by default"
+// @Success 200 {object} []deletedAPIModel "results"
+// @Failure 400 {string} string "malformed query"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/order/deleted [get]
+func (s *Server) getDeletedOrders(w http.ResponseWriter, r *http.Request) {
+	from, count, err := parsePagination(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	orders, err := s.service.GetDeletedOrders(r.Context(), from, count)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	models := make([]deletedAPIModel, len(orders))
+	for i, o := range orders {
+		models[i] = deletedAPIModel{
+			apiModel: apiModel{
+				ID:          o.ID.String(),
+				Description: o.Description,
+				BookID:      o.BookID.String(),
+			},
+			DeletedAt: o.DeletedAt,
+		}
+	}
+	writeJSON(w, models)
+}
+
+// restoreOrder godoc
+// @Summary restore order
+// @Description undelete order
+// @Tags Admin
+// @Produce json
+// @Param id path string true "order id"
+// @Success 200 {object} apiModel "restored order"
+// @Header 200 {string} ETag "order version"
+// @Failure 400 {string} string "malformed id"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 404 {string} string "deleted order not found"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/order/{id}/restore [post]
+func (s *Server) restoreOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := getUUIDFromURL(strings.TrimSuffix(r.URL.Path, "/restore"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	o, err := s.service.RestoreOrder(r.Context(), id)
+	writeResponse(w, o, err)
+}
+
+// purge godoc
+// @Summary purge deleted orders
+// @Description permanently remove orders deleted before given time
+// @Tags Admin
+// @Produce json
+// @Param before query string true "RFC 3339 time, orders deleted before it are removed"
+// @Success 200 {object} purgeAPIModel "number of removed orders"
+// @Failure 400 {string} string "malformed time"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/purge [post]
+func (s *Server) purge(w http.ResponseWriter, r *http.Request) {
+	param := r.URL.Query().Get("before")
+	if param == "" {
+		writeError(w, http.StatusBadRequest, errors.New("before is required"))
+		return
+	}
+	before, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	n, err := s.service.PurgeOrders(r.Context(), before)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, purgeAPIModel{Orders: n})
+}
+
+// getAudit godoc
+// @Summary get audit trail
+// @Description get changes of orders with who made them and changed fields, the latest first
+// @Tags Admin
+// @Produce json
+// @Param id query string false "order id"
+// @Param since query string false "RFC 3339 time, changes made before it are skipped"
+// @Param until query string false "RFC 3339 time, changes made at it or later are skipped"
+// @Param from query string false "results start"
+// @Param count query string false "results count, 10 by default"
+// @Success 200 {object} []audit.Entry "results"
+// @Failure 400 {string} string "malformed query"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/audit [get]
+func (s *Server) getAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, count, err := parsePagination(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	f, err := audit.FilterFromQuery(q)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	entries, err := s.audit.Find(r.Context(), f, from, count)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, entries)
+}
+
+func parsePagination(params url.Values) (from, count uint, err error) {
+	var val uint64
+	param := params.Get("from")
+	if param != "" {
+		val, err = strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return
+		}
+		from = uint(val)
+	}
+	param = params.Get("count")
+	if param != "" {
+		val, err = strconv.ParseUint(param, 10, 64)
+		if err != nil {
+			return
+		}
+		count = uint(val)
+	}
+	return
+}
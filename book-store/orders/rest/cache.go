@@ -0,0 +1,41 @@
+package rest
+
+import (
+	"net/http"
+
+	catalogservice "github.com/Vesninovich/go-tasks/book-store/orders/catalog/service"
+)
+
+type cacheStatsAPIModel struct {
+	Hits uint64 `json:"hits"`
+	// StaleHits are stale books returned while they are revalidated
+	StaleHits uint64 `json:"staleHits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// UseCatalogCache makes server report metrics of cache of catalog books
+func (s *Server) UseCatalogCache(c *catalogservice.Service) {
+	s.catalog = c
+}
+
+// getCacheStats godoc
+// @Summary get catalog cache stats
+// @Description get counters of cache of catalog books since start of service
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} cacheStatsAPIModel "cache stats"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/catalog/cache [get]
+func (s *Server) getCacheStats(w http.ResponseWriter, r *http.Request) {
+	stats := s.catalog.CacheStats()
+	writeJSON(w, cacheStatsAPIModel{
+		Hits:      stats.Hits,
+		StaleHits: stats.StaleHits,
+		Misses:    stats.Misses,
+		Evictions: stats.Evictions,
+	})
+}
@@ -1,28 +1,38 @@
 package rest
 
 import (
-	"context"
+	"crypto/tls"
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"regexp"
 	"strings"
 
+	"github.com/Vesninovich/go-tasks/book-store/common/audit"
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
 	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/etag"
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
 	"github.com/Vesninovich/go-tasks/book-store/common/uuid"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+	catalogservice "github.com/Vesninovich/go-tasks/book-store/orders/catalog/service"
 	_ "github.com/Vesninovich/go-tasks/book-store/orders/docs" // generated docs
 	"github.com/Vesninovich/go-tasks/book-store/orders/order"
 	orderservice "github.com/Vesninovich/go-tasks/book-store/orders/order/service"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-var ctx = context.Background()
-
 // Server of orders
 type Server struct {
 	service *orderservice.Service
 	baseURL string
 	host    string
+	tls     *tls.Config
+	auth    *auth.Middleware
+	idem    *idempotency.Guard
+	audit   *audit.Recorder
+	hooks   *webhook.Dispatcher
+	catalog *catalogservice.Service
 }
 
 type apiModel struct {
@@ -49,14 +59,46 @@ func New(host, baseURL string, s *orderservice.Service) *Server {
 	}
 }
 
+// UseTLS makes server serve HTTPS with given config
+func (s *Server) UseTLS(c *tls.Config) {
+	s.tls = c
+}
+
+// UseAuth makes server authenticate and authorize requests with given middleware
+func (s *Server) UseAuth(m *auth.Middleware) {
+	s.auth = m
+}
+
+// UseIdempotency makes server replay responses to create requests repeated with the same Idempotency-Key header
+func (s *Server) UseIdempotency(g *idempotency.Guard) {
+	s.idem = g
+}
+
+// UseAudit makes server serve audit trail saved by given recorder
+func (s *Server) UseAudit(r *audit.Recorder) {
+	s.audit = r
+}
+
 // Start builds HTTP server for application and attempts to start it on given host.
 // Created server serves requests starting from given `baseURL`.
 func (s *Server) Start() error {
 	serveMux := http.NewServeMux()
 	s.handleTaskEndpoints(serveMux)
+	s.handleAdminEndpoints(serveMux)
 	var server http.Server
 	server.Handler = serveMux
+	if s.idem != nil {
+		// only creation of entities is guarded, admin actions are idempotent by themselves
+		server.Handler = s.idem.Middleware(s.baseURL)(server.Handler)
+	}
+	if s.auth != nil {
+		server.Handler = s.auth.Wrap(server.Handler)
+	}
 	server.Addr = s.host
+	if s.tls != nil {
+		server.TLSConfig = s.tls
+		return server.ListenAndServeTLS("", "")
+	}
 	err := server.ListenAndServe()
 	return err
 }
@@ -96,14 +138,20 @@ func (s *Server) handleTaskEndpoints(serveMux *http.ServeMux) {
 
 // GetOrder godoc
 // @Summary get order
-// @Description get order by id
+// @Description get order by id, version of order is sent in ETag header
 // @Tags Order
 // @Produce json
 // @Param id path string true "order id"
 // @Success 200 {object} apiModel "requested order"
+// @Header 200 {string} ETag "order version"
 // @Failure 400 {string} string "malformed id"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
 // @Failure 404 {string} string "requested order not found"
 // @Failure 500 {string} string "internal error"
+// @Failure 503 {string} string "catalog is unavailable"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /order/{id} [get]
 func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
 	id, err := getUUIDFromURL(r.URL.Path)
@@ -111,22 +159,30 @@ func (s *Server) getOrder(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	o, err := s.service.GetOrder(ctx, id)
+	o, err := s.service.GetOrder(r.Context(), id)
 	writeResponse(w, o, err)
 }
 
 // CreateOrder godoc
 // @Summary place order
-// @Description place new book order
+// @Description place new book order, response is replayed to requests repeated with the same Idempotency-Key header
 // @Tags Order
 // @Accept json
 // @Produce json
 // @Param id path string true "order id"
+// @Param Idempotency-Key header string false "key making retries of request safe"
 // @Param order body createAPIModel true "order data"
 // @Success 200 {object} apiModel "created order"
 // @Failure 400 {string} string "malformed book id"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
 // @Failure 404 {string} string "requested book not found"
+// @Failure 409 {string} string "request with the same idempotency key is in progress"
+// @Failure 422 {string} string "idempotency key was used for another request"
 // @Failure 500 {string} string "internal error"
+// @Failure 503 {string} string "catalog is unavailable"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /order [post]
 func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
 	body, err := ioutil.ReadAll(r.Body)
@@ -145,7 +201,7 @@ func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	o, err := s.service.CreateOrder(ctx, order.CreateDTO{
+	o, err := s.service.CreateOrder(r.Context(), order.CreateDTO{
 		Description: data.Description,
 		BookID:      bID,
 	})
@@ -154,16 +210,23 @@ func (s *Server) createOrder(w http.ResponseWriter, r *http.Request) {
 
 // UpdateDescription godoc
 // @Summary update description
-// @Description change order description
+// @Description change order description, it is only changed if order still has version from If-Match header when the header is set
 // @Tags Order
 // @Accept json
 // @Produce json
 // @Param id path string true "order id"
+// @Param If-Match header string false "expected order version from ETag"
 // @Param description body descUpdAPIModel true "new description"
 // @Success 200 {object} apiModel "updated order"
+// @Header 200 {string} ETag "order version"
 // @Failure 400 {string} string "malformed order id or bad data"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
 // @Failure 404 {string} string "requested order not found"
+// @Failure 412 {string} string "order was changed since expected version"
 // @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /order/{id} [put]
 func (s *Server) updateDescription(w http.ResponseWriter, r *http.Request) {
 	id, err := getUUIDFromURL(r.URL.Path)
@@ -171,6 +234,11 @@ func (s *Server) updateDescription(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	version, err := etag.ParseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
@@ -182,9 +250,10 @@ func (s *Server) updateDescription(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	o, err := s.service.UpdateDescription(ctx, order.Order{
+	o, err := s.service.UpdateDescription(r.Context(), order.Order{
 		ID:          id,
 		Description: data.Description,
+		Version:     version,
 	})
 	writeResponse(w, o, err)
 }
@@ -197,8 +266,12 @@ func (s *Server) updateDescription(w http.ResponseWriter, r *http.Request) {
 // @Param order path string true "order id"
 // @Success 200 {object} apiModel "removed order"
 // @Failure 400 {string} string "malformed order id"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
 // @Failure 404 {string} string "requested order not found"
 // @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
 // @Router /order/{id} [delete]
 func (s *Server) removeOrder(w http.ResponseWriter, r *http.Request) {
 	id, err := getUUIDFromURL(r.URL.Path)
@@ -206,7 +279,7 @@ func (s *Server) removeOrder(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	o, err := s.service.RemoveOrder(ctx, id)
+	o, err := s.service.RemoveOrder(r.Context(), id)
 	writeResponse(w, o, err)
 }
 
@@ -217,12 +290,7 @@ func getUUIDFromURL(url string) (uuid.UUID, error) {
 
 func writeResponse(w http.ResponseWriter, o order.Order, err error) {
 	if err != nil {
-		switch err.(type) {
-		case *commonerrors.NotFound:
-			writeError(w, http.StatusNotFound, err)
-		default:
-			writeError(w, http.StatusInternalServerError, err)
-		}
+		writeServiceError(w, err)
 		return
 	}
 	res, err := json.Marshal(orderToResponse(o))
@@ -231,6 +299,32 @@ func writeResponse(w http.ResponseWriter, o order.Order, err error) {
 		return
 	}
 	w.Header().Add("Content-Type", "application/json")
+	w.Header().Set("ETag", etag.Format(o.Version))
+	w.Write(res)
+}
+
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *commonerrors.NotFound:
+		writeError(w, http.StatusNotFound, err)
+	case *commonerrors.InvalidInput:
+		writeError(w, http.StatusBadRequest, err)
+	case *commonerrors.Unavailable:
+		writeError(w, http.StatusServiceUnavailable, err)
+	case *commonerrors.VersionMismatch:
+		writeError(w, http.StatusPreconditionFailed, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, data interface{}) {
+	res, err := json.Marshal(data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
 	w.Write(res)
 }
 
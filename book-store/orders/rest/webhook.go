@@ -0,0 +1,202 @@
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+)
+
+type createWebhookAPIModel struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret signing payloads, generated if not set
+	Secret string `json:"secret"`
+}
+
+type webhookAPIModel struct {
+	ID     uint64   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	// Secret is only sent in response to subscription
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type deliveryAPIModel struct {
+	ID       uint64            `json:"id"`
+	Event    string            `json:"event"`
+	Status   string            `json:"status"`
+	Payload  json.RawMessage   `json:"payload" swaggertype:"object"`
+	Attempts []attemptAPIModel `json:"attempts"`
+	// NextAttemptAt is only set for pending deliveries
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+}
+
+type attemptAPIModel struct {
+	// StatusCode of response, not set if request failed
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	Time       time.Time `json:"time"`
+}
+
+// UseWebhooks makes server manage webhook subscriptions of given dispatcher
+func (s *Server) UseWebhooks(d *webhook.Dispatcher) {
+	s.hooks = d
+}
+
+// getWebhooks godoc
+// @Summary get webhooks
+// @Description get webhook subscriptions to events of orders, their secrets are not sent
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} []webhookAPIModel "subscriptions"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/webhook [get]
+func (s *Server) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := s.hooks.Subscriptions(r.Context(), "")
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	models := make([]webhookAPIModel, len(subs))
+	for i, sub := range subs {
+		models[i] = webhookToResponse(sub)
+		models[i].Secret = ""
+	}
+	writeJSON(w, models)
+}
+
+// createWebhook godoc
+// @Summary create webhook
+// @Description subscribe URL to events of orders: order.created, order.updated, order.deleted or order.restored.
+// @Description Payloads are signed with secret, it is generated if not set and is only sent in this response.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param webhook body createWebhookAPIModel true "subscription data"
+// @Success 200 {object} webhookAPIModel "created subscription"
+// @Failure 400 {string} string "bad data"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/webhook [post]
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var data createWebhookAPIModel
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	sub, err := s.hooks.Subscribe(r.Context(), webhook.Subscription{URL: data.URL, Events: data.Events, Secret: data.Secret})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, webhookToResponse(sub))
+}
+
+// deleteWebhook godoc
+// @Summary delete webhook
+// @Description delete webhook subscription with its deliveries
+// @Tags Admin
+// @Param id path string true "subscription id"
+// @Success 200 "subscription deleted"
+// @Failure 400 {string} string "malformed id"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 404 {string} string "subscription not found"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/webhook/{id} [delete]
+func (s *Server) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = s.hooks.Unsubscribe(r.Context(), "", id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// getDeliveries godoc
+// @Summary get webhook deliveries
+// @Description get log of deliveries of webhook subscription with all attempts, the latest first
+// @Tags Admin
+// @Produce json
+// @Param id path string true "subscription id"
+// @Param from query string false "results start"
+// @Param count query string false "results count, 10 by default"
+// @Success 200 {object} []deliveryAPIModel "results"
+// @Failure 400 {string} string "malformed id or query"
+// @Failure 401 {string} string "not authenticated"
+// @Failure 403 {string} string "not allowed"
+// @Failure 404 {string} string "subscription not found"
+// @Failure 500 {string} string "internal error"
+// @Security ApiKeyAuth
+// @Security BearerAuth
+// @Router /admin/webhook/{id}/deliveries [get]
+func (s *Server) getDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(strings.TrimSuffix(r.URL.Path, "/deliveries"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	from, count, err := parsePagination(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ds, err := s.hooks.Deliveries(r.Context(), "", id, from, count)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	models := make([]deliveryAPIModel, len(ds))
+	for i, d := range ds {
+		models[i] = deliveryToResponse(d)
+	}
+	writeJSON(w, models)
+}
+
+func getIDFromURL(url string) (uint64, error) {
+	parts := strings.Split(url, "/")
+	return strconv.ParseUint(parts[len(parts)-1], 10, 64)
+}
+
+func webhookToResponse(s webhook.Subscription) webhookAPIModel {
+	return webhookAPIModel{s.ID, s.URL, s.Events, s.Secret, s.CreatedAt}
+}
+
+func deliveryToResponse(d webhook.Delivery) deliveryAPIModel {
+	m := deliveryAPIModel{
+		ID:        d.ID,
+		Event:     d.Event,
+		Status:    string(d.Status),
+		Payload:   d.Payload,
+		Attempts:  make([]attemptAPIModel, len(d.Attempts)),
+		CreatedAt: d.CreatedAt,
+	}
+	for i, a := range d.Attempts {
+		m.Attempts[i] = attemptAPIModel{a.StatusCode, a.Error, a.Duration.Milliseconds(), a.CreatedAt}
+	}
+	if d.Status == webhook.Pending {
+		next := d.NextAttemptAt
+		m.NextAttemptAt = &next
+	}
+	return m
+}
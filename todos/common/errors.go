@@ -1,5 +1,7 @@
 package common
 
+import "fmt"
+
 // InvalidInputError represents error on invalid input from user
 type InvalidInputError struct {
 	Reason string
@@ -17,3 +19,23 @@ type NotFoundError struct {
 func (e *NotFoundError) Error() string {
 	return e.What + " not found"
 }
+
+// ForbiddenError represents error that user is not allowed to do something
+type ForbiddenError struct {
+	Reason string
+}
+
+func (e *ForbiddenError) Error() string {
+	return "Forbidden: " + e.Reason
+}
+
+// VersionMismatchError represents error that something was changed since the version client based its change on
+type VersionMismatchError struct {
+	What     string
+	Expected uint64
+	Actual   uint64
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("%s has version %d, expected %d", e.What, e.Actual, e.Expected)
+}
@@ -2,4 +2,9 @@ module github.com/Vesninovich/go-tasks/todos
 
 go 1.14
 
-require github.com/jackc/pgx/v4 v4.11.0
+require (
+	github.com/Vesninovich/go-tasks/book-store/common v0.0.0-00010101000000-000000000000
+	github.com/jackc/pgx/v4 v4.11.0
+)
+
+replace github.com/Vesninovich/go-tasks/book-store/common => ../book-store/common
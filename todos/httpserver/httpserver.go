@@ -4,21 +4,38 @@ import (
 	"net/http"
 	"regexp"
 
+	"github.com/Vesninovich/go-tasks/todos/openapi"
 	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/webhook"
 )
 
+// Middleware wraps handler of all requests, e.g. to authenticate them
+type Middleware func(http.Handler) http.Handler
+
 // StartServer builds HTTP server for application and attempts to start it on given host.
 // Created server serves requests starting from given `baseURL`.
-func StartServer(host, baseURL string, taskServer task.TasksServer) (*http.Server, error) {
-	serveMux := http.NewServeMux()
-	handleTaskEndpoints(serveMux, taskServer, baseURL+"/task")
+// Middleware is applied in given order, so the first one sees request first.
+func StartServer(host, baseURL string, taskServer task.TasksServer, webhookServer webhook.WebhooksServer, middleware ...Middleware) (*http.Server, error) {
 	var server http.Server
-	server.Handler = serveMux
+	server.Handler = newServeMux(baseURL, taskServer, webhookServer)
+	for i := len(middleware) - 1; i >= 0; i-- {
+		server.Handler = middleware[i](server.Handler)
+	}
 	server.Addr = host
 	err := server.ListenAndServe()
 	return &server, err
 }
 
+func newServeMux(baseURL string, taskServer task.TasksServer, webhookServer webhook.WebhooksServer) *http.ServeMux {
+	serveMux := http.NewServeMux()
+	handleTaskEndpoints(serveMux, taskServer, baseURL+"/task")
+	handleICSEndpoints(serveMux, taskServer, baseURL+"/task")
+	handleTagEndpoints(serveMux, taskServer, baseURL+"/tags")
+	handleWebhookEndpoints(serveMux, webhookServer, baseURL+"/webhooks")
+	handleDocsEndpoints(serveMux, baseURL+"/docs")
+	return serveMux
+}
+
 func handleTaskEndpoints(serveMux *http.ServeMux, taskServer task.TasksServer, baseURL string) {
 	serveMux.HandleFunc(baseURL, func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -31,20 +48,206 @@ func handleTaskEndpoints(serveMux *http.ServeMux, taskServer task.TasksServer, b
 		}
 	})
 
-	validPath := regexp.MustCompile(baseURL + "/\\d+$")
+	batchPath := baseURL + "/batch"
+	trashPath := baseURL + "/trash"
+	eventsPath := baseURL + "/events"
+	taskPath := regexp.MustCompile(baseURL + "/\\d+$")
+	statusPath := regexp.MustCompile(baseURL + "/\\d+/status$")
+	restorePath := regexp.MustCompile(baseURL + "/\\d+/restore$")
+	sharesPath := regexp.MustCompile(baseURL + "/\\d+/shares$")
+	sharePath := regexp.MustCompile(baseURL + "/\\d+/shares/[^/]+$")
+	subtasksPath := regexp.MustCompile(baseURL + "/\\d+/subtasks$")
+	subtaskPath := regexp.MustCompile(baseURL + "/\\d+/subtasks/\\d+$")
+	blockersPath := regexp.MustCompile(baseURL + "/\\d+/blockers$")
+	blockerPath := regexp.MustCompile(baseURL + "/\\d+/blockers/\\d+$")
+	historyPath := regexp.MustCompile(baseURL + "/\\d+/history$")
+	commentsPath := regexp.MustCompile(baseURL + "/\\d+/comments$")
 	serveMux.HandleFunc(baseURL+"/", func(w http.ResponseWriter, r *http.Request) {
-		m := validPath.FindStringSubmatch(r.URL.Path)
-		if m == nil {
+		switch {
+		case r.URL.Path == batchPath && r.Method == http.MethodPost:
+			taskServer.PostBatch(w, r)
+		case r.URL.Path == trashPath && r.Method == http.MethodGet:
+			taskServer.GetTrash(w, r)
+		case r.URL.Path == eventsPath && r.Method == http.MethodGet:
+			taskServer.GetEvents(w, r)
+		case taskPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodGet:
+				taskServer.GetOneTask(w, r)
+			case http.MethodPut:
+				taskServer.PutTask(w, r)
+			case http.MethodPatch:
+				taskServer.PatchTask(w, r)
+			case http.MethodDelete:
+				taskServer.DeleteTask(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case statusPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodPatch:
+				taskServer.PatchStatus(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case restorePath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodPost:
+				taskServer.PostRestore(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case sharesPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodGet:
+				taskServer.GetShares(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case sharePath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodPut:
+				taskServer.PutShare(w, r)
+			case http.MethodDelete:
+				taskServer.DeleteShare(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case subtasksPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodGet:
+				taskServer.GetSubtasks(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case subtaskPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodPut:
+				taskServer.PutSubtask(w, r)
+			case http.MethodDelete:
+				taskServer.DeleteSubtask(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case blockersPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodGet:
+				taskServer.GetBlockers(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case blockerPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodPut:
+				taskServer.PutBlocker(w, r)
+			case http.MethodDelete:
+				taskServer.DeleteBlocker(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case historyPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodGet:
+				taskServer.GetHistory(w, r)
+			default:
+				writeNotFound(w)
+			}
+		case commentsPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodPost:
+				taskServer.PostComment(w, r)
+			default:
+				writeNotFound(w)
+			}
+		default:
+			writeNotFound(w)
+		}
+	})
+}
+
+func handleICSEndpoints(serveMux *http.ServeMux, taskServer task.TasksServer, baseURL string) {
+	serveMux.HandleFunc(baseURL+".ics", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			taskServer.GetTasksICS(w, r)
+		default:
+			writeNotFound(w)
+		}
+	})
+	serveMux.HandleFunc(baseURL+"/import", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			taskServer.PostImport(w, r)
+		default:
 			writeNotFound(w)
-			return
 		}
+	})
+}
+
+func handleTagEndpoints(serveMux *http.ServeMux, taskServer task.TasksServer, baseURL string) {
+	serveMux.HandleFunc(baseURL, func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
 		case http.MethodGet:
-			taskServer.GetOneTask(w, r)
-		case http.MethodPut:
-			taskServer.PutTask(w, r)
-		case http.MethodDelete:
-			taskServer.DeleteTask(w, r)
+			taskServer.GetTags(w, r)
+		default:
+			writeNotFound(w)
+		}
+	})
+
+	mergePath := baseURL + "/merge"
+	tagPath := regexp.MustCompile(baseURL + "/[^/]+$")
+	serveMux.HandleFunc(baseURL+"/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == mergePath && r.Method == http.MethodPost:
+			taskServer.PostTagsMerge(w, r)
+		case tagPath.MatchString(r.URL.Path):
+			switch r.Method {
+			case http.MethodPut:
+				taskServer.PutTag(w, r)
+			default:
+				writeNotFound(w)
+			}
+		default:
+			writeNotFound(w)
+		}
+	})
+}
+
+func handleWebhookEndpoints(serveMux *http.ServeMux, webhookServer webhook.WebhooksServer, baseURL string) {
+	serveMux.HandleFunc(baseURL, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			webhookServer.GetWebhooks(w, r)
+		case http.MethodPost:
+			webhookServer.PostWebhook(w, r)
+		default:
+			writeNotFound(w)
+		}
+	})
+
+	webhookPath := regexp.MustCompile(baseURL + "/\\d+$")
+	deliveriesPath := regexp.MustCompile(baseURL + "/\\d+/deliveries$")
+	serveMux.HandleFunc(baseURL+"/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case webhookPath.MatchString(r.URL.Path) && r.Method == http.MethodDelete:
+			webhookServer.DeleteWebhook(w, r)
+		case deliveriesPath.MatchString(r.URL.Path) && r.Method == http.MethodGet:
+			webhookServer.GetDeliveries(w, r)
+		default:
+			writeNotFound(w)
+		}
+	})
+}
+
+// handleDocsEndpoints serves Swagger UI at `baseURL/` and OpenAPI document it renders at `baseURL/openapi.json`
+func handleDocsEndpoints(serveMux *http.ServeMux, baseURL string) {
+	specPath := baseURL + "/openapi.json"
+	serveMux.HandleFunc(baseURL+"/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == specPath && r.Method == http.MethodGet:
+			openapi.ServeSpec(w, r)
+		case r.URL.Path == baseURL+"/" && r.Method == http.MethodGet:
+			openapi.ServeUI(w, r)
 		default:
 			writeNotFound(w)
 		}
@@ -1,3 +1,134 @@
 package httpserver
 
-// TODO
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/todos/openapi"
+)
+
+// fakeServer serves all task and webhook endpoints by writing name of called handler
+type fakeServer struct{}
+
+func (s *fakeServer) handle(w http.ResponseWriter, name string) {
+	w.Write([]byte(name))
+}
+
+func (s *fakeServer) GetTasks(w http.ResponseWriter, r *http.Request)    { s.handle(w, "GetTasks") }
+func (s *fakeServer) GetTasksICS(w http.ResponseWriter, r *http.Request) { s.handle(w, "GetTasksICS") }
+func (s *fakeServer) PostImport(w http.ResponseWriter, r *http.Request)  { s.handle(w, "PostImport") }
+func (s *fakeServer) GetOneTask(w http.ResponseWriter, r *http.Request)  { s.handle(w, "GetOneTask") }
+func (s *fakeServer) PostTask(w http.ResponseWriter, r *http.Request)    { s.handle(w, "PostTask") }
+func (s *fakeServer) PostBatch(w http.ResponseWriter, r *http.Request)   { s.handle(w, "PostBatch") }
+func (s *fakeServer) PutTask(w http.ResponseWriter, r *http.Request)     { s.handle(w, "PutTask") }
+func (s *fakeServer) PatchTask(w http.ResponseWriter, r *http.Request)   { s.handle(w, "PatchTask") }
+func (s *fakeServer) DeleteTask(w http.ResponseWriter, r *http.Request)  { s.handle(w, "DeleteTask") }
+func (s *fakeServer) GetTrash(w http.ResponseWriter, r *http.Request)    { s.handle(w, "GetTrash") }
+func (s *fakeServer) GetEvents(w http.ResponseWriter, r *http.Request)   { s.handle(w, "GetEvents") }
+func (s *fakeServer) PostRestore(w http.ResponseWriter, r *http.Request) { s.handle(w, "PostRestore") }
+func (s *fakeServer) PatchStatus(w http.ResponseWriter, r *http.Request) { s.handle(w, "PatchStatus") }
+func (s *fakeServer) GetShares(w http.ResponseWriter, r *http.Request)   { s.handle(w, "GetShares") }
+func (s *fakeServer) PutShare(w http.ResponseWriter, r *http.Request)    { s.handle(w, "PutShare") }
+func (s *fakeServer) DeleteShare(w http.ResponseWriter, r *http.Request) { s.handle(w, "DeleteShare") }
+func (s *fakeServer) GetSubtasks(w http.ResponseWriter, r *http.Request) { s.handle(w, "GetSubtasks") }
+func (s *fakeServer) PutSubtask(w http.ResponseWriter, r *http.Request)  { s.handle(w, "PutSubtask") }
+func (s *fakeServer) DeleteSubtask(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, "DeleteSubtask")
+}
+func (s *fakeServer) GetBlockers(w http.ResponseWriter, r *http.Request) { s.handle(w, "GetBlockers") }
+func (s *fakeServer) PutBlocker(w http.ResponseWriter, r *http.Request)  { s.handle(w, "PutBlocker") }
+func (s *fakeServer) DeleteBlocker(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, "DeleteBlocker")
+}
+func (s *fakeServer) GetHistory(w http.ResponseWriter, r *http.Request)  { s.handle(w, "GetHistory") }
+func (s *fakeServer) PostComment(w http.ResponseWriter, r *http.Request) { s.handle(w, "PostComment") }
+func (s *fakeServer) GetTags(w http.ResponseWriter, r *http.Request)     { s.handle(w, "GetTags") }
+func (s *fakeServer) PutTag(w http.ResponseWriter, r *http.Request)      { s.handle(w, "PutTag") }
+func (s *fakeServer) PostTagsMerge(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, "PostTagsMerge")
+}
+func (s *fakeServer) GetWebhooks(w http.ResponseWriter, r *http.Request) { s.handle(w, "GetWebhooks") }
+func (s *fakeServer) PostWebhook(w http.ResponseWriter, r *http.Request) { s.handle(w, "PostWebhook") }
+func (s *fakeServer) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, "DeleteWebhook")
+}
+func (s *fakeServer) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	s.handle(w, "GetDeliveries")
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	d, err := openapi.Load(openapi.Spec)
+	if err != nil {
+		t.Fatalf("Got error loading spec: %s", err)
+	}
+	s := &fakeServer{}
+	mux := newServeMux("/api/v1", s, s)
+	param := regexp.MustCompile(`\{[^}]+\}`)
+	handlers := make(map[string]string)
+	for path, item := range d.Paths {
+		ops := map[string]*openapi.Operation{
+			http.MethodGet: item.Get, http.MethodPut: item.Put, http.MethodPost: item.Post,
+			http.MethodDelete: item.Delete, http.MethodPatch: item.Patch,
+		}
+		for method, op := range ops {
+			if op == nil {
+				continue
+			}
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(method, "/api/v1"+param.ReplaceAllString(path, "1"), nil))
+			handler := rec.Body.String()
+			if rec.Code != http.StatusOK || handler == "Not Found" {
+				t.Errorf("Expected documented operation %s %s to be routed, got %d", method, path, rec.Code)
+				continue
+			}
+			if other, ok := handlers[handler]; ok {
+				t.Errorf("Expected operations %s and %s %s to be routed to different handlers, both got %s", other, method, path, handler)
+			}
+			handlers[handler] = method + " " + path
+		}
+	}
+	// every handler is reached by documented operation, so no route is left undocumented
+	if len(handlers) != len(fakeHandlers) {
+		for _, name := range fakeHandlers {
+			if _, ok := handlers[name]; !ok {
+				t.Errorf("Expected route of %s to be documented", name)
+			}
+		}
+	}
+}
+
+var fakeHandlers = []string{
+	"GetTasks",
+	"GetTasksICS",
+	"PostImport",
+	"GetOneTask",
+	"PostTask",
+	"PostBatch",
+	"PutTask",
+	"PatchTask",
+	"DeleteTask",
+	"GetTrash",
+	"GetEvents",
+	"PostRestore",
+	"PatchStatus",
+	"GetShares",
+	"PutShare",
+	"DeleteShare",
+	"GetSubtasks",
+	"PutSubtask",
+	"DeleteSubtask",
+	"GetBlockers",
+	"PutBlocker",
+	"DeleteBlocker",
+	"GetHistory",
+	"PostComment",
+	"GetTags",
+	"PutTag",
+	"PostTagsMerge",
+	"GetWebhooks",
+	"PostWebhook",
+	"DeleteWebhook",
+	"GetDeliveries",
+}
@@ -3,5 +3,91 @@ CREATE TABLE IF NOT EXISTS tasks(
   name varchar NOT NULL,
   description varchar,
   dueDate timestamp,
-  status integer
+  status integer,
+  owner varchar NOT NULL DEFAULT '',
+  version bigint NOT NULL DEFAULT 1,
+  parent_id bigint REFERENCES tasks(id) ON DELETE SET NULL,
+  recurrence varchar,
+  deleted_at timestamptz
 );
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS owner varchar NOT NULL DEFAULT '';
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS version bigint NOT NULL DEFAULT 1;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS parent_id bigint REFERENCES tasks(id) ON DELETE SET NULL;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS recurrence varchar;
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+-- tasks without due date used to have it at Unix epoch
+UPDATE tasks SET dueDate = NULL WHERE dueDate = '1970-01-01 00:00:00';
+CREATE INDEX IF NOT EXISTS tasks_owner_idx ON tasks(owner);
+CREATE INDEX IF NOT EXISTS tasks_parent_idx ON tasks(parent_id);
+CREATE INDEX IF NOT EXISTS tasks_deleted_at_idx ON tasks(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS task_shares(
+  task_id bigint NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  user_id varchar NOT NULL,
+  permission integer NOT NULL,
+  PRIMARY KEY (task_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS task_shares_user_idx ON task_shares(user_id);
+
+CREATE TABLE IF NOT EXISTS task_dependencies(
+  task_id bigint NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  blocker_id bigint NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  PRIMARY KEY (task_id, blocker_id)
+);
+CREATE INDEX IF NOT EXISTS task_dependencies_blocker_idx ON task_dependencies(blocker_id);
+
+CREATE TABLE IF NOT EXISTS task_tags(
+  task_id bigint NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  tag varchar NOT NULL,
+  PRIMARY KEY (task_id, tag)
+);
+CREATE INDEX IF NOT EXISTS task_tags_tag_idx ON task_tags(tag);
+
+CREATE TABLE IF NOT EXISTS task_events(
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  task_id bigint NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+  type integer NOT NULL,
+  actor varchar NOT NULL DEFAULT '',
+  field varchar NOT NULL DEFAULT '',
+  from_value varchar NOT NULL DEFAULT '',
+  to_value varchar NOT NULL DEFAULT '',
+  text varchar NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS task_events_task_idx ON task_events(task_id, id);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys(
+  scope varchar NOT NULL,
+  key varchar NOT NULL,
+  fingerprint varchar NOT NULL,
+  done boolean NOT NULL DEFAULT false,
+  status integer NOT NULL DEFAULT 0,
+  header text,
+  body bytea,
+  created_at timestamptz NOT NULL,
+  PRIMARY KEY (scope, key)
+);
+CREATE INDEX IF NOT EXISTS idempotency_keys_created_at_idx ON idempotency_keys(created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions(
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  owner varchar NOT NULL DEFAULT '',
+  url varchar NOT NULL,
+  events jsonb NOT NULL,
+  secret varchar NOT NULL,
+  created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_owner_idx ON webhook_subscriptions(owner);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries(
+  id bigint GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY,
+  subscription_id bigint NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+  event varchar NOT NULL,
+  payload bytea NOT NULL,
+  status varchar NOT NULL,
+  attempts jsonb NOT NULL DEFAULT '[]',
+  next_attempt_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON webhook_deliveries(subscription_id, id);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
@@ -5,12 +5,23 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"strconv"
+	"time"
 
 	_ "github.com/jackc/pgx/v4/stdlib"
 
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/idempotency"
+	idempotencysql "github.com/Vesninovich/go-tasks/book-store/common/idempotency/sql"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+	webhooksql "github.com/Vesninovich/go-tasks/book-store/common/webhook/sql"
 	"github.com/Vesninovich/go-tasks/todos/httpserver"
+	"github.com/Vesninovich/go-tasks/todos/openapi"
 	taskhttp "github.com/Vesninovich/go-tasks/todos/task/http"
+	"github.com/Vesninovich/go-tasks/todos/task/live"
+	webhookhttp "github.com/Vesninovich/go-tasks/todos/webhook/http"
 
 	// "github.com/Vesninovich/go-tasks/todos/task/inmemory"
 	taskservice "github.com/Vesninovich/go-tasks/todos/task/service"
@@ -32,12 +43,50 @@ func main() {
 
 	// taskRepo := inmemory.New()
 	taskRepo := tasksql.New(db)
-	taskService := taskservice.New(taskRepo)
+	// tables of webhooks are created by init.sql in default schema
+	webhookOpts, webhookInterval, err := webhook.OptionsFromEnv("TODO", taskservice.WebhookEvents)
+	if err != nil {
+		log.Fatalf("Failed to set up webhooks\n%s", err)
+	}
+	webhooks := webhook.New(webhooksql.New(db, "public"), webhookOpts)
+	go webhooks.Run(context.Background(), webhookInterval)
+	taskService := taskservice.New(taskRepo, taskservice.Options{
+		OverdueGrace:   parseDurationEnv("TODO_OVERDUE_GRACE", 0),
+		TrashRetention: parseDurationEnv("TODO_TRASH_RETENTION", taskservice.DefaultTrashRetention),
+		Live:           live.NewBroker(parseIntEnv("TODO_LIVE_BACKLOG", live.DefaultBacklog)),
+		Webhooks:       webhooks,
+	})
+	go taskService.RunOverdueSweeps(context.Background(), parseDurationEnv("TODO_OVERDUE_SWEEP_INTERVAL", time.Minute))
+	go taskService.RunTrashPurges(context.Background(), parseDurationEnv("TODO_TRASH_PURGE_INTERVAL", time.Hour))
 	taskServer := taskhttp.New(taskService)
 
+	var middleware []httpserver.Middleware
+	authenticator, err := auth.FromEnv("TODO")
+	if err != nil {
+		log.Fatalf("Failed to set up authentication\n%s", err)
+	}
+	if authenticator != nil {
+		// API docs are public, so clients can be built without credentials
+		docs := auth.Rule{Methods: []string{http.MethodGet}, PathPrefix: "/api/v1/docs", Anonymous: true}
+		middleware = append(middleware, auth.NewMiddleware(authenticator, docs).Wrap)
+	} else {
+		log.Println("No authentication configured, API is open to everyone")
+	}
+	spec, err := openapi.Load(openapi.Spec)
+	if err != nil {
+		log.Fatalf("Failed to load OpenAPI document\n%s", err)
+	}
+	// invalid requests are rejected before idempotency keys are taken by them
+	middleware = append(middleware, openapi.NewValidator(spec, "/api/v1").Wrap)
+	// keys are scoped by user, so requests are authenticated first; only requests creating tasks need keys
+	window := parseDurationEnv("TODO_IDEMPOTENCY_WINDOW", idempotency.DefaultWindow)
+	// table of idempotency keys is created by init.sql in default schema
+	idem := idempotency.New(idempotencysql.New(db, "public"), window)
+	middleware = append(middleware, idem.Middleware("/api/v1/task", "/api/v1/task/batch"))
+
 	host := buildHost()
 	log.Printf("Starting server at host %s\n", host)
-	_, err = httpserver.StartServer(host, "/api/v1", taskServer)
+	_, err = httpserver.StartServer(host, "/api/v1", taskServer, webhookhttp.New(webhooks), middleware...)
 	if err != nil {
 		log.Fatalf("Failed to start tasks server at host %s\n%s", host, err)
 	}
@@ -70,6 +119,30 @@ func buildDbURL() (dbURL string) {
 	return fmt.Sprintf("postgresql://%s%s@%s:%s/%s", user, pwd, host, port, db)
 }
 
+func parseDurationEnv(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Failed to parse %s\n%s", name, err)
+	}
+	return d
+}
+
+func parseIntEnv(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("Failed to parse %s\n%s", name, err)
+	}
+	return n
+}
+
 func buildHost() (host string) {
 	host = os.Getenv("TODO_HOST")
 	if host == "" {
@@ -0,0 +1,36 @@
+package openapi
+
+import "net/http"
+
+// swaggerUIVersion is version of swagger-ui-dist loaded by docs page
+const swaggerUIVersion = "3.52.5"
+
+// uiPage renders Swagger UI of document served next to the page, assets are loaded from CDN
+const uiPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Todos API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({url: "openapi.json", dom_id: "#swagger-ui"});
+  </script>
+</body>
+</html>
+`
+
+// ServeSpec serves OpenAPI document
+func ServeSpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	w.Write([]byte(Spec))
+}
+
+// ServeUI serves Swagger UI page of OpenAPI document, the document must be served at `openapi.json` next to it
+func ServeUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(uiPage))
+}
@@ -0,0 +1,183 @@
+// Package openapi holds OpenAPI 3 document of todos REST API, serves it with Swagger UI
+// and validates requests against it.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	schemaRefPrefix    = "#/components/schemas/"
+	parameterRefPrefix = "#/components/parameters/"
+)
+
+// Document is the part of OpenAPI 3 document requests are validated with
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+// PathItem describes operations on single path
+type PathItem struct {
+	Parameters []*Parameter `json:"parameters"`
+	Get        *Operation   `json:"get"`
+	Put        *Operation   `json:"put"`
+	Post       *Operation   `json:"post"`
+	Delete     *Operation   `json:"delete"`
+	Patch      *Operation   `json:"patch"`
+}
+
+// Operation describes request with single method on path
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	Parameters  []*Parameter `json:"parameters"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+// Parameter describes path, query or header param of request
+type Parameter struct {
+	Ref      string  `json:"$ref"`
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes body of request by its media types
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// MediaType describes body of single media type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds schemas and params referenced from operations
+type Components struct {
+	Schemas    map[string]*Schema    `json:"schemas"`
+	Parameters map[string]*Parameter `json:"parameters"`
+}
+
+// Schema is the subset of OpenAPI schema object requests are validated with.
+// Only boolean additionalProperties is supported.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Enum                 []interface{}      `json:"enum"`
+	Nullable             bool               `json:"nullable"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Minimum              *float64           `json:"minimum"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+}
+
+// Load parses OpenAPI 3 document and resolves its references
+func Load(spec string) (*Document, error) {
+	var d Document
+	if err := json.Unmarshal([]byte(spec), &d); err != nil {
+		return nil, fmt.Errorf("malformed OpenAPI document: %w", err)
+	}
+	if !strings.HasPrefix(d.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %s", d.OpenAPI)
+	}
+	seen := make(map[*Schema]bool)
+	for path, item := range d.Paths {
+		if err := d.resolveParameters(item.Parameters, seen); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for method, op := range item.operations() {
+			if err := d.resolveOperation(op, seen); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", method, path, err)
+			}
+		}
+	}
+	return &d, nil
+}
+
+// operations returns operations of path by their methods
+func (p *PathItem) operations() map[string]*Operation {
+	ops := make(map[string]*Operation)
+	for method, op := range map[string]*Operation{
+		"GET":    p.Get,
+		"PUT":    p.Put,
+		"POST":   p.Post,
+		"DELETE": p.Delete,
+		"PATCH":  p.Patch,
+	} {
+		if op != nil {
+			ops[method] = op
+		}
+	}
+	return ops
+}
+
+func (d *Document) resolveOperation(op *Operation, seen map[*Schema]bool) error {
+	if err := d.resolveParameters(op.Parameters, seen); err != nil {
+		return err
+	}
+	if op.RequestBody == nil {
+		return nil
+	}
+	for mt, content := range op.RequestBody.Content {
+		var err error
+		if content.Schema, err = d.resolveSchema(content.Schema, seen); err != nil {
+			return fmt.Errorf("body %s: %w", mt, err)
+		}
+	}
+	return nil
+}
+
+func (d *Document) resolveParameters(params []*Parameter, seen map[*Schema]bool) error {
+	for i, p := range params {
+		if p.Ref != "" {
+			target, ok := d.Components.Parameters[strings.TrimPrefix(p.Ref, parameterRefPrefix)]
+			if !ok || !strings.HasPrefix(p.Ref, parameterRefPrefix) {
+				return fmt.Errorf("unresolved reference %s", p.Ref)
+			}
+			params[i], p = target, target
+		}
+		var err error
+		if p.Schema, err = d.resolveSchema(p.Schema, seen); err != nil {
+			return fmt.Errorf("param %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// resolveSchema replaces references in schema with schemas they refer to
+func (d *Document) resolveSchema(s *Schema, seen map[*Schema]bool) (*Schema, error) {
+	if s == nil {
+		return nil, nil
+	}
+	if s.Ref != "" {
+		target, ok := d.Components.Schemas[strings.TrimPrefix(s.Ref, schemaRefPrefix)]
+		if !ok || !strings.HasPrefix(s.Ref, schemaRefPrefix) {
+			return nil, fmt.Errorf("unresolved reference %s", s.Ref)
+		}
+		return d.resolveSchema(target, seen)
+	}
+	if seen[s] {
+		return s, nil
+	}
+	seen[s] = true
+	var err error
+	for name, prop := range s.Properties {
+		if s.Properties[name], err = d.resolveSchema(prop, seen); err != nil {
+			return nil, err
+		}
+	}
+	if s.Items, err = d.resolveSchema(s.Items, seen); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,135 @@
+package openapi_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/todos/openapi"
+)
+
+func TestLoad(t *testing.T) {
+	d, err := openapi.Load(openapi.Spec)
+	if err != nil {
+		t.Fatalf("Got error loading spec: %s", err)
+	}
+	ids := make(map[string]bool)
+	for path, item := range d.Paths {
+		for _, op := range []*openapi.Operation{item.Get, item.Put, item.Post, item.Delete, item.Patch} {
+			if op == nil {
+				continue
+			}
+			if op.OperationID == "" || ids[op.OperationID] {
+				t.Errorf("Expected operation of %s to have unique ID, got %q", path, op.OperationID)
+			}
+			ids[op.OperationID] = true
+		}
+	}
+
+	if _, err = openapi.Load(`{"openapi":"3.0.3","paths":{"/x":{"get":{"parameters":[{"$ref":"#/components/parameters/Missing"}]}}}}`); err == nil {
+		t.Error("Expected unresolved reference to be rejected")
+	}
+	if _, err = openapi.Load(`{"swagger":"2.0"}`); err == nil {
+		t.Error("Expected OpenAPI 2 document to be rejected")
+	}
+}
+
+func TestValidator(t *testing.T) {
+	d, err := openapi.Load(openapi.Spec)
+	if err != nil {
+		t.Fatalf("Got error loading spec: %s", err)
+	}
+	v := openapi.NewValidator(d, "/api/v1")
+	cases := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		err         string
+	}{
+		{"List", "GET", "/api/v1/task?from=0&count=10&status=new,done&status=overdue&sort=dueDate&order=desc", "", "", ""},
+		{"Malformed count", "GET", "/api/v1/task?count=ten", "", "", `query param "count" must be integer`},
+		{"Negative count", "GET", "/api/v1/task?count=-1", "", "", `query param "count" must be at least 0`},
+		{"Unknown status", "GET", "/api/v1/task?status=new,later", "", "", `query param "status"[1] must be one of`},
+		{"Unknown sort", "GET", "/api/v1/task.ics?sort=owner", "", "", `query param "sort" must be one of "id", "dueDate", "name"`},
+		{"Create", "POST", "/api/v1/task", "application/json", `{"name":"a","dueDate":1600000000,"tags":["x"]}`, ""},
+		{"Create without name", "POST", "/api/v1/task", "", `{"description":"a"}`, "body.name is required"},
+		{"Create with empty name", "POST", "/api/v1/task", "", `{"name":""}`, "body.name must not be empty"},
+		{"Create with fractional due date", "POST", "/api/v1/task", "", `{"name":"a","dueDate":1.5}`, "body.dueDate must be integer"},
+		{"Create with malformed JSON", "POST", "/api/v1/task", "", `{"name":`, "malformed JSON body"},
+		{"Create without body", "POST", "/api/v1/task", "", "", "body is required"},
+		{"Trash is not task", "GET", "/api/v1/task/trash", "", "", ""},
+		{"Malformed ID", "GET", "/api/v1/task/0", "", "", `path param "id" must be at least 1`},
+		{"Update without status", "PUT", "/api/v1/task/1", "", `{"name":"a"}`, "body.status is required"},
+		{"Patch", "PATCH", "/api/v1/task/1", "application/merge-patch+json", `{"description":null,"status":"done"}`, ""},
+		{"Patch with null name", "PATCH", "/api/v1/task/1", "application/merge-patch+json", `{"name":null}`, "body.name must not be null"},
+		{"Patch with unknown field", "PATCH", "/api/v1/task/1", "application/json", `{"owner":"bob"}`, "body.owner is unknown field"},
+		{"Patch of other type is left to handler", "PATCH", "/api/v1/task/1", "text/plain", `{"owner":"bob"}`, ""},
+		{"Unknown action", "PATCH", "/api/v1/task/1/status", "", `{"action":"finish"}`, `body.action must be one of "start", "complete", "cancel", "reopen"`},
+		{"Share", "PUT", "/api/v1/task/1/shares/bob", "", `{"permission":"read"}`, ""},
+		{"Subtask", "PUT", "/api/v1/task/1/subtasks/x", "", "", `path param "childId" must be integer`},
+		{"Batch", "POST", "/api/v1/task/batch", "", `{"operations":[{"action":"create","task":{"name":"a"}},{"action":"delete","id":2}]}`, ""},
+		{"Empty batch", "POST", "/api/v1/task/batch", "", `{"operations":[]}`, "body.operations must have at least 1 items"},
+		{"Batch with unknown action", "POST", "/api/v1/task/batch", "", `{"operations":[{"action":"create"},{"action":"move"}]}`, "body.operations[1].action must be one of"},
+		{"Import is left to handler", "POST", "/api/v1/task/import", "text/calendar", "BEGIN:VCALENDAR", ""},
+		{"Events", "GET", "/api/v1/task/events?type=created,deleted&task=1", "", "", ""},
+		{"Events of malformed task", "GET", "/api/v1/task/events?task=1,x", "", "", `query param "task"[1] must be integer`},
+		{"Merge tags", "POST", "/api/v1/tags/merge", "", `{"tags":"a","into":"b"}`, "body.tags must be array"},
+		{"Webhook", "POST", "/api/v1/webhooks", "", `{"url":"http://example.com","events":["task.created"]}`, ""},
+		{"Webhook of unknown event", "POST", "/api/v1/webhooks", "", `{"url":"http://example.com","events":["created"]}`, "body.events[0] must be one of"},
+		{"Undocumented method is left to router", "GET", "/api/v1/task/batch", "", "", ""},
+		{"Undocumented path is left to router", "GET", "/api/v1/unknown", "", "", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+			if c.contentType != "" {
+				req.Header.Set("Content-Type", c.contentType)
+			}
+			err := v.Validate(httptest.NewRecorder(), req)
+			switch {
+			case c.err == "" && err != nil:
+				t.Errorf("Expected request to be valid, got %s", err)
+			case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+				t.Errorf("Expected error %q, got %v", c.err, err)
+			}
+			if body, _ := ioutil.ReadAll(req.Body); string(body) != c.body {
+				t.Errorf("Expected body to be kept for handler, got %q", body)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	d, _ := openapi.Load(openapi.Spec)
+	handled := false
+	h := openapi.NewValidator(d, "/api/v1").Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handled = true
+	}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/task/1/comments", strings.NewReader(`{"text":1}`)))
+	if rec.Code != http.StatusBadRequest || handled {
+		t.Errorf("Expected invalid request to be rejected with 400, got %d", rec.Code)
+	}
+	if rec.Body.String() != "Invalid input: body.text must be string" {
+		t.Errorf("Wrong error %q", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/task/1/comments", strings.NewReader(`{"text":"hi"}`)))
+	if !handled {
+		t.Errorf("Expected valid request to be handled, got %d", rec.Code)
+	}
+
+	handled = false
+	rec = httptest.NewRecorder()
+	large := `{"text":"` + strings.Repeat("a", openapi.MaxBodySize) + `"}`
+	h.ServeHTTP(rec, httptest.NewRequest("POST", "/api/v1/task/1/comments", strings.NewReader(large)))
+	if rec.Code != http.StatusBadRequest || handled {
+		t.Errorf("Expected too large body to be rejected with 400, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+)
+
+// validate checks value decoded from JSON with numbers kept as json.Number against schema.
+// `location` names value in error, e.g. `body.operations[0].action`.
+func validate(s *Schema, value interface{}, location string) error {
+	if s == nil {
+		return nil
+	}
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return invalid(location, "must not be null")
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return invalid(location, "must be object")
+		}
+		return validateObject(s, obj, location)
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return invalid(location, "must be array")
+		}
+		if s.MinItems != nil && len(items) < *s.MinItems {
+			return invalid(location, fmt.Sprintf("must have at least %d items", *s.MinItems))
+		}
+		if s.MaxItems != nil && len(items) > *s.MaxItems {
+			return invalid(location, fmt.Sprintf("must have at most %d items", *s.MaxItems))
+		}
+		for i, item := range items {
+			if err := validate(s.Items, item, fmt.Sprintf("%s[%d]", location, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return invalid(location, "must be string")
+		}
+		length := utf8.RuneCountInString(str)
+		if s.MinLength != nil && length < *s.MinLength {
+			if *s.MinLength == 1 {
+				return invalid(location, "must not be empty")
+			}
+			return invalid(location, fmt.Sprintf("must be at least %d characters long", *s.MinLength))
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			return invalid(location, fmt.Sprintf("must be at most %d characters long", *s.MaxLength))
+		}
+	case "integer", "number":
+		n, ok := value.(json.Number)
+		if !ok || (s.Type == "integer" && strings.ContainsAny(n.String(), ".eE")) {
+			return invalid(location, "must be "+s.Type)
+		}
+		if f, err := n.Float64(); err == nil && s.Minimum != nil && f < *s.Minimum {
+			return invalid(location, fmt.Sprintf("must be at least %v", *s.Minimum))
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return invalid(location, "must be boolean")
+		}
+	}
+	return validateEnum(s, value, location)
+}
+
+func validateObject(s *Schema, obj map[string]interface{}, location string) error {
+	for _, name := range s.Required {
+		if _, ok := obj[name]; !ok {
+			return invalid(location+"."+name, "is required")
+		}
+	}
+	// fields are checked in order, so the same error is reported for the same body
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		prop, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return invalid(location+"."+name, "is unknown field")
+			}
+			continue
+		}
+		if err := validate(prop, obj[name], location+"."+name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateEnum(s *Schema, value interface{}, location string) error {
+	if len(s.Enum) == 0 {
+		return nil
+	}
+	allowed := make([]string, len(s.Enum))
+	for i, e := range s.Enum {
+		if fmt.Sprint(e) == fmt.Sprint(value) {
+			return nil
+		}
+		allowed[i] = fmt.Sprintf(`"%v"`, e)
+	}
+	return invalid(location, "must be one of "+strings.Join(allowed, ", "))
+}
+
+func invalid(location, reason string) error {
+	return &common.InvalidInputError{Reason: location + " " + reason}
+}
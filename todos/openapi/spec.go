@@ -0,0 +1,974 @@
+package openapi
+
+// Spec is OpenAPI 3 document of todos REST API.
+// It is served to clients as is and requests are validated against it, so it must be kept in sync with handlers.
+const Spec = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Todos",
+    "description": "Tasks with sharing, subtasks, blockers, history, tags, live updates and webhooks. Errors are sent as plain text.",
+    "version": "1.0.0"
+  },
+  "servers": [{"url": "/api/v1"}],
+  "security": [{"bearerAuth": []}, {"apiKey": []}, {}],
+  "tags": [
+    {"name": "tasks"},
+    {"name": "relations", "description": "Shares, subtasks and blockers of tasks"},
+    {"name": "history"},
+    {"name": "tags"},
+    {"name": "calendar", "description": "iCalendar export and import"},
+    {"name": "webhooks"}
+  ],
+  "paths": {
+    "/task": {
+      "get": {
+        "tags": ["tasks"],
+        "operationId": "listTasks",
+        "summary": "List tasks available to user",
+        "parameters": [
+          {"$ref": "#/components/parameters/From"},
+          {"$ref": "#/components/parameters/Count"},
+          {"$ref": "#/components/parameters/Status"},
+          {"$ref": "#/components/parameters/DueFrom"},
+          {"$ref": "#/components/parameters/DueTo"},
+          {"$ref": "#/components/parameters/Search"},
+          {"$ref": "#/components/parameters/Tag"},
+          {"$ref": "#/components/parameters/TagMatch"},
+          {"$ref": "#/components/parameters/Sort"},
+          {"$ref": "#/components/parameters/Order"}
+        ],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Tasks"},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "post": {
+        "tags": ["tasks"],
+        "operationId": "createTask",
+        "summary": "Create task",
+        "parameters": [{"$ref": "#/components/parameters/IdempotencyKey"}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CreateTask"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "ID of created task",
+            "content": {"text/plain": {"schema": {"type": "integer", "format": "int64"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "422": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/batch": {
+      "post": {
+        "tags": ["tasks"],
+        "operationId": "batchTasks",
+        "summary": "Create, update and delete tasks in one transaction",
+        "parameters": [{"$ref": "#/components/parameters/IdempotencyKey"}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Batch"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/BatchResults"},
+          "400": {"$ref": "#/components/responses/BatchResults"},
+          "403": {"$ref": "#/components/responses/BatchResults"},
+          "404": {"$ref": "#/components/responses/BatchResults"},
+          "409": {"$ref": "#/components/responses/BatchResults"},
+          "412": {"$ref": "#/components/responses/BatchResults"}
+        }
+      }
+    },
+    "/task/trash": {
+      "get": {
+        "tags": ["tasks"],
+        "operationId": "listTrash",
+        "summary": "List tasks of user in trash, the latest deleted first",
+        "parameters": [
+          {"$ref": "#/components/parameters/From"},
+          {"$ref": "#/components/parameters/Count"}
+        ],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Tasks"},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/events": {
+      "get": {
+        "tags": ["tasks"],
+        "operationId": "streamTaskEvents",
+        "summary": "Stream changes of tasks available to user as server-sent events",
+        "description": "Each event has ID, type and LiveEvent as data. Event reset is sent if events missed since Last-Event-ID are not kept anymore.",
+        "parameters": [
+          {
+            "name": "type",
+            "in": "query",
+            "description": "Types of events, comma separated, may be repeated",
+            "style": "form",
+            "explode": false,
+            "schema": {"type": "array", "items": {"$ref": "#/components/schemas/LiveEventType"}}
+          },
+          {
+            "name": "task",
+            "in": "query",
+            "description": "IDs of tasks, comma separated, may be repeated",
+            "style": "form",
+            "explode": false,
+            "schema": {"type": "array", "items": {"type": "integer", "format": "int64", "minimum": 0}}
+          },
+          {
+            "name": "lastEventId",
+            "in": "query",
+            "description": "ID of the last received event, used if Last-Event-ID header is not set",
+            "schema": {"type": "integer", "format": "int64", "minimum": 0}
+          },
+          {
+            "name": "Last-Event-ID",
+            "in": "header",
+            "description": "ID of the last received event",
+            "schema": {"type": "integer", "format": "int64", "minimum": 0}
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "Stream of events",
+            "content": {"text/event-stream": {"schema": {"type": "string"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "501": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/import": {
+      "post": {
+        "tags": ["calendar"],
+        "operationId": "importTasks",
+        "summary": "Create or update tasks from VTODO components of iCalendar",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "text/calendar": {"schema": {"type": "string"}},
+            "multipart/form-data": {
+              "schema": {
+                "type": "object",
+                "required": ["file"],
+                "properties": {"file": {"type": "string", "format": "binary"}}
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Result of import of each VTODO",
+            "content": {
+              "application/json": {
+                "schema": {"type": "array", "items": {"$ref": "#/components/schemas/ImportResult"}}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task.ics": {
+      "get": {
+        "tags": ["calendar"],
+        "operationId": "exportTasks",
+        "summary": "Export tasks available to user as iCalendar",
+        "parameters": [
+          {"$ref": "#/components/parameters/From"},
+          {"$ref": "#/components/parameters/Count"},
+          {"$ref": "#/components/parameters/Status"},
+          {"$ref": "#/components/parameters/DueFrom"},
+          {"$ref": "#/components/parameters/DueTo"},
+          {"$ref": "#/components/parameters/Search"},
+          {"$ref": "#/components/parameters/Tag"},
+          {"$ref": "#/components/parameters/TagMatch"},
+          {"$ref": "#/components/parameters/Sort"},
+          {"$ref": "#/components/parameters/Order"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Calendar with VTODO of each task",
+            "content": {"text/calendar": {"schema": {"type": "string"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}": {
+      "parameters": [{"$ref": "#/components/parameters/TaskID"}],
+      "get": {
+        "tags": ["tasks"],
+        "operationId": "getTask",
+        "summary": "Get task",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Task"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "put": {
+        "tags": ["tasks"],
+        "operationId": "updateTask",
+        "summary": "Replace task",
+        "parameters": [{"$ref": "#/components/parameters/IfMatch"}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/UpdateTask"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/Updated"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "412": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "patch": {
+        "tags": ["tasks"],
+        "operationId": "patchTask",
+        "summary": "Change task with JSON Merge Patch",
+        "parameters": [{"$ref": "#/components/parameters/IfMatch"}],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/merge-patch+json": {"schema": {"$ref": "#/components/schemas/TaskPatch"}},
+            "application/json": {"schema": {"$ref": "#/components/schemas/TaskPatch"}}
+          }
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/Task"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "412": {"$ref": "#/components/responses/Error"},
+          "415": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "tags": ["tasks"],
+        "operationId": "deleteTask",
+        "summary": "Move task to trash",
+        "responses": {
+          "200": {"description": "Task is moved to trash"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/status": {
+      "parameters": [{"$ref": "#/components/parameters/TaskID"}],
+      "patch": {
+        "tags": ["tasks"],
+        "operationId": "changeTaskStatus",
+        "summary": "Change status of task with action",
+        "parameters": [{"$ref": "#/components/parameters/IfMatch"}],
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/StatusAction"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/Task"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"},
+          "412": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/restore": {
+      "parameters": [{"$ref": "#/components/parameters/TaskID"}],
+      "post": {
+        "tags": ["tasks"],
+        "operationId": "restoreTask",
+        "summary": "Restore task from trash",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Task"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/shares": {
+      "parameters": [{"$ref": "#/components/parameters/TaskID"}],
+      "get": {
+        "tags": ["relations"],
+        "operationId": "listShares",
+        "summary": "List users task is shared with",
+        "responses": {
+          "200": {
+            "description": "Shares of task",
+            "content": {
+              "application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Share"}}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/shares/{user}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/TaskID"},
+        {"name": "user", "in": "path", "required": true, "schema": {"type": "string", "minLength": 1}}
+      ],
+      "put": {
+        "tags": ["relations"],
+        "operationId": "shareTask",
+        "summary": "Share task with user",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Share"}}}
+        },
+        "responses": {
+          "200": {
+            "description": "Share of task",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Share"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "tags": ["relations"],
+        "operationId": "unshareTask",
+        "summary": "Stop sharing task with user",
+        "responses": {
+          "200": {"description": "Task is not shared with user"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/subtasks": {
+      "parameters": [{"$ref": "#/components/parameters/TaskID"}],
+      "get": {
+        "tags": ["relations"],
+        "operationId": "listSubtasks",
+        "summary": "List subtasks of task",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Tasks"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/subtasks/{childId}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/TaskID"},
+        {"name": "childId", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64", "minimum": 1}}
+      ],
+      "put": {
+        "tags": ["relations"],
+        "operationId": "attachSubtask",
+        "summary": "Attach task as subtask",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Task"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "tags": ["relations"],
+        "operationId": "detachSubtask",
+        "summary": "Detach subtask",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Task"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/blockers": {
+      "parameters": [{"$ref": "#/components/parameters/TaskID"}],
+      "get": {
+        "tags": ["relations"],
+        "operationId": "listBlockers",
+        "summary": "List tasks blocking task",
+        "responses": {
+          "200": {"$ref": "#/components/responses/Tasks"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/blockers/{blockerId}": {
+      "parameters": [
+        {"$ref": "#/components/parameters/TaskID"},
+        {"name": "blockerId", "in": "path", "required": true, "schema": {"type": "integer", "format": "int64", "minimum": 1}}
+      ],
+      "put": {
+        "tags": ["relations"],
+        "operationId": "addBlocker",
+        "summary": "Make task blocked by another one",
+        "responses": {
+          "200": {"description": "Task is blocked"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      },
+      "delete": {
+        "tags": ["relations"],
+        "operationId": "removeBlocker",
+        "summary": "Remove blocker of task",
+        "responses": {
+          "200": {"description": "Task is not blocked by another one"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/history": {
+      "parameters": [{"$ref": "#/components/parameters/TaskID"}],
+      "get": {
+        "tags": ["history"],
+        "operationId": "getHistory",
+        "summary": "Read history of task",
+        "parameters": [
+          {"$ref": "#/components/parameters/From"},
+          {"$ref": "#/components/parameters/Count"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Events of task",
+            "content": {
+              "application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Event"}}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/task/{id}/comments": {
+      "parameters": [{"$ref": "#/components/parameters/TaskID"}],
+      "post": {
+        "tags": ["history"],
+        "operationId": "commentTask",
+        "summary": "Comment task",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Comment"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "Comment event",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Event"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "403": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/tags": {
+      "get": {
+        "tags": ["tags"],
+        "operationId": "listTags",
+        "summary": "List tags of tasks available to user with number of tasks having each tag",
+        "responses": {
+          "200": {
+            "description": "Tags",
+            "content": {
+              "application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/TagCount"}}}
+            }
+          }
+        }
+      }
+    },
+    "/tags/merge": {
+      "post": {
+        "tags": ["tags"],
+        "operationId": "mergeTags",
+        "summary": "Merge tags into one on tasks owned by user",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/MergeTags"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/TagChange"},
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/tags/{tag}": {
+      "parameters": [{"name": "tag", "in": "path", "required": true, "schema": {"type": "string", "minLength": 1}}],
+      "put": {
+        "tags": ["tags"],
+        "operationId": "renameTag",
+        "summary": "Rename tag on tasks owned by user",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/RenameTag"}}}
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/TagChange"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "409": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/webhooks": {
+      "get": {
+        "tags": ["webhooks"],
+        "operationId": "listWebhooks",
+        "summary": "List webhook subscriptions of user without their secrets",
+        "responses": {
+          "200": {
+            "description": "Subscriptions",
+            "content": {
+              "application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Webhook"}}}
+            }
+          }
+        }
+      },
+      "post": {
+        "tags": ["webhooks"],
+        "operationId": "createWebhook",
+        "summary": "Subscribe URL to events of tasks available to user",
+        "requestBody": {
+          "required": true,
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/CreateWebhook"}}}
+        },
+        "responses": {
+          "201": {
+            "description": "Subscription with its secret",
+            "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Webhook"}}}
+          },
+          "400": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/webhooks/{id}": {
+      "parameters": [{"$ref": "#/components/parameters/WebhookID"}],
+      "delete": {
+        "tags": ["webhooks"],
+        "operationId": "deleteWebhook",
+        "summary": "Delete subscription with its deliveries",
+        "responses": {
+          "200": {"description": "Subscription is deleted"},
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    },
+    "/webhooks/{id}/deliveries": {
+      "parameters": [{"$ref": "#/components/parameters/WebhookID"}],
+      "get": {
+        "tags": ["webhooks"],
+        "operationId": "listDeliveries",
+        "summary": "Read deliveries of subscription with their attempts, the latest first",
+        "parameters": [
+          {"$ref": "#/components/parameters/From"},
+          {"$ref": "#/components/parameters/Count"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Deliveries",
+            "content": {
+              "application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Delivery"}}}
+            }
+          },
+          "400": {"$ref": "#/components/responses/Error"},
+          "404": {"$ref": "#/components/responses/Error"}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "bearerAuth": {"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+      "apiKey": {"type": "apiKey", "in": "header", "name": "X-API-Key"}
+    },
+    "parameters": {
+      "TaskID": {
+        "name": "id",
+        "in": "path",
+        "required": true,
+        "schema": {"type": "integer", "format": "int64", "minimum": 1}
+      },
+      "WebhookID": {
+        "name": "id",
+        "in": "path",
+        "required": true,
+        "schema": {"type": "integer", "format": "int64", "minimum": 1}
+      },
+      "From": {
+        "name": "from",
+        "in": "query",
+        "description": "Number of items to skip",
+        "schema": {"type": "integer", "format": "int64", "minimum": 0}
+      },
+      "Count": {
+        "name": "count",
+        "in": "query",
+        "description": "Number of items to read, all of them if 0",
+        "schema": {"type": "integer", "format": "int64", "minimum": 0}
+      },
+      "Status": {
+        "name": "status",
+        "in": "query",
+        "description": "Statuses, comma separated, may be repeated",
+        "style": "form",
+        "explode": false,
+        "schema": {"type": "array", "items": {"$ref": "#/components/schemas/Status"}}
+      },
+      "DueFrom": {
+        "name": "dueFrom",
+        "in": "query",
+        "description": "Inclusive start of due date range, Unix time",
+        "schema": {"type": "integer", "format": "int64"}
+      },
+      "DueTo": {
+        "name": "dueTo",
+        "in": "query",
+        "description": "Inclusive end of due date range, Unix time",
+        "schema": {"type": "integer", "format": "int64"}
+      },
+      "Search": {
+        "name": "search",
+        "in": "query",
+        "description": "Case insensitive substring of name or description",
+        "schema": {"type": "string"}
+      },
+      "Tag": {
+        "name": "tag",
+        "in": "query",
+        "description": "Tags, comma separated, may be repeated",
+        "style": "form",
+        "explode": false,
+        "schema": {"type": "array", "items": {"type": "string"}}
+      },
+      "TagMatch": {
+        "name": "tagMatch",
+        "in": "query",
+        "description": "Whether tasks must have any or all of tags",
+        "schema": {"type": "string", "enum": ["any", "all"], "default": "any"}
+      },
+      "Sort": {
+        "name": "sort",
+        "in": "query",
+        "schema": {"type": "string", "enum": ["id", "dueDate", "name"], "default": "id"}
+      },
+      "Order": {
+        "name": "order",
+        "in": "query",
+        "schema": {"type": "string", "enum": ["asc", "desc"], "default": "asc"}
+      },
+      "IfMatch": {
+        "name": "If-Match",
+        "in": "header",
+        "description": "Expected version of task from its ETag, the check is skipped if it is not set or is *",
+        "schema": {"type": "string"}
+      },
+      "IdempotencyKey": {
+        "name": "Idempotency-Key",
+        "in": "header",
+        "description": "Key making retries of request safe, response to the first request is replayed",
+        "schema": {"type": "string"}
+      }
+    },
+    "responses": {
+      "Error": {
+        "description": "Error",
+        "content": {"text/plain": {"schema": {"type": "string"}}}
+      },
+      "Task": {
+        "description": "Task, its version is sent in ETag header",
+        "headers": {"ETag": {"schema": {"type": "string"}}},
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Task"}}}
+      },
+      "Tasks": {
+        "description": "Tasks",
+        "content": {
+          "application/json": {"schema": {"type": "array", "items": {"$ref": "#/components/schemas/Task"}}}
+        }
+      },
+      "Updated": {
+        "description": "Task is updated, its new version is sent in ETag header",
+        "headers": {"ETag": {"schema": {"type": "string"}}}
+      },
+      "BatchResults": {
+        "description": "Result of each operation, status of atomic batch is the one of the failed operation",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/BatchResults"}}}
+      },
+      "TagChange": {
+        "description": "Resulting tag with number of changed tasks",
+        "content": {"application/json": {"schema": {"$ref": "#/components/schemas/TagChange"}}}
+      }
+    },
+    "schemas": {
+      "Status": {
+        "type": "string",
+        "enum": ["new", "in-progress", "cancelled", "done", "overdue"]
+      },
+      "Tags": {
+        "type": "array",
+        "items": {"type": "string", "minLength": 1}
+      },
+      "Task": {
+        "type": "object",
+        "required": ["id", "name"],
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "name": {"type": "string"},
+          "description": {"type": "string"},
+          "dueDate": {"type": "integer", "format": "int64", "description": "Unix time, not set if task has no due date"},
+          "status": {"$ref": "#/components/schemas/Status"},
+          "owner": {"type": "string"},
+          "parent": {"type": "integer", "format": "int64", "description": "ID of parent task of subtask"},
+          "recurrence": {"type": "string", "description": "RRULE of recurring task"},
+          "tags": {"$ref": "#/components/schemas/Tags"},
+          "progress": {"$ref": "#/components/schemas/Progress"},
+          "deletedAt": {"type": "integer", "format": "int64", "description": "Unix time task was moved to trash"}
+        }
+      },
+      "Progress": {
+        "type": "object",
+        "description": "Number of done subtasks, only set for tasks with subtasks",
+        "required": ["done", "total"],
+        "properties": {
+          "done": {"type": "integer"},
+          "total": {"type": "integer"}
+        }
+      },
+      "CreateTask": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1},
+          "description": {"type": "string"},
+          "dueDate": {"type": "integer", "format": "int64", "minimum": 0, "description": "Unix time, 0 for no due date"},
+          "recurrence": {"type": "string", "description": "RRULE, e.g. FREQ=WEEKLY;BYDAY=MO"},
+          "tags": {"$ref": "#/components/schemas/Tags"}
+        }
+      },
+      "UpdateTask": {
+        "type": "object",
+        "required": ["name", "status"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1},
+          "description": {"type": "string"},
+          "dueDate": {"type": "integer", "format": "int64", "minimum": 0, "description": "Unix time, 0 for no due date"},
+          "status": {"$ref": "#/components/schemas/Status"},
+          "recurrence": {"type": "string", "description": "RRULE, e.g. FREQ=WEEKLY;BYDAY=MO"},
+          "tags": {"$ref": "#/components/schemas/Tags"}
+        }
+      },
+      "TaskPatch": {
+        "type": "object",
+        "description": "JSON Merge Patch of task, null removes field",
+        "additionalProperties": false,
+        "properties": {
+          "name": {"type": "string", "minLength": 1},
+          "description": {"type": "string", "nullable": true},
+          "dueDate": {"type": "integer", "format": "int64", "minimum": 0, "nullable": true, "description": "Unix time, null removes due date"},
+          "status": {"$ref": "#/components/schemas/Status"},
+          "recurrence": {"type": "string", "nullable": true},
+          "tags": {"type": "array", "items": {"type": "string", "minLength": 1}, "nullable": true}
+        }
+      },
+      "StatusAction": {
+        "type": "object",
+        "required": ["action"],
+        "properties": {
+          "action": {"type": "string", "enum": ["start", "complete", "cancel", "reopen"]}
+        }
+      },
+      "Share": {
+        "type": "object",
+        "required": ["permission"],
+        "properties": {
+          "user": {"type": "string", "readOnly": true},
+          "permission": {"type": "string", "enum": ["read", "write"]}
+        }
+      },
+      "Batch": {
+        "type": "object",
+        "required": ["operations"],
+        "properties": {
+          "atomic": {"type": "boolean", "default": true},
+          "operations": {
+            "type": "array",
+            "minItems": 1,
+            "maxItems": 100,
+            "items": {"$ref": "#/components/schemas/BatchOperation"}
+          }
+        }
+      },
+      "BatchOperation": {
+        "type": "object",
+        "required": ["action"],
+        "properties": {
+          "action": {"type": "string", "enum": ["create", "update", "delete"]},
+          "id": {"type": "integer", "format": "int64", "minimum": 0, "description": "ID of updated or deleted task"},
+          "version": {"type": "integer", "format": "int64", "minimum": 0, "description": "Expected version of updated task, 0 skips the check"},
+          "task": {"$ref": "#/components/schemas/TaskPatch"}
+        }
+      },
+      "BatchResults": {
+        "type": "object",
+        "required": ["results"],
+        "properties": {
+          "results": {"type": "array", "items": {"$ref": "#/components/schemas/BatchResult"}}
+        }
+      },
+      "BatchResult": {
+        "type": "object",
+        "required": ["status"],
+        "properties": {
+          "status": {"type": "integer", "description": "Status the operation would have on its own"},
+          "task": {"$ref": "#/components/schemas/Task"},
+          "error": {"type": "string"}
+        }
+      },
+      "Event": {
+        "type": "object",
+        "required": ["id", "type", "time"],
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "type": {"type": "string", "enum": ["created", "changed", "status", "comment", "deleted", "restored"]},
+          "actor": {"type": "string"},
+          "field": {"type": "string"},
+          "from": {"type": "string"},
+          "to": {"type": "string"},
+          "text": {"type": "string"},
+          "time": {"type": "integer", "format": "int64", "description": "Unix time"}
+        }
+      },
+      "Comment": {
+        "type": "object",
+        "required": ["text"],
+        "properties": {
+          "text": {"type": "string", "minLength": 1, "description": "At most 10000 characters"}
+        }
+      },
+      "TagCount": {
+        "type": "object",
+        "required": ["tag", "count"],
+        "properties": {
+          "tag": {"type": "string"},
+          "count": {"type": "integer", "format": "int64"}
+        }
+      },
+      "RenameTag": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "name": {"type": "string", "minLength": 1}
+        }
+      },
+      "MergeTags": {
+        "type": "object",
+        "required": ["tags", "into"],
+        "properties": {
+          "tags": {"type": "array", "minItems": 1, "items": {"type": "string", "minLength": 1}},
+          "into": {"type": "string", "minLength": 1}
+        }
+      },
+      "TagChange": {
+        "type": "object",
+        "required": ["tag", "changed"],
+        "properties": {
+          "tag": {"type": "string"},
+          "changed": {"type": "integer", "format": "int64", "description": "Number of changed tasks"}
+        }
+      },
+      "ImportResult": {
+        "type": "object",
+        "required": ["uid", "created"],
+        "properties": {
+          "uid": {"type": "string"},
+          "id": {"type": "integer", "format": "int64"},
+          "created": {"type": "boolean"},
+          "error": {"type": "string", "description": "Set if VTODO was not imported"}
+        }
+      },
+      "LiveEventType": {
+        "type": "string",
+        "enum": ["created", "updated", "completed", "overdue", "deleted", "restored"]
+      },
+      "LiveEvent": {
+        "type": "object",
+        "required": ["taskID", "time"],
+        "properties": {
+          "taskID": {"type": "integer", "format": "int64"},
+          "actor": {"type": "string"},
+          "fields": {"type": "array", "items": {"type": "string"}, "description": "Changed fields of updated task"},
+          "time": {"type": "integer", "format": "int64", "description": "Unix time"}
+        }
+      },
+      "WebhookEvent": {
+        "type": "string",
+        "enum": ["task.created", "task.updated", "task.completed", "task.overdue", "task.deleted", "task.restored"]
+      },
+      "CreateWebhook": {
+        "type": "object",
+        "required": ["url", "events"],
+        "properties": {
+          "url": {"type": "string", "format": "uri"},
+          "events": {"type": "array", "minItems": 1, "items": {"$ref": "#/components/schemas/WebhookEvent"}},
+          "secret": {"type": "string", "description": "Key of HMAC-SHA256 signature of deliveries, generated if not set"}
+        }
+      },
+      "Webhook": {
+        "type": "object",
+        "required": ["id", "url", "events", "createdAt"],
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "url": {"type": "string", "format": "uri"},
+          "events": {"type": "array", "items": {"$ref": "#/components/schemas/WebhookEvent"}},
+          "secret": {"type": "string", "description": "Only sent in response to subscription"},
+          "createdAt": {"type": "integer", "format": "int64", "description": "Unix time"}
+        }
+      },
+      "Delivery": {
+        "type": "object",
+        "required": ["id", "event", "status", "payload", "attempts", "createdAt"],
+        "properties": {
+          "id": {"type": "integer", "format": "int64"},
+          "event": {"$ref": "#/components/schemas/WebhookEvent"},
+          "status": {"type": "string", "enum": ["pending", "delivered", "failed"]},
+          "payload": {"type": "object", "description": "Body of delivery request"},
+          "attempts": {"type": "array", "items": {"$ref": "#/components/schemas/Attempt"}},
+          "nextAttemptAt": {"type": "integer", "format": "int64", "description": "Unix time of the next attempt of pending delivery"},
+          "createdAt": {"type": "integer", "format": "int64", "description": "Unix time"}
+        }
+      },
+      "Attempt": {
+        "type": "object",
+        "required": ["durationMs", "time"],
+        "properties": {
+          "statusCode": {"type": "integer"},
+          "error": {"type": "string"},
+          "durationMs": {"type": "integer", "format": "int64"},
+          "time": {"type": "integer", "format": "int64", "description": "Unix time"}
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,231 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+)
+
+var templateParam = regexp.MustCompile(`\{[^/{}]+\}`)
+
+// MaxBodySize limits size of JSON bodies read by validator
+const MaxBodySize = 1 << 20
+
+// Validator checks path and query params and JSON bodies of requests against operations of document.
+// Requests to paths and methods missing in document are passed as is, so router answers them.
+type Validator struct {
+	routes []route
+}
+
+type route struct {
+	pattern *regexp.Regexp
+	params  []string
+	item    *PathItem
+}
+
+// NewValidator creates Validator of requests to document paths served from `baseURL`
+func NewValidator(d *Document, baseURL string) *Validator {
+	var v Validator
+	for path, item := range d.Paths {
+		r := route{item: item}
+		pattern := "^" + regexp.QuoteMeta(baseURL)
+		last := 0
+		for _, loc := range templateParam.FindAllStringIndex(path, -1) {
+			pattern += regexp.QuoteMeta(path[last:loc[0]]) + "([^/]+)"
+			r.params = append(r.params, path[loc[0]+1:loc[1]-1])
+			last = loc[1]
+		}
+		r.pattern = regexp.MustCompile(pattern + regexp.QuoteMeta(path[last:]) + "$")
+		v.routes = append(v.routes, r)
+	}
+	// path matching fixed segment wins over template, e.g. /task/trash over /task/{id}
+	sort.Slice(v.routes, func(i, j int) bool {
+		if len(v.routes[i].params) != len(v.routes[j].params) {
+			return len(v.routes[i].params) < len(v.routes[j].params)
+		}
+		return v.routes[i].pattern.String() < v.routes[j].pattern.String()
+	})
+	return &v
+}
+
+// Wrap returns handler passing valid requests to `next`, invalid ones are answered with 400
+func (v *Validator) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Validate(w, r); err != nil {
+			w.Header().Add("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Validate checks request against its operation, body of request is read and replaced to be read again.
+// Bodies of media types other than JSON are left for handlers to check, JSON bodies larger than MaxBodySize are rejected.
+func (v *Validator) Validate(w http.ResponseWriter, r *http.Request) error {
+	for _, rt := range v.routes {
+		values := rt.pattern.FindStringSubmatch(r.URL.Path)
+		if values == nil {
+			continue
+		}
+		op := rt.item.operations()[r.Method]
+		if op == nil {
+			return nil
+		}
+		pathValues := make(map[string]string, len(rt.params))
+		for i, name := range rt.params {
+			pathValues[name] = values[i+1]
+		}
+		if err := validateParams(mergeParameters(rt.item.Parameters, op.Parameters), pathValues, r); err != nil {
+			return err
+		}
+		return validateBody(op.RequestBody, w, r)
+	}
+	return nil
+}
+
+// mergeParameters returns params of path overridden by params of operation
+func mergeParameters(pathParams, opParams []*Parameter) []*Parameter {
+	params := append([]*Parameter(nil), opParams...)
+	for _, p := range pathParams {
+		overridden := false
+		for _, o := range opParams {
+			if o.Name == p.Name && o.In == p.In {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// validateParams checks path and query params, arrays in query may be comma separated and repeated
+func validateParams(params []*Parameter, pathValues map[string]string, r *http.Request) error {
+	query := r.URL.Query()
+	for _, p := range params {
+		var raw []string
+		var location string
+		switch p.In {
+		case "path":
+			raw = []string{pathValues[p.Name]}
+			location = fmt.Sprintf(`path param "%s"`, p.Name)
+		case "query":
+			raw = query[p.Name]
+			location = fmt.Sprintf(`query param "%s"`, p.Name)
+		default:
+			continue
+		}
+		if len(raw) == 0 {
+			if p.Required {
+				return &common.InvalidInputError{Reason: location + " is required"}
+			}
+			continue
+		}
+		if p.Schema == nil {
+			continue
+		}
+		var value interface{}
+		if p.Schema.Type == "array" {
+			var items []interface{}
+			for _, param := range raw {
+				for _, item := range strings.Split(param, ",") {
+					items = append(items, paramValue(p.Schema.Items, item))
+				}
+			}
+			value = items
+		} else {
+			value = paramValue(p.Schema, raw[0])
+		}
+		if err := validate(p.Schema, value, location); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// paramValue converts text of param to value of type required by schema, text is kept if it can not be converted
+func paramValue(s *Schema, raw string) interface{} {
+	if s == nil {
+		return raw
+	}
+	switch s.Type {
+	case "integer", "number":
+		n := json.Number(raw)
+		if _, err := n.Float64(); err == nil {
+			return n
+		}
+	case "boolean":
+		switch raw {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+	}
+	return raw
+}
+
+func validateBody(body *RequestBody, w http.ResponseWriter, r *http.Request) error {
+	if body == nil {
+		return nil
+	}
+	content := jsonContent(body, r.Header.Get("Content-Type"))
+	if content == nil || content.Schema == nil {
+		return nil
+	}
+	data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	if err != nil {
+		return &common.InvalidInputError{Reason: fmt.Sprintf("failed to read body: %s", err)}
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(data))
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return &common.InvalidInputError{Reason: "body is required"}
+		}
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var value interface{}
+	if err = dec.Decode(&value); err != nil {
+		return &common.InvalidInputError{Reason: fmt.Sprintf("malformed JSON body: %s", err)}
+	}
+	return validate(content.Schema, value, "body")
+}
+
+// jsonContent returns JSON media type of body matching content type of request.
+// Request without content type is validated as JSON if body may be JSON.
+func jsonContent(body *RequestBody, contentType string) *MediaType {
+	if contentType == "" {
+		if content, ok := body.Content["application/json"]; ok {
+			return content
+		}
+		for mt, content := range body.Content {
+			if isJSON(mt) {
+				return content
+			}
+		}
+		return nil
+	}
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !isJSON(mt) {
+		return nil
+	}
+	return body.Content[mt]
+}
+
+func isJSON(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
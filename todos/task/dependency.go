@@ -0,0 +1,44 @@
+package task
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Progress of Task subtasks
+type Progress struct {
+	Done  uint
+	Total uint
+}
+
+// Dependency makes Task blocked by another one until the blocker is done
+type Dependency struct {
+	TaskID    uint64
+	BlockerID uint64
+}
+
+// BlockedError represents rejected completion of Task which has blockers that are not done
+type BlockedError struct {
+	ID       uint64
+	Blockers []uint64
+}
+
+func (e *BlockedError) Error() string {
+	ids := make([]string, len(e.Blockers))
+	for i, id := range e.Blockers {
+		ids[i] = strconv.FormatUint(id, 10)
+	}
+	return fmt.Sprintf("Task with ID %d can not be done before tasks with IDs %s", e.ID, strings.Join(ids, ", "))
+}
+
+// CycleError represents rejected link between tasks that would make Task its own subtask or blocker
+type CycleError struct {
+	ID       uint64
+	Other    uint64
+	Relation string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("Task with ID %d can not become %s of task with ID %d as it would create a cycle", e.Other, e.Relation, e.ID)
+}
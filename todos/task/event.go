@@ -0,0 +1,100 @@
+package task
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EventType of task Event
+type EventType uint
+
+// Possible EventTypes
+const (
+	Created EventType = iota
+	Changed
+	StatusChanged
+	Commented
+	Deleted
+	Restored
+)
+
+// Event in activity history of Task, history is append-only
+type Event struct {
+	ID     uint64
+	TaskID uint64
+	Type   EventType
+	// Actor is ID of user who caused the event, empty for changes made by the app itself, e.g. overdue sweeps
+	Actor string
+	// Field, From and To describe change of Changed and StatusChanged events, values are formatted as in REST API
+	Field string
+	From  string
+	To    string
+	// Text of comment
+	Text      string
+	CreatedAt time.Time
+}
+
+// StatusChange of Task made in bulk, e.g. by overdue sweep
+type StatusChange struct {
+	ID   uint64
+	From Status
+	To   Status
+}
+
+func (t EventType) String() string {
+	switch t {
+	case Created:
+		return "created"
+	case Changed:
+		return "changed"
+	case StatusChanged:
+		return "status"
+	case Commented:
+		return "comment"
+	case Deleted:
+		return "deleted"
+	case Restored:
+		return "restored"
+	default:
+		return ""
+	}
+}
+
+// Diff returns Changed and StatusChanged events for fields that differ between two versions of Task
+func Diff(before, after Task) []Event {
+	var events []Event
+	changed := func(field, from, to string) {
+		if from != to {
+			events = append(events, Event{TaskID: after.ID, Type: Changed, Field: field, From: from, To: to})
+		}
+	}
+	changed("name", before.Name, after.Name)
+	changed("description", before.Description, after.Description)
+	changed("dueDate", formatDueDate(before), formatDueDate(after))
+	if before.Status != after.Status {
+		events = append(events, Event{
+			TaskID: after.ID, Type: StatusChanged, Field: "status", From: before.Status.String(), To: after.Status.String(),
+		})
+	}
+	changed("recurrence", before.Recurrence.String(), after.Recurrence.String())
+	changed("tags", strings.Join(before.Tags, ","), strings.Join(after.Tags, ","))
+	changed("parent", FormatID(before.ParentID), FormatID(after.ParentID))
+	return events
+}
+
+// FormatID formats ID of Task as in events, zero ID means no task and is formatted as empty string
+func FormatID(id uint64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatUint(id, 10)
+}
+
+// formatDueDate formats due date as Unix time, empty if task has no due date
+func formatDueDate(t Task) string {
+	if !t.HasDueDate() {
+		return ""
+	}
+	return strconv.FormatInt(t.DueDate.Unix(), 10)
+}
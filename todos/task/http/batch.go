@@ -0,0 +1,103 @@
+package taskhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	task_service "github.com/Vesninovich/go-tasks/todos/task/service"
+)
+
+// PostBatch serves requests to create, update and delete tasks in one transaction.
+// By default batch is atomic: if any operation fails, nothing is saved and response has status of the failed one.
+// With "atomic": false failed operations are skipped and others are saved.
+// Response lists result of each operation in order.
+func (s *HTTPServer) PostBatch(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var data batchAPIModel
+	if err = json.Unmarshal(body, &data); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ops := make([]task_service.BatchOperation, len(data.Operations))
+	for i, op := range data.Operations {
+		ops[i] = task_service.BatchOperation{Action: op.Action, ID: op.ID}
+		if op.Task != nil {
+			ops[i].Data, err = parseMergePatch(op.Task)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("operation %d: %w", i, err))
+				return
+			}
+		}
+		ops[i].Data.Version = op.Version
+	}
+	atomic := data.Atomic == nil || *data.Atomic
+	results, err := s.service.Batch(r.Context(), ops, atomic)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	status := http.StatusOK
+	res := make([]batchResultAPIModel, len(results))
+	for i, result := range results {
+		res[i] = batchResultToAPIModel(ops[i].Action, result)
+		if atomic && result.Err != nil && res[i].Status != http.StatusFailedDependency {
+			status = res[i].Status
+		}
+	}
+	out, err := json.Marshal(batchResponseAPIModel{Results: res})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(out)
+}
+
+type batchAPIModel struct {
+	// Atomic is true if not set
+	Atomic     *bool                    `json:"atomic"`
+	Operations []batchOperationAPIModel `json:"operations"`
+}
+
+type batchOperationAPIModel struct {
+	Action string `json:"action"`
+	ID     uint64 `json:"id"`
+	// Version is expected current version of updated task, zero skips the check
+	Version uint64 `json:"version"`
+	// Task is created task or merge patch of updated one
+	Task json.RawMessage `json:"task"`
+}
+
+type batchResponseAPIModel struct {
+	Results []batchResultAPIModel `json:"results"`
+}
+
+// batchResultAPIModel is result of single operation, Status is the one the operation would have on its own
+type batchResultAPIModel struct {
+	Status int           `json:"status"`
+	Task   *taskAPIModel `json:"task,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+func batchResultToAPIModel(action string, result task_service.BatchResult) batchResultAPIModel {
+	if result.Err != nil {
+		return batchResultAPIModel{Status: serviceErrorStatus(result.Err), Error: result.Err.Error()}
+	}
+	switch action {
+	case task_service.BatchCreate:
+		t := taskToAPIModel(result.Task)
+		return batchResultAPIModel{Status: http.StatusCreated, Task: &t}
+	case task_service.BatchDelete:
+		return batchResultAPIModel{Status: http.StatusOK}
+	default:
+		t := taskToAPIModel(result.Task)
+		return batchResultAPIModel{Status: http.StatusOK, Task: &t}
+	}
+}
@@ -0,0 +1,128 @@
+package taskhttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+	"github.com/Vesninovich/go-tasks/todos/task/live"
+)
+
+// heartbeatInterval is how often comment is sent to idle stream, so proxies do not close it
+const heartbeatInterval = 15 * time.Second
+
+// GetEvents serves requests to stream changes of tasks user may read as server-sent events.
+// Events are filtered by `type` and `task` (comma separated, may be repeated) query params.
+// Client resuming after reconnect sends ID of the last received event in `Last-Event-ID` header
+// or `lastEventId` query param and gets events it missed, or `reset` event if they are not kept anymore.
+func (s *HTTPServer) GetEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+	types, taskIDs, err := parseEventsQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	lastID, err := parseLastEventID(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	sub, missed, err := s.service.Subscribe(r.Context(), types, taskIDs, lastID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	if missed {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case e, ok := <-sub.Events():
+			if !ok {
+				// subscriber did not keep up with events, client reconnects and gets missed ones
+				return
+			}
+			data, err := json.Marshal(liveEventToAPIModel(e))
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+		}
+		flusher.Flush()
+	}
+}
+
+// parseEventsQuery parses filter of live events
+func parseEventsQuery(values url.Values) (types []live.Type, taskIDs []uint64, err error) {
+	for _, param := range values["type"] {
+		for _, t := range strings.Split(param, ",") {
+			types = append(types, live.Type(t))
+		}
+	}
+	for _, param := range values["task"] {
+		for _, v := range strings.Split(param, ",") {
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return nil, nil, &common.InvalidInputError{Reason: fmt.Sprintf(`malformed task id "%s"`, v)}
+			}
+			taskIDs = append(taskIDs, id)
+		}
+	}
+	return
+}
+
+// parseLastEventID parses ID of the last event received by client, zero if it is not set
+func parseLastEventID(r *http.Request) (uint64, error) {
+	v := r.Header.Get("Last-Event-ID")
+	if v == "" {
+		v = r.URL.Query().Get("lastEventId")
+	}
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, &common.InvalidInputError{Reason: fmt.Sprintf(`malformed last event id "%s"`, v)}
+	}
+	return id, nil
+}
+
+type liveEventAPIModel struct {
+	TaskID uint64   `json:"taskID"`
+	Actor  string   `json:"actor,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+	// Time is Unix time of change
+	Time int64 `json:"time"`
+}
+
+func liveEventToAPIModel(e live.Event) liveEventAPIModel {
+	return liveEventAPIModel{
+		TaskID: e.TaskID,
+		Actor:  e.Actor,
+		Fields: e.Fields,
+		Time:   e.CreatedAt.Unix(),
+	}
+}
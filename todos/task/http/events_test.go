@@ -0,0 +1,65 @@
+package taskhttp
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
+	"github.com/Vesninovich/go-tasks/todos/task/live"
+	task_service "github.com/Vesninovich/go-tasks/todos/task/service"
+)
+
+func TestEvents(t *testing.T) {
+	s := New(task_service.New(inmemory.New(), task_service.Options{Live: live.NewBroker(0)}))
+	server := httptest.NewServer(http.HandlerFunc(s.GetEvents))
+	defer server.Close()
+
+	res, err := http.Get(server.URL + "?type=created,deleted")
+	if err != nil {
+		t.Fatalf("Got error subscribing: %s", err)
+	}
+	defer res.Body.Close()
+	checkStatus(t, http.StatusOK, res.StatusCode)
+	checkContentType(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	s.PostTask(httptest.NewRecorder(), httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"test"}`)))
+	s.PatchTask(httptest.NewRecorder(), httptest.NewRequest("PATCH", "/1", strings.NewReader(`{"name":"renamed"}`)))
+	s.DeleteTask(httptest.NewRecorder(), httptest.NewRequest("DELETE", "/1", nil))
+
+	stream := bufio.NewReader(res.Body)
+	created := readServerEvent(t, stream)
+	if !strings.HasPrefix(created[0], "id: ") || created[1] != "event: created" ||
+		!strings.HasPrefix(created[2], `data: {"taskID":1,"time":`) {
+		t.Errorf("Wrong created event: %v", created)
+	}
+	if deleted := readServerEvent(t, stream); deleted[1] != "event: deleted" {
+		t.Errorf("Expected update to be filtered out, got %v", deleted)
+	}
+
+	rec := httptest.NewRecorder()
+	s.GetEvents(rec, httptest.NewRequest("GET", "/events?type=unknown", nil))
+	checkStatus(t, http.StatusBadRequest, rec.Code)
+	rec = httptest.NewRecorder()
+	createServer().GetEvents(rec, httptest.NewRequest("GET", "/events", nil))
+	checkStatus(t, http.StatusNotImplemented, rec.Code)
+}
+
+// readServerEvent reads lines of the next event from stream
+func readServerEvent(t *testing.T, stream *bufio.Reader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := stream.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Got error reading events: %s", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+		lines = append(lines, line)
+	}
+}
@@ -1,14 +1,15 @@
 package taskhttp
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"mime"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/todos/common"
 	"github.com/Vesninovich/go-tasks/todos/task"
@@ -25,16 +26,24 @@ func New(service *task_service.Service) *HTTPServer {
 	return &HTTPServer{service}
 }
 
-// GetTasks serves requests to read slice of tasks
+// GetTasks serves requests to read slice of tasks.
+// Tasks are filtered by `status` (comma separated, may be repeated), `dueFrom` and `dueTo` (Unix time),
+// `search` and `tag` (comma separated, may be repeated, tasks with any of them match unless `tagMatch` is "all")
+// query params and sorted by `sort` ("id", "dueDate" or "name") in `order` ("asc" or "desc").
 func (s *HTTPServer) GetTasks(w http.ResponseWriter, r *http.Request) {
 	from, count, err := parsePaginationQuery(r.URL.Query())
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	tasks, err := s.service.Get(context.Background(), uint(from), uint(count))
+	query, err := parseTaskQuery(r.URL.Query())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err)
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tasks, err := s.service.Get(r.Context(), query, uint(from), uint(count))
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	res, err := json.Marshal(prepareTasks(tasks))
@@ -46,21 +55,16 @@ func (s *HTTPServer) GetTasks(w http.ResponseWriter, r *http.Request) {
 	w.Write(res)
 }
 
-// GetOneTask serves requests to get task by id
+// GetOneTask serves requests to get task by id, version of task is sent in `ETag` header
 func (s *HTTPServer) GetOneTask(w http.ResponseWriter, r *http.Request) {
 	id, err := getIDFromURL(r.URL.Path)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	tsk, err := s.service.GetOne(context.Background(), id)
+	tsk, err := s.service.GetOne(r.Context(), id)
 	if err != nil {
-		switch err.(type) {
-		case *common.NotFoundError:
-			writeError(w, http.StatusNotFound, err)
-		default:
-			writeError(w, http.StatusInternalServerError, err)
-		}
+		writeServiceError(w, err)
 		return
 	}
 	res, err := json.Marshal(taskToAPIModel(tsk))
@@ -69,6 +73,7 @@ func (s *HTTPServer) GetOneTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	w.Header().Add("Content-Type", "application/json")
+	w.Header().Set("ETag", etag(tsk.Version))
 	w.Write(res)
 }
 
@@ -85,14 +90,9 @@ func (s *HTTPServer) PostTask(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	task, err := s.service.CreateTask(context.Background(), data.Name, data.Description, int64(data.DueDate))
+	task, err := s.service.CreateTask(r.Context(), data.Name, data.Description, int64(data.DueDate), data.Recurrence, data.Tags)
 	if err != nil {
-		switch err.(type) {
-		case *common.InvalidInputError:
-			writeError(w, http.StatusBadRequest, err)
-		default:
-			writeError(w, http.StatusInternalServerError, err)
-		}
+		writeServiceError(w, err)
 		return
 	}
 	w.Header().Add("Content-Type", "text/plain")
@@ -100,8 +100,14 @@ func (s *HTTPServer) PostTask(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(fmt.Sprint(task.ID)))
 }
 
-// PutTask serves requests to update existing task
+// PutTask serves requests to update existing task.
+// Task is only updated if it still has version from `If-Match` header when it is set.
 func (s *HTTPServer) PutTask(w http.ResponseWriter, r *http.Request) {
+	version, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
@@ -118,52 +124,494 @@ func (s *HTTPServer) PutTask(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	_, err = s.service.UpdateTask(context.Background(), id, data.Name, data.Description, int64(data.DueDate), data.Status)
+	tsk, err := s.service.UpdateTask(r.Context(), id, version, data.Name, data.Description, int64(data.DueDate), data.Status, data.Recurrence, data.Tags)
 	if err != nil {
-		switch err.(type) {
-		case *common.InvalidInputError:
-			writeError(w, http.StatusBadRequest, err)
-		case *common.NotFoundError:
-			writeError(w, http.StatusNotFound, err)
-		default:
-			writeError(w, http.StatusInternalServerError, err)
-		}
+		writeServiceError(w, err)
 		return
 	}
+	w.Header().Set("ETag", etag(tsk.Version))
 	w.WriteHeader(http.StatusOK)
 }
 
-// DeleteTask serves requests to get task by id
+// PatchTask serves requests to partially update existing task with JSON Merge Patch (RFC 7396).
+// Version from `If-Match` header is checked as in PutTask.
+func (s *HTTPServer) PatchTask(w http.ResponseWriter, r *http.Request) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mt != "application/merge-patch+json" && mt != "application/json") {
+			writeError(w, http.StatusUnsupportedMediaType, fmt.Errorf("unsupported content type %s", ct))
+			return
+		}
+	}
+	id, err := getIDFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	patch, err := parseMergePatch(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	patch.Version, err = parseIfMatch(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tsk, err := s.service.PatchTask(r.Context(), id, patch)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag(tsk.Version))
+	writeJSON(w, taskToAPIModel(tsk))
+}
+
+// DeleteTask serves requests to move task to trash by id
 func (s *HTTPServer) DeleteTask(w http.ResponseWriter, r *http.Request) {
 	id, err := getIDFromURL(r.URL.Path)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
-	err = s.service.Delete(context.Background(), id)
+	err = s.service.Delete(r.Context(), id)
 	if err != nil {
-		switch err.(type) {
-		case *common.NotFoundError:
-			writeError(w, http.StatusNotFound, err)
-		default:
-			writeError(w, http.StatusInternalServerError, err)
-		}
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetTrash serves requests to list tasks of user in trash, the latest deleted go first.
+// Tasks are paginated with `from` and `count` query params.
+func (s *HTTPServer) GetTrash(w http.ResponseWriter, r *http.Request) {
+	from, count, err := parsePaginationQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tasks, err := s.service.GetTrash(r.Context(), uint(from), uint(count))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, prepareTasks(tasks))
+}
+
+// PostRestore serves requests to restore task from trash by id
+func (s *HTTPServer) PostRestore(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(strings.TrimSuffix(r.URL.Path, "/restore"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tsk, err := s.service.Restore(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag(tsk.Version))
+	writeJSON(w, taskToAPIModel(tsk))
+}
+
+// PatchStatus serves requests to change task status with action.
+// Version from `If-Match` header is checked as in PutTask.
+func (s *HTTPServer) PatchStatus(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(strings.TrimSuffix(r.URL.Path, "/status"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	version, err := parseIfMatch(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var data statusActionAPIModel
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tsk, err := s.service.ChangeStatus(r.Context(), id, version, data.Action)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag(tsk.Version))
+	writeJSON(w, taskToAPIModel(tsk))
+}
+
+// GetShares serves requests to list users task is shared with
+func (s *HTTPServer) GetShares(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(strings.TrimSuffix(r.URL.Path, "/shares"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	shares, err := s.service.GetShares(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	res := make([]shareAPIModel, len(shares))
+	for i, share := range shares {
+		res[i] = shareToAPIModel(share)
+	}
+	writeJSON(w, res)
+}
+
+// PutShare serves requests to share task with user
+func (s *HTTPServer) PutShare(w http.ResponseWriter, r *http.Request) {
+	id, user, err := getIDAndUserFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var data shareAPIModel
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	share, err := s.service.ShareTask(r.Context(), id, user, data.Permission)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, shareToAPIModel(share))
+}
+
+// DeleteShare serves requests to stop sharing task with user
+func (s *HTTPServer) DeleteShare(w http.ResponseWriter, r *http.Request) {
+	id, user, err := getIDAndUserFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	err = s.service.UnshareTask(r.Context(), id, user)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetSubtasks serves requests to list subtasks of task
+func (s *HTTPServer) GetSubtasks(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(strings.TrimSuffix(r.URL.Path, "/subtasks"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tasks, err := s.service.GetSubtasks(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, prepareTasks(tasks))
+}
+
+// PutSubtask serves requests to attach task as subtask, attached task is sent in response
+func (s *HTTPServer) PutSubtask(w http.ResponseWriter, r *http.Request) {
+	id, childID, err := getIDPairFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	child, err := s.service.AttachSubtask(r.Context(), id, childID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag(child.Version))
+	writeJSON(w, taskToAPIModel(child))
+}
+
+// DeleteSubtask serves requests to detach subtask, detached task is sent in response
+func (s *HTTPServer) DeleteSubtask(w http.ResponseWriter, r *http.Request) {
+	id, childID, err := getIDPairFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	child, err := s.service.DetachSubtask(r.Context(), id, childID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.Header().Set("ETag", etag(child.Version))
+	writeJSON(w, taskToAPIModel(child))
+}
+
+// GetBlockers serves requests to list tasks blocking task
+func (s *HTTPServer) GetBlockers(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(strings.TrimSuffix(r.URL.Path, "/blockers"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tasks, err := s.service.GetBlockers(r.Context(), id)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, prepareTasks(tasks))
+}
+
+// PutBlocker serves requests to make task blocked by another one
+func (s *HTTPServer) PutBlocker(w http.ResponseWriter, r *http.Request) {
+	id, blockerID, err := getIDPairFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	err = s.service.AddBlocker(r.Context(), id, blockerID)
+	if err != nil {
+		writeServiceError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
+// DeleteBlocker serves requests to remove blocker of task
+func (s *HTTPServer) DeleteBlocker(w http.ResponseWriter, r *http.Request) {
+	id, blockerID, err := getIDPairFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	err = s.service.RemoveBlocker(r.Context(), id, blockerID)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetHistory serves requests to read history of task, events are paginated with `from` and `count` query params
+func (s *HTTPServer) GetHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(strings.TrimSuffix(r.URL.Path, "/history"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	from, count, err := parsePaginationQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	events, err := s.service.GetHistory(r.Context(), id, uint(from), uint(count))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	res := make([]eventAPIModel, len(events))
+	for i, e := range events {
+		res[i] = eventToAPIModel(e)
+	}
+	writeJSON(w, res)
+}
+
+// PostComment serves requests to comment task
+func (s *HTTPServer) PostComment(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(strings.TrimSuffix(r.URL.Path, "/comments"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var data commentAPIModel
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	e, err := s.service.Comment(r.Context(), id, data.Text)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	res, err := json.Marshal(eventToAPIModel(e))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(res)
+}
+
+// GetTags serves requests to list tags of tasks available to user with number of tasks having each tag
+func (s *HTTPServer) GetTags(w http.ResponseWriter, r *http.Request) {
+	tags, err := s.service.GetTags(r.Context())
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	res := make([]tagAPIModel, len(tags))
+	for i, tc := range tags {
+		res[i] = tagAPIModel{tc.Tag, tc.Count}
+	}
+	writeJSON(w, res)
+}
+
+// PutTag serves requests to rename tag on tasks owned by user
+func (s *HTTPServer) PutTag(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	tag := parts[len(parts)-1]
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var data renameTagAPIModel
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	changed, err := s.service.RenameTag(r.Context(), tag, data.Name)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	name, _ := task.NormalizeTag(data.Name)
+	writeJSON(w, tagChangeAPIModel{name, changed})
+}
+
+// PostTagsMerge serves requests to merge tags into one on tasks owned by user
+func (s *HTTPServer) PostTagsMerge(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	var data mergeTagsAPIModel
+	err = json.Unmarshal(body, &data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	changed, err := s.service.MergeTags(r.Context(), data.Tags, data.Into)
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	into, _ := task.NormalizeTag(data.Into)
+	writeJSON(w, tagChangeAPIModel{into, changed})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	res, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.Write(res)
+}
+
+// writeServiceError writes error returned by service with according status
+func writeServiceError(w http.ResponseWriter, err error) {
+	writeError(w, serviceErrorStatus(err), err)
+}
+
+// serviceErrorStatus returns status of response to request failed with error returned by service
+func serviceErrorStatus(err error) int {
+	if err == task_service.ErrLiveDisabled {
+		return http.StatusNotImplemented
+	}
+	switch err.(type) {
+	case *common.InvalidInputError:
+		return http.StatusBadRequest
+	case *common.ForbiddenError:
+		return http.StatusForbidden
+	case *common.NotFoundError:
+		return http.StatusNotFound
+	case *task.TransitionError, *task.BlockedError, *task.CycleError, *task.TagExistsError:
+		return http.StatusConflict
+	case *common.VersionMismatchError:
+		return http.StatusPreconditionFailed
+	case *task_service.NotAppliedError:
+		return http.StatusFailedDependency
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
 func writeError(w http.ResponseWriter, status int, err error) {
 	w.Header().Add("Content-Type", "text/plain")
 	w.WriteHeader(status)
 	w.Write([]byte(err.Error()))
 }
 
+// etag formats version of task as strong entity tag
+func etag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// parseIfMatch parses expected version of task from `If-Match` header, zero if it is not set or is "*".
+// Only single entity tag is supported.
+func parseIfMatch(r *http.Request) (uint64, error) {
+	h := strings.TrimSpace(r.Header.Get("If-Match"))
+	if h == "" || h == "*" {
+		return 0, nil
+	}
+	if len(h) < 2 || h[0] != '"' || h[len(h)-1] != '"' {
+		return 0, fmt.Errorf("malformed If-Match header %s", h)
+	}
+	version, err := strconv.ParseUint(h[1:len(h)-1], 10, 64)
+	if err != nil || version == 0 {
+		return 0, fmt.Errorf("malformed If-Match header %s", h)
+	}
+	return version, nil
+}
+
 func getIDFromURL(url string) (uint64, error) {
 	parts := strings.Split(url, "/")
 	return strconv.ParseUint(parts[len(parts)-1], 10, 64)
 }
 
+// getIDAndUserFromURL parses paths ending with `{id}/shares/{user}`
+func getIDAndUserFromURL(url string) (uint64, string, error) {
+	parts := strings.Split(url, "/")
+	if len(parts) < 3 {
+		return 0, "", fmt.Errorf("malformed share path %s", url)
+	}
+	id, err := strconv.ParseUint(parts[len(parts)-3], 10, 64)
+	return id, parts[len(parts)-1], err
+}
+
+// getIDPairFromURL parses paths ending with `{id}/subtasks/{otherID}` or `{id}/blockers/{otherID}`
+func getIDPairFromURL(url string) (uint64, uint64, error) {
+	id, other, err := getIDAndUserFromURL(url)
+	if err != nil {
+		return 0, 0, err
+	}
+	otherID, err := strconv.ParseUint(other, 10, 64)
+	return id, otherID, err
+}
+
 func parsePaginationQuery(query url.Values) (from uint64, count uint64, err error) {
 	f := query.Get("from")
 	c := query.Get("count")
@@ -186,24 +634,199 @@ func parsePaginationQuery(query url.Values) (from uint64, count uint64, err erro
 	return
 }
 
+// parseMergePatch parses JSON Merge Patch of task.
+// null removes description, due date, recurrence and tags, name and status can not be removed.
+func parseMergePatch(body []byte) (task_service.TaskPatch, error) {
+	var patch task_service.TaskPatch
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return patch, fmt.Errorf("merge patch must be JSON object: %w", err)
+	}
+	for key, raw := range fields {
+		null := string(raw) == "null"
+		var err error
+		switch key {
+		case "name":
+			if null {
+				return patch, fmt.Errorf("name can not be removed")
+			}
+			patch.Name = new(string)
+			err = json.Unmarshal(raw, patch.Name)
+		case "description":
+			patch.Description = new(string)
+			if !null {
+				err = json.Unmarshal(raw, patch.Description)
+			}
+		case "dueDate":
+			patch.DueDate = &time.Time{}
+			if !null {
+				var sec int64
+				err = json.Unmarshal(raw, &sec)
+				*patch.DueDate = time.Unix(sec, 0)
+			}
+		case "status":
+			if null {
+				return patch, fmt.Errorf("status can not be removed")
+			}
+			patch.Status = new(string)
+			err = json.Unmarshal(raw, patch.Status)
+		case "recurrence":
+			patch.Recurrence = new(string)
+			if !null {
+				err = json.Unmarshal(raw, patch.Recurrence)
+			}
+		case "tags":
+			patch.Tags = &[]string{}
+			if !null {
+				err = json.Unmarshal(raw, patch.Tags)
+			}
+		default:
+			return patch, fmt.Errorf(`unknown field "%s"`, key)
+		}
+		if err != nil {
+			return patch, fmt.Errorf(`malformed "%s": %w`, key, err)
+		}
+	}
+	return patch, nil
+}
+
+func parseTaskQuery(values url.Values) (task.Query, error) {
+	var q task.Query
+	for _, param := range values["status"] {
+		for _, st := range strings.Split(param, ",") {
+			status, err := task.StatusFromText(st)
+			if err != nil {
+				return q, err
+			}
+			q.Statuses = append(q.Statuses, status)
+		}
+	}
+	var err error
+	if q.DueFrom, err = parseTimeParam(values, "dueFrom"); err != nil {
+		return q, err
+	}
+	if q.DueTo, err = parseTimeParam(values, "dueTo"); err != nil {
+		return q, err
+	}
+	q.Search = values.Get("search")
+	for _, param := range values["tag"] {
+		q.Tags = append(q.Tags, strings.Split(param, ",")...)
+	}
+	switch values.Get("tagMatch") {
+	case "", "any":
+	case "all":
+		q.AllTags = true
+	default:
+		return q, &common.InvalidInputError{Reason: `tagMatch must be "any" or "all"`}
+	}
+	if sort := values.Get("sort"); sort != "" {
+		if q.SortBy, err = task.SortFieldFromText(sort); err != nil {
+			return q, err
+		}
+	}
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		q.Descending = true
+	default:
+		return q, &common.InvalidInputError{Reason: `order must be "asc" or "desc"`}
+	}
+	return q, nil
+}
+
+// parseTimeParam parses Unix time query param, returns zero time if it is not set
+func parseTimeParam(values url.Values, name string) (time.Time, error) {
+	v := values.Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, &common.InvalidInputError{Reason: fmt.Sprintf(`"%s" must be Unix time`, name)}
+	}
+	return time.Unix(sec, 0), nil
+}
+
 type taskAPIModel struct {
 	ID          uint64 `json:"id"`
 	Name        string `json:"name"`
 	Description string `json:"description,omitempty"`
-	DueDate     int64  `json:"dueDate,omitempty"`
-	Status      string `json:"status,omitempty"`
+	// DueDate is Unix time, not set if task has no due date
+	DueDate *int64 `json:"dueDate,omitempty"`
+	Status  string `json:"status,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	Parent  uint64 `json:"parent,omitempty"`
+	// Recurrence is RRULE of recurring task
+	Recurrence string   `json:"recurrence,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	// Progress is only set for tasks with subtasks
+	Progress *progressAPIModel `json:"progress,omitempty"`
+	// DeletedAt is Unix time task was moved to trash, only set for tasks in trash
+	DeletedAt int64 `json:"deletedAt,omitempty"`
+}
+
+type progressAPIModel struct {
+	Done  uint `json:"done"`
+	Total uint `json:"total"`
+}
+
+type eventAPIModel struct {
+	ID    uint64 `json:"id"`
+	Type  string `json:"type"`
+	Actor string `json:"actor,omitempty"`
+	Field string `json:"field,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+	Text  string `json:"text,omitempty"`
+	// Time is Unix time of event
+	Time int64 `json:"time"`
+}
+
+type commentAPIModel struct {
+	Text string `json:"text"`
+}
+
+type tagAPIModel struct {
+	Tag   string `json:"tag"`
+	Count uint64 `json:"count"`
+}
+
+type renameTagAPIModel struct {
+	Name string `json:"name"`
+}
+
+type mergeTagsAPIModel struct {
+	Tags []string `json:"tags"`
+	Into string   `json:"into"`
+}
+
+// tagChangeAPIModel is result of tag rename or merge
+type tagChangeAPIModel struct {
+	Tag string `json:"tag"`
+	// Changed is number of changed tasks
+	Changed uint64 `json:"changed"`
+}
+
+type statusActionAPIModel struct {
+	Action string `json:"action"`
+}
+
+type shareAPIModel struct {
+	User       string `json:"user,omitempty"`
+	Permission string `json:"permission"`
 }
 
 type createTaskAPIModel struct {
-	Name        string
-	Description string
-	DueDate     int
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	DueDate     int      `json:"dueDate"`
+	Recurrence  string   `json:"recurrence"`
+	Tags        []string `json:"tags"`
 }
 
 type updateTaskAPIModel struct {
 	createTaskAPIModel
-	ID     uint64
-	Status string
+	Status string `json:"status"`
 }
 
 func prepareTasks(tasks []task.Task) []taskAPIModel {
@@ -215,11 +838,48 @@ func prepareTasks(tasks []task.Task) []taskAPIModel {
 }
 
 func taskToAPIModel(t task.Task) taskAPIModel {
-	return taskAPIModel{
-		t.ID,
-		t.Name,
-		t.Description,
-		t.DueDate.Unix(),
-		t.Status.String(),
+	m := taskAPIModel{
+		ID:          t.ID,
+		Name:        t.Name,
+		Description: t.Description,
+		DueDate:     dueDateToUnix(t),
+		Status:      t.Status.String(),
+		Owner:       t.OwnerID,
+		Parent:      t.ParentID,
+		Recurrence:  t.Recurrence.String(),
+		Tags:        t.Tags,
 	}
+	if t.Progress.Total > 0 {
+		m.Progress = &progressAPIModel{t.Progress.Done, t.Progress.Total}
+	}
+	if t.IsDeleted() {
+		m.DeletedAt = t.DeletedAt.Unix()
+	}
+	return m
+}
+
+// dueDateToUnix returns due date of task as Unix time, nil if task has no due date
+func dueDateToUnix(t task.Task) *int64 {
+	if !t.HasDueDate() {
+		return nil
+	}
+	sec := t.DueDate.Unix()
+	return &sec
+}
+
+func eventToAPIModel(e task.Event) eventAPIModel {
+	return eventAPIModel{
+		ID:    e.ID,
+		Type:  e.Type.String(),
+		Actor: e.Actor,
+		Field: e.Field,
+		From:  e.From,
+		To:    e.To,
+		Text:  e.Text,
+		Time:  e.CreatedAt.Unix(),
+	}
+}
+
+func shareToAPIModel(s task.Share) shareAPIModel {
+	return shareAPIModel{s.UserID, s.Permission.String()}
 }
@@ -1,13 +1,18 @@
 package taskhttp
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
 	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
 	task_service "github.com/Vesninovich/go-tasks/todos/task/service"
 )
@@ -18,10 +23,11 @@ func TestPostValid(t *testing.T) {
 		`{"name":"testB","description":"asd"}`,
 		`{"name":"testC","description":"dsa","dueDate":87654321}`,
 	}
+	// tasks with due dates in the past become overdue on read
 	savedTasks := []string{
-		`{"id":0,"name":"testA","dueDate":12345678,"status":"new"}`,
-		`{"id":1,"name":"testB","description":"asd","status":"new"}`,
-		`{"id":2,"name":"testC","description":"dsa","dueDate":87654321,"status":"new"}`,
+		`{"id":1,"name":"testA","dueDate":12345678,"status":"overdue"}`,
+		`{"id":2,"name":"testB","description":"asd","status":"new"}`,
+		`{"id":3,"name":"testC","description":"dsa","dueDate":87654321,"status":"overdue"}`,
 	}
 	tasksJSON := "[" + strings.Join(savedTasks, ",") + "]"
 
@@ -60,6 +66,208 @@ func TestPostInvalid(t *testing.T) {
 	}
 }
 
+func TestShares(t *testing.T) {
+	s := createServer()
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	bob := auth.NewContext(context.Background(), auth.Principal{Subject: "bob"})
+
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"shared"}`)).WithContext(alice)
+	rec := httptest.NewRecorder()
+	s.PostTask(rec, req)
+	checkStatus(t, http.StatusCreated, rec.Code)
+	id := rec.Body.String()
+
+	req = httptest.NewRequest("GET", "/"+id, nil).WithContext(bob)
+	rec = httptest.NewRecorder()
+	s.GetOneTask(rec, req)
+	checkStatus(t, http.StatusNotFound, rec.Code)
+
+	req = httptest.NewRequest("PUT", "/"+id+"/shares/bob", strings.NewReader(`{"permission":"read"}`)).WithContext(alice)
+	rec = httptest.NewRecorder()
+	s.PutShare(rec, req)
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); body != `{"user":"bob","permission":"read"}` {
+		t.Errorf("Wrong share in response: %s", body)
+	}
+
+	req = httptest.NewRequest("GET", "/"+id, nil).WithContext(bob)
+	rec = httptest.NewRecorder()
+	s.GetOneTask(rec, req)
+	checkStatus(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest("PUT", "/"+id, strings.NewReader(`{"name":"changed","status":"new"}`)).WithContext(bob)
+	rec = httptest.NewRecorder()
+	s.PutTask(rec, req)
+	checkStatus(t, http.StatusForbidden, rec.Code)
+
+	req = httptest.NewRequest("GET", "/"+id+"/shares", nil).WithContext(alice)
+	rec = httptest.NewRecorder()
+	s.GetShares(rec, req)
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); body != `[{"user":"bob","permission":"read"}]` {
+		t.Errorf("Wrong shares in response: %s", body)
+	}
+
+	req = httptest.NewRequest("DELETE", "/"+id+"/shares/bob", nil).WithContext(alice)
+	rec = httptest.NewRecorder()
+	s.DeleteShare(rec, req)
+	checkStatus(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest("GET", "/"+id, nil).WithContext(bob)
+	rec = httptest.NewRecorder()
+	s.GetOneTask(rec, req)
+	checkStatus(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPatchStatus(t *testing.T) {
+	s := createServer()
+	_, _, id := postTask(t, s, `{"name":"test"}`)
+
+	patch := func(action string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/"+id+"/status", strings.NewReader(`{"action":"`+action+`"}`))
+		rec := httptest.NewRecorder()
+		s.PatchStatus(rec, req)
+		return rec
+	}
+	rec := patch("complete")
+	checkStatus(t, http.StatusOK, rec.Code)
+	checkContentType(t, "application/json", rec.Header().Get("Content-Type"))
+	if body := rec.Body.String(); body != `{"id":1,"name":"test","status":"done"}` {
+		t.Errorf("Wrong task in response: %s", body)
+	}
+	rec = patch("start")
+	checkStatus(t, http.StatusConflict, rec.Code)
+	if !strings.Contains(rec.Body.String(), "allowed transitions: new") {
+		t.Errorf("Expected allowed transitions in error, got %s", rec.Body.String())
+	}
+	rec = patch("unknown")
+	checkStatus(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestPatchTask(t *testing.T) {
+	s := createServer()
+	_, _, id := postTask(t, s, `{"name":"test","description":"desc","dueDate":4102444800}`)
+
+	patch := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PATCH", "/"+id, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		rec := httptest.NewRecorder()
+		s.PatchTask(rec, req)
+		return rec
+	}
+	rec := patch("application/merge-patch+json", `{"name":"renamed","dueDate":null}`)
+	checkStatus(t, http.StatusOK, rec.Code)
+	checkContentType(t, "application/json", rec.Header().Get("Content-Type"))
+	if body := rec.Body.String(); body != `{"id":1,"name":"renamed","description":"desc","status":"new"}` {
+		t.Errorf("Wrong task in response: %s", body)
+	}
+
+	invalid := []string{`{"name":null}`, `{"status":null}`, `{"owner":"bob"}`, `{"dueDate":"tomorrow"}`, `[]`}
+	for _, body := range invalid {
+		rec = patch("application/merge-patch+json", body)
+		checkStatus(t, http.StatusBadRequest, rec.Code)
+	}
+	rec = patch("text/plain", `{"name":"renamed"}`)
+	checkStatus(t, http.StatusUnsupportedMediaType, rec.Code)
+	rec = patch("application/merge-patch+json", `{"dueDate":0}`)
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); !strings.Contains(body, `"dueDate":0,`) {
+		t.Errorf("Expected task to be due at Unix epoch, got %s", body)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	s := createServer()
+	_, _, id := postTask(t, s, `{"name":"test"}`)
+
+	req := httptest.NewRequest("GET", "/"+id, nil)
+	rec := httptest.NewRecorder()
+	s.GetOneTask(rec, req)
+	tag := rec.Header().Get("ETag")
+	if tag != `"1"` {
+		t.Fatalf("Expected ETag of created task to be \"1\", got %s", tag)
+	}
+
+	put := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/"+id, strings.NewReader(`{"name":"renamed","status":"new"}`))
+		req.Header.Set("If-Match", ifMatch)
+		rec := httptest.NewRecorder()
+		s.PutTask(rec, req)
+		return rec
+	}
+	rec = put(tag)
+	checkStatus(t, http.StatusOK, rec.Code)
+	if rec.Header().Get("ETag") != `"2"` {
+		t.Errorf("Expected ETag of updated task to be \"2\", got %s", rec.Header().Get("ETag"))
+	}
+	rec = put(tag)
+	checkStatus(t, http.StatusPreconditionFailed, rec.Code)
+	rec = put("W/1")
+	checkStatus(t, http.StatusBadRequest, rec.Code)
+
+	req = httptest.NewRequest("PATCH", "/"+id+"/status", strings.NewReader(`{"action":"start"}`))
+	req.Header.Set("If-Match", tag)
+	rec = httptest.NewRecorder()
+	s.PatchStatus(rec, req)
+	checkStatus(t, http.StatusPreconditionFailed, rec.Code)
+
+	rec = put("*")
+	checkStatus(t, http.StatusOK, rec.Code)
+}
+
+func TestGetTasksQuery(t *testing.T) {
+	s := createServer()
+	future := time.Now().Add(time.Hour).Unix()
+	for _, tsk := range []string{
+		fmt.Sprintf(`{"name":"b task","dueDate":%d}`, future+10),
+		fmt.Sprintf(`{"name":"a task","dueDate":%d}`, future+20),
+		`{"name":"other"}`,
+	} {
+		postTask(t, s, tsk)
+	}
+
+	get := func(query string) (int, string) {
+		req := httptest.NewRequest("GET", "/?"+query, nil)
+		rec := httptest.NewRecorder()
+		s.GetTasks(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+	ids := func(body string) []uint64 {
+		var tasks []taskAPIModel
+		if err := json.Unmarshal([]byte(body), &tasks); err != nil {
+			t.Fatalf("Got error parsing tasks: %s", err)
+		}
+		res := make([]uint64, len(tasks))
+		for i, tsk := range tasks {
+			res[i] = tsk.ID
+		}
+		return res
+	}
+
+	status, body := get("search=task&sort=name")
+	checkStatus(t, http.StatusOK, status)
+	if got := fmt.Sprint(ids(body)); got != "[2 1]" {
+		t.Errorf("Expected tasks [2 1], got %s", got)
+	}
+	status, body = get(fmt.Sprintf("dueFrom=%d&sort=dueDate&order=desc", future))
+	checkStatus(t, http.StatusOK, status)
+	if got := fmt.Sprint(ids(body)); got != "[2 1]" {
+		t.Errorf("Expected tasks [2 1], got %s", got)
+	}
+	status, body = get("status=new,done&status=cancelled&count=1")
+	checkStatus(t, http.StatusOK, status)
+	if got := fmt.Sprint(ids(body)); got != "[1]" {
+		t.Errorf("Expected tasks [1], got %s", got)
+	}
+
+	for _, query := range []string{"status=unknown", "sort=owner", "order=up", "dueTo=yesterday", "dueFrom=20&dueTo=10"} {
+		status, _ = get(query)
+		if status != http.StatusBadRequest {
+			t.Errorf("Expected status %d for query %s, got %d", http.StatusBadRequest, query, status)
+		}
+	}
+}
+
 func getTasks(t *testing.T, s *HTTPServer) (status int, contentType, body string) {
 	req := httptest.NewRequest("GET", "/", nil)
 	rec := httptest.NewRecorder()
@@ -97,5 +305,269 @@ func checkStatus(t *testing.T, expected, actual int) {
 }
 
 func createServer() *HTTPServer {
-	return New(task_service.New(inmemory.New()))
+	return New(task_service.New(inmemory.New(), task_service.DefaultOptions))
+}
+
+func TestSubtasksAndBlockers(t *testing.T) {
+	s := createServer()
+	ids := make([]string, 3)
+	for i := range ids {
+		rec := httptest.NewRecorder()
+		s.PostTask(rec, httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"t"}`)))
+		ids[i] = rec.Body.String()
+	}
+
+	rec := httptest.NewRecorder()
+	s.PutSubtask(rec, httptest.NewRequest("PUT", "/"+ids[0]+"/subtasks/"+ids[1], nil))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); !strings.Contains(body, `"parent":`+ids[0]) {
+		t.Errorf("Expected subtask with parent in response, got %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.PutSubtask(rec, httptest.NewRequest("PUT", "/"+ids[1]+"/subtasks/"+ids[0], nil))
+	checkStatus(t, http.StatusConflict, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.GetOneTask(rec, httptest.NewRequest("GET", "/"+ids[0], nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"progress":{"done":0,"total":1}`) {
+		t.Errorf("Expected progress in response, got %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.PutBlocker(rec, httptest.NewRequest("PUT", "/"+ids[1]+"/blockers/"+ids[2], nil))
+	checkStatus(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.GetBlockers(rec, httptest.NewRequest("GET", "/"+ids[1]+"/blockers", nil))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); !strings.Contains(body, `"id":`+ids[2]) {
+		t.Errorf("Expected blocker in response, got %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.PatchStatus(rec, httptest.NewRequest("PATCH", "/"+ids[1]+"/status", strings.NewReader(`{"action":"complete"}`)))
+	checkStatus(t, http.StatusConflict, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.DeleteBlocker(rec, httptest.NewRequest("DELETE", "/"+ids[1]+"/blockers/"+ids[2], nil))
+	checkStatus(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.PatchStatus(rec, httptest.NewRequest("PATCH", "/"+ids[1]+"/status", strings.NewReader(`{"action":"complete"}`)))
+	checkStatus(t, http.StatusOK, rec.Code)
+}
+
+func TestRecurrence(t *testing.T) {
+	s := createServer()
+	rec := httptest.NewRecorder()
+	s.PostTask(rec, httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"t","dueDate":1609750800,"recurrence":"weekly"}`)))
+	checkStatus(t, http.StatusCreated, rec.Code)
+	id := rec.Body.String()
+
+	rec = httptest.NewRecorder()
+	s.GetOneTask(rec, httptest.NewRequest("GET", "/"+id, nil))
+	if body := rec.Body.String(); !strings.Contains(body, `"recurrence":"FREQ=WEEKLY"`) {
+		t.Errorf("Expected recurrence in response, got %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.PatchTask(rec, httptest.NewRequest("PATCH", "/"+id, strings.NewReader(`{"recurrence":"FREQ=MONTHLY;BYDAY=MO"}`)))
+	checkStatus(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.PatchTask(rec, httptest.NewRequest("PATCH", "/"+id, strings.NewReader(`{"recurrence":null}`)))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); strings.Contains(body, `"recurrence"`) {
+		t.Errorf("Expected recurrence to be removed, got %s", body)
+	}
+}
+
+func TestTags(t *testing.T) {
+	s := createServer()
+	for _, body := range []string{`{"name":"a","tags":["home","urgent"]}`, `{"name":"b","tags":["work"]}`} {
+		rec := httptest.NewRecorder()
+		s.PostTask(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+		checkStatus(t, http.StatusCreated, rec.Code)
+	}
+
+	rec := httptest.NewRecorder()
+	s.GetTasks(rec, httptest.NewRequest("GET", "/?tag=home,work&tagMatch=all", nil))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); body != "[]" {
+		t.Errorf("Expected no tasks with all tags, got %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.PutTag(rec, httptest.NewRequest("PUT", "/tags/home", strings.NewReader(`{"name":"work"}`)))
+	checkStatus(t, http.StatusConflict, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.PostTagsMerge(rec, httptest.NewRequest("POST", "/tags/merge", strings.NewReader(`{"tags":["home"],"into":"work"}`)))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); body != `{"tag":"work","changed":1}` {
+		t.Errorf("Wrong merge result: %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.GetTags(rec, httptest.NewRequest("GET", "/tags", nil))
+	if body := rec.Body.String(); body != `[{"tag":"urgent","count":1},{"tag":"work","count":2}]` {
+		t.Errorf("Wrong tags in response: %s", body)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	s := createServer()
+	rec := httptest.NewRecorder()
+	s.PostTask(rec, httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"t"}`)))
+	id := rec.Body.String()
+
+	rec = httptest.NewRecorder()
+	s.PostComment(rec, httptest.NewRequest("POST", "/"+id+"/comments", strings.NewReader(`{"text":"hi"}`)))
+	checkStatus(t, http.StatusCreated, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.PostComment(rec, httptest.NewRequest("POST", "/"+id+"/comments", strings.NewReader(`{}`)))
+	checkStatus(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.GetHistory(rec, httptest.NewRequest("GET", "/"+id+"/history?from=1", nil))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); !strings.HasPrefix(body, `[{"id":2,"type":"comment","text":"hi","time":`) {
+		t.Errorf("Wrong history in response: %s", body)
+	}
+}
+
+func TestICSRoundTrip(t *testing.T) {
+	s := createServer()
+	for _, body := range []string{
+		`{"name":"chores","description":"weekly, on Monday","dueDate":1609750800,"recurrence":"weekly","tags":["home"]}`,
+		`{"name":"no due date"}`,
+	} {
+		rec := httptest.NewRecorder()
+		s.PostTask(rec, httptest.NewRequest("POST", "/", strings.NewReader(body)))
+	}
+	rec := httptest.NewRecorder()
+	s.PatchStatus(rec, httptest.NewRequest("PATCH", "/2/status", strings.NewReader(`{"action":"start"}`)))
+
+	export := func(s *HTTPServer) string {
+		rec := httptest.NewRecorder()
+		s.GetTasksICS(rec, httptest.NewRequest("GET", "/task.ics", nil))
+		checkStatus(t, http.StatusOK, rec.Code)
+		checkContentType(t, "text/calendar; charset=utf-8", rec.Header().Get("Content-Type"))
+		return rec.Body.String()
+	}
+	listTasks := func(s *HTTPServer) string {
+		rec := httptest.NewRecorder()
+		s.GetTasks(rec, httptest.NewRequest("GET", "/", nil))
+		return rec.Body.String()
+	}
+	ics := export(s)
+	if !strings.Contains(ics, "DUE:20210104T090000Z\r\n") || !strings.Contains(ics, "STATUS:IN-PROCESS\r\n") {
+		t.Errorf("Wrong exported calendar:\n%s", ics)
+	}
+
+	imported := createServer()
+	rec = httptest.NewRecorder()
+	imported.PostImport(rec, httptest.NewRequest("POST", "/import", strings.NewReader(ics)))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); body != `[{"uid":"task-1@todos","id":1,"created":true},{"uid":"task-2@todos","id":2,"created":true}]` {
+		t.Errorf("Wrong import result: %s", body)
+	}
+	if expected, actual := listTasks(s), listTasks(imported); expected != actual {
+		t.Errorf("Expected imported tasks %s, got %s", expected, actual)
+	}
+
+	// importing again updates the same tasks
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/import", strings.NewReader(strings.Replace(ics, "SUMMARY:chores", "SUMMARY:renamed", 1)))
+	req.Header.Set("Content-Type", "text/calendar")
+	s.PostImport(rec, req)
+	if body := rec.Body.String(); !strings.HasPrefix(body, `[{"uid":"task-1@todos","id":1,"created":false}`) {
+		t.Errorf("Wrong import result: %s", body)
+	}
+	if tasks := listTasks(s); !strings.Contains(tasks, `"name":"renamed"`) || strings.Count(tasks, `"id"`) != 2 {
+		t.Errorf("Expected task to be renamed, got %s", tasks)
+	}
+
+	rec = httptest.NewRecorder()
+	s.PostImport(rec, httptest.NewRequest("POST", "/import", strings.NewReader("BEGIN:VTODO\r\nUID:x\r\nEND:VTODO\r\n")))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); body != `[{"uid":"x","created":false,"error":"Invalid input: name is required"}]` {
+		t.Errorf("Wrong import result: %s", body)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	s := createServer()
+	rec := httptest.NewRecorder()
+	s.PostTask(rec, httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"first"}`)))
+
+	batch := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.PostBatch(rec, httptest.NewRequest("POST", "/batch", strings.NewReader(body)))
+		return rec
+	}
+	operations := `"operations":[
+		{"action":"create","task":{"name":"second","tags":["sprint"]}},
+		{"action":"update","id":1,"version":1,"task":{"status":"done"}},
+		{"action":"delete","id":5}
+	]`
+	rec = batch(`{` + operations + `}`)
+	checkStatus(t, http.StatusNotFound, rec.Code)
+	if body := rec.Body.String(); !strings.Contains(body, `{"status":424,"error":"Operation is not applied as operation 2 failed"}`) {
+		t.Errorf("Wrong batch result: %s", body)
+	}
+
+	rec = batch(`{"atomic":false,` + operations + `}`)
+	checkStatus(t, http.StatusOK, rec.Code)
+	var res struct {
+		Results []struct {
+			Status int
+			Task   struct {
+				ID     uint64
+				Status string
+			}
+		}
+	}
+	json.Unmarshal(rec.Body.Bytes(), &res)
+	if len(res.Results) != 3 || res.Results[0].Status != http.StatusCreated || res.Results[0].Task.ID != 2 ||
+		res.Results[1].Status != http.StatusOK || res.Results[1].Task.Status != "done" || res.Results[2].Status != http.StatusNotFound {
+		t.Errorf("Wrong batch result: %s", rec.Body.String())
+	}
+
+	rec = batch(`{"operations":[{"action":"update","id":1,"task":{"name":null}}]}`)
+	checkStatus(t, http.StatusBadRequest, rec.Code)
+	rec = batch(`{"operations":[]}`)
+	checkStatus(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestTrash(t *testing.T) {
+	s := createServer()
+	rec := httptest.NewRecorder()
+	s.PostTask(rec, httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"test"}`)))
+
+	rec = httptest.NewRecorder()
+	s.DeleteTask(rec, httptest.NewRequest("DELETE", "/1", nil))
+	checkStatus(t, http.StatusOK, rec.Code)
+	rec = httptest.NewRecorder()
+	s.GetOneTask(rec, httptest.NewRequest("GET", "/1", nil))
+	checkStatus(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.GetTrash(rec, httptest.NewRequest("GET", "/trash", nil))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); !strings.HasPrefix(body, `[{"id":1,"name":"test","status":"new","deletedAt":`) {
+		t.Errorf("Wrong trash: %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	s.PostRestore(rec, httptest.NewRequest("POST", "/1/restore", nil))
+	checkStatus(t, http.StatusOK, rec.Code)
+	if body := rec.Body.String(); body != `{"id":1,"name":"test","status":"new"}` {
+		t.Errorf("Wrong restored task: %s", body)
+	}
+	rec = httptest.NewRecorder()
+	s.PostRestore(rec, httptest.NewRequest("POST", "/1/restore", nil))
+	checkStatus(t, http.StatusNotFound, rec.Code)
 }
@@ -0,0 +1,91 @@
+package taskhttp
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/task"
+	taskical "github.com/Vesninovich/go-tasks/todos/task/ical"
+)
+
+// maxImportSize limits size of imported calendars
+const maxImportSize = 10 << 20
+
+// GetTasksICS serves requests to export tasks as iCalendar VTODO components.
+// Tasks are filtered, sorted and paginated with the same query params as in GetTasks.
+func (s *HTTPServer) GetTasksICS(w http.ResponseWriter, r *http.Request) {
+	from, count, err := parsePaginationQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	query, err := parseTaskQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	tasks, err := s.service.Get(r.Context(), query, uint(from), uint(count))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	todos := make([]taskical.Todo, len(tasks))
+	for i, t := range tasks {
+		todos[i] = taskical.FromTask(t)
+	}
+	var buf bytes.Buffer
+	if err = taskical.Write(&buf, todos, time.Now()); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Add("Content-Type", taskical.ContentType)
+	w.Header().Add("Content-Disposition", `attachment; filename="tasks.ics"`)
+	w.Write(buf.Bytes())
+}
+
+// PostImport serves requests to import tasks from iCalendar file sent as request body
+// or as `file` field of multipart form. VTODO with UID of exported task updates the task if user may change it,
+// others create new tasks. Result of import of each VTODO is reported separately.
+func (s *HTTPServer) PostImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var body io.Reader = r.Body
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+	todos, err := taskical.Read(body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	results := make([]importResultAPIModel, len(todos))
+	for i, td := range todos {
+		results[i].UID = td.UID
+		patch, err := td.Patch()
+		if err == nil {
+			var t task.Task
+			t, results[i].Created, err = s.service.ImportTask(r.Context(), td.TaskID(), patch)
+			results[i].ID = t.ID
+		}
+		if err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+	writeJSON(w, results)
+}
+
+// importResultAPIModel is result of import of single VTODO, error is set if it was not imported
+type importResultAPIModel struct {
+	UID     string `json:"uid"`
+	ID      uint64 `json:"id,omitempty"`
+	Created bool   `json:"created"`
+	Error   string `json:"error,omitempty"`
+}
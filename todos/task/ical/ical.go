@@ -0,0 +1,369 @@
+// Package taskical converts tasks to and from iCalendar (RFC 5545) VTODO components
+package taskical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+	"github.com/Vesninovich/go-tasks/todos/task"
+	taskservice "github.com/Vesninovich/go-tasks/todos/task/service"
+)
+
+// ContentType of iCalendar documents
+const ContentType = "text/calendar; charset=utf-8"
+
+const (
+	prodID      = "-//go-tasks//todos//EN"
+	uidFormat   = "task-%d@todos"
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+	// lines longer than that are folded
+	maxLineLength = 75
+)
+
+// Todo is VTODO component of iCalendar
+type Todo struct {
+	UID         string
+	Summary     string
+	Description string
+	// Due is zero if todo has no due date
+	Due time.Time
+	// Status is one of NEEDS-ACTION, IN-PROCESS, COMPLETED and CANCELLED
+	Status     string
+	RRule      string
+	Categories []string
+	// RelatedTo is UID of parent todo
+	RelatedTo string
+	Sequence  uint64
+	// Present lists properties set in parsed todo, absent ones are left as is on import
+	Present map[string]bool
+}
+
+var statuses = map[task.Status]string{
+	task.New:        "NEEDS-ACTION",
+	task.InProgress: "IN-PROCESS",
+	task.Done:       "COMPLETED",
+	task.Cancelled:  "CANCELLED",
+	// iCalendar has no overdue status, todo past due date is overdue anyway
+	task.Overdue: "NEEDS-ACTION",
+}
+
+// UID returns UID of todo made from task with given id
+func UID(id uint64) string {
+	return fmt.Sprintf(uidFormat, id)
+}
+
+// TaskID returns ID of task todo was made from, zero if todo was made elsewhere
+func (td Todo) TaskID() uint64 {
+	var id uint64
+	if _, err := fmt.Sscanf(td.UID, uidFormat, &id); err != nil || UID(id) != td.UID {
+		return 0
+	}
+	return id
+}
+
+// FromTask makes todo from task
+func FromTask(t task.Task) Todo {
+	td := Todo{
+		UID:         UID(t.ID),
+		Summary:     t.Name,
+		Description: t.Description,
+		Due:         t.DueDate,
+		Status:      statuses[t.Status],
+		RRule:       t.Recurrence.String(),
+		Categories:  t.Tags,
+		Sequence:    t.Version - 1,
+	}
+	if t.ParentID != 0 {
+		td.RelatedTo = UID(t.ParentID)
+	}
+	return td
+}
+
+// Patch returns changes of task described by todo, only properties present in todo are changed
+func (td Todo) Patch() (taskservice.TaskPatch, error) {
+	var patch taskservice.TaskPatch
+	if td.Present["SUMMARY"] {
+		patch.Name = &td.Summary
+	}
+	if td.Present["DESCRIPTION"] {
+		patch.Description = &td.Description
+	}
+	if td.Present["DUE"] {
+		due := td.Due
+		patch.DueDate = &due
+	}
+	if td.Present["STATUS"] {
+		st, err := statusFromText(td.Status)
+		if err != nil {
+			return patch, err
+		}
+		s := st.String()
+		patch.Status = &s
+	}
+	if td.Present["RRULE"] {
+		patch.Recurrence = &td.RRule
+	}
+	if td.Present["CATEGORIES"] {
+		patch.Tags = &td.Categories
+	}
+	return patch, nil
+}
+
+func statusFromText(s string) (task.Status, error) {
+	for st, text := range statuses {
+		if text == s && st != task.Overdue {
+			return st, nil
+		}
+	}
+	return task.New, &common.InvalidInputError{Reason: fmt.Sprintf(`STATUS "%s" is not supported`, s)}
+}
+
+// Write writes calendar with given todos stamped with given time
+func Write(w io.Writer, todos []Todo, stamp time.Time) error {
+	bw := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", prodID)
+	for _, td := range todos {
+		line("BEGIN", "VTODO")
+		line("UID", escape(td.UID))
+		line("DTSTAMP", stamp.UTC().Format(utcLayout))
+		line("SEQUENCE", strconv.FormatUint(td.Sequence, 10))
+		line("SUMMARY", escape(td.Summary))
+		if td.Description != "" {
+			line("DESCRIPTION", escape(td.Description))
+		}
+		if !td.Due.IsZero() {
+			line("DUE", td.Due.UTC().Format(utcLayout))
+		}
+		if td.Status != "" {
+			line("STATUS", td.Status)
+		}
+		if td.RRule != "" {
+			line("RRULE", td.RRule)
+		}
+		if len(td.Categories) > 0 {
+			categories := make([]string, len(td.Categories))
+			for i, c := range td.Categories {
+				categories[i] = escape(c)
+			}
+			line("CATEGORIES", strings.Join(categories, ","))
+		}
+		if td.RelatedTo != "" {
+			line("RELATED-TO", escape(td.RelatedTo))
+		}
+		line("END", "VTODO")
+	}
+	line("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// writeFolded writes content line folding it into lines of at most 75 octets without splitting characters
+func writeFolded(w *bufio.Writer, l string) {
+	limit := maxLineLength
+	for len(l) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(l[cut]) {
+			cut--
+		}
+		w.WriteString(l[:cut])
+		w.WriteString("\r\n ")
+		l = l[cut:]
+		// continuation lines start with space
+		limit = maxLineLength - 1
+	}
+	w.WriteString(l)
+	w.WriteString("\r\n")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escape(s string) string {
+	return escaper.Replace(s)
+}
+
+// Read reads todos from calendar, other components are skipped
+func Read(r io.Reader) ([]Todo, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var todos []Todo
+	var current *Todo
+	// depth of components nested in VTODO, e.g. VALARM
+	nested := 0
+	for n, l := range lines {
+		name, params, value, err := parseLine(l)
+		if err != nil {
+			return nil, &common.InvalidInputError{Reason: fmt.Sprintf("line %d: %s", n+1, err)}
+		}
+		switch {
+		case name == "BEGIN" && value == "VTODO" && current == nil:
+			current = &Todo{Present: make(map[string]bool)}
+		case current == nil:
+		case name == "BEGIN":
+			nested++
+		case name == "END" && nested > 0:
+			nested--
+		case nested > 0:
+		case name == "END" && value == "VTODO":
+			todos = append(todos, *current)
+			current = nil
+		default:
+			if err = current.set(name, params, value); err != nil {
+				return nil, &common.InvalidInputError{Reason: fmt.Sprintf("line %d: %s", n+1, err)}
+			}
+		}
+	}
+	if current != nil {
+		return nil, &common.InvalidInputError{Reason: "VTODO is not ended"}
+	}
+	return todos, nil
+}
+
+// set sets property of todo, unsupported properties are skipped
+func (td *Todo) set(name string, params map[string]string, value string) error {
+	var err error
+	switch name {
+	case "UID":
+		td.UID = unescape(value)
+	case "SUMMARY":
+		td.Summary = unescape(value)
+	case "DESCRIPTION":
+		td.Description = unescape(value)
+	case "DUE":
+		td.Due, err = parseTime(params, value)
+	case "STATUS":
+		td.Status = strings.ToUpper(value)
+	case "RRULE":
+		td.RRule = value
+	case "CATEGORIES":
+		// property may be repeated
+		for _, c := range splitEscaped(value) {
+			td.Categories = append(td.Categories, unescape(c))
+		}
+	case "RELATED-TO":
+		td.RelatedTo = unescape(value)
+	case "SEQUENCE":
+		td.Sequence, err = strconv.ParseUint(value, 10, 64)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("malformed %s: %w", name, err)
+	}
+	td.Present[name] = true
+	return nil
+}
+
+// unfold reads content lines joining folded ones
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		l := strings.TrimSuffix(scanner.Text(), "\r")
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits content line into upper case name, parameters and value
+func parseLine(l string) (string, map[string]string, string, error) {
+	// colon may appear in quoted parameter values
+	quoted := false
+	colon := -1
+	for i, c := range l {
+		if c == '"' {
+			quoted = !quoted
+		} else if c == ':' && !quoted {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("malformed content line %s", l)
+	}
+	parts := strings.Split(l[:colon], ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return "", nil, "", fmt.Errorf("malformed parameter %s", p)
+		}
+		params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+	}
+	return strings.ToUpper(parts[0]), params, l[colon+1:], nil
+}
+
+// parseTime parses date or date-time value, floating time is treated as UTC
+func parseTime(params map[string]string, value string) (time.Time, error) {
+	if params["VALUE"] == "DATE" {
+		return time.Parse(dateLayout, value)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse(utcLayout, value)
+	}
+	loc := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if len(value) == len(dateLayout) {
+		return time.ParseInLocation(dateLayout, value, loc)
+	}
+	return time.ParseInLocation(localLayout, value, loc)
+}
+
+// splitEscaped splits list value by commas which are not escaped
+func splitEscaped(value string) []string {
+	var parts []string
+	start := 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case ',':
+			parts = append(parts, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, value[start:])
+}
+
+func unescape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,124 @@
+package taskical
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+func TestRoundTrip(t *testing.T) {
+	todos := []Todo{
+		{
+			UID:         UID(1),
+			Summary:     "Купить молоко; хлеб, яйца",
+			Description: "line one\nline two with \\ backslash and " + strings.Repeat("очень длинное описание ", 10),
+			Due:         time.Date(2021, 1, 4, 9, 0, 0, 0, time.UTC),
+			Status:      "IN-PROCESS",
+			RRule:       "FREQ=WEEKLY;BYDAY=MO,TH",
+			Categories:  []string{"home", "a;b"},
+			RelatedTo:   UID(2),
+			Sequence:    3,
+		},
+		{UID: UID(2), Summary: "parent", Status: "NEEDS-ACTION"},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, todos, time.Now()); err != nil {
+		t.Fatalf("Got error writing calendar: %s", err)
+	}
+	for _, l := range strings.Split(buf.String(), "\r\n") {
+		if len(l) > maxLineLength {
+			t.Errorf("Line is not folded: %s", l)
+		}
+	}
+	read, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Got error reading calendar: %s", err)
+	}
+	if len(read) != len(todos) {
+		t.Fatalf("Expected %d todos, got %d", len(todos), len(read))
+	}
+	for i := range read {
+		read[i].Present = nil
+		if !reflect.DeepEqual(read[i], todos[i]) {
+			t.Errorf("Expected todo %+v, got %+v", todos[i], read[i])
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	cal := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"SUMMARY:not a todo",
+		"END:VEVENT",
+		"BEGIN:VTODO",
+		"UID:external-1",
+		"SUMMARY:Long summary",
+		"  folded",
+		"DUE;VALUE=DATE:20210105",
+		"STATUS:COMPLETED",
+		"CATEGORIES:work,home",
+		"CATEGORIES:urgent",
+		"BEGIN:VALARM",
+		"DESCRIPTION:alarm",
+		"END:VALARM",
+		"END:VTODO",
+		"BEGIN:VTODO",
+		"UID:" + UID(7),
+		`DESCRIPTION;LANGUAGE="en:us":a\, b`,
+		"DUE;TZID=UTC:20210105T100000",
+		"END:VTODO",
+		"END:VCALENDAR",
+	}, "\r\n")
+	todos, err := Read(strings.NewReader(cal))
+	if err != nil {
+		t.Fatalf("Got error reading calendar: %s", err)
+	}
+	if len(todos) != 2 {
+		t.Fatalf("Expected 2 todos, got %+v", todos)
+	}
+	patch, err := todos[0].Patch()
+	switch {
+	case err != nil:
+		t.Errorf("Got error making patch: %s", err)
+	case todos[0].TaskID() != 0:
+		t.Errorf("Expected external todo to have no task, got %d", todos[0].TaskID())
+	case *patch.Name != "Long summary folded":
+		t.Errorf("Wrong name %s", *patch.Name)
+	case !patch.DueDate.Equal(time.Date(2021, 1, 5, 0, 0, 0, 0, time.UTC)):
+		t.Errorf("Wrong due date %s", *patch.DueDate)
+	case *patch.Status != task.Done.String():
+		t.Errorf("Wrong status %s", *patch.Status)
+	case !reflect.DeepEqual(*patch.Tags, []string{"work", "home", "urgent"}):
+		t.Errorf("Wrong tags %v", *patch.Tags)
+	case patch.Description != nil || patch.Recurrence != nil:
+		t.Errorf("Expected absent properties to not be patched, got %+v", patch)
+	}
+
+	patch, _ = todos[1].Patch()
+	switch {
+	case todos[1].TaskID() != 7:
+		t.Errorf("Expected todo of task 7, got %d", todos[1].TaskID())
+	case patch.Name != nil:
+		t.Errorf("Expected absent name to not be patched, got %s", *patch.Name)
+	case *patch.Description != "a, b":
+		t.Errorf("Wrong description %s", *patch.Description)
+	case !patch.DueDate.Equal(time.Date(2021, 1, 5, 10, 0, 0, 0, time.UTC)):
+		t.Errorf("Wrong due date %s", *patch.DueDate)
+	}
+
+	for _, invalid := range []string{"BEGIN:VTODO\r\nSUMMARY:x", "BEGIN:VTODO\r\nno colon\r\nEND:VTODO", "BEGIN:VTODO\r\nDUE:tomorrow\r\nEND:VTODO"} {
+		if _, err = Read(strings.NewReader(invalid)); err == nil {
+			t.Errorf("Expected error reading %q", invalid)
+		}
+	}
+	td := Todo{Status: "DRAFT", Present: map[string]bool{"STATUS": true}}
+	if _, err = td.Patch(); err == nil {
+		t.Error("Expected error making patch with unsupported status")
+	}
+}
@@ -2,8 +2,11 @@ package inmemory
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/todos/common"
 	"github.com/Vesninovich/go-tasks/todos/task"
@@ -11,36 +14,51 @@ import (
 
 // Repository represents an in-memory repository of tasks
 type Repository struct {
-	tasks []task.Task
-	lock  sync.RWMutex
-	id    uint64
+	tasks  []task.Task
+	shares map[uint64]map[string]task.Permission
+	// blockers of each task
+	blockers map[uint64]map[uint64]bool
+	events   []task.Event
+	lock     sync.RWMutex
+	id       uint64
+	eventID  uint64
 }
 
 // New creates new instance of in-memory Repository
 func New() *Repository {
 	return &Repository{
-		tasks: make([]task.Task, 0),
+		tasks:    make([]task.Task, 0),
+		shares:   make(map[uint64]map[string]task.Permission),
+		blockers: make(map[uint64]map[uint64]bool),
 	}
 }
 
-// Read reads `count` saved tasks starting from `from`
-func (r *Repository) Read(ctx context.Context, from, count uint) ([]task.Task, error) {
+// Read reads `count` tasks owned by or shared with user and matching query starting from `from`
+func (r *Repository) Read(ctx context.Context, userID string, query task.Query, from, count uint) ([]task.Task, error) {
 	r.lock.RLock()
 	defer r.lock.RUnlock()
 
-	if from > uint(len(r.tasks)-1) {
+	visible := make([]task.Task, 0)
+	for _, tsk := range r.tasks {
+		if !tsk.IsDeleted() && r.access(tsk, userID) != task.NoAccess && query.Matches(tsk) {
+			visible = append(visible, r.withProgress(tsk))
+		}
+	}
+	sort.Slice(visible, func(i, j int) bool { return query.Less(visible[i], visible[j]) })
+
+	if from >= uint(len(visible)) {
 		return make([]task.Task, 0), nil
 	}
 
 	if count == 0 {
-		return r.tasks[from:], nil
+		return visible[from:], nil
 	}
 
 	to := int(from + count)
-	if to > len(r.tasks) {
-		to = len(r.tasks)
+	if to > len(visible) {
+		to = len(visible)
 	}
-	return r.tasks[from:to], nil
+	return visible[from:to], nil
 }
 
 // ReadOne searches for task with given id, returns error if it is not found
@@ -50,8 +68,8 @@ func (r *Repository) ReadOne(ctx context.Context, id uint64) (task.Task, error)
 
 	var empty task.Task
 	for _, tsk := range r.tasks {
-		if id == tsk.ID {
-			return tsk, nil
+		if id == tsk.ID && !tsk.IsDeleted() {
+			return r.withProgress(tsk), nil
 		}
 	}
 	return empty, &common.NotFoundError{What: "Task with ID " + strconv.FormatUint(id, 10)}
@@ -62,14 +80,19 @@ func (r *Repository) Create(ctx context.Context, taskDTO task.DTO) (task.Task, e
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	// IDs start from 1 as zero ID means no task, e.g. in ParentID
+	r.id++
 	t := task.Task{
 		ID:          r.id,
 		Name:        taskDTO.Name,
 		Description: taskDTO.Description,
 		DueDate:     taskDTO.DueDate,
 		Status:      taskDTO.Status,
+		OwnerID:     taskDTO.OwnerID,
+		Version:     1,
+		Recurrence:  taskDTO.Recurrence,
+		Tags:        taskDTO.Tags,
 	}
-	r.id++
 
 	r.tasks = append(r.tasks, t)
 	return t, nil
@@ -81,36 +104,523 @@ func (r *Repository) Update(ctx context.Context, id uint64, taskDTO task.DTO) (t
 	defer r.lock.Unlock()
 
 	for i, tsk := range r.tasks {
-		if id == tsk.ID {
+		if id == tsk.ID && !tsk.IsDeleted() {
+			if taskDTO.Version != 0 && taskDTO.Version != tsk.Version {
+				var empty task.Task
+				return empty, versionMismatchError(id, taskDTO.Version, tsk.Version)
+			}
 			update := task.Task{
 				ID:          id,
 				Name:        taskDTO.Name,
 				Description: taskDTO.Description,
 				DueDate:     taskDTO.DueDate,
 				Status:      taskDTO.Status,
+				OwnerID:     tsk.OwnerID,
+				Version:     tsk.Version + 1,
+				ParentID:    tsk.ParentID,
+				Recurrence:  taskDTO.Recurrence,
+				Tags:        taskDTO.Tags,
 			}
 			r.tasks[i] = update
-			return update, nil
+			return r.withProgress(update), nil
 		}
 	}
 	var empty task.Task
 	return empty, notFoundError(id)
 }
 
-// Delete deletes task with given id, returns error if it is not found
-func (r *Repository) Delete(ctx context.Context, id uint64) error {
+// Patch updates only fields set in patch of task with given id, returns error if it is not found
+func (r *Repository) Patch(ctx context.Context, id uint64, patch task.Patch) (task.Task, error) {
 	r.lock.Lock()
 	defer r.lock.Unlock()
 
+	var empty task.Task
+	i := r.find(id)
+	if i < 0 {
+		return empty, notFoundError(id)
+	}
+	if patch.Version != 0 && patch.Version != r.tasks[i].Version {
+		return empty, versionMismatchError(id, patch.Version, r.tasks[i].Version)
+	}
+	if patch.IsEmpty() {
+		return r.withProgress(r.tasks[i]), nil
+	}
+	r.tasks[i] = patch.Apply(r.tasks[i])
+	r.tasks[i].Version++
+	return r.withProgress(r.tasks[i]), nil
+}
+
+// Delete moves task with given id to trash at given time, returns error if it is not found.
+// Subtasks of the task become top-level tasks.
+func (r *Repository) Delete(ctx context.Context, id uint64, at time.Time) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	i := r.find(id)
+	if i < 0 {
+		return notFoundError(id)
+	}
+	r.tasks[i].DeletedAt = at
 	for i, tsk := range r.tasks {
-		if id == tsk.ID {
-			r.tasks = append(r.tasks[0:i], r.tasks[i+1:len(r.tasks)]...)
-			return nil
+		if tsk.ParentID == id && !tsk.IsDeleted() {
+			r.tasks[i].ParentID = 0
 		}
 	}
-	return notFoundError(id)
+	return nil
+}
+
+// ReadTrash reads `count` tasks of given owner in trash starting from `from`, the latest deleted go first
+func (r *Repository) ReadTrash(ctx context.Context, ownerID string, from, count uint) ([]task.Task, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	trash := make([]task.Task, 0)
+	for _, tsk := range r.tasks {
+		if tsk.IsDeleted() && tsk.OwnerID == ownerID {
+			trash = append(trash, r.withProgress(tsk))
+		}
+	}
+	sort.Slice(trash, func(i, j int) bool {
+		if !trash[i].DeletedAt.Equal(trash[j].DeletedAt) {
+			return trash[i].DeletedAt.After(trash[j].DeletedAt)
+		}
+		return trash[i].ID > trash[j].ID
+	})
+	if from >= uint(len(trash)) {
+		return make([]task.Task, 0), nil
+	}
+	trash = trash[from:]
+	if count > 0 && count < uint(len(trash)) {
+		trash = trash[:count]
+	}
+	return trash, nil
+}
+
+// Restore moves task with given id from trash of given owner, returns error if there is no such task in trash.
+// Task becomes top-level one if its parent is in trash.
+func (r *Repository) Restore(ctx context.Context, id uint64, ownerID string) (task.Task, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, tsk := range r.tasks {
+		if tsk.ID != id || !tsk.IsDeleted() || tsk.OwnerID != ownerID {
+			continue
+		}
+		if tsk.ParentID != 0 && r.find(tsk.ParentID) < 0 {
+			tsk.ParentID = 0
+		}
+		tsk.DeletedAt = time.Time{}
+		tsk.Version++
+		r.tasks[i] = tsk
+		return r.withProgress(tsk), nil
+	}
+	return task.Task{}, &common.NotFoundError{What: "Deleted task with ID " + strconv.FormatUint(id, 10)}
+}
+
+// Purge deletes tasks moved to trash before given time, returns their ids.
+// Shares, dependencies and history of the tasks are deleted with them, their subtasks become top-level tasks.
+func (r *Repository) Purge(ctx context.Context, before time.Time) ([]uint64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	purged := make(map[uint64]bool)
+	kept := r.tasks[:0]
+	for _, tsk := range r.tasks {
+		if tsk.IsDeleted() && tsk.DeletedAt.Before(before) {
+			purged[tsk.ID] = true
+		} else {
+			kept = append(kept, tsk)
+		}
+	}
+	r.tasks = kept
+	if len(purged) == 0 {
+		return nil, nil
+	}
+	ids := make([]uint64, 0, len(purged))
+	for id := range purged {
+		ids = append(ids, id)
+		delete(r.shares, id)
+		delete(r.blockers, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, blockers := range r.blockers {
+		for id := range purged {
+			delete(blockers, id)
+		}
+	}
+	for i := range r.tasks {
+		if purged[r.tasks[i].ParentID] {
+			r.tasks[i].ParentID = 0
+		}
+	}
+	events := r.events[:0]
+	for _, e := range r.events {
+		if !purged[e.TaskID] {
+			events = append(events, e)
+		}
+	}
+	r.events = events
+	return ids, nil
+}
+
+// MarkOverdue sweeps through tasks setting Overdue status to new and in progress ones due before given time.
+// Only tasks with given ids are affected if any ids are passed.
+func (r *Repository) MarkOverdue(ctx context.Context, before time.Time, ids ...uint64) ([]task.StatusChange, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	only := make(map[uint64]bool, len(ids))
+	for _, id := range ids {
+		only[id] = true
+	}
+	var changes []task.StatusChange
+	for i, tsk := range r.tasks {
+		if len(only) > 0 && !only[tsk.ID] {
+			continue
+		}
+		if !tsk.IsDeleted() && tsk.IsOverdueAt(before) {
+			r.tasks[i].Status = task.Overdue
+			r.tasks[i].Version++
+			changes = append(changes, task.StatusChange{ID: tsk.ID, From: tsk.Status, To: task.Overdue})
+		}
+	}
+	return changes, nil
+}
+
+// Access returns permission of user to task with given id, returns error if task is not found
+func (r *Repository) Access(ctx context.Context, id uint64, userID string) (task.Permission, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	i := r.find(id)
+	if i < 0 {
+		return task.NoAccess, notFoundError(id)
+	}
+	return r.access(r.tasks[i], userID), nil
+}
+
+// ReadShares reads all shares of task with given id, returns error if task is not found
+func (r *Repository) ReadShares(ctx context.Context, id uint64) ([]task.Share, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.find(id) < 0 {
+		return nil, notFoundError(id)
+	}
+	shares := make([]task.Share, 0, len(r.shares[id]))
+	for user, p := range r.shares[id] {
+		shares = append(shares, task.Share{TaskID: id, UserID: user, Permission: p})
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].UserID < shares[j].UserID })
+	return shares, nil
+}
+
+// Readers returns owner of task with given id followed by users it is shared with, tasks in trash included
+func (r *Repository) Readers(ctx context.Context, id uint64) ([]string, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, tsk := range r.tasks {
+		if tsk.ID != id {
+			continue
+		}
+		users := make([]string, 0, len(r.shares[id]))
+		for user := range r.shares[id] {
+			users = append(users, user)
+		}
+		sort.Strings(users)
+		return append([]string{tsk.OwnerID}, users...), nil
+	}
+	return nil, notFoundError(id)
+}
+
+// Share grants user permission to task, returns error if task is not found
+func (r *Repository) Share(ctx context.Context, share task.Share) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.find(share.TaskID) < 0 {
+		return notFoundError(share.TaskID)
+	}
+	if r.shares[share.TaskID] == nil {
+		r.shares[share.TaskID] = make(map[string]task.Permission)
+	}
+	r.shares[share.TaskID][share.UserID] = share.Permission
+	return nil
+}
+
+// Unshare revokes permission granted to user, returns error if there is no such share
+func (r *Repository) Unshare(ctx context.Context, id uint64, userID string) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.shares[id][userID]; !ok {
+		return shareNotFoundError(id, userID)
+	}
+	delete(r.shares[id], userID)
+	return nil
+}
+
+// SetParent makes task with given id subtask of parent, zero parent makes it top-level task
+func (r *Repository) SetParent(ctx context.Context, id, parentID uint64) (task.Task, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var empty task.Task
+	i := r.find(id)
+	if i < 0 {
+		return empty, notFoundError(id)
+	}
+	if parentID != 0 && r.find(parentID) < 0 {
+		return empty, notFoundError(parentID)
+	}
+	r.tasks[i].ParentID = parentID
+	r.tasks[i].Version++
+	return r.withProgress(r.tasks[i]), nil
+}
+
+// ReadSubtasks reads subtasks of task with given id, returns error if task is not found
+func (r *Repository) ReadSubtasks(ctx context.Context, id uint64) ([]task.Task, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.find(id) < 0 {
+		return nil, notFoundError(id)
+	}
+	subtasks := make([]task.Task, 0)
+	for _, tsk := range r.tasks {
+		if tsk.ParentID == id && !tsk.IsDeleted() {
+			subtasks = append(subtasks, r.withProgress(tsk))
+		}
+	}
+	return subtasks, nil
+}
+
+// ReadBlockers reads tasks blocking task with given id, returns error if task is not found
+func (r *Repository) ReadBlockers(ctx context.Context, id uint64) ([]task.Task, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.find(id) < 0 {
+		return nil, notFoundError(id)
+	}
+	blockers := make([]task.Task, 0, len(r.blockers[id]))
+	for _, tsk := range r.tasks {
+		if r.blockers[id][tsk.ID] && !tsk.IsDeleted() {
+			blockers = append(blockers, r.withProgress(tsk))
+		}
+	}
+	return blockers, nil
+}
+
+// AddDependency makes task blocked by another one, returns error if any of them is not found
+func (r *Repository) AddDependency(ctx context.Context, dep task.Dependency) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, id := range []uint64{dep.TaskID, dep.BlockerID} {
+		if r.find(id) < 0 {
+			return notFoundError(id)
+		}
+	}
+	if r.blockers[dep.TaskID] == nil {
+		r.blockers[dep.TaskID] = make(map[uint64]bool)
+	}
+	r.blockers[dep.TaskID][dep.BlockerID] = true
+	return nil
+}
+
+// ReadTags reads tags of tasks owned by or shared with user with number of such tasks having each tag
+func (r *Repository) ReadTags(ctx context.Context, userID string) ([]task.TagCount, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	counts := make(map[string]uint64)
+	for _, tsk := range r.tasks {
+		if tsk.IsDeleted() || r.access(tsk, userID) == task.NoAccess {
+			continue
+		}
+		for _, tag := range tsk.Tags {
+			counts[tag]++
+		}
+	}
+	tags := make([]task.TagCount, 0, len(counts))
+	for tag, count := range counts {
+		tags = append(tags, task.TagCount{Tag: tag, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Tag < tags[j].Tag })
+	return tags, nil
+}
+
+// MergeTags replaces given tags with `into` tag on tasks owned by given user, returns ids of changed tasks
+func (r *Repository) MergeTags(ctx context.Context, ownerID string, tags []string, into string) ([]uint64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	merged := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		merged[tag] = true
+	}
+	var changed []uint64
+	for i, tsk := range r.tasks {
+		if tsk.OwnerID != ownerID {
+			continue
+		}
+		replaced := make([]string, 0, len(tsk.Tags))
+		found := false
+		for _, tag := range tsk.Tags {
+			if merged[tag] && tag != into {
+				found = true
+			} else if tag != into {
+				replaced = append(replaced, tag)
+			}
+		}
+		if !found {
+			continue
+		}
+		replaced = append(replaced, into)
+		sort.Strings(replaced)
+		r.tasks[i].Tags = replaced
+		r.tasks[i].Version++
+		changed = append(changed, tsk.ID)
+	}
+	return changed, nil
+}
+
+// AppendEvents adds events to history of tasks, assigning IDs to given events
+func (r *Repository) AppendEvents(ctx context.Context, events ...task.Event) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i := range events {
+		r.eventID++
+		events[i].ID = r.eventID
+		r.events = append(r.events, events[i])
+	}
+	return nil
+}
+
+// ReadEvents reads `count` events of task with given id starting from `from`, returns error if task is not found
+func (r *Repository) ReadEvents(ctx context.Context, id uint64, from, count uint) ([]task.Event, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	if r.find(id) < 0 {
+		return nil, notFoundError(id)
+	}
+	events := make([]task.Event, 0)
+	for _, e := range r.events {
+		if e.TaskID != id {
+			continue
+		}
+		if from > 0 {
+			from--
+			continue
+		}
+		if count > 0 && uint(len(events)) == count {
+			break
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// RemoveDependency returns error if there is no such dependency
+func (r *Repository) RemoveDependency(ctx context.Context, dep task.Dependency) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.blockers[dep.TaskID][dep.BlockerID] {
+		return dependencyNotFoundError(dep)
+	}
+	delete(r.blockers[dep.TaskID], dep.BlockerID)
+	return nil
+}
+
+// withProgress returns task with progress of its subtasks, must be called under lock
+// InTransaction runs f with copy of repository which replaces its state if f succeeds.
+// Other calls wait until transaction ends.
+func (r *Repository) InTransaction(ctx context.Context, f func(task.Repository) error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	tx := r.clone()
+	if err := f(tx); err != nil {
+		return err
+	}
+	r.tasks, r.shares, r.blockers, r.events = tx.tasks, tx.shares, tx.blockers, tx.events
+	r.id, r.eventID = tx.id, tx.eventID
+	return nil
+}
+
+// clone copies state of repository, tags of tasks are shared as they are replaced rather than changed
+func (r *Repository) clone() *Repository {
+	c := &Repository{
+		tasks:    append(make([]task.Task, 0, len(r.tasks)), r.tasks...),
+		shares:   make(map[uint64]map[string]task.Permission, len(r.shares)),
+		blockers: make(map[uint64]map[uint64]bool, len(r.blockers)),
+		events:   append([]task.Event(nil), r.events...),
+		id:       r.id,
+		eventID:  r.eventID,
+	}
+	for id, shares := range r.shares {
+		c.shares[id] = make(map[string]task.Permission, len(shares))
+		for user, p := range shares {
+			c.shares[id][user] = p
+		}
+	}
+	for id, blockers := range r.blockers {
+		c.blockers[id] = make(map[uint64]bool, len(blockers))
+		for b := range blockers {
+			c.blockers[id][b] = true
+		}
+	}
+	return c
+}
+
+func (r *Repository) withProgress(t task.Task) task.Task {
+	t.Progress = task.Progress{}
+	for _, sub := range r.tasks {
+		if sub.ParentID == t.ID && !sub.IsDeleted() {
+			t.Progress.Total++
+			if sub.Status == task.Done {
+				t.Progress.Done++
+			}
+		}
+	}
+	return t
+}
+
+// find returns index of task with given id or -1, tasks in trash are not found. Must be called under lock
+func (r *Repository) find(id uint64) int {
+	for i, tsk := range r.tasks {
+		if id == tsk.ID && !tsk.IsDeleted() {
+			return i
+		}
+	}
+	return -1
+}
+
+// access must be called under lock
+func (r *Repository) access(tsk task.Task, userID string) task.Permission {
+	if tsk.OwnerID == userID {
+		return task.OwnerAccess
+	}
+	return r.shares[tsk.ID][userID]
+}
+
+func shareNotFoundError(id uint64, userID string) *common.NotFoundError {
+	return &common.NotFoundError{What: "Share of task with ID " + strconv.FormatUint(id, 10) + " with user " + userID}
+}
+
+func dependencyNotFoundError(dep task.Dependency) *common.NotFoundError {
+	return &common.NotFoundError{What: fmt.Sprintf("Dependency of task with ID %d on task with ID %d", dep.TaskID, dep.BlockerID)}
 }
 
 func notFoundError(id uint64) *common.NotFoundError {
 	return &common.NotFoundError{What: "Task with ID " + strconv.FormatUint(id, 10)}
 }
+
+func versionMismatchError(id, expected, actual uint64) *common.VersionMismatchError {
+	return &common.VersionMismatchError{What: "Task with ID " + strconv.FormatUint(id, 10), Expected: expected, Actual: actual}
+}
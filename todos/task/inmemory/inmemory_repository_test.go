@@ -2,9 +2,11 @@ package inmemory_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
+	"github.com/Vesninovich/go-tasks/todos/common"
 	"github.com/Vesninovich/go-tasks/todos/task"
 	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
 )
@@ -34,7 +36,7 @@ func TestCreate(t *testing.T) {
 	if err != nil {
 		t.Errorf("Got error while saving task 1: %s", err)
 	}
-	saved, err := r.Read(context.Background(), 0, 0)
+	saved, err := r.Read(context.Background(), "", task.Query{}, 0, 0)
 	switch {
 	case err != nil:
 		t.Errorf("Got error while reading tasks: %s", err)
@@ -44,3 +46,331 @@ func TestCreate(t *testing.T) {
 		t.Errorf("Saved tasks have same ID")
 	}
 }
+
+func TestPatch(t *testing.T) {
+	ctx := context.Background()
+	r := inmemory.New()
+	created, _ := r.Create(ctx, tasks[1])
+
+	desc := ""
+	noDue := time.Time{}
+	patched, err := r.Patch(ctx, created.ID, task.Patch{Description: &desc, DueDate: &noDue})
+	if err != nil {
+		t.Fatalf("Got error while patching task: %s", err)
+	}
+	switch {
+	case patched.Name != created.Name || patched.Status != created.Status:
+		t.Errorf("Patch changed fields that are not set: %+v", patched)
+	case patched.Description != "":
+		t.Errorf("Patch did not change description: %s", patched.Description)
+	case patched.HasDueDate():
+		t.Errorf("Patch did not remove due date: %s", patched.DueDate)
+	}
+	if _, err = r.Patch(ctx, created.ID+1, task.Patch{Description: &desc}); err == nil {
+		t.Error("Expected error while patching missing task")
+	}
+}
+
+func TestUpdateVersion(t *testing.T) {
+	ctx := context.Background()
+	r := inmemory.New()
+	created, _ := r.Create(ctx, tasks[0])
+	if created.Version != 1 {
+		t.Fatalf("Expected created task to have version 1, got %d", created.Version)
+	}
+
+	dto := tasks[0]
+	dto.Version = created.Version
+	updated, err := r.Update(ctx, created.ID, dto)
+	if err != nil {
+		t.Fatalf("Got error while updating task at expected version: %s", err)
+	}
+	if updated.Version != 2 {
+		t.Errorf("Expected updated task to have version 2, got %d", updated.Version)
+	}
+	if _, err = r.Update(ctx, created.ID, dto); err == nil {
+		t.Fatal("Expected error while updating task at stale version")
+	} else if _, ok := err.(*common.VersionMismatchError); !ok {
+		t.Errorf("Expected version mismatch error, got %T", err)
+	}
+	desc := "patched"
+	if _, err = r.Patch(ctx, created.ID, task.Patch{Description: &desc, Version: dto.Version}); err == nil {
+		t.Error("Expected error while patching task at stale version")
+	}
+	patched, err := r.Patch(ctx, created.ID, task.Patch{Description: &desc})
+	if err != nil {
+		t.Fatalf("Got error while patching task without version: %s", err)
+	}
+	if patched.Version != 3 {
+		t.Errorf("Expected patched task to have version 3, got %d", patched.Version)
+	}
+}
+
+func TestReadScopedToUser(t *testing.T) {
+	ctx := context.Background()
+	r := inmemory.New()
+	alice := tasks[0]
+	alice.OwnerID = "alice"
+	bob := tasks[1]
+	bob.OwnerID = "bob"
+	aliceTask, _ := r.Create(ctx, alice)
+	bobTask, _ := r.Create(ctx, bob)
+
+	checkRead := func(user string, expected ...uint64) {
+		t.Helper()
+		saved, err := r.Read(ctx, user, task.Query{}, 0, 0)
+		if err != nil {
+			t.Fatalf("Got error while reading tasks of %s: %s", user, err)
+		}
+		if len(saved) != len(expected) {
+			t.Fatalf("Expected %s to see %d tasks, got %d", user, len(expected), len(saved))
+		}
+		for i, id := range expected {
+			if saved[i].ID != id {
+				t.Errorf("Expected %s to see task %d at %d, got %d", user, id, i, saved[i].ID)
+			}
+		}
+	}
+	checkRead("alice", aliceTask.ID)
+	checkRead("bob", bobTask.ID)
+
+	err := r.Share(ctx, task.Share{TaskID: aliceTask.ID, UserID: "bob", Permission: task.ReadAccess})
+	if err != nil {
+		t.Fatalf("Got error while sharing task: %s", err)
+	}
+	checkRead("bob", aliceTask.ID, bobTask.ID)
+	p, err := r.Access(ctx, aliceTask.ID, "bob")
+	if err != nil || p != task.ReadAccess {
+		t.Errorf("Expected bob to have read access, got %s, %v", p, err)
+	}
+	p, _ = r.Access(ctx, aliceTask.ID, "alice")
+	if p != task.OwnerAccess {
+		t.Errorf("Expected alice to have owner access, got %s", p)
+	}
+
+	err = r.Unshare(ctx, aliceTask.ID, "bob")
+	if err != nil {
+		t.Fatalf("Got error while unsharing task: %s", err)
+	}
+	checkRead("bob", bobTask.ID)
+	if err = r.Unshare(ctx, aliceTask.ID, "bob"); err == nil {
+		t.Error("Expected error while removing missing share")
+	}
+}
+
+func TestReadQuery(t *testing.T) {
+	ctx := context.Background()
+	r := inmemory.New()
+	base := time.Unix(1000000, 0)
+	dtos := []task.DTO{
+		{Name: "Buy milk", Description: "at the store", DueDate: base.Add(2 * time.Hour), Status: task.New},
+		{Name: "Write report", Description: "quarterly", DueDate: base.Add(time.Hour), Status: task.InProgress},
+		{Name: "call mom", Description: "", Status: task.New},
+		{Name: "Buy bread", Description: "100% rye", DueDate: base.Add(3 * time.Hour), Status: task.Done},
+	}
+	for _, dto := range dtos {
+		if _, err := r.Create(ctx, dto); err != nil {
+			t.Fatalf("Got error while saving task: %s", err)
+		}
+	}
+
+	cases := []struct {
+		name     string
+		query    task.Query
+		expected []uint64
+	}{
+		{"All", task.Query{}, []uint64{1, 2, 3, 4}},
+		{"Statuses", task.Query{Statuses: []task.Status{task.New, task.Done}}, []uint64{1, 3, 4}},
+		{"Due from", task.Query{DueFrom: base.Add(2 * time.Hour)}, []uint64{1, 4}},
+		{"Due range", task.Query{DueFrom: base, DueTo: base.Add(2 * time.Hour)}, []uint64{1, 2}},
+		{"Search in name", task.Query{Search: "buy"}, []uint64{1, 4}},
+		{"Search in description", task.Query{Search: "STORE"}, []uint64{1}},
+		{"Search special characters", task.Query{Search: "100%"}, []uint64{4}},
+		{"Sort by due date", task.Query{SortBy: task.SortByDueDate}, []uint64{2, 1, 4, 3}},
+		{"Sort by name descending", task.Query{SortBy: task.SortByName, Descending: true}, []uint64{3, 2, 1, 4}},
+		{"Combined", task.Query{Search: "buy", SortBy: task.SortByDueDate, Descending: true}, []uint64{4, 1}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			saved, err := r.Read(ctx, "", c.query, 0, 0)
+			if err != nil {
+				t.Fatalf("Got error while reading tasks: %s", err)
+			}
+			ids := make([]uint64, len(saved))
+			for i, tsk := range saved {
+				ids[i] = tsk.ID
+			}
+			if len(ids) != len(c.expected) {
+				t.Fatalf("Expected tasks %v, got %v", c.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != c.expected[i] {
+					t.Fatalf("Expected tasks %v, got %v", c.expected, ids)
+				}
+			}
+		})
+	}
+
+	paged, _ := r.Read(ctx, "", task.Query{SortBy: task.SortByDueDate}, 1, 2)
+	if len(paged) != 2 || paged[0].ID != 1 || paged[1].ID != 4 {
+		t.Errorf("Wrong page of sorted tasks: %v", paged)
+	}
+}
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	r := inmemory.New()
+	a, _ := r.Create(ctx, task.DTO{Name: "a", OwnerID: "alice", Tags: []string{"home", "urgent"}})
+	b, _ := r.Create(ctx, task.DTO{Name: "b", OwnerID: "alice", Tags: []string{"chores", "home"}})
+	c, _ := r.Create(ctx, task.DTO{Name: "c", OwnerID: "bob", Tags: []string{"home"}})
+	r.Share(ctx, task.Share{TaskID: c.ID, UserID: "alice", Permission: task.ReadAccess})
+
+	read := func(q task.Query) []uint64 {
+		found, _ := r.Read(ctx, "alice", q, 0, 0)
+		ids := make([]uint64, len(found))
+		for i, tsk := range found {
+			ids[i] = tsk.ID
+		}
+		return ids
+	}
+	if ids := read(task.Query{Tags: []string{"urgent", "chores"}}); len(ids) != 2 || ids[0] != a.ID || ids[1] != b.ID {
+		t.Errorf("Expected tasks with any of tags, got %v", ids)
+	}
+	if ids := read(task.Query{Tags: []string{"home", "chores"}, AllTags: true}); len(ids) != 1 || ids[0] != b.ID {
+		t.Errorf("Expected tasks with all tags, got %v", ids)
+	}
+
+	tags, _ := r.ReadTags(ctx, "alice")
+	expected := []task.TagCount{{Tag: "chores", Count: 1}, {Tag: "home", Count: 3}, {Tag: "urgent", Count: 1}}
+	if len(tags) != len(expected) {
+		t.Fatalf("Expected tags %v, got %v", expected, tags)
+	}
+	for i := range tags {
+		if tags[i] != expected[i] {
+			t.Errorf("Expected tags %v, got %v", expected, tags)
+		}
+	}
+
+	changed, err := r.MergeTags(ctx, "alice", []string{"home", "urgent"}, "house")
+	if err != nil || len(changed) != 2 {
+		t.Errorf("Expected 2 changed tasks, got %v, %v", changed, err)
+	}
+	merged, _ := r.ReadOne(ctx, a.ID)
+	if len(merged.Tags) != 1 || merged.Tags[0] != "house" || merged.Version != 2 {
+		t.Errorf("Expected tags of task to be merged, got %+v", merged)
+	}
+	other, _ := r.ReadOne(ctx, c.ID)
+	if len(other.Tags) != 1 || other.Tags[0] != "home" {
+		t.Errorf("Expected tags of task owned by other user to stay, got %v", other.Tags)
+	}
+}
+
+func TestInTransaction(t *testing.T) {
+	ctx := context.Background()
+	r := inmemory.New()
+	kept, _ := r.Create(ctx, task.DTO{Name: "kept"})
+
+	err := r.InTransaction(ctx, func(tx task.Repository) error {
+		tx.Create(ctx, task.DTO{Name: "discarded"})
+		tx.Share(ctx, task.Share{TaskID: kept.ID, UserID: "bob", Permission: task.ReadAccess})
+		return errors.New("failed")
+	})
+	if err == nil || err.Error() != "failed" {
+		t.Errorf("Expected error of transaction, got %v", err)
+	}
+	if all, _ := r.Read(ctx, "", task.Query{}, 0, 0); len(all) != 1 {
+		t.Errorf("Expected changes of failed transaction to be discarded, got %v", all)
+	}
+	if shares, _ := r.ReadShares(ctx, kept.ID); len(shares) != 0 {
+		t.Errorf("Expected share of failed transaction to be discarded, got %v", shares)
+	}
+
+	err = r.InTransaction(ctx, func(tx task.Repository) error {
+		tx.Create(ctx, task.DTO{Name: "saved"})
+		// nested transaction is discarded on its own
+		tx.InTransaction(ctx, func(nested task.Repository) error {
+			nested.Delete(ctx, kept.ID, time.Now())
+			return errors.New("failed")
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Got error in transaction: %s", err)
+	}
+	all, _ := r.Read(ctx, "", task.Query{}, 0, 0)
+	if len(all) != 2 || all[0].ID != kept.ID || all[1].ID != kept.ID+1 {
+		t.Errorf("Expected task created in transaction to be saved, got %v", all)
+	}
+	if created, _ := r.Create(ctx, task.DTO{Name: "next"}); created.ID != kept.ID+2 {
+		t.Errorf("Expected IDs to continue after transaction, got %d", created.ID)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	r := inmemory.New()
+	deleted, _ := r.Create(ctx, task.DTO{Name: "deleted", OwnerID: "alice"})
+	blocked, _ := r.Create(ctx, task.DTO{Name: "blocked", OwnerID: "alice"})
+	r.AddDependency(ctx, task.Dependency{TaskID: blocked.ID, BlockerID: deleted.ID})
+	r.AppendEvents(ctx, task.Event{TaskID: deleted.ID, Type: task.Created})
+	at := time.Unix(1000000, 0)
+
+	if err := r.Delete(ctx, deleted.ID, at); err != nil {
+		t.Fatalf("Got error deleting task: %s", err)
+	}
+	if err := r.Delete(ctx, deleted.ID, at); err == nil {
+		t.Error("Expected error deleting task in trash")
+	}
+	if blockers, _ := r.ReadBlockers(ctx, blocked.ID); len(blockers) != 0 {
+		t.Errorf("Expected task in trash not to block others, got %v", blockers)
+	}
+	if _, err := r.Access(ctx, deleted.ID, "alice"); err == nil {
+		t.Error("Expected error accessing task in trash")
+	}
+	if _, err := r.Restore(ctx, deleted.ID, "bob"); err == nil {
+		t.Error("Expected error restoring task of other user")
+	}
+
+	restored, err := r.Restore(ctx, deleted.ID, "alice")
+	if err != nil || restored.IsDeleted() || restored.Version != 2 {
+		t.Errorf("Expected task to be restored, got %+v, %v", restored, err)
+	}
+	if blockers, _ := r.ReadBlockers(ctx, blocked.ID); len(blockers) != 1 {
+		t.Errorf("Expected dependency of restored task to be kept, got %v", blockers)
+	}
+
+	r.Delete(ctx, deleted.ID, at)
+	if ids, _ := r.Purge(ctx, at); len(ids) != 0 {
+		t.Errorf("Expected tasks deleted at given time to be kept, got %v", ids)
+	}
+	ids, err := r.Purge(ctx, at.Add(time.Second))
+	if err != nil || len(ids) != 1 || ids[0] != deleted.ID {
+		t.Errorf("Expected task to be purged, got %v, %v", ids, err)
+	}
+	if trash, _ := r.ReadTrash(ctx, "alice", 0, 0); len(trash) != 0 {
+		t.Errorf("Expected trash to be empty, got %v", trash)
+	}
+	if _, err := r.ReadEvents(ctx, deleted.ID, 0, 0); err == nil {
+		t.Error("Expected error reading history of purged task")
+	}
+	if blockers, _ := r.ReadBlockers(ctx, blocked.ID); len(blockers) != 0 {
+		t.Errorf("Expected dependency on purged task to be removed, got %v", blockers)
+	}
+}
+
+func TestReaders(t *testing.T) {
+	ctx := context.Background()
+	r := inmemory.New()
+	created, _ := r.Create(ctx, task.DTO{Name: "shared", OwnerID: "alice"})
+	r.Share(ctx, task.Share{TaskID: created.ID, UserID: "carol", Permission: task.ReadAccess})
+	r.Share(ctx, task.Share{TaskID: created.ID, UserID: "bob", Permission: task.WriteAccess})
+	r.Delete(ctx, created.ID, time.Now())
+
+	readers, err := r.Readers(ctx, created.ID)
+	if err != nil || len(readers) != 3 || readers[0] != "alice" || readers[1] != "bob" || readers[2] != "carol" {
+		t.Errorf("Expected owner and users task in trash is shared with, got %v, %v", readers, err)
+	}
+	if _, err = r.Readers(ctx, 100); err == nil {
+		t.Error("Expected error getting readers of missing task")
+	}
+}
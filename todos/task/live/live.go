@@ -0,0 +1,214 @@
+// Package live delivers changes of tasks to connected clients as they happen
+package live
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+)
+
+// Type of live Event
+type Type string
+
+// Possible Types
+const (
+	Created  Type = "created"
+	Updated  Type = "updated"
+	Deleted  Type = "deleted"
+	Restored Type = "restored"
+	// Overdue is sent instead of Updated when task became overdue
+	Overdue Type = "overdue"
+	// Completed is sent instead of Updated when task became done
+	Completed Type = "completed"
+)
+
+// Types of events in order they are documented
+var Types = []Type{Created, Updated, Completed, Overdue, Deleted, Restored}
+
+// Event about change of task
+type Event struct {
+	// ID is assigned by Broker, IDs of later events are greater
+	ID     uint64
+	Type   Type
+	TaskID uint64
+	// Actor is ID of user who made the change, empty for changes made by the app itself
+	Actor string
+	// Fields are names of changed fields of Updated event as in REST API
+	Fields []string
+	// Readers are users who may read the task, only they receive the event
+	Readers   []string
+	CreatedAt time.Time
+}
+
+// Publisher publishes live events
+type Publisher interface {
+	Publish(events ...Event)
+}
+
+// Publishers publishes events with each of publishers in order
+type Publishers []Publisher
+
+// Publish events with each of publishers
+func (ps Publishers) Publish(events ...Event) {
+	for _, p := range ps {
+		p.Publish(events...)
+	}
+}
+
+// Filter of events delivered to subscriber
+type Filter struct {
+	// User receiving events, only events of tasks user may read are delivered
+	User string
+	// Types of delivered events, all if empty
+	Types []Type
+	// TaskIDs of delivered events, all if empty
+	TaskIDs []uint64
+}
+
+// DefaultBacklog is number of latest events kept for clients resuming after reconnect
+const DefaultBacklog = 1000
+
+// subscriberBuffer is number of events waiting for delivery to subscriber before it is dropped
+const subscriberBuffer = 64
+
+// Broker publishes events to subscribers and keeps the latest of them, so reconnecting clients do not miss any
+type Broker struct {
+	lock    sync.Mutex
+	lastID  uint64
+	backlog []Event
+	size    int
+	subs    map[*Subscription]bool
+}
+
+// Subscription to events of Broker
+type Subscription struct {
+	broker *Broker
+	filter Filter
+	events chan Event
+}
+
+// NewBroker creates Broker keeping `backlog` latest events, DefaultBacklog if it is not positive
+func NewBroker(backlog int) *Broker {
+	if backlog <= 0 {
+		backlog = DefaultBacklog
+	}
+	return &Broker{
+		// IDs start from current time, so IDs known to clients before restart are older than any new one
+		lastID: uint64(time.Now().UnixNano()),
+		size:   backlog,
+		subs:   make(map[*Subscription]bool),
+	}
+}
+
+// Publish assigns IDs to events and delivers them to subscribers.
+// Subscriber which does not keep up with events is dropped, its client should reconnect.
+func (b *Broker) Publish(events ...Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for _, e := range events {
+		b.lastID++
+		e.ID = b.lastID
+		b.backlog = append(b.backlog, e)
+		for s := range b.subs {
+			if !s.filter.matches(e) {
+				continue
+			}
+			select {
+			case s.events <- e:
+			default:
+				b.drop(s)
+			}
+		}
+	}
+	if len(b.backlog) > b.size {
+		b.backlog = append(b.backlog[:0:0], b.backlog[len(b.backlog)-b.size:]...)
+	}
+}
+
+// Subscribe starts delivering events matching filter. Kept events published after event with `lastID`
+// are delivered first, zero `lastID` skips them. `missed` is set if some events after `lastID` are not kept,
+// so client should read tasks again.
+func (b *Broker) Subscribe(f Filter, lastID uint64) (s *Subscription, missed bool, err error) {
+	for _, t := range f.Types {
+		if !hasType(Types, t) {
+			return nil, false, &common.InvalidInputError{Reason: fmt.Sprintf(`unknown event type "%s"`, t)}
+		}
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var missedEvents []Event
+	if lastID != 0 {
+		missed = lastID > b.lastID || lastID < b.lastID-uint64(len(b.backlog))
+		for _, e := range b.backlog {
+			if e.ID > lastID && f.matches(e) {
+				missedEvents = append(missedEvents, e)
+			}
+		}
+	}
+	s = &Subscription{
+		broker: b,
+		filter: f,
+		events: make(chan Event, len(missedEvents)+subscriberBuffer),
+	}
+	for _, e := range missedEvents {
+		s.events <- e
+	}
+	b.subs[s] = true
+	return s, missed, nil
+}
+
+// Events returns channel of events, it is closed when subscription is closed or dropped
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops delivery of events
+func (s *Subscription) Close() {
+	s.broker.lock.Lock()
+	defer s.broker.lock.Unlock()
+	s.broker.drop(s)
+}
+
+// drop must be called under lock
+func (b *Broker) drop(s *Subscription) {
+	if b.subs[s] {
+		delete(b.subs, s)
+		close(s.events)
+	}
+}
+
+func (f Filter) matches(e Event) bool {
+	return contains(e.Readers, f.User) && hasType(f.Types, e.Type) && hasTask(f.TaskIDs, e.TaskID)
+}
+
+func contains(users []string, user string) bool {
+	for _, u := range users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+func hasType(types []Type, t Type) bool {
+	for _, ft := range types {
+		if ft == t {
+			return true
+		}
+	}
+	return len(types) == 0
+}
+
+func hasTask(ids []uint64, id uint64) bool {
+	for _, fid := range ids {
+		if fid == id {
+			return true
+		}
+	}
+	return len(ids) == 0
+}
@@ -0,0 +1,95 @@
+package live
+
+import (
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+)
+
+func TestSubscribe(t *testing.T) {
+	b := NewBroker(2)
+	alice, _, err := b.Subscribe(Filter{User: "alice"}, 0)
+	if err != nil {
+		t.Fatalf("Got error subscribing: %s", err)
+	}
+	created, _, _ := b.Subscribe(Filter{User: "alice", Types: []Type{Created}}, 0)
+	other, _, _ := b.Subscribe(Filter{User: "alice", TaskIDs: []uint64{2}}, 0)
+
+	b.Publish(
+		Event{Type: Created, TaskID: 1, Readers: []string{"alice"}},
+		Event{Type: Updated, TaskID: 1, Readers: []string{"alice"}},
+		Event{Type: Created, TaskID: 3, Readers: []string{"bob"}},
+	)
+	first := <-alice.Events()
+	second := <-alice.Events()
+	if first.TaskID != 1 || first.Type != Created || second.Type != Updated || second.ID != first.ID+1 {
+		t.Errorf("Expected events of readable task in order, got %+v, %+v", first, second)
+	}
+	if len(alice.Events()) != 0 {
+		t.Errorf("Expected events of not readable task to be skipped, got %+v", <-alice.Events())
+	}
+	if len(created.Events()) != 1 || len(other.Events()) != 0 {
+		t.Errorf("Expected events to be filtered by type and task, got %d and %d", len(created.Events()), len(other.Events()))
+	}
+
+	alice.Close()
+	if _, ok := <-alice.Events(); ok {
+		t.Errorf("Expected closed subscription to have no events")
+	}
+
+	if _, _, err = b.Subscribe(Filter{Types: []Type{"unknown"}}, 0); err == nil {
+		t.Errorf("Expected unknown type to be rejected")
+	} else if _, ok := err.(*common.InvalidInputError); !ok {
+		t.Errorf("Expected invalid input error, got %s", err)
+	}
+}
+
+func TestResume(t *testing.T) {
+	b := NewBroker(2)
+	sub, _, _ := b.Subscribe(Filter{User: "alice"}, 0)
+	for i := uint64(1); i <= 3; i++ {
+		b.Publish(Event{Type: Created, TaskID: i, Readers: []string{"alice"}})
+	}
+	first := <-sub.Events()
+	second := <-sub.Events()
+	sub.Close()
+
+	resumed, missed, _ := b.Subscribe(Filter{User: "alice"}, second.ID)
+	if missed {
+		t.Errorf("Expected no events to be missed after kept event")
+	}
+	if e := <-resumed.Events(); e.TaskID != 3 || len(resumed.Events()) != 0 {
+		t.Errorf("Expected only event after the last received one, got %+v", e)
+	}
+
+	resumed, missed, _ = b.Subscribe(Filter{User: "alice"}, first.ID)
+	if missed {
+		t.Errorf("Expected no events to be missed when all of them are kept")
+	}
+	if len(resumed.Events()) != 2 {
+		t.Errorf("Expected 2 kept events, got %d", len(resumed.Events()))
+	}
+
+	if _, missed, _ = b.Subscribe(Filter{User: "alice"}, first.ID-1); !missed {
+		t.Errorf("Expected events dropped from backlog to be missed")
+	}
+	if _, missed, _ = b.Subscribe(Filter{User: "alice"}, 1); !missed {
+		t.Errorf("Expected events before restart to be missed")
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	b := NewBroker(0)
+	sub, _, _ := b.Subscribe(Filter{User: "alice"}, 0)
+	for i := 0; i <= subscriberBuffer; i++ {
+		b.Publish(Event{Type: Updated, TaskID: 1, Readers: []string{"alice"}})
+	}
+	n := 0
+	for range sub.Events() {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("Expected %d buffered events before drop, got %d", subscriberBuffer, n)
+	}
+	sub.Close()
+}
@@ -0,0 +1,144 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+)
+
+// SortField is field tasks can be sorted by
+type SortField uint
+
+// Possible SortFields
+const (
+	SortByID SortField = iota
+	SortByDueDate
+	SortByName
+)
+
+// Query filters and orders tasks.
+// Zero Query matches all tasks sorted by ID.
+type Query struct {
+	// Statuses task must have one of, any status if empty
+	Statuses []Status
+	// DueFrom and DueTo limit due date of task inclusively when set,
+	// tasks without due date do not match limited range
+	DueFrom time.Time
+	DueTo   time.Time
+	// Search is case insensitive substring of name or description
+	Search string
+	// Tags task must have any of, or all of if AllTags is set, any tags if empty
+	Tags       []string
+	AllTags    bool
+	SortBy     SortField
+	Descending bool
+}
+
+// Validate checks that Query makes sense
+func (q Query) Validate() error {
+	if !q.DueFrom.IsZero() && !q.DueTo.IsZero() && q.DueFrom.After(q.DueTo) {
+		return &common.InvalidInputError{Reason: "due date range start is after its end"}
+	}
+	return nil
+}
+
+// HasStatus reports whether status matches Query
+func (q Query) HasStatus(s Status) bool {
+	if len(q.Statuses) == 0 {
+		return true
+	}
+	for _, st := range q.Statuses {
+		if st == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether task matches Query filters
+func (q Query) Matches(t Task) bool {
+	if !q.HasStatus(t.Status) {
+		return false
+	}
+	if !q.DueFrom.IsZero() && (!t.HasDueDate() || t.DueDate.Before(q.DueFrom)) {
+		return false
+	}
+	if !q.DueTo.IsZero() && (!t.HasDueDate() || t.DueDate.After(q.DueTo)) {
+		return false
+	}
+	if q.Search != "" {
+		search := strings.ToLower(q.Search)
+		if !strings.Contains(strings.ToLower(t.Name), search) && !strings.Contains(strings.ToLower(t.Description), search) {
+			return false
+		}
+	}
+	return q.HasTags(t)
+}
+
+// HasTags reports whether tags of task match Query
+func (q Query) HasTags(t Task) bool {
+	if len(q.Tags) == 0 {
+		return true
+	}
+	for _, tag := range q.Tags {
+		has := t.HasTag(tag)
+		if has && !q.AllTags {
+			return true
+		}
+		if !has && q.AllTags {
+			return false
+		}
+	}
+	return q.AllTags
+}
+
+// Less reports whether task `a` goes before task `b` in Query order.
+// Tasks with equal sort field are ordered by ID, tasks without due date go after ones with it.
+func (q Query) Less(a, b Task) bool {
+	if q.Descending {
+		a, b = b, a
+	}
+	switch q.SortBy {
+	case SortByDueDate:
+		if a.HasDueDate() != b.HasDueDate() {
+			return a.HasDueDate()
+		}
+		if !a.DueDate.Equal(b.DueDate) {
+			return a.DueDate.Before(b.DueDate)
+		}
+	case SortByName:
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+	}
+	return a.ID < b.ID
+}
+
+func (f SortField) String() string {
+	switch f {
+	case SortByID:
+		return "id"
+	case SortByDueDate:
+		return "dueDate"
+	case SortByName:
+		return "name"
+	default:
+		return ""
+	}
+}
+
+// SortFieldFromText returns according enum sort field value
+func SortFieldFromText(s string) (SortField, error) {
+	switch s {
+	case "id":
+		return SortByID, nil
+	case "dueDate":
+		return SortByDueDate, nil
+	case "name":
+		return SortByName, nil
+	default:
+		return SortByID, &common.InvalidInputError{Reason: fmt.Sprintf(`can not sort by "%s"`, s)}
+	}
+}
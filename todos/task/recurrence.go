@@ -0,0 +1,316 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+)
+
+// Frequency of Recurrence
+type Frequency uint
+
+// Possible Frequencies, NoRecurrence means task does not repeat
+const (
+	NoRecurrence Frequency = iota
+	Daily
+	Weekly
+	Monthly
+	Yearly
+)
+
+// Recurrence rule of Task, subset of RFC 5545 RRULE.
+// Weeks start on Monday, time of day of occurrences is the one of the first occurrence.
+type Recurrence struct {
+	Freq Frequency
+	// Interval between occurrences in units of Freq, zero means 1
+	Interval uint
+	// ByDay limits occurrences to given weekdays, only with Daily and Weekly Freq
+	ByDay []time.Weekday
+	// ByMonthDay limits occurrences to given days of month, negative days count from the end of month.
+	// Only with Monthly Freq.
+	ByMonthDay []int
+	// Count of occurrences left including current one, zero means unlimited
+	Count uint
+	// Until is the latest time of occurrence, zero means unlimited
+	Until time.Time
+}
+
+// maxSteps limits search for next occurrence, e.g. BYMONTHDAY=31 with INTERVAL=2 may never match
+const maxSteps = 1000
+
+var frequencyNames = map[Frequency]string{Daily: "DAILY", Weekly: "WEEKLY", Monthly: "MONTHLY", Yearly: "YEARLY"}
+
+var weekdayNames = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+const untilLayout = "20060102T150405Z"
+
+// IsZero reports whether task does not repeat
+func (r Recurrence) IsZero() bool {
+	return r.Freq == NoRecurrence
+}
+
+// ParseRecurrence parses recurrence rule. Rule may be "daily", "weekly", "monthly", "yearly"
+// or RRULE with FREQ, INTERVAL, BYDAY, BYMONTHDAY, COUNT and UNTIL parts, e.g. "FREQ=WEEKLY;BYDAY=MO,TH".
+// Empty rule means task does not repeat.
+func ParseRecurrence(s string) (Recurrence, error) {
+	var r Recurrence
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, nil
+	}
+	for f, name := range frequencyNames {
+		if strings.EqualFold(s, name) {
+			r.Freq = f
+			return r, nil
+		}
+	}
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return r, recurrenceError("malformed part %s", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+		var err error
+		switch key {
+		case "FREQ":
+			for f, name := range frequencyNames {
+				if value == name {
+					r.Freq = f
+				}
+			}
+			if r.Freq == NoRecurrence {
+				err = recurrenceError("FREQ %s is not supported", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = parsePositive(key, value)
+		case "COUNT":
+			r.Count, err = parsePositive(key, value)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			r.ByDay, err = parseWeekdays(value)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseMonthDays(value)
+		default:
+			err = recurrenceError("%s is not supported", key)
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+	switch {
+	case r.Freq == NoRecurrence:
+		return r, recurrenceError("FREQ is required")
+	case len(r.ByDay) > 0 && r.Freq != Daily && r.Freq != Weekly:
+		return r, recurrenceError("BYDAY is only supported with DAILY and WEEKLY FREQ")
+	case len(r.ByMonthDay) > 0 && r.Freq != Monthly:
+		return r, recurrenceError("BYMONTHDAY is only supported with MONTHLY FREQ")
+	case r.Count > 0 && !r.Until.IsZero():
+		return r, recurrenceError("COUNT and UNTIL can not be used together")
+	}
+	return r, nil
+}
+
+// String formats recurrence as RRULE, empty if task does not repeat
+func (r Recurrence) String() string {
+	if r.IsZero() {
+		return ""
+	}
+	parts := []string{"FREQ=" + frequencyNames[r.Freq]}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.FormatUint(uint64(r.Interval), 10))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = weekdayNames[d]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.FormatUint(uint64(r.Count), 10))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Next returns first occurrence after given one, false if there are no more occurrences
+func (r Recurrence) Next(after time.Time) (time.Time, bool) {
+	if r.IsZero() || r.Count == 1 {
+		return after, false
+	}
+	interval := int(r.Interval)
+	if interval == 0 {
+		interval = 1
+	}
+	var next time.Time
+	var found bool
+	switch r.Freq {
+	case Daily:
+		next, found = r.nextDay(after, interval)
+	case Weekly:
+		next, found = r.nextWeekday(after, interval)
+	case Monthly:
+		next, found = r.nextMonthDay(after, interval)
+	case Yearly:
+		next, found = nextYear(after, interval)
+	}
+	if !found || (!r.Until.IsZero() && next.After(r.Until)) {
+		return after, false
+	}
+	return next, true
+}
+
+// After returns recurrence of occurrence following the one with this recurrence
+func (r Recurrence) After() Recurrence {
+	if r.Count > 0 {
+		r.Count--
+	}
+	return r
+}
+
+func (r Recurrence) nextDay(after time.Time, interval int) (time.Time, bool) {
+	for i := 1; i <= maxSteps; i++ {
+		next := after.AddDate(0, 0, i*interval)
+		if r.hasWeekday(next.Weekday()) {
+			return next, true
+		}
+	}
+	return after, false
+}
+
+func (r Recurrence) nextWeekday(after time.Time, interval int) (time.Time, bool) {
+	if len(r.ByDay) == 0 {
+		return after.AddDate(0, 0, 7*interval), true
+	}
+	// days after Monday of the week of first occurrence
+	offset := (int(after.Weekday()) + 6) % 7
+	for day := offset + 1; day < 7*interval+7; day++ {
+		if (day/7)%interval != 0 {
+			continue
+		}
+		next := after.AddDate(0, 0, day-offset)
+		if r.hasWeekday(next.Weekday()) {
+			return next, true
+		}
+	}
+	return after, false
+}
+
+func (r Recurrence) nextMonthDay(after time.Time, interval int) (time.Time, bool) {
+	days := r.ByMonthDay
+	if len(days) == 0 {
+		days = []int{after.Day()}
+	}
+	year, month, _ := after.Date()
+	for i := 0; i <= maxSteps; i++ {
+		m := month + time.Month(i*interval)
+		last := time.Date(year, m+1, 0, 0, 0, 0, 0, after.Location()).Day()
+		candidates := make([]int, 0, len(days))
+		for _, d := range days {
+			if d < 0 {
+				d = last + d + 1
+			}
+			// months without such day are skipped
+			if d >= 1 && d <= last {
+				candidates = append(candidates, d)
+			}
+		}
+		sort.Ints(candidates)
+		for _, d := range candidates {
+			next := time.Date(year, m, d, after.Hour(), after.Minute(), after.Second(), 0, after.Location())
+			if next.After(after) {
+				return next, true
+			}
+		}
+	}
+	return after, false
+}
+
+func nextYear(after time.Time, interval int) (time.Time, bool) {
+	for i := 1; i <= maxSteps; i++ {
+		next := after.AddDate(i*interval, 0, 0)
+		// February 29 only occurs in leap years
+		if next.Day() == after.Day() {
+			return next, true
+		}
+	}
+	return after, false
+}
+
+func (r Recurrence) hasWeekday(d time.Weekday) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, day := range r.ByDay {
+		if day == d {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePositive(key, value string) (uint, error) {
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || n == 0 {
+		return 0, recurrenceError("%s must be positive integer", key)
+	}
+	return uint(n), nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	if t, err := time.Parse(untilLayout, value); err == nil {
+		return t, nil
+	}
+	// date includes the whole day
+	if t, err := time.Parse("20060102", value); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, recurrenceError("UNTIL must be date (YYYYMMDD) or UTC time (YYYYMMDDTHHMMSSZ)")
+}
+
+func parseWeekdays(value string) ([]time.Weekday, error) {
+	var days []time.Weekday
+	for _, name := range strings.Split(value, ",") {
+		found := false
+		for d, n := range weekdayNames {
+			if name == n {
+				days = append(days, time.Weekday(d))
+				found = true
+			}
+		}
+		if !found {
+			return nil, recurrenceError("BYDAY %s is not supported, only weekdays without position are", name)
+		}
+	}
+	return days, nil
+}
+
+func parseMonthDays(value string) ([]int, error) {
+	var days []int
+	for _, s := range strings.Split(value, ",") {
+		d, err := strconv.Atoi(s)
+		if err != nil || d == 0 || d < -31 || d > 31 {
+			return nil, recurrenceError("BYMONTHDAY %s must be day of month from 1 to 31 or from -31 to -1", s)
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+func recurrenceError(format string, args ...interface{}) *common.InvalidInputError {
+	return &common.InvalidInputError{Reason: "recurrence: " + fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,85 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceNext(t *testing.T) {
+	// 4th of January 2021 is Monday
+	day := func(month time.Month, d, year int) time.Time {
+		return time.Date(year, month, d, 9, 0, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		rule     string
+		after    time.Time
+		expected time.Time
+	}{
+		{"daily", day(1, 4, 2021), day(1, 5, 2021)},
+		{"FREQ=DAILY;BYDAY=MO,TU,WE,TH,FR", day(1, 8, 2021), day(1, 11, 2021)},
+		{"weekly", day(1, 4, 2021), day(1, 11, 2021)},
+		{"FREQ=WEEKLY;BYDAY=MO,TH", day(1, 4, 2021), day(1, 7, 2021)},
+		{"FREQ=WEEKLY;BYDAY=MO,TH", day(1, 7, 2021), day(1, 11, 2021)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", day(1, 8, 2021), day(1, 18, 2021)},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=SU", day(1, 4, 2021), day(1, 10, 2021)},
+		{"monthly", day(1, 31, 2021), day(3, 31, 2021)},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", day(1, 31, 2021), day(2, 28, 2021)},
+		{"FREQ=MONTHLY;BYMONTHDAY=15,1", day(1, 4, 2021), day(1, 15, 2021)},
+		{"FREQ=MONTHLY;INTERVAL=3", day(11, 30, 2020), day(5, 30, 2021)},
+		{"yearly", day(2, 29, 2020), day(2, 29, 2024)},
+		{"FREQ=DAILY;UNTIL=20210105", day(1, 4, 2021), day(1, 5, 2021)},
+		{"FREQ=DAILY;COUNT=2", day(1, 4, 2021), day(1, 5, 2021)},
+	}
+	for _, c := range cases {
+		r, err := ParseRecurrence(c.rule)
+		if err != nil {
+			t.Fatalf("Got error parsing %s: %s", c.rule, err)
+		}
+		next, ok := r.Next(c.after)
+		if !ok || !next.Equal(c.expected) {
+			t.Errorf("Expected %s after %s by %s, got %s, %v", c.expected, c.after, c.rule, next, ok)
+		}
+	}
+
+	for _, rule := range []string{"FREQ=DAILY;UNTIL=20210105", "FREQ=DAILY;COUNT=1"} {
+		r, _ := ParseRecurrence(rule)
+		if next, ok := r.Next(day(1, 5, 2021)); ok {
+			t.Errorf("Expected no occurrences by %s, got %s", rule, next)
+		}
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := ParseRecurrence("RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=3")
+	if err != nil {
+		t.Fatalf("Got error parsing rule: %s", err)
+	}
+	if s := r.String(); s != "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=3" {
+		t.Errorf("Wrong formatted rule %s", s)
+	}
+	if s := r.After().String(); s != "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=2" {
+		t.Errorf("Expected count to decrease for next occurrence, got %s", s)
+	}
+	if r, _ = ParseRecurrence(""); !r.IsZero() {
+		t.Errorf("Expected empty rule to not repeat, got %s", r)
+	}
+
+	invalid := []string{
+		"hourly",
+		"FREQ=HOURLY",
+		"BYDAY=MO",
+		"FREQ=MONTHLY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=2;UNTIL=20210105",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=DAILY;WKST=SU",
+	}
+	for _, rule := range invalid {
+		if _, err := ParseRecurrence(rule); err == nil {
+			t.Errorf("Expected error parsing %s", rule)
+		}
+	}
+}
@@ -9,15 +9,121 @@ import (
 type DTO struct {
 	Name        string
 	Description string
-	DueDate     time.Time
-	Status      Status
+	// DueDate is zero if task has no due date
+	DueDate time.Time
+	Status  Status
+	// OwnerID is only set on creation, updates keep the owner
+	OwnerID string
+	// Version is expected current version of task on update, zero skips the check
+	Version    uint64
+	Recurrence Recurrence
+	Tags       []string
+}
+
+// Patch describes partial update of Task, only set fields are changed
+type Patch struct {
+	Name        *string
+	Description *string
+	// DueDate pointing to zero time removes due date
+	DueDate *time.Time
+	Status  *Status
+	// Recurrence pointing to zero Recurrence makes task not repeat
+	Recurrence *Recurrence
+	// Tags replace all tags of task
+	Tags *[]string
+	// Version is expected current version of task, zero skips the check
+	Version uint64
+}
+
+// IsEmpty reports whether patch changes nothing
+func (p Patch) IsEmpty() bool {
+	return p.Name == nil && p.Description == nil && p.DueDate == nil && p.Status == nil && p.Recurrence == nil && p.Tags == nil
+}
+
+// Apply returns task with patch applied
+func (p Patch) Apply(t Task) Task {
+	if p.Name != nil {
+		t.Name = *p.Name
+	}
+	if p.Description != nil {
+		t.Description = *p.Description
+	}
+	if p.DueDate != nil {
+		t.DueDate = *p.DueDate
+	}
+	if p.Status != nil {
+		t.Status = *p.Status
+	}
+	if p.Recurrence != nil {
+		t.Recurrence = *p.Recurrence
+	}
+	if p.Tags != nil {
+		t.Tags = *p.Tags
+	}
+	return t
 }
 
 // Repository interface represents objects that handle CRUD operations with storage
 type Repository interface {
-	Read(ctx context.Context, from, count uint) ([]Task, error)
+	// Read reads tasks owned by or shared with given user and matching query
+	Read(ctx context.Context, userID string, query Query, from, count uint) ([]Task, error)
 	ReadOne(ctx context.Context, id uint64) (Task, error)
 	Create(ctx context.Context, task DTO) (Task, error)
+	// Update replaces task with given id, returns *common.VersionMismatchError
+	// if expected version is set and task was changed since then
 	Update(ctx context.Context, id uint64, task DTO) (Task, error)
-	Delete(ctx context.Context, id uint64) error
+	// Patch updates only fields set in patch of task with given id, version is checked as in Update
+	Patch(ctx context.Context, id uint64, patch Patch) (Task, error)
+	// Delete moves task with given id to trash at given time, its subtasks become top-level tasks.
+	// Tasks in trash are not found by other methods but ReadTrash, Restore and Purge.
+	Delete(ctx context.Context, id uint64, at time.Time) error
+	// ReadTrash reads tasks of given owner in trash, the latest deleted go first
+	ReadTrash(ctx context.Context, ownerID string, from, count uint) ([]Task, error)
+	// Restore moves task with given id from trash of given owner, returns error if there is no such task in trash.
+	// Task becomes top-level one if its parent is in trash.
+	Restore(ctx context.Context, id uint64, ownerID string) (Task, error)
+	// Purge deletes tasks moved to trash before given time with their shares, dependencies and history,
+	// returns ids of deleted tasks
+	Purge(ctx context.Context, before time.Time) ([]uint64, error)
+	// MarkOverdue sets Overdue status to new and in progress tasks due before given time.
+	// Only tasks with given ids are affected if any ids are passed. Returns changes of status of affected tasks.
+	MarkOverdue(ctx context.Context, before time.Time, ids ...uint64) ([]StatusChange, error)
+
+	// Access returns permission of user to task with given id, returns error if task is not found
+	Access(ctx context.Context, id uint64, userID string) (Permission, error)
+	ReadShares(ctx context.Context, id uint64) ([]Share, error)
+	// Readers returns owner of task with given id followed by users it is shared with, tasks in trash included.
+	// Returns error if task is not found.
+	Readers(ctx context.Context, id uint64) ([]string, error)
+	// Share grants user permission to task, replacing previously granted one
+	Share(ctx context.Context, share Share) error
+	// Unshare revokes permission granted to user, returns error if there is no such share
+	Unshare(ctx context.Context, id uint64, userID string) error
+
+	// SetParent makes task with given id subtask of parent, zero parent makes it top-level task
+	SetParent(ctx context.Context, id, parentID uint64) (Task, error)
+	// ReadSubtasks reads subtasks of task with given id, returns error if task is not found
+	ReadSubtasks(ctx context.Context, id uint64) ([]Task, error)
+	// ReadBlockers reads tasks blocking task with given id, returns error if task is not found
+	ReadBlockers(ctx context.Context, id uint64) ([]Task, error)
+	// AddDependency makes task blocked by another one, adding existing dependency changes nothing
+	AddDependency(ctx context.Context, dep Dependency) error
+	// RemoveDependency returns error if there is no such dependency
+	RemoveDependency(ctx context.Context, dep Dependency) error
+
+	// ReadTags reads tags of tasks owned by or shared with given user with number of such tasks having each tag
+	ReadTags(ctx context.Context, userID string) ([]TagCount, error)
+	// MergeTags replaces given tags with `into` tag on tasks owned by given user, returns ids of changed tasks
+	MergeTags(ctx context.Context, ownerID string, tags []string, into string) ([]uint64, error)
+
+	// AppendEvents adds events to history of tasks, assigning IDs to given events
+	AppendEvents(ctx context.Context, events ...Event) error
+	// ReadEvents reads `count` events of task with given id in order they happened starting from `from`,
+	// returns error if task is not found
+	ReadEvents(ctx context.Context, id uint64, from, count uint) ([]Event, error)
+
+	// InTransaction runs f with repository whose changes are saved only if f succeeds and are discarded otherwise.
+	// f must only use given repository. Transaction started inside another one is discarded separately
+	// and is saved with the outer one.
+	InTransaction(ctx context.Context, f func(Repository) error) error
 }
@@ -5,8 +5,30 @@ import "net/http"
 // TasksServer interface represents objects that serve HTTP requests for Tasks
 type TasksServer interface {
 	GetTasks(w http.ResponseWriter, r *http.Request)
+	GetTasksICS(w http.ResponseWriter, r *http.Request)
+	PostImport(w http.ResponseWriter, r *http.Request)
 	GetOneTask(w http.ResponseWriter, r *http.Request)
 	PostTask(w http.ResponseWriter, r *http.Request)
+	PostBatch(w http.ResponseWriter, r *http.Request)
 	PutTask(w http.ResponseWriter, r *http.Request)
+	PatchTask(w http.ResponseWriter, r *http.Request)
 	DeleteTask(w http.ResponseWriter, r *http.Request)
+	GetTrash(w http.ResponseWriter, r *http.Request)
+	GetEvents(w http.ResponseWriter, r *http.Request)
+	PostRestore(w http.ResponseWriter, r *http.Request)
+	PatchStatus(w http.ResponseWriter, r *http.Request)
+	GetShares(w http.ResponseWriter, r *http.Request)
+	PutShare(w http.ResponseWriter, r *http.Request)
+	DeleteShare(w http.ResponseWriter, r *http.Request)
+	GetSubtasks(w http.ResponseWriter, r *http.Request)
+	PutSubtask(w http.ResponseWriter, r *http.Request)
+	DeleteSubtask(w http.ResponseWriter, r *http.Request)
+	GetBlockers(w http.ResponseWriter, r *http.Request)
+	PutBlocker(w http.ResponseWriter, r *http.Request)
+	DeleteBlocker(w http.ResponseWriter, r *http.Request)
+	GetHistory(w http.ResponseWriter, r *http.Request)
+	PostComment(w http.ResponseWriter, r *http.Request)
+	GetTags(w http.ResponseWriter, r *http.Request)
+	PutTag(w http.ResponseWriter, r *http.Request)
+	PostTagsMerge(w http.ResponseWriter, r *http.Request)
 }
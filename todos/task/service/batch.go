@@ -0,0 +1,146 @@
+package taskservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/live"
+)
+
+// MaxBatchSize limits number of operations in batch
+const MaxBatchSize = 100
+
+// Actions of BatchOperation
+const (
+	BatchCreate = "create"
+	BatchUpdate = "update"
+	BatchDelete = "delete"
+)
+
+// BatchOperation is single operation of batch
+type BatchOperation struct {
+	// Action is one of BatchCreate, BatchUpdate and BatchDelete
+	Action string
+	// ID of task to update or delete
+	ID uint64
+	// Data of task to create or fields to change on update, name is required on create
+	Data TaskPatch
+}
+
+// BatchResult is result of single operation of batch
+type BatchResult struct {
+	// Task is created or updated task, zero on delete and on failure
+	Task task.Task
+	Err  error
+	// Applied reports whether changes of operation are saved
+	Applied bool
+}
+
+// NotAppliedError represents operation of atomic batch discarded as another one failed
+type NotAppliedError struct {
+	Failed int
+}
+
+func (e *NotAppliedError) Error() string {
+	return fmt.Sprintf("Operation is not applied as operation %d failed", e.Failed)
+}
+
+// Batch runs operations in order in single transaction, each is authorized as if it was run alone.
+// If atomic, operations are applied only if all of them succeed, otherwise the first failed one has its error
+// and the rest have *NotAppliedError. If not atomic, changes of failed operations are discarded and others are applied.
+// Returned error is not nil only if batch itself is invalid or could not be saved.
+func (s *Service) Batch(ctx context.Context, ops []BatchOperation, atomic bool) ([]BatchResult, error) {
+	if len(ops) == 0 {
+		return nil, &common.InvalidInputError{Reason: "batch must have at least one operation"}
+	}
+	if len(ops) > MaxBatchSize {
+		return nil, &common.InvalidInputError{Reason: fmt.Sprintf("batch must have at most %d operations", MaxBatchSize)}
+	}
+	results := make([]BatchResult, len(ops))
+	failed := -1
+	err := s.inTransaction(ctx, func(txService *Service) error {
+		for i, op := range ops {
+			if atomic {
+				results[i].Task, results[i].Err = txService.runOperation(ctx, op)
+				if results[i].Err != nil {
+					failed = i
+					return results[i].Err
+				}
+				continue
+			}
+			// failed operation is discarded on its own
+			err := txService.inTransaction(ctx, func(opService *Service) error {
+				var err error
+				results[i].Task, err = opService.runOperation(ctx, op)
+				return err
+			})
+			if err != nil {
+				results[i] = BatchResult{Err: err}
+			}
+		}
+		return nil
+	})
+	if failed >= 0 {
+		for i := range results {
+			if i != failed {
+				results[i] = BatchResult{Err: &NotAppliedError{Failed: failed}}
+			}
+		}
+		results[failed].Task = task.Task{}
+		return results, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Applied = results[i].Err == nil
+	}
+	return results, nil
+}
+
+func (s *Service) runOperation(ctx context.Context, op BatchOperation) (task.Task, error) {
+	var empty task.Task
+	switch op.Action {
+	case BatchCreate:
+		return s.createFromPatch(ctx, op.Data)
+	case BatchUpdate:
+		return s.PatchTask(ctx, op.ID, op.Data)
+	case BatchDelete:
+		return empty, s.Delete(ctx, op.ID)
+	default:
+		return empty, &common.InvalidInputError{Reason: fmt.Sprintf(`unknown action "%s"`, op.Action)}
+	}
+}
+
+// inTransaction runs f with service whose changes are saved only if f succeeds,
+// live events about the changes are published only once they are saved
+func (s *Service) inTransaction(ctx context.Context, f func(tx *Service) error) error {
+	pending := &pendingEvents{}
+	err := s.repository.InTransaction(ctx, func(r task.Repository) error {
+		tx := s.withRepository(r)
+		if s.publisher != nil {
+			tx.publisher = pending
+		}
+		return f(tx)
+	})
+	if err == nil && s.publisher != nil && len(pending.events) != 0 {
+		s.publisher.Publish(pending.events...)
+	}
+	return err
+}
+
+// withRepository returns service with the same options and publisher using given repository
+func (s *Service) withRepository(r task.Repository) *Service {
+	return &Service{r, s.options, s.publisher}
+}
+
+// pendingEvents collects live events of transaction until it is saved
+type pendingEvents struct {
+	events []live.Event
+}
+
+func (p *pendingEvents) Publish(events ...live.Event) {
+	p.events = append(p.events, events...)
+}
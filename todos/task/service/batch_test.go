@@ -0,0 +1,69 @@
+package taskservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	s := createService()
+	first, _ := s.CreateTask(ctx, "first", "", 0, "", nil)
+	second, _ := s.CreateTask(ctx, "second", "", 0, "", nil)
+	name, done := "created", task.Done.String()
+
+	ops := []BatchOperation{
+		{Action: BatchCreate, Data: TaskPatch{Name: &name, Status: &done}},
+		{Action: BatchUpdate, ID: first.ID, Data: TaskPatch{Status: &done}},
+		{Action: BatchDelete, ID: second.ID},
+		{Action: BatchUpdate, ID: 100, Data: TaskPatch{Status: &done}},
+	}
+	results, err := s.Batch(ctx, ops, true)
+	if err != nil {
+		t.Fatalf("Got error running batch: %s", err)
+	}
+	if !isNotFound(results[3].Err) {
+		t.Errorf("Expected failed operation to have its error, got %v", results[3].Err)
+	}
+	for _, r := range results[:3] {
+		if _, ok := r.Err.(*NotAppliedError); !ok || r.Applied {
+			t.Errorf("Expected other operations not to be applied, got %+v", r)
+		}
+	}
+	if all, _ := s.Get(ctx, task.Query{}, 0, 0); len(all) != 2 || all[0].Status != task.New {
+		t.Errorf("Expected atomic batch to change nothing, got %v", all)
+	}
+
+	results, err = s.Batch(ctx, ops, false)
+	if err != nil {
+		t.Fatalf("Got error running batch: %s", err)
+	}
+	for i, r := range results[:3] {
+		if r.Err != nil || !r.Applied {
+			t.Errorf("Expected operation %d to be applied, got %+v", i, r)
+		}
+	}
+	if !isNotFound(results[3].Err) || results[3].Applied {
+		t.Errorf("Expected failed operation not to be applied, got %+v", results[3])
+	}
+	if results[0].Task.Name != name || results[0].Task.Status != task.Done {
+		t.Errorf("Expected created task in result, got %+v", results[0].Task)
+	}
+	all, _ := s.Get(ctx, task.Query{}, 0, 0)
+	if len(all) != 2 || all[0].ID != first.ID || all[0].Status != task.Done || all[1].ID != results[0].Task.ID {
+		t.Errorf("Expected batch to be applied, got %v", all)
+	}
+	if history, _ := s.GetHistory(ctx, first.ID, 0, 0); len(history) != 2 {
+		t.Errorf("Expected change of batch to be recorded, got %v", history)
+	}
+
+	if _, err = s.Batch(ctx, nil, true); err == nil {
+		t.Error("Expected error running empty batch")
+	}
+	results, _ = s.Batch(ctx, []BatchOperation{{Action: "archive", ID: first.ID}}, false)
+	if results[0].Err == nil {
+		t.Error("Expected error running unknown action")
+	}
+}
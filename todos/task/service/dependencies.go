@@ -0,0 +1,165 @@
+package taskservice
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+// GetSubtasks reads subtasks of task if caller may read it
+func (s *Service) GetSubtasks(ctx context.Context, id uint64) ([]task.Task, error) {
+	if err := s.authorize(ctx, id, task.ReadAccess); err != nil {
+		return nil, err
+	}
+	return s.repository.ReadSubtasks(ctx, id)
+}
+
+// AttachSubtask makes task with childID subtask of task with given id, moving it from its previous parent if any.
+// Caller must be able to change both tasks. Returns *task.CycleError if task is a subtask of child at any depth.
+func (s *Service) AttachSubtask(ctx context.Context, id, childID uint64) (task.Task, error) {
+	var empty task.Task
+	if id == childID {
+		return empty, &common.InvalidInputError{Reason: "task can not be subtask of itself"}
+	}
+	for _, tid := range []uint64{id, childID} {
+		if err := s.authorize(ctx, tid, task.WriteAccess); err != nil {
+			return empty, err
+		}
+	}
+	// walk up from new parent, cycle is found if child is among its ancestors
+	visited := map[uint64]bool{}
+	for ancestor := id; ancestor != 0 && !visited[ancestor]; {
+		if ancestor == childID {
+			return empty, &task.CycleError{ID: id, Other: childID, Relation: "subtask"}
+		}
+		visited[ancestor] = true
+		t, err := s.repository.ReadOne(ctx, ancestor)
+		if err != nil {
+			return empty, err
+		}
+		ancestor = t.ParentID
+	}
+	child, err := s.repository.ReadOne(ctx, childID)
+	if err != nil {
+		return empty, err
+	}
+	return s.setParent(ctx, child, id)
+}
+
+// DetachSubtask makes subtask of task with given id top-level task, caller must be able to change both tasks
+func (s *Service) DetachSubtask(ctx context.Context, id, childID uint64) (task.Task, error) {
+	var empty task.Task
+	for _, tid := range []uint64{id, childID} {
+		if err := s.authorize(ctx, tid, task.WriteAccess); err != nil {
+			return empty, err
+		}
+	}
+	child, err := s.repository.ReadOne(ctx, childID)
+	if err != nil {
+		return empty, err
+	}
+	if child.ParentID != id {
+		return empty, &common.NotFoundError{What: fmt.Sprintf("Subtask with ID %d of task with ID %d", childID, id)}
+	}
+	return s.setParent(ctx, child, 0)
+}
+
+// setParent makes child subtask of parent, zero parent makes it top-level task
+func (s *Service) setParent(ctx context.Context, child task.Task, parentID uint64) (task.Task, error) {
+	updated, err := s.repository.SetParent(ctx, child.ID, parentID)
+	if err != nil {
+		return updated, err
+	}
+	return updated, s.recordChanges(ctx, child, updated)
+}
+
+// GetBlockers reads tasks blocking task if caller may read it
+func (s *Service) GetBlockers(ctx context.Context, id uint64) ([]task.Task, error) {
+	if err := s.authorize(ctx, id, task.ReadAccess); err != nil {
+		return nil, err
+	}
+	return s.repository.ReadBlockers(ctx, id)
+}
+
+// AddBlocker makes task with given id blocked by task with blockerID, so it can not be done before the blocker.
+// Caller must be able to change the task and read the blocker.
+// Returns *task.CycleError if blocker is already blocked by the task at any depth.
+func (s *Service) AddBlocker(ctx context.Context, id, blockerID uint64) error {
+	if id == blockerID {
+		return &common.InvalidInputError{Reason: "task can not block itself"}
+	}
+	if err := s.authorize(ctx, id, task.WriteAccess); err != nil {
+		return err
+	}
+	if err := s.authorize(ctx, blockerID, task.ReadAccess); err != nil {
+		return err
+	}
+	// walk through blockers of blocker, cycle is found if task is among them
+	visited := map[uint64]bool{blockerID: true}
+	queue := []uint64{blockerID}
+	for len(queue) > 0 {
+		blockers, err := s.repository.ReadBlockers(ctx, queue[0])
+		if err != nil {
+			return err
+		}
+		queue = queue[1:]
+		for _, b := range blockers {
+			if b.ID == id {
+				return &task.CycleError{ID: id, Other: blockerID, Relation: "blocker"}
+			}
+			if !visited[b.ID] {
+				visited[b.ID] = true
+				queue = append(queue, b.ID)
+			}
+		}
+	}
+	current, err := s.repository.ReadBlockers(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, b := range current {
+		if b.ID == blockerID {
+			return nil
+		}
+	}
+	if err = s.repository.AddDependency(ctx, task.Dependency{TaskID: id, BlockerID: blockerID}); err != nil {
+		return err
+	}
+	_, err = s.record(ctx, task.Event{TaskID: id, Type: task.Changed, Field: "blockers", To: task.FormatID(blockerID)})
+	return err
+}
+
+// RemoveBlocker makes task with given id not blocked by task with blockerID anymore, caller must be able to change the task
+func (s *Service) RemoveBlocker(ctx context.Context, id, blockerID uint64) error {
+	if err := s.authorize(ctx, id, task.WriteAccess); err != nil {
+		return err
+	}
+	if err := s.repository.RemoveDependency(ctx, task.Dependency{TaskID: id, BlockerID: blockerID}); err != nil {
+		return err
+	}
+	_, err := s.record(ctx, task.Event{TaskID: id, Type: task.Changed, Field: "blockers", From: task.FormatID(blockerID)})
+	return err
+}
+
+// checkBlockers checks that task is not completed while any of its blockers is not done
+func (s *Service) checkBlockers(ctx context.Context, t task.Task, to task.Status) error {
+	if to != task.Done || t.Status == task.Done {
+		return nil
+	}
+	blockers, err := s.repository.ReadBlockers(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	var pending []uint64
+	for _, b := range blockers {
+		if b.Status != task.Done {
+			pending = append(pending, b.ID)
+		}
+	}
+	if len(pending) > 0 {
+		return &task.BlockedError{ID: t.ID, Blockers: pending}
+	}
+	return nil
+}
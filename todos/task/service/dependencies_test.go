@@ -0,0 +1,104 @@
+package taskservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+func TestSubtasks(t *testing.T) {
+	ctx := context.Background()
+	s := createService()
+	parent, _ := s.CreateTask(ctx, "parent", "", 0, "", nil)
+	a, _ := s.CreateTask(ctx, "a", "", 0, "", nil)
+	b, _ := s.CreateTask(ctx, "b", "", 0, "", nil)
+
+	for _, child := range []task.Task{a, b} {
+		attached, err := s.AttachSubtask(ctx, parent.ID, child.ID)
+		if err != nil {
+			t.Fatalf("Got error attaching subtask: %s", err)
+		}
+		if attached.ParentID != parent.ID {
+			t.Errorf("Expected subtask to have parent %d, got %d", parent.ID, attached.ParentID)
+		}
+	}
+	if _, err := s.ChangeStatus(ctx, a.ID, 0, "complete"); err != nil {
+		t.Fatalf("Got error completing subtask: %s", err)
+	}
+	got, _ := s.GetOne(ctx, parent.ID)
+	if got.Progress != (task.Progress{Done: 1, Total: 2}) {
+		t.Errorf("Expected progress 1/2, got %+v", got.Progress)
+	}
+	subtasks, _ := s.GetSubtasks(ctx, parent.ID)
+	if len(subtasks) != 2 {
+		t.Errorf("Expected 2 subtasks, got %v", subtasks)
+	}
+
+	if _, err := s.AttachSubtask(ctx, a.ID, parent.ID); !isCycle(err) {
+		t.Errorf("Expected cycle error attaching parent to its subtask, got %v", err)
+	}
+	if _, err := s.AttachSubtask(ctx, a.ID, a.ID); err == nil {
+		t.Error("Expected error attaching task to itself")
+	}
+	if _, err := s.DetachSubtask(ctx, a.ID, b.ID); !isNotFound(err) {
+		t.Errorf("Expected not found detaching task which is not a subtask, got %v", err)
+	}
+	detached, err := s.DetachSubtask(ctx, parent.ID, b.ID)
+	if err != nil || detached.ParentID != 0 {
+		t.Errorf("Expected subtask to be detached, got %+v, %v", detached, err)
+	}
+}
+
+func TestBlockers(t *testing.T) {
+	ctx := context.Background()
+	s := createService()
+	a, _ := s.CreateTask(ctx, "a", "", 0, "", nil)
+	b, _ := s.CreateTask(ctx, "b", "", 0, "", nil)
+	c, _ := s.CreateTask(ctx, "c", "", 0, "", nil)
+
+	if err := s.AddBlocker(ctx, c.ID, b.ID); err != nil {
+		t.Fatalf("Got error adding blocker: %s", err)
+	}
+	if err := s.AddBlocker(ctx, b.ID, a.ID); err != nil {
+		t.Fatalf("Got error adding blocker: %s", err)
+	}
+	if err := s.AddBlocker(ctx, a.ID, c.ID); !isCycle(err) {
+		t.Errorf("Expected cycle error, got %v", err)
+	}
+
+	_, err := s.ChangeStatus(ctx, c.ID, 0, "complete")
+	blocked, ok := err.(*task.BlockedError)
+	if !ok || len(blocked.Blockers) != 1 || blocked.Blockers[0] != b.ID {
+		t.Errorf("Expected task to be blocked by %d, got %v", b.ID, err)
+	}
+	if _, err = s.UpdateTask(ctx, b.ID, 0, "b", "", 0, "done", "", nil); err == nil {
+		t.Error("Expected completion by update to be blocked")
+	}
+	done := "done"
+	if _, err = s.PatchTask(ctx, b.ID, TaskPatch{Status: &done}); err == nil {
+		t.Error("Expected completion by patch to be blocked")
+	}
+	if _, err = s.ChangeStatus(ctx, c.ID, 0, "start"); err != nil {
+		t.Errorf("Expected blocked task to be started, got %s", err)
+	}
+
+	s.ChangeStatus(ctx, a.ID, 0, "complete")
+	if _, err = s.ChangeStatus(ctx, b.ID, 0, "complete"); err != nil {
+		t.Errorf("Expected task to be completed after its blocker, got %s", err)
+	}
+	if err = s.RemoveBlocker(ctx, c.ID, b.ID); err != nil {
+		t.Fatalf("Got error removing blocker: %s", err)
+	}
+	if err = s.RemoveBlocker(ctx, c.ID, b.ID); !isNotFound(err) {
+		t.Errorf("Expected not found removing missing blocker, got %v", err)
+	}
+	if blockers, _ := s.GetBlockers(ctx, c.ID); len(blockers) != 0 {
+		t.Errorf("Expected no blockers, got %v", blockers)
+	}
+}
+
+func isCycle(err error) bool {
+	_, ok := err.(*task.CycleError)
+	return ok
+}
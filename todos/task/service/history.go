@@ -0,0 +1,126 @@
+package taskservice
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/live"
+)
+
+// MaxCommentLength limits length of comments in characters
+const MaxCommentLength = 10000
+
+// GetHistory reads `count` events of task starting from `from` in order they happened if caller may read the task
+func (s *Service) GetHistory(ctx context.Context, id uint64, from, count uint) ([]task.Event, error) {
+	if err := s.authorize(ctx, id, task.ReadAccess); err != nil {
+		return nil, err
+	}
+	return s.repository.ReadEvents(ctx, id, from, count)
+}
+
+// Comment adds comment to history of task, anyone who may read the task may comment it
+func (s *Service) Comment(ctx context.Context, id uint64, text string) (task.Event, error) {
+	e := task.Event{TaskID: id, Type: task.Commented, Text: strings.TrimSpace(text)}
+	if e.Text == "" {
+		return e, &common.InvalidInputError{Reason: "comment text is required"}
+	}
+	if len([]rune(e.Text)) > MaxCommentLength {
+		return e, &common.InvalidInputError{Reason: fmt.Sprintf("comment must be at most %d characters long", MaxCommentLength)}
+	}
+	if err := s.authorize(ctx, id, task.ReadAccess); err != nil {
+		return e, err
+	}
+	events, err := s.record(ctx, e)
+	if err != nil {
+		return e, err
+	}
+	return events[0], nil
+}
+
+// recordChanges records events for fields that differ between versions of task changed by caller
+func (s *Service) recordChanges(ctx context.Context, before, after task.Task) error {
+	_, err := s.record(ctx, task.Diff(before, after)...)
+	return err
+}
+
+// record appends events caused by caller at current time to history of tasks, returns appended events
+func (s *Service) record(ctx context.Context, events ...task.Event) ([]task.Event, error) {
+	return s.appendEvents(ctx, caller(ctx), events...)
+}
+
+// appendEvents appends events caused by actor at current time to history of tasks,
+// empty actor means events are caused by the app itself
+func (s *Service) appendEvents(ctx context.Context, actor string, events ...task.Event) ([]task.Event, error) {
+	if len(events) == 0 {
+		return events, nil
+	}
+	now := s.options.Now()
+	for i := range events {
+		events[i].Actor = actor
+		events[i].CreatedAt = now
+	}
+	if err := s.repository.AppendEvents(ctx, events...); err != nil {
+		return events, err
+	}
+	s.publish(ctx, events)
+	return events, nil
+}
+
+// publish sends single live event for each task changed by appended history events.
+// Changes are already saved, so failure to find readers of task is logged instead of being returned.
+func (s *Service) publish(ctx context.Context, events []task.Event) {
+	if s.publisher == nil {
+		return
+	}
+	var changed []live.Event
+	byTask := make(map[uint64]int)
+	for _, e := range events {
+		i, ok := byTask[e.TaskID]
+		if !ok {
+			i = len(changed)
+			byTask[e.TaskID] = i
+			changed = append(changed, live.Event{TaskID: e.TaskID, Actor: e.Actor, CreatedAt: e.CreatedAt})
+		}
+		le := &changed[i]
+		switch e.Type {
+		case task.Created:
+			le.Type = live.Created
+		case task.Deleted:
+			le.Type = live.Deleted
+		case task.Restored:
+			le.Type = live.Restored
+		case task.Changed, task.StatusChanged:
+			le.Fields = append(le.Fields, e.Field)
+			switch {
+			case le.Type == live.Created:
+			case e.Type == task.StatusChanged && e.To == task.Overdue.String():
+				le.Type = live.Overdue
+			case e.Type == task.StatusChanged && e.To == task.Done.String():
+				le.Type = live.Completed
+			case le.Type == "":
+				le.Type = live.Updated
+			}
+		}
+	}
+	published := changed[:0]
+	for _, le := range changed {
+		// comments do not change task
+		if le.Type == "" {
+			continue
+		}
+		readers, err := s.repository.Readers(ctx, le.TaskID)
+		if err != nil {
+			log.Printf("Failed to publish %s event of task %d: %s", le.Type, le.TaskID, err)
+			continue
+		}
+		le.Readers = readers
+		published = append(published, le)
+	}
+	if len(published) != 0 {
+		s.publisher.Publish(published...)
+	}
+}
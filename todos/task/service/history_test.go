@@ -0,0 +1,64 @@
+package taskservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
+)
+
+func TestHistory(t *testing.T) {
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	bob := auth.NewContext(context.Background(), auth.Principal{Subject: "bob"})
+	clock := &fakeClock{time.Unix(1000000, 0)}
+	s := New(inmemory.New(), Options{Now: clock.Now})
+
+	created, _ := s.CreateTask(alice, "test", "", clock.now.Add(time.Hour).Unix(), "", nil)
+	s.ShareTask(alice, created.ID, "bob", "read")
+	name := "renamed"
+	s.PatchTask(alice, created.ID, TaskPatch{Name: &name, Tags: &[]string{"home"}})
+	s.ChangeStatus(alice, created.ID, 0, "start")
+	if _, err := s.Comment(bob, created.ID, "  looks good "); err != nil {
+		t.Fatalf("Got error commenting task: %s", err)
+	}
+	if _, err := s.Comment(bob, created.ID, " "); err == nil {
+		t.Error("Expected error adding empty comment")
+	}
+	clock.now = clock.now.Add(2 * time.Hour)
+	s.SweepOverdue(context.Background())
+
+	expected := []task.Event{
+		{Type: task.Created, Actor: "alice"},
+		{Type: task.Changed, Actor: "alice", Field: "shares", To: "bob:read"},
+		{Type: task.Changed, Actor: "alice", Field: "name", From: "test", To: "renamed"},
+		{Type: task.Changed, Actor: "alice", Field: "tags", To: "home"},
+		{Type: task.StatusChanged, Actor: "alice", Field: "status", From: "new", To: "in-progress"},
+		{Type: task.Commented, Actor: "bob", Text: "looks good"},
+		{Type: task.StatusChanged, Field: "status", From: "in-progress", To: "overdue"},
+	}
+	events, err := s.GetHistory(bob, created.ID, 0, 0)
+	if err != nil {
+		t.Fatalf("Got error reading history: %s", err)
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), events)
+	}
+	for i, e := range events {
+		exp := expected[i]
+		exp.ID, exp.TaskID, exp.CreatedAt = e.ID, created.ID, e.CreatedAt
+		if e != exp {
+			t.Errorf("Expected event %+v, got %+v", exp, e)
+		}
+	}
+
+	page, _ := s.GetHistory(alice, created.ID, 5, 1)
+	if len(page) != 1 || page[0].Type != task.Commented {
+		t.Errorf("Expected single comment on page, got %+v", page)
+	}
+	if _, err = s.GetHistory(context.Background(), created.ID, 0, 0); !isNotFound(err) {
+		t.Errorf("Expected history of task not shared with caller to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,72 @@
+package taskservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+// ImportTask saves task imported from external source, returns saved task and whether it was created.
+// Task with given id is updated with set fields of data if caller may change it.
+// If id is zero or there is no such task, new task is created, it gets "new" status unless other one is set.
+func (s *Service) ImportTask(ctx context.Context, id uint64, data TaskPatch) (task.Task, bool, error) {
+	if id != 0 {
+		if current, err := s.GetOne(ctx, id); err == nil && s.staysOverdue(current, data) {
+			data.Status = nil
+		}
+		t, err := s.PatchTask(ctx, id, data)
+		if _, ok := err.(*common.NotFoundError); !ok {
+			return t, false, err
+		}
+	}
+	t, err := s.createFromPatch(ctx, data)
+	return t, err == nil || t.ID != 0, err
+}
+
+// staysOverdue tells if imported unfinished task is overdue task exported as unfinished,
+// which keeps its status unless its due date is moved to the future
+func (s *Service) staysOverdue(current task.Task, data TaskPatch) bool {
+	if current.Status != task.Overdue || data.Status == nil || *data.Status != task.New.String() {
+		return false
+	}
+	due := current.DueDate
+	if data.DueDate != nil {
+		due = *data.DueDate
+	}
+	return (task.Task{Status: task.New, DueDate: due}).IsOverdueAt(s.overdueBefore())
+}
+
+// createFromPatch creates task with set fields of data, name is required.
+// Task gets "new" status unless other one is set, then it is changed along allowed transitions.
+func (s *Service) createFromPatch(ctx context.Context, data TaskPatch) (task.Task, error) {
+	var empty task.Task
+	if data.Name == nil {
+		return empty, &common.InvalidInputError{Reason: "name is required"}
+	}
+	var desc, recurrence string
+	var due time.Time
+	var tags []string
+	if data.Description != nil {
+		desc = *data.Description
+	}
+	if data.DueDate != nil {
+		due = *data.DueDate
+	}
+	if data.Recurrence != nil {
+		recurrence = *data.Recurrence
+	}
+	if data.Tags != nil {
+		tags = *data.Tags
+	}
+	created, err := s.create(ctx, *data.Name, desc, due, recurrence, tags)
+	if err != nil || data.Status == nil || *data.Status == task.New.String() {
+		return created, err
+	}
+	updated, err := s.PatchTask(ctx, created.ID, TaskPatch{Status: data.Status})
+	if err != nil {
+		return created, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,20 @@
+package taskservice
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Vesninovich/go-tasks/todos/task/live"
+)
+
+// ErrLiveDisabled is returned on subscription to live events if service has no live.Broker
+var ErrLiveDisabled = errors.New("live events are not enabled")
+
+// Subscribe starts delivering live events of tasks caller may read, events of given types and tasks only if set.
+// Events published after event with `lastID` are delivered first, `missed` is set if some of them are not kept anymore.
+func (s *Service) Subscribe(ctx context.Context, types []live.Type, taskIDs []uint64, lastID uint64) (sub *live.Subscription, missed bool, err error) {
+	if s.options.Live == nil {
+		return nil, false, ErrLiveDisabled
+	}
+	return s.options.Live.Subscribe(live.Filter{User: caller(ctx), Types: types, TaskIDs: taskIDs}, lastID)
+}
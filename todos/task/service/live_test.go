@@ -0,0 +1,66 @@
+package taskservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
+	"github.com/Vesninovich/go-tasks/todos/task/live"
+)
+
+func TestLiveEvents(t *testing.T) {
+	s := New(inmemory.New(), Options{Live: live.NewBroker(0)})
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	bob := auth.NewContext(context.Background(), auth.Principal{Subject: "bob"})
+	aliceSub, _, err := s.Subscribe(alice, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("Got error subscribing: %s", err)
+	}
+	bobSub, _, _ := s.Subscribe(bob, nil, nil, 0)
+
+	created, _ := s.CreateTask(alice, "test", "", 0, "", nil)
+	checkLiveEvent(t, <-aliceSub.Events(), live.Created, created.ID, "alice")
+	s.ShareTask(alice, created.ID, "bob", "read")
+	checkLiveEvent(t, <-aliceSub.Events(), live.Updated, created.ID, "alice")
+	checkLiveEvent(t, <-bobSub.Events(), live.Updated, created.ID, "alice")
+	s.Comment(alice, created.ID, "comment")
+	name, inProgress, done := "renamed", task.InProgress.String(), task.Done.String()
+	s.PatchTask(alice, created.ID, TaskPatch{Status: &inProgress})
+	checkLiveEvent(t, <-aliceSub.Events(), live.Updated, created.ID, "alice")
+	checkLiveEvent(t, <-bobSub.Events(), live.Updated, created.ID, "alice")
+	s.PatchTask(alice, created.ID, TaskPatch{Name: &name, Status: &done})
+	e := <-aliceSub.Events()
+	checkLiveEvent(t, e, live.Completed, created.ID, "alice")
+	if len(e.Fields) != 2 || e.Fields[0] != "name" || e.Fields[1] != "status" {
+		t.Errorf("Expected changed fields in single event, got %v", e.Fields)
+	}
+	if len(aliceSub.Events()) != 0 {
+		t.Errorf("Expected no events for comment, got %+v", <-aliceSub.Events())
+	}
+	checkLiveEvent(t, <-bobSub.Events(), live.Completed, created.ID, "alice")
+
+	s.Delete(alice, created.ID)
+	checkLiveEvent(t, <-aliceSub.Events(), live.Deleted, created.ID, "alice")
+	checkLiveEvent(t, <-bobSub.Events(), live.Deleted, created.ID, "alice")
+
+	// events of failed atomic batch are not published
+	s.Batch(alice, []BatchOperation{
+		{Action: BatchCreate, Data: TaskPatch{Name: &name}},
+		{Action: BatchUpdate, ID: 100, Data: TaskPatch{Name: &name}},
+	}, true)
+	s.Restore(alice, created.ID)
+	checkLiveEvent(t, <-aliceSub.Events(), live.Restored, created.ID, "alice")
+
+	if _, _, err = createService().Subscribe(alice, nil, nil, 0); err != ErrLiveDisabled {
+		t.Errorf("Expected subscription to fail without broker, got %v", err)
+	}
+}
+
+func checkLiveEvent(t *testing.T, e live.Event, typ live.Type, taskID uint64, actor string) {
+	t.Helper()
+	if e.Type != typ || e.TaskID != taskID || e.Actor != actor {
+		t.Errorf("Expected %s event of task %d by %s, got %+v", typ, taskID, actor, e)
+	}
+}
@@ -0,0 +1,95 @@
+package taskservice
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+// SweepOverdue makes all unfinished tasks past due date and grace period overdue.
+// Returns number of changed tasks.
+func (s *Service) SweepOverdue(ctx context.Context) (uint64, error) {
+	changes, err := s.repository.MarkOverdue(ctx, s.overdueBefore())
+	if err != nil {
+		return 0, err
+	}
+	return uint64(len(changes)), s.recordOverdue(ctx, changes)
+}
+
+// RunOverdueSweeps calls SweepOverdue every `interval` until context is done
+func (s *Service) RunOverdueSweeps(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.SweepOverdue(ctx)
+			if err != nil {
+				log.Printf("Failed to mark overdue tasks: %s", err)
+			} else if n > 0 {
+				log.Printf("Marked %d tasks overdue", n)
+			}
+		}
+	}
+}
+
+// markOverdue makes read tasks that became overdue since last sweep overdue
+// both in storage and in given slice
+func (s *Service) markOverdue(ctx context.Context, tasks []task.Task) error {
+	before := s.overdueBefore()
+	var ids []uint64
+	for _, t := range tasks {
+		if t.IsOverdueAt(before) {
+			ids = append(ids, t.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	changes, err := s.repository.MarkOverdue(ctx, before, ids...)
+	if err != nil {
+		return err
+	}
+	if err = s.recordOverdue(ctx, changes); err != nil {
+		return err
+	}
+	changed := make(map[uint64]bool, len(changes))
+	for _, c := range changes {
+		changed[c.ID] = true
+	}
+	for i := range tasks {
+		if !tasks[i].IsOverdueAt(before) {
+			continue
+		}
+		if changed[tasks[i].ID] {
+			// repository bumps version of marked tasks, so read copy keeps matching stored one
+			tasks[i].Status = task.Overdue
+			tasks[i].Version++
+			continue
+		}
+		// task was changed by someone else since it was read, e.g. by concurrent sweep
+		if tasks[i], err = s.repository.ReadOne(ctx, tasks[i].ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordOverdue records status changes made by overdue sweeps as made by the app itself
+func (s *Service) recordOverdue(ctx context.Context, changes []task.StatusChange) error {
+	events := make([]task.Event, len(changes))
+	for i, c := range changes {
+		events[i] = task.Event{TaskID: c.ID, Type: task.StatusChanged, Field: "status", From: c.From.String(), To: c.To.String()}
+	}
+	_, err := s.appendEvents(ctx, "", events...)
+	return err
+}
+
+// overdueBefore returns time unfinished tasks due before are overdue
+func (s *Service) overdueBefore() time.Time {
+	return s.options.Now().Add(-s.options.OverdueGrace)
+}
@@ -0,0 +1,181 @@
+package taskservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func TestSweepOverdue(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{time.Unix(1000000, 0)}
+	r := inmemory.New()
+	s := New(r, Options{OverdueGrace: time.Hour, Now: clock.Now})
+
+	due := clock.now.Add(time.Minute)
+	pending, _ := s.CreateTask(ctx, "pending", "", due.Unix(), "", nil)
+	noDue, _ := s.CreateTask(ctx, "no due date", "", 0, "", nil)
+	done, _ := r.Create(ctx, task.DTO{Name: "done", DueDate: due, Status: task.Done})
+
+	clock.now = due.Add(30 * time.Minute)
+	if n, err := s.SweepOverdue(ctx); err != nil || n != 0 {
+		t.Errorf("Expected no tasks to become overdue during grace period, got %d, %v", n, err)
+	}
+
+	clock.now = due.Add(2 * time.Hour)
+	n, err := s.SweepOverdue(ctx)
+	if err != nil {
+		t.Fatalf("Got error while sweeping: %s", err)
+	}
+	if n != 1 {
+		t.Errorf("Expected 1 task to become overdue, got %d", n)
+	}
+	checkStatus(t, r, pending.ID, task.Overdue)
+	checkStatus(t, r, noDue.ID, task.New)
+	checkStatus(t, r, done.ID, task.Done)
+}
+
+func TestStatusFilterPagesOverdueOnRead(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{time.Unix(1000000, 0)}
+	r := inmemory.New()
+	s := New(r, Options{Now: clock.Now})
+
+	due := clock.now.Add(time.Minute)
+	for _, name := range []string{"a", "b", "c"} {
+		s.CreateTask(ctx, name, "", due.Unix(), "", nil)
+	}
+	for _, name := range []string{"d", "e"} {
+		s.CreateTask(ctx, name, "", 0, "", nil)
+	}
+
+	clock.now = due.Add(time.Second)
+	overdue, err := s.Get(ctx, task.Query{Statuses: []task.Status{task.Overdue}}, 0, 2)
+	if err != nil {
+		t.Fatalf("Got error while reading overdue tasks: %s", err)
+	}
+	if len(overdue) != 2 || overdue[0].Name != "a" || overdue[1].Name != "b" {
+		t.Errorf("Expected first page of tasks which became overdue, got %+v", overdue)
+	}
+	fresh, err := s.Get(ctx, task.Query{Statuses: []task.Status{task.New}}, 0, 2)
+	if err != nil {
+		t.Fatalf("Got error while reading new tasks: %s", err)
+	}
+	if len(fresh) != 2 || fresh[0].Name != "d" || fresh[1].Name != "e" {
+		t.Errorf("Expected full page of new tasks, got %+v", fresh)
+	}
+}
+
+func TestOverdueOnRead(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{time.Unix(1000000, 0)}
+	r := inmemory.New()
+	s := New(r, Options{Now: clock.Now})
+
+	due := clock.now.Add(time.Minute)
+	created, _ := s.CreateTask(ctx, "test", "", due.Unix(), "", nil)
+	other, _ := s.CreateTask(ctx, "other", "", due.Add(time.Hour).Unix(), "", nil)
+
+	clock.now = due.Add(time.Second)
+	read, err := s.GetOne(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Got error while reading task: %s", err)
+	}
+	if read.Status != task.Overdue {
+		t.Errorf("Expected read task to be overdue, got %s", read.Status)
+	}
+	checkStatus(t, r, created.ID, task.Overdue)
+	checkStatus(t, r, other.ID, task.New)
+
+	clock.now = due.Add(2 * time.Hour)
+	tasks, err := s.Get(ctx, task.Query{}, 0, 0)
+	if err != nil {
+		t.Fatalf("Got error while reading tasks: %s", err)
+	}
+	for _, tsk := range tasks {
+		if tsk.Status != task.Overdue {
+			t.Errorf("Expected task %d to be overdue, got %s", tsk.ID, tsk.Status)
+		}
+	}
+	checkStatus(t, r, other.ID, task.Overdue)
+}
+
+func TestOverdueOnReadKeepsVersion(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{time.Unix(1000000, 0)}
+	s := New(inmemory.New(), Options{Now: clock.Now})
+	due := clock.now.Add(time.Minute)
+	created, _ := s.CreateTask(ctx, "test", "", due.Unix(), "", nil)
+
+	clock.now = due.Add(time.Second)
+	read, err := s.GetOne(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("Got error while reading task: %s", err)
+	}
+	if read.Version == created.Version {
+		t.Errorf("Expected version of task made overdue on read to change")
+	}
+	// client sends version of read task as If-Match
+	updated, err := s.UpdateTask(ctx, created.ID, read.Version, "renamed", "", due.Unix(), "overdue", "", nil)
+	if err != nil {
+		t.Fatalf("Expected update with version of read task to succeed, got %s", err)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	tasks, _ := s.Get(ctx, task.Query{}, 0, 0)
+	if len(tasks) != 1 || tasks[0].Version != updated.Version {
+		t.Fatalf("Expected listed task to have version %d, got %+v", updated.Version, tasks)
+	}
+	if _, err = s.ChangeStatus(ctx, created.ID, tasks[0].Version, "complete"); err != nil {
+		t.Errorf("Expected status change with version of listed task to succeed, got %s", err)
+	}
+}
+
+func TestRunOverdueSweeps(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := &fakeClock{time.Unix(1000000, 0)}
+	r := inmemory.New()
+	s := New(r, Options{Now: clock.Now})
+	created, _ := s.CreateTask(ctx, "test", "", clock.now.Add(-time.Minute).Unix(), "", nil)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.RunOverdueSweeps(ctx, time.Millisecond)
+		close(stopped)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for {
+		tsk, _ := r.ReadOne(ctx, created.ID)
+		if tsk.Status == task.Overdue {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Task was not made overdue by scheduler")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-stopped
+}
+
+func checkStatus(t *testing.T, r task.Repository, id uint64, expected task.Status) {
+	t.Helper()
+	tsk, err := r.ReadOne(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Got error while reading task %d: %s", id, err)
+	}
+	if tsk.Status != expected {
+		t.Errorf("Expected task %d to have status %s, got %s", id, expected, tsk.Status)
+	}
+}
@@ -0,0 +1,69 @@
+package taskservice
+
+import (
+	"context"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+// completesRecurring reports whether moving task from one status to another completes it while it repeats by rule
+func completesRecurring(from, to task.Status, rule task.Recurrence) bool {
+	return to == task.Done && from != task.Done && !rule.IsZero()
+}
+
+// createNextOccurrence creates next occurrence of completed task repeating by given rule.
+// Next occurrence is due at first occurrence after due date of completed task that is still in the future,
+// missed occurrences are skipped. Tasks without due date repeat from the time they are completed.
+// Next occurrence keeps owner, tags, parent and shares of completed task.
+func (s *Service) createNextOccurrence(ctx context.Context, t task.Task, rule task.Recurrence) error {
+	now := s.options.Now()
+	at := t.DueDate
+	if !t.HasDueDate() {
+		at = now
+	}
+	var due time.Time
+	for {
+		next, ok := rule.Next(at)
+		if !ok {
+			return nil
+		}
+		rule = rule.After()
+		if next.After(now) {
+			due = next
+			break
+		}
+		at = next
+	}
+	created, err := s.repository.Create(ctx, task.DTO{
+		Name:        t.Name,
+		Description: t.Description,
+		DueDate:     due,
+		Status:      task.New,
+		OwnerID:     t.OwnerID,
+		Recurrence:  rule,
+		Tags:        t.Tags,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = s.record(ctx, task.Event{TaskID: created.ID, Type: task.Created}); err != nil {
+		return err
+	}
+	if t.ParentID != 0 {
+		if _, err = s.repository.SetParent(ctx, created.ID, t.ParentID); err != nil {
+			return err
+		}
+	}
+	shares, err := s.repository.ReadShares(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	for _, share := range shares {
+		share.TaskID = created.ID
+		if err = s.repository.Share(ctx, share); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package taskservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
+	"github.com/Vesninovich/go-tasks/todos/task/live"
+)
+
+func TestCompleteRecurring(t *testing.T) {
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	bob := auth.NewContext(context.Background(), auth.Principal{Subject: "bob"})
+	clock := &fakeClock{time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC)}
+	s := New(inmemory.New(), Options{Now: clock.Now})
+
+	due := time.Date(2021, 1, 4, 18, 0, 0, 0, time.UTC)
+	created, err := s.CreateTask(alice, "chores", "", due.Unix(), "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=4", nil)
+	if err != nil {
+		t.Fatalf("Got error creating task: %s", err)
+	}
+	s.ShareTask(alice, created.ID, "bob", "write")
+
+	done, err := s.ChangeStatus(bob, created.ID, 0, "complete")
+	if err != nil {
+		t.Fatalf("Got error completing task: %s", err)
+	}
+	if !done.Recurrence.IsZero() {
+		t.Errorf("Expected completed task to stop repeating, got %s", done.Recurrence)
+	}
+	next := checkNextOccurrence(bob, t, s, time.Date(2021, 1, 7, 18, 0, 0, 0, time.UTC), "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=3")
+	if next.OwnerID != "alice" {
+		t.Errorf("Expected next occurrence to keep owner, got %s", next.OwnerID)
+	}
+
+	// missed occurrences are skipped
+	clock.now = time.Date(2021, 1, 12, 0, 0, 0, 0, time.UTC)
+	status := "done"
+	if _, err = s.PatchTask(alice, next.ID, TaskPatch{Status: &status}); err != nil {
+		t.Fatalf("Got error completing task: %s", err)
+	}
+	last := checkNextOccurrence(alice, t, s, time.Date(2021, 1, 14, 18, 0, 0, 0, time.UTC), "FREQ=WEEKLY;BYDAY=MO,TH;COUNT=1")
+	if _, err = s.UpdateTask(alice, last.ID, 0, "chores", "", last.DueDate.Unix(), "done", last.Recurrence.String(), nil); err != nil {
+		t.Fatalf("Got error completing task: %s", err)
+	}
+	if tasks, _ := s.Get(alice, task.Query{Statuses: []task.Status{task.New}}, 0, 0); len(tasks) != 0 {
+		t.Errorf("Expected no more occurrences, got %v", tasks)
+	}
+
+	if _, err = s.CreateTask(alice, "invalid", "", 0, "FREQ=HOURLY", nil); err == nil {
+		t.Error("Expected error creating task with unsupported recurrence")
+	}
+}
+
+// checkNextOccurrence checks that there is single new task due at given time and repeating by given rule
+func checkNextOccurrence(ctx context.Context, t *testing.T, s *Service, due time.Time, rule string) task.Task {
+	tasks, err := s.Get(ctx, task.Query{Statuses: []task.Status{task.New}}, 0, 0)
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("Expected single next occurrence, got %v, %v", tasks, err)
+	}
+	if !tasks[0].DueDate.Equal(due) {
+		t.Errorf("Expected next occurrence to be due at %s, got %s", due, tasks[0].DueDate)
+	}
+	if r := tasks[0].Recurrence.String(); r != rule {
+		t.Errorf("Expected next occurrence to repeat by %s, got %s", rule, r)
+	}
+	return tasks[0]
+}
+
+// failingCreate fails to create tasks once fail is set, inside transactions too
+type failingCreate struct {
+	task.Repository
+	fail *bool
+}
+
+func (r failingCreate) Create(ctx context.Context, dto task.DTO) (task.Task, error) {
+	if *r.fail {
+		return task.Task{}, errors.New("failure")
+	}
+	return r.Repository.Create(ctx, dto)
+}
+
+func (r failingCreate) InTransaction(ctx context.Context, f func(task.Repository) error) error {
+	return r.Repository.InTransaction(ctx, func(tx task.Repository) error {
+		return f(failingCreate{tx, r.fail})
+	})
+}
+
+func TestCompleteRecurringIsAtomic(t *testing.T) {
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	fail := false
+	s := New(failingCreate{inmemory.New(), &fail}, Options{Live: live.NewBroker(0)})
+	created, err := s.CreateTask(alice, "chores", "", time.Now().Add(time.Hour).Unix(), "FREQ=DAILY", nil)
+	if err != nil {
+		t.Fatalf("Got error creating task: %s", err)
+	}
+	sub, _, err := s.Subscribe(alice, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("Got error subscribing: %s", err)
+	}
+
+	// next occurrence can not be created, so completion must not be saved either
+	fail = true
+	status := "done"
+	if _, err = s.ChangeStatus(alice, created.ID, 0, "complete"); err == nil {
+		t.Error("Expected completion to fail")
+	}
+	if _, err = s.PatchTask(alice, created.ID, TaskPatch{Status: &status}); err == nil {
+		t.Error("Expected completion to fail")
+	}
+	if _, err = s.UpdateTask(alice, created.ID, 0, "chores", "", created.DueDate.Unix(), "done", "FREQ=DAILY", nil); err == nil {
+		t.Error("Expected completion to fail")
+	}
+	current, err := s.GetOne(alice, created.ID)
+	if err != nil || current.Status != task.New || current.Recurrence.IsZero() || current.Version != created.Version {
+		t.Errorf("Expected task to be left as is, got %+v, %v", current, err)
+	}
+	if events, _ := s.GetHistory(alice, created.ID, 0, 0); len(events) != 1 {
+		t.Errorf("Expected only creation in history, got %v", events)
+	}
+	select {
+	case e := <-sub.Events():
+		t.Errorf("Expected no live events of failed completion, got %+v", e)
+	default:
+	}
+}
@@ -2,48 +2,148 @@ package taskservice
 
 import (
 	"context"
+	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
 	"github.com/Vesninovich/go-tasks/todos/common"
 	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/live"
 )
 
 // Service handles tasks manipulation
 type Service struct {
 	repository task.Repository
+	options    Options
+	// publisher of live events, inside transaction it keeps events until transaction is saved
+	publisher live.Publisher
 }
 
+// Options of Service
+type Options struct {
+	// OverdueGrace is how long after due date unfinished task becomes overdue
+	OverdueGrace time.Duration
+	// Now returns current time, time.Now is used if not set
+	Now func() time.Time
+	// TrashRetention is how long deleted tasks are kept in trash, DefaultTrashRetention is used if not set
+	TrashRetention time.Duration
+	// Live delivers events about changes of tasks to subscribers, nothing is published if not set
+	Live *live.Broker
+	// Webhooks delivers events about changes of tasks to webhook subscriptions, nothing is delivered if not set
+	Webhooks *webhook.Dispatcher
+}
+
+// DefaultTrashRetention keeps deleted tasks for 30 days
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// DefaultOptions make tasks overdue right after due date
+var DefaultOptions = Options{}
+
 // New creates new instance of Service
-func New(r task.Repository) *Service {
-	return &Service{r}
+func New(r task.Repository, opts Options) *Service {
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	if opts.TrashRetention == 0 {
+		opts.TrashRetention = DefaultTrashRetention
+	}
+	s := &Service{repository: r, options: opts}
+	var publishers live.Publishers
+	if opts.Live != nil {
+		publishers = append(publishers, opts.Live)
+	}
+	if opts.Webhooks != nil {
+		publishers = append(publishers, webhookPublisher{opts.Webhooks})
+	}
+	if len(publishers) != 0 {
+		s.publisher = publishers
+	}
+	return s
 }
 
-// Get reads all stored tasks
-func (s *Service) Get(ctx context.Context, from, count uint) ([]task.Task, error) {
-	return s.repository.Read(ctx, from, count)
+// Get reads stored tasks owned by or shared with caller and matching query
+func (s *Service) Get(ctx context.Context, query task.Query, from, count uint) ([]task.Task, error) {
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	var err error
+	if query.Tags, err = task.NormalizeTags(query.Tags); err != nil {
+		return nil, err
+	}
+	if len(query.Statuses) > 0 {
+		// repository filters by stored status before paginating,
+		// so tasks which became overdue since last sweep must be stored as overdue first
+		if _, err = s.SweepOverdue(ctx); err != nil {
+			return nil, err
+		}
+	}
+	tasks, err := s.repository.Read(ctx, caller(ctx), query, from, count)
+	if err != nil {
+		return nil, err
+	}
+	return tasks, s.markOverdue(ctx, tasks)
 }
 
-// GetOne reads stored task by id
+// GetOne reads stored task by id if caller may read it
 func (s *Service) GetOne(ctx context.Context, id uint64) (task.Task, error) {
-	return s.repository.ReadOne(ctx, id)
+	if err := s.authorize(ctx, id, task.ReadAccess); err != nil {
+		var empty task.Task
+		return empty, err
+	}
+	t, err := s.repository.ReadOne(ctx, id)
+	if err != nil {
+		return t, err
+	}
+	tasks := []task.Task{t}
+	return tasks[0], s.markOverdue(ctx, tasks)
 }
 
 // CreateTask validates data, creates task if data is valid and saves it, returns error otherwise.
-// All created tasks get "New" status assigned to them.
-func (s *Service) CreateTask(ctx context.Context, name, desc string, dueDate int64) (task.Task, error) {
+// All created tasks get "New" status assigned to them and are owned by caller.
+// Zero due date means task has no due date, empty recurrence rule means task does not repeat.
+func (s *Service) CreateTask(ctx context.Context, name, desc string, dueDate int64, recurrence string, tags []string) (task.Task, error) {
+	return s.create(ctx, name, desc, dueDateFromUnix(dueDate), recurrence, tags)
+}
+
+// create creates task due at given time, zero time means task has no due date
+func (s *Service) create(ctx context.Context, name, desc string, due time.Time, recurrence string, tags []string) (task.Task, error) {
 	var empty task.Task
 	if name == "" {
 		return empty, &common.InvalidInputError{Reason: "name is required"}
 	}
-	if dueDate < 0 {
-		return empty, &common.InvalidInputError{Reason: "\"dueDate\" must be non-negative integer"}
+	if err := checkDueDate(due); err != nil {
+		return empty, err
 	}
-	due := time.Unix(dueDate, 0)
-	return s.repository.Create(ctx, task.DTO{Name: name, Description: desc, DueDate: due, Status: task.New})
+	rule, err := task.ParseRecurrence(recurrence)
+	if err != nil {
+		return empty, err
+	}
+	if tags, err = task.NormalizeTags(tags); err != nil {
+		return empty, err
+	}
+	created, err := s.repository.Create(ctx, task.DTO{
+		Name:        name,
+		Description: desc,
+		DueDate:     due,
+		Status:      task.New,
+		OwnerID:     caller(ctx),
+		Recurrence:  rule,
+		Tags:        tags,
+	})
+	if err != nil {
+		return created, err
+	}
+	_, err = s.record(ctx, task.Event{TaskID: created.ID, Type: task.Created})
+	return created, err
 }
 
-// UpdateTask validates data, updates task if data is valid and task is found, returns error otherwise.
-func (s *Service) UpdateTask(ctx context.Context, id uint64, name, desc string, dueDate int64, status string) (task.Task, error) {
+// UpdateTask validates data, updates task if data is valid, task is found and caller may change it, returns error otherwise.
+// Status may only be changed along allowed transitions and task may not be done before its blockers.
+// Non-zero version must match current version of task.
+// Completed recurring task stops repeating and its next occurrence is created with given recurrence rule.
+func (s *Service) UpdateTask(ctx context.Context, id, version uint64, name, desc string, dueDate int64, status, recurrence string, tags []string) (task.Task, error) {
 	var empty task.Task
 	if name == "" {
 		return empty, &common.InvalidInputError{Reason: "name is required"}
@@ -55,11 +155,279 @@ func (s *Service) UpdateTask(ctx context.Context, id uint64, name, desc string,
 	if err != nil {
 		return empty, err
 	}
-	due := time.Unix(dueDate, 0)
-	return s.repository.Update(ctx, id, task.DTO{Name: name, Description: desc, DueDate: due, Status: st})
+	rule, err := task.ParseRecurrence(recurrence)
+	if err != nil {
+		return empty, err
+	}
+	if tags, err = task.NormalizeTags(tags); err != nil {
+		return empty, err
+	}
+	current, err := s.GetOne(ctx, id)
+	if err != nil {
+		return empty, err
+	}
+	if err = s.authorize(ctx, id, task.WriteAccess); err != nil {
+		return empty, err
+	}
+	if err = checkVersion(current, version); err != nil {
+		return empty, err
+	}
+	due := dueDateFromUnix(dueDate)
+	if err = s.checkTransition(current, st, due); err != nil {
+		return empty, err
+	}
+	if err = s.checkBlockers(ctx, current, st); err != nil {
+		return empty, err
+	}
+	dto := task.DTO{Name: name, Description: desc, DueDate: due, Status: st, Version: version, Recurrence: rule, Tags: tags}
+	completes := completesRecurring(current.Status, st, rule)
+	if completes {
+		dto.Recurrence = task.Recurrence{}
+	}
+	return s.saveChange(ctx, current, completes, rule, func(r task.Repository) (task.Task, error) {
+		return r.Update(ctx, id, dto)
+	})
+}
+
+// TaskPatch lists task fields to change, nil fields are left as is
+type TaskPatch struct {
+	Name        *string
+	Description *string
+	// DueDate is due date, zero time removes it
+	DueDate *time.Time
+	Status  *string
+	// Recurrence is recurrence rule, empty rule makes task not repeat
+	Recurrence *string
+	// Tags replace all tags of task
+	Tags *[]string
+	// Version is expected current version of task, zero skips the check
+	Version uint64
+}
+
+// PatchTask validates data, updates set fields of task if data is valid, task is found and caller may change it,
+// returns error otherwise. Status may only be changed along allowed transitions.
+// Completed recurring task stops repeating and its next occurrence is created.
+func (s *Service) PatchTask(ctx context.Context, id uint64, data TaskPatch) (task.Task, error) {
+	var empty task.Task
+	patch := task.Patch{Version: data.Version}
+	if data.Name != nil {
+		if *data.Name == "" {
+			return empty, &common.InvalidInputError{Reason: "name is required"}
+		}
+		patch.Name = data.Name
+	}
+	patch.Description = data.Description
+	if data.DueDate != nil {
+		if err := checkDueDate(*data.DueDate); err != nil {
+			return empty, err
+		}
+		patch.DueDate = data.DueDate
+	}
+	if data.Status != nil {
+		st, err := task.StatusFromText(*data.Status)
+		if err != nil {
+			return empty, err
+		}
+		patch.Status = &st
+	}
+	if data.Recurrence != nil {
+		rule, err := task.ParseRecurrence(*data.Recurrence)
+		if err != nil {
+			return empty, err
+		}
+		patch.Recurrence = &rule
+	}
+	if data.Tags != nil {
+		tags, err := task.NormalizeTags(*data.Tags)
+		if err != nil {
+			return empty, err
+		}
+		patch.Tags = &tags
+	}
+	current, err := s.GetOne(ctx, id)
+	if err != nil {
+		return empty, err
+	}
+	if err = s.authorize(ctx, id, task.WriteAccess); err != nil {
+		return empty, err
+	}
+	if err = checkVersion(current, patch.Version); err != nil {
+		return empty, err
+	}
+	patched := patch.Apply(current)
+	if patch.Status != nil {
+		if err = s.checkTransition(current, *patch.Status, patched.DueDate); err != nil {
+			return empty, err
+		}
+		if err = s.checkBlockers(ctx, current, *patch.Status); err != nil {
+			return empty, err
+		}
+	}
+	rule := patched.Recurrence
+	completes := patch.Status != nil && completesRecurring(current.Status, *patch.Status, rule)
+	if completes {
+		patch.Recurrence = &task.Recurrence{}
+	}
+	return s.saveChange(ctx, current, completes, rule, func(r task.Repository) (task.Task, error) {
+		return r.Patch(ctx, id, patch)
+	})
+}
+
+// saveChange saves change of task made by change together with its history and, if change completes
+// recurring task, the next occurrence of the task with given rule. All of them are saved in one transaction.
+func (s *Service) saveChange(ctx context.Context, current task.Task, completes bool, rule task.Recurrence, change func(r task.Repository) (task.Task, error)) (task.Task, error) {
+	var updated task.Task
+	err := s.inTransaction(ctx, func(tx *Service) error {
+		var err error
+		if updated, err = change(tx.repository); err != nil {
+			return err
+		}
+		if err = tx.recordChanges(ctx, current, updated); err != nil || !completes {
+			return err
+		}
+		return tx.createNextOccurrence(ctx, updated, rule)
+	})
+	if err != nil {
+		var empty task.Task
+		return empty, err
+	}
+	return updated, nil
 }
 
-// Delete deletes stored task by id
+// Delete moves task to trash of its owner, only owner may do it.
+// Task is purged after trash retention unless it is restored.
 func (s *Service) Delete(ctx context.Context, id uint64) error {
-	return s.repository.Delete(ctx, id)
+	if err := s.authorize(ctx, id, task.OwnerAccess); err != nil {
+		return err
+	}
+	if err := s.repository.Delete(ctx, id, s.options.Now()); err != nil {
+		return err
+	}
+	_, err := s.record(ctx, task.Event{TaskID: id, Type: task.Deleted})
+	return err
+}
+
+// GetShares reads users task is shared with, only owner may do it
+func (s *Service) GetShares(ctx context.Context, id uint64) ([]task.Share, error) {
+	if err := s.authorize(ctx, id, task.OwnerAccess); err != nil {
+		return nil, err
+	}
+	return s.repository.ReadShares(ctx, id)
+}
+
+// ShareTask grants user "read" or "write" permission to task, only owner may do it
+func (s *Service) ShareTask(ctx context.Context, id uint64, userID, permission string) (task.Share, error) {
+	var empty task.Share
+	if userID == "" {
+		return empty, &common.InvalidInputError{Reason: "user is required"}
+	}
+	p, err := task.SharePermissionFromText(permission)
+	if err != nil {
+		return empty, err
+	}
+	if err = s.authorize(ctx, id, task.OwnerAccess); err != nil {
+		return empty, err
+	}
+	if userID == caller(ctx) {
+		return empty, &common.InvalidInputError{Reason: "task can not be shared with its owner"}
+	}
+	previous, err := s.sharePermission(ctx, id, userID)
+	if err != nil {
+		return empty, err
+	}
+	share := task.Share{TaskID: id, UserID: userID, Permission: p}
+	if err = s.repository.Share(ctx, share); err != nil {
+		return empty, err
+	}
+	return share, s.recordShare(ctx, id, userID, previous, p)
+}
+
+// UnshareTask revokes permission granted to user, only owner may do it
+func (s *Service) UnshareTask(ctx context.Context, id uint64, userID string) error {
+	if err := s.authorize(ctx, id, task.OwnerAccess); err != nil {
+		return err
+	}
+	previous, err := s.sharePermission(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if err = s.repository.Unshare(ctx, id, userID); err != nil {
+		return err
+	}
+	return s.recordShare(ctx, id, userID, previous, task.NoAccess)
+}
+
+// sharePermission returns permission granted to user by sharing task, NoAccess if task is not shared with user
+func (s *Service) sharePermission(ctx context.Context, id uint64, userID string) (task.Permission, error) {
+	shares, err := s.repository.ReadShares(ctx, id)
+	if err != nil {
+		return task.NoAccess, err
+	}
+	for _, share := range shares {
+		if share.UserID == userID {
+			return share.Permission, nil
+		}
+	}
+	return task.NoAccess, nil
+}
+
+// recordShare records change of permission granted to user, values of change are formatted as `user:permission`
+func (s *Service) recordShare(ctx context.Context, id uint64, userID string, from, to task.Permission) error {
+	if from == to {
+		return nil
+	}
+	format := func(p task.Permission) string {
+		if p == task.NoAccess {
+			return ""
+		}
+		return userID + ":" + p.String()
+	}
+	_, err := s.record(ctx, task.Event{TaskID: id, Type: task.Changed, Field: "shares", From: format(from), To: format(to)})
+	return err
+}
+
+// authorize checks that caller has at least `required` permission to task.
+// Tasks caller has no access to are reported as not found to not disclose their existence.
+func (s *Service) authorize(ctx context.Context, id uint64, required task.Permission) error {
+	p, err := s.repository.Access(ctx, id, caller(ctx))
+	if err != nil {
+		return err
+	}
+	if p == task.NoAccess {
+		return &common.NotFoundError{What: "Task with ID " + strconv.FormatUint(id, 10)}
+	}
+	if p < required {
+		return &common.ForbiddenError{Reason: fmt.Sprintf("%s permission to task %d is required", required, id)}
+	}
+	return nil
+}
+
+// checkVersion checks that task is still at expected version, zero expected version skips the check
+func checkVersion(t task.Task, expected uint64) error {
+	if expected != 0 && expected != t.Version {
+		return &common.VersionMismatchError{What: fmt.Sprintf("Task with ID %d", t.ID), Expected: expected, Actual: t.Version}
+	}
+	return nil
+}
+
+// dueDateFromUnix converts Unix time to due date, zero means no due date
+func dueDateFromUnix(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// checkDueDate rejects due dates before Unix epoch, zero time means no due date and is valid
+func checkDueDate(due time.Time) error {
+	if !due.IsZero() && due.Unix() < 0 {
+		return &common.InvalidInputError{Reason: "\"dueDate\" must be non-negative integer"}
+	}
+	return nil
+}
+
+// caller returns ID of user making request, empty if request is not authenticated
+func caller(ctx context.Context) string {
+	p, _ := auth.FromContext(ctx)
+	return p.Subject
 }
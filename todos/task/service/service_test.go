@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
 	"github.com/Vesninovich/go-tasks/todos/common"
 	"github.com/Vesninovich/go-tasks/todos/task"
 	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
@@ -12,7 +13,7 @@ import (
 
 func TestCreateValid(t *testing.T) {
 	s := createService()
-	created, err := s.CreateTask(context.Background(), "test", "", time.Now().Unix())
+	created, err := s.CreateTask(context.Background(), "test", "", time.Now().Unix(), "", nil)
 	if err != nil {
 		t.Errorf("Got error while creating valid task: %s", err)
 	}
@@ -23,7 +24,7 @@ func TestCreateValid(t *testing.T) {
 
 func TestCreateWithEmptyName(t *testing.T) {
 	s := createService()
-	_, err := s.CreateTask(context.Background(), "", "", time.Now().Unix())
+	_, err := s.CreateTask(context.Background(), "", "", time.Now().Unix(), "", nil)
 	if err == nil {
 		t.Errorf("Expected to get error while creating task with empty name")
 	}
@@ -34,7 +35,7 @@ func TestCreateWithEmptyName(t *testing.T) {
 
 func TestCreateWithNegativeDueDate(t *testing.T) {
 	s := createService()
-	_, err := s.CreateTask(context.Background(), "test", "", -1234)
+	_, err := s.CreateTask(context.Background(), "test", "", -1234, "", nil)
 	if err == nil {
 		t.Errorf("Expected to get error while creating task with negative due date")
 	}
@@ -43,6 +44,116 @@ func TestCreateWithNegativeDueDate(t *testing.T) {
 	}
 }
 
+func TestPatchTask(t *testing.T) {
+	ctx := context.Background()
+	s := createService()
+	due := time.Now().Add(time.Hour).Unix()
+	created, _ := s.CreateTask(ctx, "test", "desc", due, "", nil)
+
+	name := "renamed"
+	patched, err := s.PatchTask(ctx, created.ID, TaskPatch{Name: &name})
+	if err != nil {
+		t.Fatalf("Got error while patching task: %s", err)
+	}
+	if patched.Name != name || patched.Description != "desc" || patched.DueDate.Unix() != due || patched.Status != task.New {
+		t.Errorf("Patch changed fields that are not set: %+v", patched)
+	}
+
+	var noDue time.Time
+	patched, err = s.PatchTask(ctx, created.ID, TaskPatch{DueDate: &noDue})
+	if err != nil {
+		t.Fatalf("Got error while removing due date: %s", err)
+	}
+	if patched.HasDueDate() {
+		t.Errorf("Expected due date to be removed, got %s", patched.DueDate)
+	}
+
+	empty, negative, overdue := "", time.Unix(-1, 0), "overdue"
+	invalid := []TaskPatch{{Name: &empty}, {DueDate: &negative}}
+	for _, patch := range invalid {
+		if _, err = s.PatchTask(ctx, created.ID, patch); err == nil {
+			t.Errorf("Expected error while applying invalid patch %+v", patch)
+		}
+	}
+	if _, err = s.PatchTask(ctx, created.ID, TaskPatch{Status: &overdue}); err == nil {
+		t.Error("Expected transition error while setting overdue manually")
+	}
+
+	epoch := time.Unix(0, 0)
+	patched, err = s.PatchTask(ctx, created.ID, TaskPatch{DueDate: &epoch})
+	if err != nil {
+		t.Fatalf("Got error while moving due date to Unix epoch: %s", err)
+	}
+	if !patched.HasDueDate() || patched.DueDate.Unix() != 0 {
+		t.Errorf("Expected due date to be Unix epoch, got %s", patched.DueDate)
+	}
+}
+
 func createService() *Service {
-	return New(inmemory.New())
+	return New(inmemory.New(), DefaultOptions)
+}
+
+func TestOwnership(t *testing.T) {
+	s := createService()
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	bob := auth.NewContext(context.Background(), auth.Principal{Subject: "bob"})
+	created, err := s.CreateTask(alice, "test", "", time.Now().Unix(), "", nil)
+	if err != nil {
+		t.Fatalf("Got error while creating task: %s", err)
+	}
+	if created.OwnerID != "alice" {
+		t.Errorf("Expected task to be owned by caller, got %q", created.OwnerID)
+	}
+
+	if _, err = s.GetOne(bob, created.ID); !isNotFound(err) {
+		t.Errorf("Expected task of other user to be not found, got %v", err)
+	}
+	if err = s.Delete(bob, created.ID); !isNotFound(err) {
+		t.Errorf("Expected not to be able to delete task of other user, got %v", err)
+	}
+	if tasks, _ := s.Get(bob, task.Query{}, 0, 0); len(tasks) != 0 {
+		t.Errorf("Expected other user to see no tasks, got %d", len(tasks))
+	}
+
+	if _, err = s.ShareTask(alice, created.ID, "bob", "read"); err != nil {
+		t.Fatalf("Got error while sharing task: %s", err)
+	}
+	if _, err = s.GetOne(bob, created.ID); err != nil {
+		t.Errorf("Expected to read shared task, got %s", err)
+	}
+	_, err = s.UpdateTask(bob, created.ID, 0, "changed", "", 0, "new", "", nil)
+	if _, ok := err.(*common.ForbiddenError); !ok {
+		t.Errorf("Expected to be forbidden to change task shared for reading, got %v", err)
+	}
+
+	if _, err = s.ShareTask(alice, created.ID, "bob", "write"); err != nil {
+		t.Fatalf("Got error while sharing task: %s", err)
+	}
+	if _, err = s.UpdateTask(bob, created.ID, 0, "changed", "", 0, "new", "", nil); err != nil {
+		t.Errorf("Expected to change task shared for writing, got %s", err)
+	}
+	if _, err = s.ShareTask(bob, created.ID, "carol", "read"); err == nil {
+		t.Error("Expected only owner to be able to share task")
+	}
+	if err = s.Delete(bob, created.ID); err == nil {
+		t.Error("Expected only owner to be able to delete task")
+	}
+	if _, err = s.ShareTask(alice, created.ID, "carol", "owner"); err == nil {
+		t.Error("Expected error sharing task with owner permission")
+	}
+
+	if err = s.UnshareTask(alice, created.ID, "bob"); err != nil {
+		t.Fatalf("Got error while unsharing task: %s", err)
+	}
+	if _, err = s.GetOne(bob, created.ID); !isNotFound(err) {
+		t.Errorf("Expected unshared task to be not found, got %v", err)
+	}
+	if err = s.Delete(alice, created.ID); err != nil {
+		t.Errorf("Got error deleting own task: %s", err)
+	}
+}
+
+func isNotFound(err error) bool {
+	_, ok := err.(*common.NotFoundError)
+	return ok
 }
@@ -0,0 +1,73 @@
+package taskservice
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+// GetTags reads tags of tasks owned by or shared with caller with number of such tasks having each tag
+func (s *Service) GetTags(ctx context.Context) ([]task.TagCount, error) {
+	return s.repository.ReadTags(ctx, caller(ctx))
+}
+
+// RenameTag renames tag on tasks owned by caller, returns number of changed tasks.
+// Returns *task.TagExistsError if caller already sees tag with new name, such tags may only be merged.
+func (s *Service) RenameTag(ctx context.Context, from, to string) (uint64, error) {
+	from, err := task.NormalizeTag(from)
+	if err != nil {
+		return 0, err
+	}
+	to, err = task.NormalizeTag(to)
+	if err != nil {
+		return 0, err
+	}
+	if from == to {
+		return 0, &common.InvalidInputError{Reason: "new tag name must differ from the current one"}
+	}
+	tags, err := s.repository.ReadTags(ctx, caller(ctx))
+	if err != nil {
+		return 0, err
+	}
+	for _, tc := range tags {
+		if tc.Tag == to {
+			return 0, &task.TagExistsError{Tag: to}
+		}
+	}
+	return s.mergeTags(ctx, []string{from}, to)
+}
+
+// MergeTags replaces given tags with `into` tag on tasks owned by caller, returns number of changed tasks
+func (s *Service) MergeTags(ctx context.Context, tags []string, into string) (uint64, error) {
+	if len(tags) == 0 {
+		return 0, &common.InvalidInputError{Reason: "tags to merge are required"}
+	}
+	tags, err := task.NormalizeTags(tags)
+	if err != nil {
+		return 0, err
+	}
+	into, err = task.NormalizeTag(into)
+	if err != nil {
+		return 0, err
+	}
+	return s.mergeTags(ctx, tags, into)
+}
+
+// mergeTags merges tags and records the change in history of changed tasks, `from` of the change lists merged tags
+func (s *Service) mergeTags(ctx context.Context, tags []string, into string) (uint64, error) {
+	changed, err := s.repository.MergeTags(ctx, caller(ctx), tags, into)
+	if err != nil {
+		return 0, err
+	}
+	if len(changed) == 0 {
+		return 0, &common.NotFoundError{What: "Tasks with tags " + strings.Join(tags, ", ")}
+	}
+	events := make([]task.Event, len(changed))
+	for i, id := range changed {
+		events[i] = task.Event{TaskID: id, Type: task.Changed, Field: "tags", From: strings.Join(tags, ","), To: into}
+	}
+	_, err = s.record(ctx, events...)
+	return uint64(len(changed)), err
+}
@@ -0,0 +1,55 @@
+package taskservice
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+	s := createService()
+	created, err := s.CreateTask(ctx, "a", "", 0, "", []string{" Home", "home", "Urgent"})
+	if err != nil {
+		t.Fatalf("Got error creating task: %s", err)
+	}
+	if len(created.Tags) != 2 || created.Tags[0] != "home" || created.Tags[1] != "urgent" {
+		t.Errorf("Expected tags to be normalized, got %v", created.Tags)
+	}
+	s.CreateTask(ctx, "b", "", 0, "", []string{"work"})
+	if _, err = s.CreateTask(ctx, "c", "", 0, "", []string{"a,b"}); err == nil {
+		t.Error("Expected error creating task with comma in tag")
+	}
+
+	found, _ := s.Get(ctx, task.Query{Tags: []string{"URGENT"}}, 0, 0)
+	if len(found) != 1 || found[0].ID != created.ID {
+		t.Errorf("Expected tasks to be filtered by normalized tag, got %v", found)
+	}
+
+	if _, err = s.RenameTag(ctx, "home", "work"); err == nil {
+		t.Error("Expected error renaming tag to existing one")
+	} else if _, ok := err.(*task.TagExistsError); !ok {
+		t.Errorf("Expected tag exists error, got %s", err)
+	}
+	if _, err = s.RenameTag(ctx, "missing", "other"); !isNotFound(err) {
+		t.Errorf("Expected not found renaming missing tag, got %v", err)
+	}
+	if changed, err := s.RenameTag(ctx, "home", "house"); err != nil || changed != 1 {
+		t.Errorf("Expected 1 changed task, got %d, %v", changed, err)
+	}
+	if changed, err := s.MergeTags(ctx, []string{"house", "urgent"}, "Work"); err != nil || changed != 1 {
+		t.Errorf("Expected 1 changed task, got %d, %v", changed, err)
+	}
+	if _, err = s.MergeTags(ctx, nil, "work"); err == nil {
+		t.Error("Expected error merging no tags")
+	} else if _, ok := err.(*common.InvalidInputError); !ok {
+		t.Errorf("Expected invalid input error, got %s", err)
+	}
+
+	tags, _ := s.GetTags(ctx)
+	if len(tags) != 1 || tags[0] != (task.TagCount{Tag: "work", Count: 2}) {
+		t.Errorf("Expected tags to be merged, got %v", tags)
+	}
+}
@@ -0,0 +1,94 @@
+package taskservice
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+// transitions lists statuses task may be moved to by users from each status.
+// Overdue is never set by users, only by overdue sweeps.
+var transitions = map[task.Status][]task.Status{
+	task.New:        {task.InProgress, task.Done, task.Cancelled},
+	task.InProgress: {task.New, task.Done, task.Cancelled},
+	task.Overdue:    {task.New, task.InProgress, task.Done, task.Cancelled},
+	task.Done:       {task.New},
+	task.Cancelled:  {task.New},
+}
+
+// actions map status change actions to target statuses
+var actions = map[string]task.Status{
+	"start":    task.InProgress,
+	"complete": task.Done,
+	"cancel":   task.Cancelled,
+	"reopen":   task.New,
+}
+
+// ChangeStatus applies status change action ("start", "complete", "cancel" or "reopen") to task.
+// Returns *task.TransitionError if task is in status the action can not be applied to
+// and *task.BlockedError if task is completed before its blockers.
+// Non-zero version must match current version of task.
+// Completed recurring task stops repeating and its next occurrence is created.
+func (s *Service) ChangeStatus(ctx context.Context, id, version uint64, action string) (task.Task, error) {
+	var empty task.Task
+	to, ok := actions[action]
+	if !ok {
+		return empty, &common.InvalidInputError{Reason: fmt.Sprintf(`action "%s" does not exist`, action)}
+	}
+	t, err := s.GetOne(ctx, id)
+	if err != nil {
+		return empty, err
+	}
+	if err = s.authorize(ctx, id, task.WriteAccess); err != nil {
+		return empty, err
+	}
+	if err = checkVersion(t, version); err != nil {
+		return empty, err
+	}
+	if t.Status == to {
+		return empty, transitionError(t, to)
+	}
+	if err = s.checkTransition(t, to, t.DueDate); err != nil {
+		return empty, err
+	}
+	if err = s.checkBlockers(ctx, t, to); err != nil {
+		return empty, err
+	}
+	patch := task.Patch{Status: &to, Version: version}
+	completes := completesRecurring(t.Status, to, t.Recurrence)
+	if completes {
+		patch.Recurrence = &task.Recurrence{}
+	}
+	return s.saveChange(ctx, t, completes, t.Recurrence, func(r task.Repository) (task.Task, error) {
+		return r.Patch(ctx, id, patch)
+	})
+}
+
+// checkTransition checks that task may be moved to given status, keeping the status is always allowed.
+// Overdue task may only be reopened or started if its due date, as it is after the change, has not passed,
+// otherwise it would become overdue again right away.
+func (s *Service) checkTransition(t task.Task, to task.Status, due time.Time) error {
+	if t.Status == to {
+		return nil
+	}
+	for _, allowed := range transitions[t.Status] {
+		if allowed != to {
+			continue
+		}
+		if t.Status == task.Overdue && (task.Task{Status: to, DueDate: due}).IsOverdueAt(s.overdueBefore()) {
+			return &task.TransitionError{
+				ID: t.ID, From: t.Status, To: to, Allowed: []task.Status{task.Done, task.Cancelled},
+				Reason: "due date has passed, it must be moved to the future",
+			}
+		}
+		return nil
+	}
+	return transitionError(t, to)
+}
+
+func transitionError(t task.Task, to task.Status) *task.TransitionError {
+	return &task.TransitionError{ID: t.ID, From: t.Status, To: to, Allowed: transitions[t.Status]}
+}
@@ -0,0 +1,118 @@
+package taskservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
+)
+
+func TestChangeStatus(t *testing.T) {
+	ctx := context.Background()
+	s := createService()
+	created, _ := s.CreateTask(ctx, "test", "", time.Now().Add(time.Hour).Unix(), "", nil)
+
+	steps := []struct {
+		action string
+		status task.Status
+		ok     bool
+	}{
+		{"reopen", task.New, false},
+		{"start", task.InProgress, true},
+		{"start", task.InProgress, false},
+		{"complete", task.Done, true},
+		{"cancel", task.Done, false},
+		{"start", task.Done, false},
+		{"reopen", task.New, true},
+		{"cancel", task.Cancelled, true},
+		{"complete", task.Cancelled, false},
+		{"reopen", task.New, true},
+	}
+	for _, step := range steps {
+		updated, err := s.ChangeStatus(ctx, created.ID, 0, step.action)
+		if step.ok && err != nil {
+			t.Errorf("Got error on %s: %s", step.action, err)
+		}
+		if !step.ok {
+			if _, ok := err.(*task.TransitionError); !ok {
+				t.Errorf("Expected transition error on %s, got %v", step.action, err)
+			}
+			continue
+		}
+		if updated.Status != step.status {
+			t.Errorf("Expected status %s after %s, got %s", step.status, step.action, updated.Status)
+		}
+	}
+
+	if _, err := s.ChangeStatus(ctx, created.ID, 0, "explode"); err == nil {
+		t.Error("Expected error on unknown action")
+	}
+}
+
+func TestUpdateStatusTransitions(t *testing.T) {
+	ctx := context.Background()
+	s := createService()
+	due := time.Now().Add(time.Hour).Unix()
+	created, _ := s.CreateTask(ctx, "test", "", due, "", nil)
+
+	_, err := s.UpdateTask(ctx, created.ID, 0, "test", "", due, "overdue", "", nil)
+	terr, ok := err.(*task.TransitionError)
+	if !ok {
+		t.Fatalf("Expected transition error on setting overdue manually, got %v", err)
+	}
+	if len(terr.Allowed) != 3 || terr.From != task.New || terr.To != task.Overdue {
+		t.Errorf("Wrong transition error: %+v", terr)
+	}
+	if terr.Error() != "Status of task with ID 1 can not be changed from new to overdue, allowed transitions: in-progress, done, cancelled" {
+		t.Errorf("Wrong error message: %s", terr)
+	}
+
+	if _, err = s.UpdateTask(ctx, created.ID, 0, "renamed", "", due, "new", "", nil); err != nil {
+		t.Errorf("Expected keeping status to be allowed, got %s", err)
+	}
+	if _, err = s.UpdateTask(ctx, created.ID, 0, "renamed", "", due, "done", "", nil); err != nil {
+		t.Errorf("Expected completing task to be allowed, got %s", err)
+	}
+	if _, err = s.UpdateTask(ctx, created.ID, 0, "renamed", "", due, "in-progress", "", nil); err == nil {
+		t.Error("Expected starting completed task to be rejected")
+	}
+}
+
+func TestReopenOverdue(t *testing.T) {
+	ctx := context.Background()
+	clock := &fakeClock{time.Unix(1000000, 0)}
+	r := inmemory.New()
+	s := New(r, Options{Now: clock.Now})
+	due := clock.now.Add(time.Minute)
+	created, _ := s.CreateTask(ctx, "test", "", due.Unix(), "", nil)
+	clock.now = due.Add(time.Hour)
+	s.SweepOverdue(ctx)
+
+	_, err := s.ChangeStatus(ctx, created.ID, 0, "reopen")
+	terr, ok := err.(*task.TransitionError)
+	if !ok {
+		t.Fatalf("Expected transition error on reopening task past due date, got %v", err)
+	}
+	if terr.Error() != "Status of task with ID 1 can not be changed from overdue to new (due date has passed, it must be moved to the future), allowed transitions: done, cancelled" {
+		t.Errorf("Wrong error message: %s", terr)
+	}
+	if _, err = s.UpdateTask(ctx, created.ID, 0, "test", "", due.Unix(), "in-progress", "", nil); err == nil {
+		t.Error("Expected starting task past due date to be rejected")
+	}
+	checkStatus(t, r, created.ID, task.Overdue)
+
+	later := clock.now.Add(time.Hour)
+	status := "new"
+	updated, err := s.PatchTask(ctx, created.ID, TaskPatch{DueDate: &later, Status: &status})
+	if err != nil {
+		t.Fatalf("Expected reopening task with due date moved to the future to be allowed, got %s", err)
+	}
+	if updated.Status != task.New {
+		t.Errorf("Expected task to be reopened, got %s", updated.Status)
+	}
+	if read, _ := s.GetOne(ctx, created.ID); read.Status != task.New {
+		t.Errorf("Expected reopened task not to become overdue again, got %s", read.Status)
+	}
+}
@@ -0,0 +1,49 @@
+package taskservice
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/todos/task"
+)
+
+// GetTrash reads `count` tasks of caller in trash starting from `from`, the latest deleted go first
+func (s *Service) GetTrash(ctx context.Context, from, count uint) ([]task.Task, error) {
+	return s.repository.ReadTrash(ctx, caller(ctx), from, count)
+}
+
+// Restore moves task from trash of caller back, task becomes top-level one if its parent is in trash
+func (s *Service) Restore(ctx context.Context, id uint64) (task.Task, error) {
+	t, err := s.repository.Restore(ctx, id, caller(ctx))
+	if err != nil {
+		return t, err
+	}
+	_, err = s.record(ctx, task.Event{TaskID: id, Type: task.Restored})
+	return t, err
+}
+
+// PurgeTrash deletes tasks which are in trash longer than trash retention, returns number of deleted tasks
+func (s *Service) PurgeTrash(ctx context.Context) (uint64, error) {
+	ids, err := s.repository.Purge(ctx, s.options.Now().Add(-s.options.TrashRetention))
+	return uint64(len(ids)), err
+}
+
+// RunTrashPurges calls PurgeTrash every `interval` until context is done
+func (s *Service) RunTrashPurges(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.PurgeTrash(ctx)
+			if err != nil {
+				log.Printf("Failed to purge trash: %s", err)
+			} else if n > 0 {
+				log.Printf("Purged %d deleted tasks", n)
+			}
+		}
+	}
+}
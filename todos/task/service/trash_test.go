@@ -0,0 +1,79 @@
+package taskservice
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
+)
+
+func TestTrash(t *testing.T) {
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	bob := auth.NewContext(context.Background(), auth.Principal{Subject: "bob"})
+	clock := &fakeClock{time.Unix(1000000, 0)}
+	s := New(inmemory.New(), Options{Now: clock.Now, TrashRetention: time.Hour})
+
+	parent, _ := s.CreateTask(alice, "parent", "", 0, "", nil)
+	child, _ := s.CreateTask(alice, "child", "", 0, "", nil)
+	s.AttachSubtask(alice, parent.ID, child.ID)
+	s.ShareTask(alice, child.ID, "bob", "write")
+
+	if err := s.Delete(bob, child.ID); err == nil {
+		t.Error("Expected error deleting task by user it is shared with")
+	}
+	if err := s.Delete(alice, child.ID); err != nil {
+		t.Fatalf("Got error deleting task: %s", err)
+	}
+	if _, err := s.GetOne(alice, child.ID); !isNotFound(err) {
+		t.Errorf("Expected deleted task not to be found, got %v", err)
+	}
+	if read, _ := s.GetOne(alice, parent.ID); read.Progress.Total != 0 {
+		t.Errorf("Expected deleted subtask not to be counted, got %+v", read.Progress)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	s.Delete(alice, parent.ID)
+	trash, _ := s.GetTrash(alice, 0, 0)
+	if len(trash) != 2 || trash[0].ID != parent.ID || trash[1].ID != child.ID || !trash[0].IsDeleted() {
+		t.Errorf("Expected deleted tasks in trash, the latest first, got %v", trash)
+	}
+	if trash, _ := s.GetTrash(bob, 0, 0); len(trash) != 0 {
+		t.Errorf("Expected trash of other user to be empty, got %v", trash)
+	}
+
+	if _, err := s.Restore(bob, child.ID); !isNotFound(err) {
+		t.Errorf("Expected not found restoring task of other user, got %v", err)
+	}
+	restored, err := s.Restore(alice, child.ID)
+	if err != nil {
+		t.Fatalf("Got error restoring task: %s", err)
+	}
+	if restored.IsDeleted() || restored.ParentID != 0 {
+		t.Errorf("Expected task restored as top-level one as its parent is in trash, got %+v", restored)
+	}
+	if shared, err := s.GetOne(bob, child.ID); err != nil || shared.ID != child.ID {
+		t.Errorf("Expected shares of restored task to be kept, got %v", err)
+	}
+	history, _ := s.GetHistory(alice, child.ID, 0, 0)
+	if last := history[len(history)-1]; len(history) < 2 || history[len(history)-2].Type != task.Deleted || last.Type != task.Restored {
+		t.Errorf("Expected deletion and restoration to be recorded, got %v", history)
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	if n, _ := s.PurgeTrash(alice); n != 0 {
+		t.Errorf("Expected no tasks to be purged before retention passes, got %d", n)
+	}
+	clock.now = clock.now.Add(time.Second)
+	if n, err := s.PurgeTrash(alice); err != nil || n != 1 {
+		t.Errorf("Expected 1 purged task, got %d, %v", n, err)
+	}
+	if _, err := s.Restore(alice, parent.ID); !isNotFound(err) {
+		t.Errorf("Expected purged task not to be restored, got %v", err)
+	}
+	if _, err := s.GetOne(alice, child.ID); err != nil {
+		t.Errorf("Expected restored task to stay, got %v", err)
+	}
+}
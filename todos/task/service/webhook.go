@@ -0,0 +1,47 @@
+package taskservice
+
+import (
+	"context"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+	"github.com/Vesninovich/go-tasks/todos/task/live"
+)
+
+// webhookEventPrefix is prepended to type of live event to get type of webhook event, e.g. "task.completed"
+const webhookEventPrefix = "task."
+
+// WebhookEvents are events webhook subscriptions may be made to
+var WebhookEvents = webhookEvents()
+
+func webhookEvents() []string {
+	events := make([]string, len(live.Types))
+	for i, t := range live.Types {
+		events[i] = webhookEventPrefix + string(t)
+	}
+	return events
+}
+
+// webhookPublisher delivers live events to webhook subscriptions of users who may read changed tasks
+type webhookPublisher struct {
+	dispatcher *webhook.Dispatcher
+}
+
+type webhookData struct {
+	TaskID uint64   `json:"taskID"`
+	Actor  string   `json:"actor,omitempty"`
+	Fields []string `json:"fields,omitempty"`
+}
+
+func (p webhookPublisher) Publish(events ...live.Event) {
+	hooks := make([]webhook.Event, len(events))
+	for i, e := range events {
+		hooks[i] = webhook.Event{
+			Type:       webhookEventPrefix + string(e.Type),
+			Data:       webhookData{e.TaskID, e.Actor, e.Fields},
+			Recipients: e.Readers,
+			CreatedAt:  e.CreatedAt,
+		}
+	}
+	// changes are already saved, so deliveries are created even if request is cancelled
+	p.dispatcher.Publish(context.Background(), hooks...)
+}
@@ -0,0 +1,47 @@
+package taskservice
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+	webhookinmemory "github.com/Vesninovich/go-tasks/book-store/common/webhook/inmemory"
+	"github.com/Vesninovich/go-tasks/todos/task"
+	"github.com/Vesninovich/go-tasks/todos/task/inmemory"
+)
+
+func TestWebhooks(t *testing.T) {
+	// subscriptions are made to local URL, nothing is delivered in test
+	loopback, _ := webhook.ParseNetworks("127.0.0.0/8,::1/128")
+	hooks := webhook.New(webhookinmemory.New(), webhook.Options{Events: WebhookEvents, AllowedNetworks: loopback})
+	s := New(inmemory.New(), Options{Webhooks: hooks})
+	alice := auth.NewContext(context.Background(), auth.Principal{Subject: "alice"})
+	bob := auth.NewContext(context.Background(), auth.Principal{Subject: "bob"})
+	subscribe := func(owner string) webhook.Subscription {
+		sub, err := hooks.Subscribe(context.Background(), webhook.Subscription{
+			Owner: owner, URL: "http://localhost/hook", Events: []string{"task.completed"},
+		})
+		if err != nil {
+			t.Fatalf("Got error subscribing: %s", err)
+		}
+		return sub
+	}
+	aliceSub, bobSub := subscribe("alice"), subscribe("bob")
+
+	created, _ := s.CreateTask(alice, "test", "", 0, "", nil)
+	done := task.Done.String()
+	s.PatchTask(alice, created.ID, TaskPatch{Status: &done})
+
+	ds, _ := hooks.Deliveries(alice, "alice", aliceSub.ID, 0, 0)
+	if len(ds) != 1 || ds[0].Event != "task.completed" || ds[0].Status != webhook.Pending {
+		t.Fatalf("Expected delivery of completed task, got %+v", ds)
+	}
+	if payload := string(ds[0].Payload); !strings.HasPrefix(payload, `{"event":"task.completed","time":`) {
+		t.Errorf("Wrong payload %s", payload)
+	}
+	if ds, _ = hooks.Deliveries(bob, "bob", bobSub.ID, 0, 0); len(ds) != 0 {
+		t.Errorf("Expected no deliveries of task user may not read, got %+v", ds)
+	}
+}
@@ -0,0 +1,51 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+)
+
+// Permission of user to access the Task
+type Permission uint
+
+// Possible Permissions, each next one includes previous
+const (
+	NoAccess Permission = iota
+	ReadAccess
+	WriteAccess
+	OwnerAccess
+)
+
+// Share grants user other than owner access to the Task
+type Share struct {
+	TaskID     uint64
+	UserID     string
+	Permission Permission
+}
+
+func (p Permission) String() string {
+	switch p {
+	case ReadAccess:
+		return "read"
+	case WriteAccess:
+		return "write"
+	case OwnerAccess:
+		return "owner"
+	default:
+		return ""
+	}
+}
+
+// SharePermissionFromText returns according enum permission value.
+// Only permissions which can be granted by sharing are accepted.
+func SharePermissionFromText(s string) (Permission, error) {
+	switch s {
+	case "read":
+		return ReadAccess, nil
+	case "write":
+		return WriteAccess, nil
+	default:
+		return NoAccess, &common.InvalidInputError{Reason: fmt.Sprintf(`permission "%s" can not be granted`, s)}
+	}
+}
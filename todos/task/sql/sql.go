@@ -5,46 +5,50 @@ import (
 	"database/sql"
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Vesninovich/go-tasks/todos/common"
 	"github.com/Vesninovich/go-tasks/todos/task"
 )
 
+// columns of task, progress of subtasks is counted on read and tags are joined with commas they can not contain
+var columns = fmt.Sprintf(`id, name, description, dueDate, status, owner, version, parent_id, recurrence, deleted_at,
+	(SELECT COALESCE(string_agg(tag, ',' ORDER BY tag COLLATE "C"), '') FROM task_tags WHERE task_id=tasks.id),
+	(SELECT count(*) FROM tasks sub WHERE sub.parent_id=tasks.id AND sub.deleted_at IS NULL),
+	(SELECT count(*) FROM tasks sub WHERE sub.parent_id=tasks.id AND sub.deleted_at IS NULL AND sub.status=%d)`, task.Done)
+
 // SQLRepository provides access to relational DB storage of tasks
 type SQLRepository struct {
 	db *sql.DB
+	// conn runs statements, it is tx inside transaction and db otherwise
+	conn querier
+	tx   *sql.Tx
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 // New creates a new instance of SQLRepository
 func New(db *sql.DB) *SQLRepository {
-	return &SQLRepository{db}
+	return &SQLRepository{db: db, conn: db}
 }
 
-// Read reads `count` saved tasks starting from `from`
-func (r *SQLRepository) Read(ctx context.Context, from, count uint) ([]task.Task, error) {
-	stmt := makeReadStatement(from, count)
-	rows, err := r.db.QueryContext(ctx, stmt)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var t task.Task
-	tasks := make([]task.Task, 0)
-	for rows.Next() {
-		if err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.DueDate, &t.Status); err != nil {
-			return nil, err
-		}
-		tasks = append(tasks, t)
-	}
-	return tasks, nil
+// Read reads `count` tasks owned by or shared with user and matching query starting from `from`
+func (r *SQLRepository) Read(ctx context.Context, userID string, query task.Query, from, count uint) ([]task.Task, error) {
+	stmt, args := makeReadStatement(userID, query, from, count)
+	return r.readTasks(ctx, stmt, args...)
 }
 
 // ReadOne searches for task with given id, returns error if it is not found
 func (r *SQLRepository) ReadOne(ctx context.Context, id uint64) (task.Task, error) {
-	var t task.Task
-	err := r.db.QueryRowContext(
-		ctx, "SELECT * FROM tasks WHERE id=$1;", id,
-	).Scan(&t.ID, &t.Name, &t.Description, &t.DueDate, &t.Status)
+	t, err := scanTask(r.conn.QueryRowContext(
+		ctx, "SELECT "+columns+" FROM tasks WHERE id=$1 AND deleted_at IS NULL;", id,
+	))
 	if err == sql.ErrNoRows {
 		return t, notFoundError(id)
 	}
@@ -54,53 +58,712 @@ func (r *SQLRepository) ReadOne(ctx context.Context, id uint64) (task.Task, erro
 // Create adds new task to saved
 func (r *SQLRepository) Create(ctx context.Context, dto task.DTO) (task.Task, error) {
 	var t task.Task
-	err := r.db.QueryRowContext(
-		ctx,
-		`INSERT INTO tasks (name, description, dueDate, status)
-			VALUES ($1, $2, $3, $4)
-			RETURNING id, name, description, dueDate, status;`,
-		dto.Name, dto.Description, dto.DueDate, dto.Status,
-	).Scan(&t.ID, &t.Name, &t.Description, &t.DueDate, &t.Status)
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		t, err = scanTask(tx.QueryRowContext(
+			ctx,
+			`INSERT INTO tasks (name, description, dueDate, status, owner, recurrence)
+				VALUES ($1, $2, $3, $4, $5, $6)
+				RETURNING `+columns+`;`,
+			dto.Name, dto.Description, nullTime(dto.DueDate), dto.Status, dto.OwnerID, nullRecurrence(dto.Recurrence),
+		))
+		if err != nil {
+			return err
+		}
+		t.Tags = dto.Tags
+		return setTags(ctx, tx, t.ID, dto.Tags)
+	})
 	return t, err
 }
 
 // Update updates task with given id, returns error if it is not found
 func (r *SQLRepository) Update(ctx context.Context, id uint64, dto task.DTO) (task.Task, error) {
 	var t task.Task
-	err := r.db.QueryRowContext(
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		t, err = scanTask(tx.QueryRowContext(
+			ctx,
+			`UPDATE tasks
+				SET name=$2, description=$3, dueDate=$4, status=$5, recurrence=$7, version=version+1
+				WHERE id=$1 AND deleted_at IS NULL AND (version=$6 OR $6=0)
+				RETURNING `+columns+`;`,
+			id, dto.Name, dto.Description, nullTime(dto.DueDate), dto.Status, dto.Version, nullRecurrence(dto.Recurrence),
+		))
+		if err == sql.ErrNoRows {
+			return r.updateError(ctx, id, dto.Version)
+		}
+		if err != nil {
+			return err
+		}
+		t.Tags = dto.Tags
+		return setTags(ctx, tx, id, dto.Tags)
+	})
+	return t, err
+}
+
+// Patch updates only fields set in patch of task with given id, returns error if it is not found
+func (r *SQLRepository) Patch(ctx context.Context, id uint64, patch task.Patch) (task.Task, error) {
+	if patch.IsEmpty() {
+		t, err := r.ReadOne(ctx, id)
+		if err == nil && patch.Version != 0 && patch.Version != t.Version {
+			err = versionMismatchError(id, patch.Version, t.Version)
+		}
+		return t, err
+	}
+	args := []interface{}{id, patch.Version}
+	var sets []string
+	set := func(column string, v interface{}) {
+		args = append(args, v)
+		sets = append(sets, column+"=$"+strconv.Itoa(len(args)))
+	}
+	if patch.Name != nil {
+		set("name", *patch.Name)
+	}
+	if patch.Description != nil {
+		set("description", *patch.Description)
+	}
+	if patch.DueDate != nil {
+		set("dueDate", nullTime(*patch.DueDate))
+	}
+	if patch.Status != nil {
+		set("status", *patch.Status)
+	}
+	if patch.Recurrence != nil {
+		set("recurrence", nullRecurrence(*patch.Recurrence))
+	}
+	sets = append(sets, "version=version+1")
+	var t task.Task
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		t, err = scanTask(tx.QueryRowContext(
+			ctx,
+			"UPDATE tasks SET "+strings.Join(sets, ", ")+" WHERE id=$1 AND deleted_at IS NULL AND (version=$2 OR $2=0) RETURNING "+columns+";",
+			args...,
+		))
+		if err == sql.ErrNoRows {
+			return r.updateError(ctx, id, patch.Version)
+		}
+		if err != nil || patch.Tags == nil {
+			return err
+		}
+		t.Tags = *patch.Tags
+		return setTags(ctx, tx, id, *patch.Tags)
+	})
+	return t, err
+}
+
+// updateError tells why conditional update of task with given id changed nothing
+func (r *SQLRepository) updateError(ctx context.Context, id, expected uint64) error {
+	var actual uint64
+	err := r.conn.QueryRowContext(ctx, "SELECT version FROM tasks WHERE id=$1 AND deleted_at IS NULL;", id).Scan(&actual)
+	if err == sql.ErrNoRows {
+		return notFoundError(id)
+	}
+	if err != nil {
+		return err
+	}
+	return versionMismatchError(id, expected, actual)
+}
+
+// Delete moves task with given id to trash at given time, returns error if it is not found.
+// Subtasks of the task become top-level tasks.
+func (r *SQLRepository) Delete(ctx context.Context, id uint64, at time.Time) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "UPDATE tasks SET deleted_at=$2 WHERE id=$1 AND deleted_at IS NULL;", id, at)
+		if err != nil {
+			return err
+		}
+		count, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return notFoundError(id)
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE tasks SET parent_id=NULL WHERE parent_id=$1 AND deleted_at IS NULL;", id)
+		return err
+	})
+}
+
+// ReadTrash reads `count` tasks of given owner in trash starting from `from`, the latest deleted go first
+func (r *SQLRepository) ReadTrash(ctx context.Context, ownerID string, from, count uint) ([]task.Task, error) {
+	stmt := fmt.Sprintf(
+		"SELECT %s FROM tasks WHERE owner=$1 AND deleted_at IS NOT NULL ORDER BY deleted_at DESC, id DESC OFFSET %d",
+		columns, from,
+	)
+	if count > 0 {
+		stmt += fmt.Sprintf(" LIMIT %d", count)
+	}
+	return r.readTasks(ctx, stmt+";", ownerID)
+}
+
+// Restore moves task with given id from trash of given owner, returns error if there is no such task in trash.
+// Task becomes top-level one if its parent is in trash.
+func (r *SQLRepository) Restore(ctx context.Context, id uint64, ownerID string) (task.Task, error) {
+	t, err := scanTask(r.conn.QueryRowContext(
 		ctx,
-		`UPDATE tasks
-			SET name=$2, description=$3, dueDate=$4, status=$5
-			WHERE id=$1
-			RETURNING id, name, description, dueDate, status;`,
-		id, dto.Name, dto.Description, dto.DueDate, dto.Status,
-	).Scan(&t.ID, &t.Name, &t.Description, &t.DueDate, &t.Status)
+		`UPDATE tasks SET deleted_at=NULL, version=version+1,
+				parent_id=(SELECT p.id FROM tasks p WHERE p.id=tasks.parent_id AND p.deleted_at IS NULL)
+			WHERE id=$1 AND owner=$2 AND deleted_at IS NOT NULL
+			RETURNING `+columns+`;`,
+		id, ownerID,
+	))
+	if err == sql.ErrNoRows {
+		return t, &common.NotFoundError{What: "Deleted task with ID " + strconv.FormatUint(id, 10)}
+	}
+	return t, err
+}
+
+// Purge deletes tasks moved to trash before given time, returns their ids.
+// Shares, dependencies, tags and history of the tasks are deleted by cascade.
+func (r *SQLRepository) Purge(ctx context.Context, before time.Time) ([]uint64, error) {
+	rows, err := r.conn.QueryContext(ctx, "DELETE FROM tasks WHERE deleted_at < $1 RETURNING id;", before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []uint64
+	for rows.Next() {
+		var id uint64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// MarkOverdue sets Overdue status to new and in progress tasks due before given time in single statement.
+// Only tasks with given ids are affected if any ids are passed.
+func (r *SQLRepository) MarkOverdue(ctx context.Context, before time.Time, ids ...uint64) ([]task.StatusChange, error) {
+	cond := "status IN ($2, $3) AND dueDate IS NOT NULL AND dueDate < $4 AND deleted_at IS NULL"
+	args := []interface{}{task.Overdue, task.New, task.InProgress, before}
+	if len(ids) > 0 {
+		cond += " AND id = ANY($5)"
+		args = append(args, idsArray(ids))
+	}
+	// previous status is selected before update as RETURNING only sees the new one
+	rows, err := r.conn.QueryContext(
+		ctx,
+		`WITH old AS (SELECT id, status FROM tasks WHERE `+cond+` FOR UPDATE)
+			UPDATE tasks SET status=$1, version=version+1 FROM old WHERE tasks.id=old.id
+			RETURNING tasks.id, old.status;`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var changes []task.StatusChange
+	for rows.Next() {
+		c := task.StatusChange{To: task.Overdue}
+		if err := rows.Scan(&c.ID, &c.From); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// Access returns permission of user to task with given id, returns error if task is not found
+func (r *SQLRepository) Access(ctx context.Context, id uint64, userID string) (task.Permission, error) {
+	var p task.Permission
+	err := r.conn.QueryRowContext(
+		ctx,
+		fmt.Sprintf(`SELECT CASE WHEN t.owner=$2 THEN %d ELSE COALESCE(s.permission, %d) END
+			FROM tasks t
+			LEFT JOIN task_shares s ON s.task_id=t.id AND s.user_id=$2
+			WHERE t.id=$1 AND t.deleted_at IS NULL;`, task.OwnerAccess, task.NoAccess),
+		id, userID,
+	).Scan(&p)
+	if err == sql.ErrNoRows {
+		return p, notFoundError(id)
+	}
+	return p, err
+}
+
+// ReadShares reads all shares of task with given id, returns error if task is not found
+func (r *SQLRepository) ReadShares(ctx context.Context, id uint64) ([]task.Share, error) {
+	if _, err := r.ReadOne(ctx, id); err != nil {
+		return nil, err
+	}
+	rows, err := r.conn.QueryContext(
+		ctx, "SELECT task_id, user_id, permission FROM task_shares WHERE task_id=$1 ORDER BY user_id;", id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	shares := make([]task.Share, 0)
+	for rows.Next() {
+		var s task.Share
+		if err := rows.Scan(&s.TaskID, &s.UserID, &s.Permission); err != nil {
+			return nil, err
+		}
+		shares = append(shares, s)
+	}
+	return shares, rows.Err()
+}
+
+// Readers returns owner of task with given id followed by users it is shared with, tasks in trash included
+func (r *SQLRepository) Readers(ctx context.Context, id uint64) ([]string, error) {
+	rows, err := r.conn.QueryContext(
+		ctx,
+		`SELECT t.owner, s.user_id
+			FROM tasks t
+			LEFT JOIN task_shares s ON s.task_id=t.id
+			WHERE t.id=$1
+			ORDER BY s.user_id;`,
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var users []string
+	for rows.Next() {
+		var owner string
+		var user sql.NullString
+		if err = rows.Scan(&owner, &user); err != nil {
+			return nil, err
+		}
+		if users == nil {
+			users = []string{owner}
+		}
+		if user.Valid {
+			users = append(users, user.String)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if users == nil {
+		return nil, notFoundError(id)
+	}
+	return users, nil
+}
+
+// Share grants user permission to task, returns error if task is not found
+func (r *SQLRepository) Share(ctx context.Context, share task.Share) error {
+	res, err := r.conn.ExecContext(
+		ctx,
+		`INSERT INTO task_shares (task_id, user_id, permission)
+			SELECT id, $2, $3 FROM tasks WHERE id=$1 AND deleted_at IS NULL
+			ON CONFLICT (task_id, user_id) DO UPDATE SET permission=EXCLUDED.permission;`,
+		share.TaskID, share.UserID, share.Permission,
+	)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if count == 0 {
+		return notFoundError(share.TaskID)
+	}
+	return err
+}
+
+// Unshare revokes permission granted to user, returns error if there is no such share
+func (r *SQLRepository) Unshare(ctx context.Context, id uint64, userID string) error {
+	res, err := r.conn.ExecContext(ctx, "DELETE FROM task_shares WHERE task_id=$1 AND user_id=$2;", id, userID)
+	if err != nil {
+		return err
+	}
+	count, err := res.RowsAffected()
+	if count == 0 {
+		return &common.NotFoundError{What: "Share of task with ID " + strconv.FormatUint(id, 10) + " with user " + userID}
+	}
+	return err
+}
+
+// SetParent makes task with given id subtask of parent, zero parent makes it top-level task
+func (r *SQLRepository) SetParent(ctx context.Context, id, parentID uint64) (task.Task, error) {
+	if parentID != 0 {
+		if _, err := r.ReadOne(ctx, parentID); err != nil {
+			return task.Task{}, err
+		}
+	}
+	t, err := scanTask(r.conn.QueryRowContext(
+		ctx,
+		"UPDATE tasks SET parent_id=$2, version=version+1 WHERE id=$1 AND deleted_at IS NULL RETURNING "+columns+";",
+		id, sql.NullInt64{Int64: int64(parentID), Valid: parentID != 0},
+	))
 	if err == sql.ErrNoRows {
 		return t, notFoundError(id)
 	}
 	return t, err
 }
 
-// Delete deletes task with given id, returns error if it is not found
-func (r *SQLRepository) Delete(ctx context.Context, id uint64) error {
-	res, err := r.db.ExecContext(ctx, "DELETE FROM tasks WHERE id=$1;", id)
+// ReadSubtasks reads subtasks of task with given id, returns error if task is not found
+func (r *SQLRepository) ReadSubtasks(ctx context.Context, id uint64) ([]task.Task, error) {
+	if _, err := r.ReadOne(ctx, id); err != nil {
+		return nil, err
+	}
+	return r.readTasks(ctx, "SELECT "+columns+" FROM tasks WHERE parent_id=$1 AND deleted_at IS NULL ORDER BY id;", id)
+}
+
+// ReadBlockers reads tasks blocking task with given id, returns error if task is not found
+func (r *SQLRepository) ReadBlockers(ctx context.Context, id uint64) ([]task.Task, error) {
+	if _, err := r.ReadOne(ctx, id); err != nil {
+		return nil, err
+	}
+	return r.readTasks(
+		ctx,
+		"SELECT "+columns+` FROM tasks
+			WHERE id IN (SELECT blocker_id FROM task_dependencies WHERE task_id=$1) AND deleted_at IS NULL ORDER BY id;`,
+		id,
+	)
+}
+
+// AddDependency makes task blocked by another one, returns error if any of them is not found
+func (r *SQLRepository) AddDependency(ctx context.Context, dep task.Dependency) error {
+	for _, id := range []uint64{dep.TaskID, dep.BlockerID} {
+		if _, err := r.ReadOne(ctx, id); err != nil {
+			return err
+		}
+	}
+	_, err := r.conn.ExecContext(
+		ctx,
+		`INSERT INTO task_dependencies (task_id, blocker_id) VALUES ($1, $2)
+			ON CONFLICT (task_id, blocker_id) DO NOTHING;`,
+		dep.TaskID, dep.BlockerID,
+	)
+	return err
+}
+
+// RemoveDependency returns error if there is no such dependency
+func (r *SQLRepository) RemoveDependency(ctx context.Context, dep task.Dependency) error {
+	res, err := r.conn.ExecContext(
+		ctx, "DELETE FROM task_dependencies WHERE task_id=$1 AND blocker_id=$2;", dep.TaskID, dep.BlockerID,
+	)
 	if err != nil {
 		return err
 	}
 	count, err := res.RowsAffected()
 	if count == 0 {
-		return notFoundError(id)
+		return &common.NotFoundError{What: fmt.Sprintf("Dependency of task with ID %d on task with ID %d", dep.TaskID, dep.BlockerID)}
+	}
+	return err
+}
+
+// ReadTags reads tags of tasks owned by or shared with user with number of such tasks having each tag
+func (r *SQLRepository) ReadTags(ctx context.Context, userID string) ([]task.TagCount, error) {
+	rows, err := r.conn.QueryContext(
+		ctx,
+		`SELECT tag, count(*) FROM task_tags
+			WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NULL
+				AND (owner=$1 OR id IN (SELECT task_id FROM task_shares WHERE user_id=$1)))
+			GROUP BY tag ORDER BY tag COLLATE "C";`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := make([]task.TagCount, 0)
+	for rows.Next() {
+		var tc task.TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tc)
+	}
+	return tags, rows.Err()
+}
+
+// MergeTags replaces given tags with `into` tag on tasks owned by given user, returns ids of changed tasks
+func (r *SQLRepository) MergeTags(ctx context.Context, ownerID string, tags []string, into string) ([]uint64, error) {
+	var changed []uint64
+	err := r.inTx(ctx, func(tx *sql.Tx) error {
+		merged := textArray(tags)
+		rows, err := tx.QueryContext(
+			ctx,
+			`UPDATE tasks SET version=version+1
+				WHERE owner=$1 AND id IN (SELECT task_id FROM task_tags WHERE tag = ANY($2::varchar[]) AND tag<>$3)
+				RETURNING id;`,
+			ownerID, merged, into,
+		)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id uint64
+			if err = rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			changed = append(changed, id)
+		}
+		rows.Close()
+		if err = rows.Err(); err != nil || len(changed) == 0 {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO task_tags (task_id, tag)
+				SELECT DISTINCT tt.task_id, $3 FROM task_tags tt JOIN tasks t ON t.id=tt.task_id
+				WHERE t.owner=$1 AND tt.tag = ANY($2::varchar[])
+				ON CONFLICT (task_id, tag) DO NOTHING;`,
+			ownerID, merged, into,
+		)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(
+			ctx,
+			`DELETE FROM task_tags tt USING tasks t
+				WHERE t.id=tt.task_id AND t.owner=$1 AND tt.tag = ANY($2::varchar[]) AND tt.tag<>$3;`,
+			ownerID, merged, into,
+		)
+		return err
+	})
+	return changed, err
+}
+
+// AppendEvents adds events to history of tasks, assigning IDs to given events
+func (r *SQLRepository) AppendEvents(ctx context.Context, events ...task.Event) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		for i, e := range events {
+			err := tx.QueryRowContext(
+				ctx,
+				`INSERT INTO task_events (task_id, type, actor, field, from_value, to_value, text, created_at)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+					RETURNING id;`,
+				e.TaskID, e.Type, e.Actor, e.Field, e.From, e.To, e.Text, e.CreatedAt,
+			).Scan(&events[i].ID)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ReadEvents reads `count` events of task with given id starting from `from`, returns error if task is not found
+func (r *SQLRepository) ReadEvents(ctx context.Context, id uint64, from, count uint) ([]task.Event, error) {
+	if _, err := r.ReadOne(ctx, id); err != nil {
+		return nil, err
+	}
+	stmt := fmt.Sprintf(
+		`SELECT id, task_id, type, actor, field, from_value, to_value, text, created_at
+			FROM task_events WHERE task_id=$1 ORDER BY id OFFSET %d`, from,
+	)
+	if count > 0 {
+		stmt += fmt.Sprintf(" LIMIT %d", count)
+	}
+	rows, err := r.conn.QueryContext(ctx, stmt+";", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := make([]task.Event, 0)
+	for rows.Next() {
+		var e task.Event
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.Type, &e.Actor, &e.Field, &e.From, &e.To, &e.Text, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// InTransaction runs f with repository running statements in transaction which is committed if f succeeds
+// and is rolled back otherwise. Transaction started inside another one is a savepoint of the outer transaction.
+func (r *SQLRepository) InTransaction(ctx context.Context, f func(task.Repository) error) error {
+	return r.inTx(ctx, func(tx *sql.Tx) error {
+		return f(&SQLRepository{db: r.db, conn: tx, tx: tx})
+	})
+}
+
+// inTx runs f in transaction which is committed if f succeeds and is rolled back otherwise.
+// Inside transaction f is run in savepoint, so its changes are rolled back separately.
+func (r *SQLRepository) inTx(ctx context.Context, f func(tx *sql.Tx) error) error {
+	if r.tx != nil {
+		return inSavepoint(ctx, r.tx, f)
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// inSavepoint runs f in savepoint of transaction, PostgreSQL allows nesting savepoints with the same name
+// and refers to the latest one on release and rollback
+func inSavepoint(ctx context.Context, tx *sql.Tx, f func(tx *sql.Tx) error) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT nested;"); err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT nested;"); rbErr != nil {
+			return rbErr
+		}
+		// savepoint is kept after rollback to it
+		tx.ExecContext(ctx, "RELEASE SAVEPOINT nested;")
+		return err
 	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT nested;")
 	return err
 }
 
-func makeReadStatement(from, count uint) string {
+// setTags replaces all tags of task with given id
+func setTags(ctx context.Context, tx *sql.Tx, id uint64, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM task_tags WHERE task_id=$1;", id); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO task_tags (task_id, tag) VALUES ($1, $2);", id, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *SQLRepository) readTasks(ctx context.Context, stmt string, args ...interface{}) ([]task.Task, error) {
+	rows, err := r.conn.QueryContext(ctx, stmt, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tasks := make([]task.Task, 0)
+	for rows.Next() {
+		t, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, t)
+	}
+	return tasks, rows.Err()
+}
+
+// idsArray formats ids as PostgreSQL array literal
+func idsArray(ids []uint64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(id, 10)
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// textArray formats strings as PostgreSQL array literal
+func textArray(values []string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = `"` + arrayEscaper.Replace(v) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+var arrayEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTask(row scanner) (task.Task, error) {
+	var t task.Task
+	var due sql.NullTime
+	var desc sql.NullString
+	var parent sql.NullInt64
+	var rule sql.NullString
+	var deleted sql.NullTime
+	var tags string
+	err := row.Scan(
+		&t.ID, &t.Name, &desc, &due, &t.Status, &t.OwnerID, &t.Version, &parent, &rule, &deleted, &tags,
+		&t.Progress.Total, &t.Progress.Done,
+	)
+	if err != nil {
+		return t, err
+	}
+	t.Description = desc.String
+	t.ParentID = uint64(parent.Int64)
+	if due.Valid {
+		t.DueDate = due.Time
+	}
+	if deleted.Valid {
+		t.DeletedAt = deleted.Time
+	}
+	if tags != "" {
+		t.Tags = strings.Split(tags, ",")
+	}
+	t.Recurrence, err = task.ParseRecurrence(rule.String)
+	return t, err
+}
+
+// nullRecurrence stores rule of task that does not repeat as NULL
+func nullRecurrence(r task.Recurrence) sql.NullString {
+	return sql.NullString{String: r.String(), Valid: !r.IsZero()}
+}
+
+// nullTime stores zero time as NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+var sortColumns = map[task.SortField]string{
+	task.SortByID:      "id",
+	task.SortByDueDate: "dueDate", // NULLs go last in ascending order as in task.Query.Less
+	task.SortByName:    `name COLLATE "C"`,
+}
+
+// makeReadStatement builds parameterized statement reading tasks visible to user and matching query
+func makeReadStatement(userID string, query task.Query, from, count uint) (string, []interface{}) {
+	args := []interface{}{userID}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	conds := []string{"deleted_at IS NULL", "(owner=$1 OR id IN (SELECT task_id FROM task_shares WHERE user_id=$1))"}
+	if len(query.Statuses) > 0 {
+		statuses := make([]uint64, len(query.Statuses))
+		for i, st := range query.Statuses {
+			statuses[i] = uint64(st)
+		}
+		conds = append(conds, "status = ANY("+arg(idsArray(statuses))+"::integer[])")
+	}
+	if !query.DueFrom.IsZero() {
+		conds = append(conds, "dueDate >= "+arg(query.DueFrom))
+	}
+	if !query.DueTo.IsZero() {
+		conds = append(conds, "dueDate <= "+arg(query.DueTo))
+	}
+	if query.Search != "" {
+		p := arg("%" + likeEscaper.Replace(query.Search) + "%")
+		conds = append(conds, "(name ILIKE "+p+" OR description ILIKE "+p+")")
+	}
+	if len(query.Tags) > 0 {
+		tags := arg(textArray(query.Tags))
+		if query.AllTags {
+			conds = append(conds, fmt.Sprintf(
+				"(SELECT count(*) FROM task_tags WHERE task_id=tasks.id AND tag = ANY(%s::varchar[])) = %d", tags, len(query.Tags),
+			))
+		} else {
+			conds = append(conds, "id IN (SELECT task_id FROM task_tags WHERE tag = ANY("+tags+"::varchar[]))")
+		}
+	}
+	dir := "ASC"
+	if query.Descending {
+		dir = "DESC"
+	}
+	stmt := fmt.Sprintf(
+		"SELECT %s FROM tasks WHERE %s ORDER BY %s %s, id %s OFFSET %d",
+		columns, strings.Join(conds, " AND "), sortColumns[query.SortBy], dir, dir, from,
+	)
 	if count == 0 {
-		return fmt.Sprintf("SELECT * FROM tasks OFFSET %d;", from)
+		return stmt + ";", args
 	}
-	return fmt.Sprintf("SELECT * FROM tasks OFFSET %d LIMIT %d;", from, count)
+	return fmt.Sprintf("%s LIMIT %d;", stmt, count), args
 }
 
 func notFoundError(id uint64) *common.NotFoundError {
 	return &common.NotFoundError{What: "Task with ID " + strconv.FormatUint(id, 10)}
 }
+
+func versionMismatchError(id, expected, actual uint64) *common.VersionMismatchError {
+	return &common.VersionMismatchError{What: "Task with ID " + strconv.FormatUint(id, 10), Expected: expected, Actual: actual}
+}
@@ -0,0 +1,75 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Vesninovich/go-tasks/todos/common"
+)
+
+// MaxTagLength limits length of tags
+const MaxTagLength = 64
+
+// MaxTags limits number of tags of single Task
+const MaxTags = 32
+
+// TagCount is a tag with number of tasks having it
+type TagCount struct {
+	Tag   string
+	Count uint64
+}
+
+// TagExistsError represents rejected rename of tag to name of another existing tag
+type TagExistsError struct {
+	Tag string
+}
+
+func (e *TagExistsError) Error() string {
+	return fmt.Sprintf("Tag %s already exists, tags may be merged instead", e.Tag)
+}
+
+// NormalizeTag trims and lowercases tag, returns error if it is empty, too long or contains commas or slashes
+func NormalizeTag(tag string) (string, error) {
+	t := strings.ToLower(strings.TrimSpace(tag))
+	switch {
+	case t == "":
+		return t, &common.InvalidInputError{Reason: "tag must not be empty"}
+	case len(t) > MaxTagLength:
+		return t, &common.InvalidInputError{Reason: fmt.Sprintf("tag must be at most %d characters long", MaxTagLength)}
+	case strings.ContainsAny(t, ",/"):
+		return t, &common.InvalidInputError{Reason: fmt.Sprintf(`tag "%s" must not contain commas and slashes`, tag)}
+	}
+	return t, nil
+}
+
+// NormalizeTags normalizes each tag, removes duplicates and sorts them
+func NormalizeTags(tags []string) ([]string, error) {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		t, err := NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[t] {
+			seen[t] = true
+			normalized = append(normalized, t)
+		}
+	}
+	if len(normalized) > MaxTags {
+		return nil, &common.InvalidInputError{Reason: fmt.Sprintf("task may have at most %d tags", MaxTags)}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// HasTag reports whether Task has given tag
+func (t Task) HasTag(tag string) bool {
+	for _, tg := range t.Tags {
+		if tg == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -24,8 +24,37 @@ type Task struct {
 	ID          uint64
 	Name        string
 	Description string
-	DueDate     time.Time
-	Status      Status
+	// DueDate is zero if task has no due date
+	DueDate time.Time
+	Status  Status
+	OwnerID string
+	// Version is incremented on every change of Task and is used to detect concurrent changes
+	Version uint64
+	// ParentID is zero if task is not a subtask
+	ParentID uint64
+	// Progress of subtasks, it is computed on read and is not stored
+	Progress Progress
+	// Recurrence is zero if task does not repeat
+	Recurrence Recurrence
+	// Tags are normalized and sorted
+	Tags []string
+	// DeletedAt is zero unless task is in trash
+	DeletedAt time.Time
+}
+
+// HasDueDate reports whether due date is set for the Task
+func (t Task) HasDueDate() bool {
+	return !t.DueDate.IsZero()
+}
+
+// IsDeleted reports whether Task is in trash
+func (t Task) IsDeleted() bool {
+	return !t.DeletedAt.IsZero()
+}
+
+// IsOverdueAt reports whether Task that is not finished yet should become Overdue at given time
+func (t Task) IsOverdueAt(at time.Time) bool {
+	return (t.Status == New || t.Status == InProgress) && t.HasDueDate() && t.DueDate.Before(at)
 }
 
 func (s Status) String() string {
@@ -0,0 +1,35 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TransitionError represents rejected change of Task status
+type TransitionError struct {
+	ID      uint64
+	From    Status
+	To      Status
+	Allowed []Status
+	// Reason explains why transition that is allowed in general is rejected for the task, may be empty
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	allowed := make([]string, len(e.Allowed))
+	for i, s := range e.Allowed {
+		allowed[i] = s.String()
+	}
+	list := strings.Join(allowed, ", ")
+	if list == "" {
+		list = "none"
+	}
+	reason := ""
+	if e.Reason != "" {
+		reason = " (" + e.Reason + ")"
+	}
+	return fmt.Sprintf(
+		"Status of task with ID %d can not be changed from %s to %s%s, allowed transitions: %s",
+		e.ID, e.From, e.To, reason, list,
+	)
+}
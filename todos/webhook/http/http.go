@@ -0,0 +1,205 @@
+package webhookhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Vesninovich/go-tasks/book-store/common/auth"
+	"github.com/Vesninovich/go-tasks/book-store/common/commonerrors"
+	"github.com/Vesninovich/go-tasks/book-store/common/webhook"
+)
+
+// HTTPServer serves requests for webhook subscriptions of user
+type HTTPServer struct {
+	dispatcher *webhook.Dispatcher
+}
+
+// New creates new instance of HTTPServer
+func New(d *webhook.Dispatcher) *HTTPServer {
+	return &HTTPServer{d}
+}
+
+// GetWebhooks serves requests to list subscriptions of user, their secrets are not sent
+func (s *HTTPServer) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := s.dispatcher.Subscriptions(r.Context(), caller(r))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	res := make([]subscriptionAPIModel, len(subs))
+	for i, sub := range subs {
+		res[i] = subscriptionToAPIModel(sub)
+		res[i].Secret = ""
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// PostWebhook serves requests to subscribe URL to events of tasks user may read.
+// Secret is generated if it is not set, it is sent only in response to this request.
+func (s *HTTPServer) PostWebhook(w http.ResponseWriter, r *http.Request) {
+	var data createSubscriptionAPIModel
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	sub, err := s.dispatcher.Subscribe(r.Context(), webhook.Subscription{
+		Owner:  caller(r),
+		URL:    data.URL,
+		Events: data.Events,
+		Secret: data.Secret,
+	})
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, subscriptionToAPIModel(sub))
+}
+
+// DeleteWebhook serves requests to delete subscription of user by id with its deliveries
+func (s *HTTPServer) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(r.URL.Path)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err = s.dispatcher.Unsubscribe(r.Context(), caller(r), id); err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetDeliveries serves requests to read log of deliveries of subscription with their attempts, the latest first.
+// Deliveries are paginated with `from` and `count` query params.
+func (s *HTTPServer) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, err := getIDFromURL(strings.TrimSuffix(r.URL.Path, "/deliveries"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	from, count, err := parsePaginationQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ds, err := s.dispatcher.Deliveries(r.Context(), caller(r), id, uint(from), uint(count))
+	if err != nil {
+		writeServiceError(w, err)
+		return
+	}
+	res := make([]deliveryAPIModel, len(ds))
+	for i, d := range ds {
+		res[i] = deliveryToAPIModel(d)
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// caller returns ID of user making request, empty if request is not authenticated
+func caller(r *http.Request) string {
+	p, _ := auth.FromContext(r.Context())
+	return p.Subject
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	res, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(res)
+}
+
+// writeServiceError maps errors of shared webhook package, they are not of todos error types
+func writeServiceError(w http.ResponseWriter, err error) {
+	switch err.(type) {
+	case *commonerrors.InvalidInput:
+		writeError(w, http.StatusBadRequest, err)
+	case *commonerrors.NotFound:
+		writeError(w, http.StatusNotFound, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Add("Content-Type", "text/plain")
+	w.WriteHeader(status)
+	w.Write([]byte(err.Error()))
+}
+
+func getIDFromURL(url string) (uint64, error) {
+	parts := strings.Split(url, "/")
+	return strconv.ParseUint(parts[len(parts)-1], 10, 64)
+}
+
+func parsePaginationQuery(r *http.Request) (from uint64, count uint64, err error) {
+	if f := r.URL.Query().Get("from"); f != "" {
+		if from, err = strconv.ParseUint(f, 10, 64); err != nil {
+			return
+		}
+	}
+	if c := r.URL.Query().Get("count"); c != "" {
+		count, err = strconv.ParseUint(c, 10, 64)
+	}
+	return
+}
+
+type createSubscriptionAPIModel struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+type subscriptionAPIModel struct {
+	ID     uint64   `json:"id"`
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret,omitempty"`
+	// CreatedAt is Unix time of subscription
+	CreatedAt int64 `json:"createdAt"`
+}
+
+type deliveryAPIModel struct {
+	ID       uint64            `json:"id"`
+	Event    string            `json:"event"`
+	Status   string            `json:"status"`
+	Payload  json.RawMessage   `json:"payload"`
+	Attempts []attemptAPIModel `json:"attempts"`
+	// NextAttemptAt is Unix time of the next attempt of pending delivery
+	NextAttemptAt int64 `json:"nextAttemptAt,omitempty"`
+	CreatedAt     int64 `json:"createdAt"`
+}
+
+type attemptAPIModel struct {
+	StatusCode int    `json:"statusCode,omitempty"`
+	Error      string `json:"error,omitempty"`
+	// DurationMs is duration of request in milliseconds
+	DurationMs int64 `json:"durationMs"`
+	Time       int64 `json:"time"`
+}
+
+func subscriptionToAPIModel(s webhook.Subscription) subscriptionAPIModel {
+	return subscriptionAPIModel{s.ID, s.URL, s.Events, s.Secret, s.CreatedAt.Unix()}
+}
+
+func deliveryToAPIModel(d webhook.Delivery) deliveryAPIModel {
+	m := deliveryAPIModel{
+		ID:        d.ID,
+		Event:     d.Event,
+		Status:    string(d.Status),
+		Payload:   d.Payload,
+		Attempts:  make([]attemptAPIModel, len(d.Attempts)),
+		CreatedAt: d.CreatedAt.Unix(),
+	}
+	for i, a := range d.Attempts {
+		m.Attempts[i] = attemptAPIModel{a.StatusCode, a.Error, a.Duration.Milliseconds(), a.CreatedAt.Unix()}
+	}
+	if d.Status == webhook.Pending {
+		m.NextAttemptAt = d.NextAttemptAt.Unix()
+	}
+	return m
+}
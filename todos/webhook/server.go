@@ -0,0 +1,11 @@
+package webhook
+
+import "net/http"
+
+// WebhooksServer interface represents objects that serve HTTP requests for webhook subscriptions
+type WebhooksServer interface {
+	GetWebhooks(w http.ResponseWriter, r *http.Request)
+	PostWebhook(w http.ResponseWriter, r *http.Request)
+	DeleteWebhook(w http.ResponseWriter, r *http.Request)
+	GetDeliveries(w http.ResponseWriter, r *http.Request)
+}